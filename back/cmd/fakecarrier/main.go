@@ -0,0 +1,23 @@
+// Тестовый перевозчик для разработки: go run ./cmd/fakecarrier -addr :8091 -step 1m
+// и в .env сервера CARRIER_NAME=fake, CARRIER_API_URL=http://localhost:8091.
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/lamoda-seller-app/internal/carrier"
+)
+
+func main() {
+	addr := flag.String("addr", ":8091", "адрес, на котором слушает тестовый перевозчик")
+	step := flag.Duration("step", time.Minute, "через сколько посылка переходит на следующий этап доставки")
+	flag.Parse()
+
+	log.Printf("🚚 Тестовый перевозчик слушает %s, шаг доставки %s", *addr, *step)
+	if err := http.ListenAndServe(*addr, carrier.NewFakeServer(*step)); err != nil {
+		log.Fatalf("❌ Fake carrier failed: %v", err)
+	}
+}
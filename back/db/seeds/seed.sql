@@ -70,10 +70,10 @@ VALUES (
 RAISE NOTICE 'Создан/обновлен пользователь % (%) с балансом % копеек', user1_name, user1_email, user1_balance;
 
 -- === 2. ПОСТАВЩИКИ ===
-INSERT INTO suppliers (id, name, contact) VALUES
-(supplier_zara_id, 'ZARA Distribution', 'supply@zara.com'),
-(supplier_hm_id, 'H&M Logistics', 'logistics@hm.com'),
-(supplier_nike_id, 'Nike Europe', 'contact@nike.com')
+INSERT INTO suppliers (id, name, contact, user_id) VALUES
+(supplier_zara_id, 'ZARA Distribution', 'supply@zara.com', user1_id),
+(supplier_hm_id, 'H&M Logistics', 'logistics@hm.com', user1_id),
+(supplier_nike_id, 'Nike Europe', 'contact@nike.com', user1_id)
 ON CONFLICT (id) DO NOTHING;
 
 -- === 3. ПРОДУКТЫ ===
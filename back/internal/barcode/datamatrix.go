@@ -0,0 +1,354 @@
+package barcode
+
+import "errors"
+
+// Matrix - двумерный штрихкод. Cells[row][col], true - темный модуль.
+type Matrix struct {
+	Cells [][]bool
+	Quiet int // Минимальная свободная зона вокруг символа, в модулях
+}
+
+// Size возвращает количество модулей по вертикали и горизонтали.
+func (m *Matrix) Size() (rows, cols int) {
+	if len(m.Cells) == 0 {
+		return 0, 0
+	}
+	return len(m.Cells), len(m.Cells[0])
+}
+
+// ErrDataMatrixCapacity - данные не помещаются в квадратный символ Data Matrix
+var ErrDataMatrixCapacity = errors.New("data is too long for Data Matrix")
+
+// dmSymbol - параметры квадратного символа Data Matrix ECC 200
+type dmSymbol struct {
+	size    int // Модулей по стороне
+	regions int // Областей данных по стороне
+	data    int // Кодовых слов данных
+	ecc     int // Кодовых слов коррекции
+	blocks  int // Блоков Рида-Соломона (чередуются)
+}
+
+var dmSymbols = []dmSymbol{
+	{10, 1, 3, 5, 1}, {12, 1, 5, 7, 1}, {14, 1, 8, 10, 1}, {16, 1, 12, 12, 1},
+	{18, 1, 18, 14, 1}, {20, 1, 22, 18, 1}, {22, 1, 30, 20, 1}, {24, 1, 36, 24, 1},
+	{26, 1, 44, 28, 1}, {32, 2, 62, 36, 1}, {36, 2, 86, 42, 1}, {40, 2, 114, 48, 1},
+	{44, 2, 144, 56, 1}, {48, 2, 174, 68, 1}, {52, 2, 204, 84, 2}, {64, 4, 280, 112, 2},
+	{72, 4, 368, 144, 4}, {80, 4, 456, 192, 4}, {88, 4, 576, 224, 4}, {96, 4, 696, 272, 4},
+	{104, 4, 816, 336, 6}, {120, 6, 1050, 408, 6}, {132, 6, 1304, 496, 8},
+}
+
+const (
+	dmPad        = 129
+	dmFNC1       = 232
+	dmUpperShift = 235
+)
+
+// EncodeDataMatrix кодирует данные символом Data Matrix ECC 200 (режим ASCII, квадратные символы).
+func EncodeDataMatrix(data []byte) (*Matrix, error) {
+	return encodeDataMatrix(dmEncodeASCII(data, false))
+}
+
+// EncodeGS1DataMatrix кодирует строку GS1 (например, код маркировки "Честного ЗНАКа"):
+// символ начинается с FNC1, разделители групп передаются символом GS.
+func EncodeGS1DataMatrix(data string) (*Matrix, error) {
+	return encodeDataMatrix(dmEncodeASCII([]byte(data), true))
+}
+
+func dmEncodeASCII(data []byte, gs1 bool) []byte {
+	var cw []byte
+	if gs1 {
+		cw = append(cw, dmFNC1)
+	}
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		switch {
+		case isDigit(c) && i+1 < len(data) && isDigit(data[i+1]):
+			cw = append(cw, 130+(c-'0')*10+(data[i+1]-'0'))
+			i++
+		case c < 128:
+			cw = append(cw, c+1)
+		default:
+			cw = append(cw, dmUpperShift, c-127)
+		}
+	}
+	return cw
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+func encodeDataMatrix(codewords []byte) (*Matrix, error) {
+	var symbol *dmSymbol
+	for i := range dmSymbols {
+		if dmSymbols[i].data >= len(codewords) {
+			symbol = &dmSymbols[i]
+			break
+		}
+	}
+	if symbol == nil {
+		return nil, ErrDataMatrixCapacity
+	}
+
+	// Заполнение: первое слово 129, следующие псевдослучайные в зависимости от позиции
+	if len(codewords) < symbol.data {
+		codewords = append(codewords, dmPad)
+	}
+	for len(codewords) < symbol.data {
+		pos := len(codewords) + 1
+		v := dmPad + (149*pos)%253 + 1
+		if v > 254 {
+			v -= 254
+		}
+		codewords = append(codewords, byte(v))
+	}
+
+	all := dmAddECC(codewords, symbol)
+	return dmPlace(all, symbol), nil
+}
+
+// --- Коррекция ошибок Рида-Соломона в GF(256), образующий полином 301 ---
+
+var dmExp, dmLog [256]int
+
+func init() {
+	x := 1
+	for i := 0; i < 255; i++ {
+		dmExp[i] = x
+		dmLog[x] = i
+		x <<= 1
+		if x >= 256 {
+			x ^= 301
+		}
+	}
+	dmExp[255] = dmExp[0]
+}
+
+func dmMul(a, b int) int {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return dmExp[(dmLog[a]+dmLog[b])%255]
+}
+
+// dmGenerator возвращает коэффициенты порождающего полинома (x - a^1)...(x - a^n) без старшего.
+func dmGenerator(n int) []int {
+	poly := make([]int, n+1)
+	poly[0] = 1
+	for i := 1; i <= n; i++ {
+		for j := i; j >= 1; j-- {
+			poly[j] = poly[j-1] ^ dmMul(poly[j], dmExp[i])
+		}
+		poly[0] = dmMul(poly[0], dmExp[i])
+	}
+	return poly
+}
+
+func dmReedSolomon(data []byte, n int) []byte {
+	gen := dmGenerator(n)
+	ecc := make([]int, n)
+	for _, d := range data {
+		m := int(d) ^ ecc[n-1]
+		for k := n - 1; k > 0; k-- {
+			ecc[k] = ecc[k-1] ^ dmMul(m, gen[k])
+		}
+		ecc[0] = dmMul(m, gen[0])
+	}
+	out := make([]byte, n)
+	for i := 0; i < n; i++ {
+		out[i] = byte(ecc[n-1-i])
+	}
+	return out
+}
+
+// dmAddECC дописывает кодовые слова коррекции; при нескольких блоках данные и коррекция чередуются.
+func dmAddECC(data []byte, symbol *dmSymbol) []byte {
+	out := make([]byte, symbol.data+symbol.ecc)
+	copy(out, data)
+	eccPerBlock := symbol.ecc / symbol.blocks
+	for b := 0; b < symbol.blocks; b++ {
+		var block []byte
+		for i := b; i < symbol.data; i += symbol.blocks {
+			block = append(block, data[i])
+		}
+		ecc := dmReedSolomon(block, eccPerBlock)
+		for j, e := range ecc {
+			out[symbol.data+j*symbol.blocks+b] = e
+		}
+	}
+	return out
+}
+
+// --- Размещение модулей (ISO/IEC 16022, приложение F) ---
+
+type dmPlacement struct {
+	nrow, ncol int
+	bits       []int // 0 - не занято, 1 - темный модуль угла, иначе 10*номер слова + номер бита
+}
+
+func (p *dmPlacement) module(row, col, chr, bit int) {
+	if row < 0 {
+		row += p.nrow
+		col += 4 - ((p.nrow + 4) % 8)
+	}
+	if col < 0 {
+		col += p.ncol
+		row += 4 - ((p.ncol + 4) % 8)
+	}
+	p.bits[row*p.ncol+col] = 10*chr + bit
+}
+
+func (p *dmPlacement) utah(row, col, chr int) {
+	p.module(row-2, col-2, chr, 1)
+	p.module(row-2, col-1, chr, 2)
+	p.module(row-1, col-2, chr, 3)
+	p.module(row-1, col-1, chr, 4)
+	p.module(row-1, col, chr, 5)
+	p.module(row, col-2, chr, 6)
+	p.module(row, col-1, chr, 7)
+	p.module(row, col, chr, 8)
+}
+
+func (p *dmPlacement) corner1(chr int) {
+	p.module(p.nrow-1, 0, chr, 1)
+	p.module(p.nrow-1, 1, chr, 2)
+	p.module(p.nrow-1, 2, chr, 3)
+	p.module(0, p.ncol-2, chr, 4)
+	p.module(0, p.ncol-1, chr, 5)
+	p.module(1, p.ncol-1, chr, 6)
+	p.module(2, p.ncol-1, chr, 7)
+	p.module(3, p.ncol-1, chr, 8)
+}
+
+func (p *dmPlacement) corner2(chr int) {
+	p.module(p.nrow-3, 0, chr, 1)
+	p.module(p.nrow-2, 0, chr, 2)
+	p.module(p.nrow-1, 0, chr, 3)
+	p.module(0, p.ncol-4, chr, 4)
+	p.module(0, p.ncol-3, chr, 5)
+	p.module(0, p.ncol-2, chr, 6)
+	p.module(0, p.ncol-1, chr, 7)
+	p.module(1, p.ncol-1, chr, 8)
+}
+
+func (p *dmPlacement) corner3(chr int) {
+	p.module(p.nrow-3, 0, chr, 1)
+	p.module(p.nrow-2, 0, chr, 2)
+	p.module(p.nrow-1, 0, chr, 3)
+	p.module(0, p.ncol-2, chr, 4)
+	p.module(0, p.ncol-1, chr, 5)
+	p.module(1, p.ncol-1, chr, 6)
+	p.module(2, p.ncol-1, chr, 7)
+	p.module(3, p.ncol-1, chr, 8)
+}
+
+func (p *dmPlacement) corner4(chr int) {
+	p.module(p.nrow-1, 0, chr, 1)
+	p.module(p.nrow-1, p.ncol-1, chr, 2)
+	p.module(0, p.ncol-3, chr, 3)
+	p.module(0, p.ncol-2, chr, 4)
+	p.module(0, p.ncol-1, chr, 5)
+	p.module(1, p.ncol-3, chr, 6)
+	p.module(1, p.ncol-2, chr, 7)
+	p.module(1, p.ncol-1, chr, 8)
+}
+
+func (p *dmPlacement) place() {
+	nrow, ncol := p.nrow, p.ncol
+	row, col, chr := 4, 0, 1
+	for {
+		if row == nrow && col == 0 {
+			p.corner1(chr)
+			chr++
+		}
+		if row == nrow-2 && col == 0 && ncol%4 != 0 {
+			p.corner2(chr)
+			chr++
+		}
+		if row == nrow-2 && col == 0 && ncol%8 == 4 {
+			p.corner3(chr)
+			chr++
+		}
+		if row == nrow+4 && col == 2 && ncol%8 == 0 {
+			p.corner4(chr)
+			chr++
+		}
+		// Диагональ вверх-вправо
+		for {
+			if row < nrow && col >= 0 && p.bits[row*ncol+col] == 0 {
+				p.utah(row, col, chr)
+				chr++
+			}
+			row -= 2
+			col += 2
+			if row < 0 || col >= ncol {
+				break
+			}
+		}
+		row++
+		col += 3
+		// Диагональ вниз-влево
+		for {
+			if row >= 0 && col < ncol && p.bits[row*ncol+col] == 0 {
+				p.utah(row, col, chr)
+				chr++
+			}
+			row += 2
+			col -= 2
+			if row >= nrow || col < 0 {
+				break
+			}
+		}
+		row += 3
+		col++
+		if row >= nrow && col >= ncol {
+			break
+		}
+	}
+	if p.bits[nrow*ncol-1] == 0 {
+		p.bits[nrow*ncol-1] = 1
+		p.bits[nrow*ncol-ncol-2] = 1
+	}
+}
+
+// dmPlace размещает кодовые слова в областях данных и добавляет шаблоны поиска вокруг каждой области.
+func dmPlace(codewords []byte, symbol *dmSymbol) *Matrix {
+	region := symbol.size/symbol.regions - 2
+	p := &dmPlacement{nrow: region * symbol.regions, ncol: region * symbol.regions}
+	p.bits = make([]int, p.nrow*p.ncol)
+	p.place()
+
+	cells := make([][]bool, symbol.size)
+	for i := range cells {
+		cells[i] = make([]bool, symbol.size)
+	}
+
+	// Шаблоны поиска: сплошные левая и нижняя стороны, пунктир сверху и справа
+	block := region + 2
+	for r := 0; r < symbol.size; r++ {
+		for c := 0; c < symbol.size; c++ {
+			br, bc := r%block, c%block
+			switch {
+			case bc == 0 || br == block-1:
+				cells[r][c] = true
+			case br == 0:
+				cells[r][c] = bc%2 == 0
+			case bc == block-1:
+				cells[r][c] = br%2 == 1
+			}
+		}
+	}
+
+	for i := 0; i < p.nrow; i++ {
+		for j := 0; j < p.ncol; j++ {
+			v := p.bits[i*p.ncol+j]
+			dark := v == 1
+			if v >= 10 {
+				cw := codewords[v/10-1]
+				dark = cw&(1<<(8-v%10)) != 0
+			}
+			r := i/region*block + 1 + i%region
+			c := j/region*block + 1 + j%region
+			cells[r][c] = dark
+		}
+	}
+	return &Matrix{Cells: cells, Quiet: 1}
+}
@@ -0,0 +1,65 @@
+package barcode
+
+import (
+	"bytes"
+	"testing"
+)
+
+// Пример из ISO/IEC 16022, приложение O: "123456" в символе 10x10
+func TestDataMatrixISOVector(t *testing.T) {
+	data := dmEncodeASCII([]byte("123456"), false)
+	if want := []byte{142, 164, 186}; !bytes.Equal(data, want) {
+		t.Fatalf("data codewords = %v, want %v", data, want)
+	}
+	ecc := dmReedSolomon(data, 5)
+	if want := []byte{114, 25, 5, 88, 102}; !bytes.Equal(ecc, want) {
+		t.Fatalf("ECC codewords = %v, want %v", ecc, want)
+	}
+}
+
+func TestDataMatrixASCII(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		gs1  bool
+		want []byte
+	}{
+		{name: "digit pairs and odd tail", data: "12345", want: []byte{142, 164, '5' + 1}},
+		{name: "letters", data: "Ab", want: []byte{'A' + 1, 'b' + 1}},
+		{name: "extended ASCII", data: "\xe9", want: []byte{dmUpperShift, 0xe9 - 127}},
+		{name: "GS1 starts with FNC1", data: "0104\x1d91", gs1: true, want: []byte{dmFNC1, 131, 134, 0x1d + 1, 221}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := dmEncodeASCII([]byte(tt.data), tt.gs1); !bytes.Equal(got, tt.want) {
+				t.Errorf("dmEncodeASCII(%q) = %v, want %v", tt.data, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodeDataMatrixFinderPattern(t *testing.T) {
+	m, err := EncodeDataMatrix([]byte("123456"))
+	if err != nil {
+		t.Fatalf("EncodeDataMatrix: %v", err)
+	}
+	rows, cols := m.Size()
+	if rows != 10 || cols != 10 {
+		t.Fatalf("size = %dx%d, want 10x10", rows, cols)
+	}
+	for i := 0; i < rows; i++ {
+		// Сплошная граница слева и снизу, пунктир сверху и справа
+		if !m.Cells[i][0] || !m.Cells[rows-1][i] {
+			t.Fatalf("solid finder border broken at %d", i)
+		}
+		if m.Cells[0][i] != (i%2 == 0) || m.Cells[i][cols-1] != (i%2 == 1) {
+			t.Fatalf("alternating finder border broken at %d", i)
+		}
+	}
+}
+
+func TestEncodeDataMatrixCapacity(t *testing.T) {
+	if _, err := EncodeDataMatrix(bytes.Repeat([]byte("a"), 1559)); err != ErrDataMatrixCapacity {
+		t.Errorf("error = %v, want %v", err, ErrDataMatrixCapacity)
+	}
+}
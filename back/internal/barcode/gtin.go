@@ -0,0 +1,116 @@
+// Package barcode кодирует штрихкоды EAN-13, Code 128 и Data Matrix и проверяет номера GTIN.
+package barcode
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrGTINFormat     = errors.New("GTIN must contain 8, 12, 13 or 14 digits")
+	ErrGTINCheckDigit = errors.New("GTIN check digit is invalid")
+	ErrGS1Prefix      = errors.New("GS1 company prefix must contain 6 to 11 digits")
+	ErrGS1Range       = errors.New("GS1 prefix range is exhausted")
+)
+
+// CheckDigit вычисляет контрольную цифру GTIN для номера без нее (веса 3 и 1 справа налево).
+func CheckDigit(body string) (byte, error) {
+	if !digitsOnly(body) {
+		return 0, ErrGTINFormat
+	}
+	sum := 0
+	for i := 0; i < len(body); i++ {
+		d := int(body[len(body)-1-i] - '0')
+		if i%2 == 0 {
+			d *= 3
+		}
+		sum += d
+	}
+	return byte('0' + (10-sum%10)%10), nil
+}
+
+// ValidateGTIN проверяет длину и контрольную цифру GTIN-8, GTIN-12 (UPC), GTIN-13 (EAN-13) или GTIN-14.
+func ValidateGTIN(code string) error {
+	switch len(code) {
+	case 8, 12, 13, 14:
+	default:
+		return ErrGTINFormat
+	}
+	check, err := CheckDigit(code[:len(code)-1])
+	if err != nil {
+		return err
+	}
+	if code[len(code)-1] != check {
+		return fmt.Errorf("%w: expected %c", ErrGTINCheckDigit, check)
+	}
+	return nil
+}
+
+// NormalizeGTIN проверяет GTIN и приводит его к 14 цифрам, как он хранится у вариантов и в кодах маркировки.
+func NormalizeGTIN(code string) (string, error) {
+	code = strings.TrimSpace(code)
+	if err := ValidateGTIN(code); err != nil {
+		return "", err
+	}
+	return strings.Repeat("0", 14-len(code)) + code, nil
+}
+
+// EAN13 возвращает номер для печати штрихкодом EAN-13 или false, если GTIN в EAN-13 не помещается
+// (GTIN-14 с ненулевым индикатором упаковки).
+func EAN13(gtin string) (string, bool) {
+	switch {
+	case len(gtin) == 13:
+		return gtin, true
+	case len(gtin) == 14 && gtin[0] == '0':
+		return gtin[1:], true
+	case len(gtin) == 12:
+		return "0" + gtin, true
+	}
+	return "", false
+}
+
+// ValidateGS1Prefix проверяет префикс компании GS1 (с кодом страны), из диапазона которого выпускаются EAN-13.
+func ValidateGS1Prefix(prefix string) error {
+	if len(prefix) < 6 || len(prefix) > 11 || !digitsOnly(prefix) {
+		return ErrGS1Prefix
+	}
+	return nil
+}
+
+// GS1Capacity возвращает, сколько номеров товара помещается в диапазон префикса.
+func GS1Capacity(prefix string) int64 {
+	capacity := int64(1)
+	for i := len(prefix); i < 12; i++ {
+		capacity *= 10
+	}
+	return capacity
+}
+
+// GenerateEAN13 собирает EAN-13 из префикса компании GS1 и номера товара в его диапазоне.
+func GenerateEAN13(prefix string, itemRef int64) (string, error) {
+	if err := ValidateGS1Prefix(prefix); err != nil {
+		return "", err
+	}
+	if itemRef < 0 || itemRef >= GS1Capacity(prefix) {
+		return "", ErrGS1Range
+	}
+	body := fmt.Sprintf("%s%0*d", prefix, 12-len(prefix), itemRef)
+	check, err := CheckDigit(body)
+	if err != nil {
+		return "", err
+	}
+	return body + string(check), nil
+}
+
+func digitsOnly(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,67 @@
+package barcode
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckDigit(t *testing.T) {
+	tests := []struct {
+		body string
+		want byte
+	}{
+		{"400638133393", '1'},  // EAN-13 4006381333931
+		{"03600029145", '2'},   // UPC-A 036000291452
+		{"9638507", '4'},       // EAN-8 96385074
+		{"0400638133393", '1'}, // GTIN-14 04006381333931
+	}
+	for _, tt := range tests {
+		got, err := CheckDigit(tt.body)
+		if err != nil {
+			t.Fatalf("CheckDigit(%q): %v", tt.body, err)
+		}
+		if got != tt.want {
+			t.Errorf("CheckDigit(%q) = %c, want %c", tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeGTIN(t *testing.T) {
+	tests := []struct {
+		code    string
+		want    string
+		wantErr error
+	}{
+		{code: "4006381333931", want: "04006381333931"},
+		{code: " 96385074 ", want: "00000096385074"},
+		{code: "036000291452", want: "00036000291452"},
+		{code: "4006381333932", wantErr: ErrGTINCheckDigit},
+		{code: "40063813339", wantErr: ErrGTINFormat},
+		{code: "40063813339x1", wantErr: ErrGTINFormat},
+	}
+	for _, tt := range tests {
+		got, err := NormalizeGTIN(tt.code)
+		if tt.wantErr != nil {
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("NormalizeGTIN(%q) error = %v, want %v", tt.code, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("NormalizeGTIN(%q) = %q, %v, want %q", tt.code, got, err, tt.want)
+		}
+	}
+}
+
+func TestGenerateEAN13(t *testing.T) {
+	code, err := GenerateEAN13("4006381", 33393)
+	if err != nil {
+		t.Fatalf("GenerateEAN13: %v", err)
+	}
+	if code != "4006381333931" {
+		t.Errorf("GenerateEAN13 = %s, want 4006381333931", code)
+	}
+	if _, err := GenerateEAN13("4006381", GS1Capacity("4006381")); !errors.Is(err, ErrGS1Range) {
+		t.Errorf("GenerateEAN13 past capacity error = %v, want %v", err, ErrGS1Range)
+	}
+}
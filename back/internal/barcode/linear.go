@@ -0,0 +1,189 @@
+package barcode
+
+import (
+	"errors"
+	"strings"
+)
+
+// Linear - одномерный штрихкод. Modules - модули слева направо, true - штрих.
+type Linear struct {
+	Modules []bool
+	Text    string // Подпись под штрихкодом
+	Quiet   int    // Минимальная свободная зона слева и справа, в модулях
+}
+
+func (l *Linear) appendPattern(pattern string) {
+	for i := 0; i < len(pattern); i++ {
+		l.Modules = append(l.Modules, pattern[i] == '1')
+	}
+}
+
+// appendWidths добавляет чередующиеся штрихи и пробелы заданной ширины, начиная со штриха.
+func (l *Linear) appendWidths(widths string) {
+	bar := true
+	for i := 0; i < len(widths); i++ {
+		for n := 0; n < int(widths[i]-'0'); n++ {
+			l.Modules = append(l.Modules, bar)
+		}
+		bar = !bar
+	}
+}
+
+// --- EAN-13 ---
+
+var (
+	eanL = [10]string{"0001101", "0011001", "0010011", "0111101", "0100011", "0110001", "0101111", "0111011", "0110111", "0001011"}
+	eanG = [10]string{"0100111", "0110011", "0011011", "0100001", "0011101", "0111001", "0000101", "0010001", "0001001", "0010111"}
+	eanR = [10]string{"1110010", "1100110", "1101100", "1000010", "1011100", "1001110", "1010000", "1000100", "1001000", "1110100"}
+	// Набор L/G для цифр 2-7 определяется первой цифрой
+	eanParity = [10]string{"LLLLLL", "LLGLGG", "LLGGLG", "LLGGGL", "LGLLGG", "LGGLLG", "LGGGLL", "LGLGLG", "LGLGGL", "LGGLGL"}
+)
+
+// EncodeEAN13 кодирует 13-значный номер (или 12 цифр без контрольной) штрихкодом EAN-13.
+func EncodeEAN13(code string) (*Linear, error) {
+	if len(code) == 12 && digitsOnly(code) {
+		check, _ := CheckDigit(code)
+		code += string(check)
+	}
+	if len(code) != 13 {
+		return nil, errors.New("EAN-13 requires 13 digits")
+	}
+	if err := ValidateGTIN(code); err != nil {
+		return nil, err
+	}
+
+	l := &Linear{Text: code, Quiet: 11}
+	parity := eanParity[code[0]-'0']
+	l.appendPattern("101")
+	for i := 1; i <= 6; i++ {
+		d := code[i] - '0'
+		if parity[i-1] == 'G' {
+			l.appendPattern(eanG[d])
+		} else {
+			l.appendPattern(eanL[d])
+		}
+	}
+	l.appendPattern("01010")
+	for i := 7; i <= 12; i++ {
+		l.appendPattern(eanR[code[i]-'0'])
+	}
+	l.appendPattern("101")
+	return l, nil
+}
+
+// --- Code 128 ---
+
+// Ширины штрихов и пробелов символов Code 128 по значению 0-105, 106 - стоп-символ
+var code128Widths = [107]string{
+	"212222", "222122", "222221", "121223", "121322", "131222", "122213", "122312", "132212", "221213",
+	"221312", "231212", "112232", "122132", "122231", "113222", "123122", "123221", "223211", "221132",
+	"221231", "213212", "223112", "312131", "311222", "321122", "321221", "312212", "322112", "322211",
+	"212123", "212321", "232121", "111323", "131123", "131321", "112313", "132113", "132311", "211313",
+	"231113", "231311", "112133", "112331", "132131", "113123", "113321", "133121", "313121", "211331",
+	"231131", "213113", "213311", "213131", "311123", "311321", "331121", "312113", "312311", "332111",
+	"314111", "221411", "431111", "111224", "111422", "121124", "121421", "141122", "141221", "112214",
+	"112412", "122114", "122411", "142112", "142211", "241211", "221114", "413111", "241112", "134111",
+	"111242", "121142", "121241", "114212", "124112", "124211", "411212", "421112", "421211", "212141",
+	"214121", "412121", "111143", "111341", "131141", "114113", "114311", "411113", "411311", "113141",
+	"114131", "311141", "411131", "211412", "211214", "211232", "2331112",
+}
+
+const (
+	code128CodeB  = 100
+	code128CodeC  = 99
+	code128FNC1   = 102
+	code128StartB = 104
+	code128StartC = 105
+	code128Stop   = 106
+)
+
+// EncodeCode128 кодирует строку из печатных символов ASCII штрихкодом Code 128.
+// Серии из четырех и более цифр кодируются набором C (по две цифры в символе).
+func EncodeCode128(text string) (*Linear, error) {
+	return encodeCode128(text, false)
+}
+
+// EncodeGS1128 кодирует строку GS1 (например, "01" + GTIN) штрихкодом GS1-128:
+// после стартового символа ставится FNC1, разделитель GS заменяется на FNC1.
+func EncodeGS1128(text string) (*Linear, error) {
+	l, err := encodeCode128(text, true)
+	if err != nil {
+		return nil, err
+	}
+	l.Text = strings.ReplaceAll(text, "\x1d", "")
+	return l, nil
+}
+
+func encodeCode128(text string, gs1 bool) (*Linear, error) {
+	if text == "" {
+		return nil, errors.New("Code 128 requires at least one character")
+	}
+	for i := 0; i < len(text); i++ {
+		if (text[i] < 32 || text[i] > 126) && !(gs1 && text[i] == 0x1d) {
+			return nil, errors.New("Code 128 supports printable ASCII characters only")
+		}
+	}
+
+	digitRun := func(from int) int {
+		n := 0
+		for from+n < len(text) && text[from+n] >= '0' && text[from+n] <= '9' {
+			n++
+		}
+		return n
+	}
+
+	var values []int
+	set := code128StartB
+	if run := digitRun(0); run >= 4 || (run == len(text) && run%2 == 0) {
+		set = code128StartC
+	}
+	values = append(values, set)
+	if set == code128StartC {
+		set = code128CodeC
+	} else {
+		set = code128CodeB
+	}
+	if gs1 {
+		values = append(values, code128FNC1)
+	}
+
+	for i := 0; i < len(text); {
+		if gs1 && text[i] == 0x1d {
+			values = append(values, code128FNC1)
+			i++
+			continue
+		}
+		run := digitRun(i)
+		switch {
+		case set == code128CodeC && run >= 2:
+			values = append(values, int(text[i]-'0')*10+int(text[i+1]-'0'))
+			i += 2
+		case set == code128CodeC:
+			values = append(values, code128CodeB)
+			set = code128CodeB
+		case run >= 4 && (run%2 == 0 || i+run == len(text) || run >= 6):
+			// Нечетную серию начинаем в наборе B, чтобы остаток делился на пары
+			if run%2 == 1 {
+				values = append(values, int(text[i])-32)
+				i++
+			}
+			values = append(values, code128CodeC)
+			set = code128CodeC
+		default:
+			values = append(values, int(text[i])-32)
+			i++
+		}
+	}
+
+	checksum := values[0]
+	for i := 1; i < len(values); i++ {
+		checksum += i * values[i]
+	}
+	values = append(values, checksum%103, code128Stop)
+
+	l := &Linear{Text: text, Quiet: 10}
+	for _, v := range values {
+		l.appendWidths(code128Widths[v])
+	}
+	return l, nil
+}
@@ -0,0 +1,56 @@
+package barcode
+
+import (
+	"strings"
+	"testing"
+)
+
+func modules(l *Linear) string {
+	var b strings.Builder
+	for _, m := range l.Modules {
+		if m {
+			b.WriteByte('1')
+		} else {
+			b.WriteByte('0')
+		}
+	}
+	return b.String()
+}
+
+func TestEncodeEAN13(t *testing.T) {
+	l, err := EncodeEAN13("400638133393")
+	if err != nil {
+		t.Fatalf("EncodeEAN13: %v", err)
+	}
+	if l.Text != "4006381333931" {
+		t.Errorf("Text = %s, want 4006381333931", l.Text)
+	}
+	// Первая цифра 4 задает четность LGLLGG для цифр 0,0,6,3,8,1
+	want := "101" +
+		"0001101" + "0100111" + "0101111" + "0111101" + "0001001" + "0110011" +
+		"01010" +
+		"1000010" + "1000010" + "1000010" + "1110100" + "1000010" + "1100110" +
+		"101"
+	if got := modules(l); got != want {
+		t.Errorf("modules =\n%s\nwant\n%s", got, want)
+	}
+
+	if _, err := EncodeEAN13("4006381333932"); err == nil {
+		t.Error("expected check digit error")
+	}
+}
+
+func TestEncodeCode128(t *testing.T) {
+	l, err := EncodeCode128("1234")
+	if err != nil {
+		t.Fatalf("EncodeCode128: %v", err)
+	}
+	// Start C (105), 12, 34, контрольный символ (105 + 12 + 2*34) % 103 = 82, Stop
+	want := new(Linear)
+	for _, w := range []string{code128Widths[105], code128Widths[12], code128Widths[34], code128Widths[82], code128Widths[106]} {
+		want.appendWidths(w)
+	}
+	if got := modules(l); got != modules(want) {
+		t.Errorf("modules =\n%s\nwant\n%s", got, modules(want))
+	}
+}
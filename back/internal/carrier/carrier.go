@@ -0,0 +1,95 @@
+// Package carrier связывает отправления заказов со службами доставки:
+// регистрация посылки у перевозчика, получение событий отслеживания и отмена.
+package carrier
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/lamoda-seller-app/internal/model"
+)
+
+var (
+	ErrUnknownCarrier   = errors.New("carrier is not configured")
+	ErrParcelNotFound   = errors.New("parcel not found at the carrier")
+	ErrCancelRejected   = errors.New("carrier refused to cancel the parcel")
+	ErrCarrierAPIFailed = errors.New("carrier API request failed")
+)
+
+// Adapter - клиент службы доставки. Адаптер приводит коды статусов перевозчика
+// к model.TrackingStatus* и не хранит состояние между вызовами.
+type Adapter interface {
+	// CreateShipment регистрирует посылку у перевозчика и возвращает ее трек-номер.
+	CreateShipment(ctx context.Context, req ShipmentRequest) (*CreatedShipment, error)
+	// TrackingEvents возвращает все события отслеживания посылки в порядке их наступления.
+	TrackingEvents(ctx context.Context, trackingNumber string) ([]model.TrackingEvent, error)
+	// CancelShipment отменяет посылку, пока перевозчик ее не принял.
+	CancelShipment(ctx context.Context, trackingNumber string) error
+}
+
+// ShipmentRequest - данные посылки для регистрации у перевозчика
+type ShipmentRequest struct {
+	Reference     string        `json:"reference"` // Номер отправления у продавца
+	OrderNumber   string        `json:"order_number"`
+	Recipient     Recipient     `json:"recipient"`
+	Items         []ParcelItem  `json:"items"`
+	DeclaredValue float64       `json:"declared_value"`
+	DeliveryType  string        `json:"delivery_type"`
+	Address       model.Address `json:"address"`
+}
+
+// Recipient - получатель посылки
+type Recipient struct {
+	Name  string `json:"name"`
+	Phone string `json:"phone"`
+	Email string `json:"email"`
+}
+
+// ParcelItem - вложение посылки
+type ParcelItem struct {
+	SKU      string  `json:"sku"`
+	Name     string  `json:"name"`
+	Quantity int     `json:"quantity"`
+	Price    float64 `json:"price"`
+}
+
+// CreatedShipment - ответ перевозчика на регистрацию посылки
+type CreatedShipment struct {
+	TrackingNumber string `json:"tracking_number"`
+	LabelURL       string `json:"label_url,omitempty"`
+}
+
+// Registry - перевозчики, подключенные к серверу, по названию. Название совпадает
+// с полем carrier отправления, по нему поллер и обработчики выбирают адаптер.
+type Registry struct {
+	adapters map[string]Adapter
+}
+
+func NewRegistry() *Registry {
+	return &Registry{adapters: make(map[string]Adapter)}
+}
+
+// Register подключает адаптер под названием name. Регистрировать нужно до запуска сервера.
+func (r *Registry) Register(name string, adapter Adapter) {
+	r.adapters[name] = adapter
+}
+
+// Get возвращает адаптер перевозчика или ErrUnknownCarrier.
+func (r *Registry) Get(name string) (Adapter, error) {
+	adapter, ok := r.adapters[name]
+	if !ok {
+		return nil, ErrUnknownCarrier
+	}
+	return adapter, nil
+}
+
+// Names возвращает названия подключенных перевозчиков.
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.adapters))
+	for name := range r.adapters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,138 @@
+package carrier
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lamoda-seller-app/internal/model"
+)
+
+// fakeRoute - этапы, которые проходит посылка у тестового перевозчика, по одному за шаг времени
+var fakeRoute = []struct {
+	status, description, location string
+}{
+	{model.TrackingStatusCreated, "Отправление зарегистрировано", ""},
+	{model.TrackingStatusAccepted, "Принято от отправителя", "Сортировочный центр, Москва"},
+	{model.TrackingStatusInTransit, "Передано в доставку", "Сортировочный центр, Москва"},
+	{model.TrackingStatusOutForDelivery, "Передано курьеру", ""},
+	{model.TrackingStatusDelivered, "Вручено получателю", ""},
+}
+
+// FakeServer - тестовый перевозчик с тем же API, что ожидает HTTPAdapter. Посылки хранятся в памяти
+// и проходят этапы fakeRoute через каждые step после регистрации. Используется при разработке
+// (cmd/fakecarrier) и в тестах через httptest.NewServer.
+type FakeServer struct {
+	step time.Duration
+	now  func() time.Time
+
+	mu      sync.Mutex
+	seq     int
+	parcels map[string]*fakeParcel
+}
+
+type fakeParcel struct {
+	request     ShipmentRequest
+	createdAt   time.Time
+	cancelledAt *time.Time
+}
+
+func NewFakeServer(step time.Duration) *FakeServer {
+	return &FakeServer{step: step, now: time.Now, parcels: make(map[string]*fakeParcel)}
+}
+
+func (s *FakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(r.URL.Path, "/")
+	parts := strings.Split(path, "/")
+	switch {
+	case r.Method == http.MethodPost && path == "shipments":
+		s.create(w, r)
+	case r.Method == http.MethodGet && len(parts) == 3 && parts[0] == "shipments" && parts[2] == "events":
+		s.events(w, parts[1])
+	case r.Method == http.MethodDelete && len(parts) == 2 && parts[0] == "shipments":
+		s.cancel(w, parts[1])
+	default:
+		writeFakeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
+	}
+}
+
+func (s *FakeServer) create(w http.ResponseWriter, r *http.Request) {
+	var req ShipmentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeFakeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	s.mu.Lock()
+	s.seq++
+	tracking := fmt.Sprintf("FAKE%010d", s.seq)
+	s.parcels[tracking] = &fakeParcel{request: req, createdAt: s.now()}
+	s.mu.Unlock()
+
+	writeFakeJSON(w, http.StatusCreated, CreatedShipment{TrackingNumber: tracking})
+}
+
+func (s *FakeServer) events(w http.ResponseWriter, tracking string) {
+	s.mu.Lock()
+	parcel, ok := s.parcels[tracking]
+	s.mu.Unlock()
+	if !ok {
+		writeFakeJSON(w, http.StatusNotFound, map[string]string{"error": "parcel not found"})
+		return
+	}
+
+	now := s.now()
+	var events []httpEvent
+	for i, stage := range fakeRoute {
+		at := parcel.createdAt.Add(time.Duration(i) * s.step)
+		if at.After(now) || (parcel.cancelledAt != nil && at.After(*parcel.cancelledAt)) {
+			break
+		}
+		events = append(events, httpEvent{
+			ID:          fmt.Sprintf("%s-%d", tracking, i),
+			Status:      stage.status,
+			Description: stage.description,
+			Location:    stage.location,
+			OccurredAt:  at,
+		})
+	}
+	if parcel.cancelledAt != nil {
+		events = append(events, httpEvent{
+			ID:          tracking + "-cancel",
+			Status:      model.TrackingStatusCancelled,
+			Description: "Отправление отменено",
+			OccurredAt:  *parcel.cancelledAt,
+		})
+	}
+	writeFakeJSON(w, http.StatusOK, map[string]interface{}{"events": events})
+}
+
+func (s *FakeServer) cancel(w http.ResponseWriter, tracking string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	parcel, ok := s.parcels[tracking]
+	if !ok {
+		writeFakeJSON(w, http.StatusNotFound, map[string]string{"error": "parcel not found"})
+		return
+	}
+	now := s.now()
+	// После приема посылки (второй этап маршрута) отменить ее нельзя
+	if parcel.cancelledAt == nil && !now.Before(parcel.createdAt.Add(s.step)) {
+		writeFakeJSON(w, http.StatusConflict, map[string]string{"error": "parcel already accepted"})
+		return
+	}
+	if parcel.cancelledAt == nil {
+		parcel.cancelledAt = &now
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeFakeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,152 @@
+package carrier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/lamoda-seller-app/internal/model"
+)
+
+// HTTPAdapter - адаптер перевозчика с JSON API:
+//
+//	POST   {base}/shipments                   - регистрация посылки, ответ {"tracking_number", "label_url"}
+//	GET    {base}/shipments/{tracking}/events - события, ответ {"events": [{"id", "status", "description", "location", "occurred_at"}]}
+//	DELETE {base}/shipments/{tracking}        - отмена; 409 - посылка уже принята
+//
+// Ключ API передается заголовком Authorization: Bearer. Коды статусов перевозчика
+// приводятся к model.TrackingStatus* через statusMap; неизвестные коды сохраняются как есть.
+type HTTPAdapter struct {
+	baseURL   string
+	apiKey    string
+	client    *http.Client
+	statusMap map[string]string
+}
+
+func NewHTTPAdapter(baseURL, apiKey string, timeout time.Duration, statusMap map[string]string) *HTTPAdapter {
+	return &HTTPAdapter{
+		baseURL:   strings.TrimRight(baseURL, "/"),
+		apiKey:    apiKey,
+		client:    &http.Client{Timeout: timeout},
+		statusMap: statusMap,
+	}
+}
+
+type httpEvent struct {
+	ID          string    `json:"id"`
+	Status      string    `json:"status"`
+	Description string    `json:"description"`
+	Location    string    `json:"location"`
+	OccurredAt  time.Time `json:"occurred_at"`
+}
+
+func (a *HTTPAdapter) CreateShipment(ctx context.Context, req ShipmentRequest) (*CreatedShipment, error) {
+	var created CreatedShipment
+	if err := a.do(ctx, http.MethodPost, "/shipments", req, &created); err != nil {
+		return nil, err
+	}
+	if created.TrackingNumber == "" {
+		return nil, fmt.Errorf("%w: empty tracking number in response", ErrCarrierAPIFailed)
+	}
+	return &created, nil
+}
+
+func (a *HTTPAdapter) TrackingEvents(ctx context.Context, trackingNumber string) ([]model.TrackingEvent, error) {
+	var resp struct {
+		Events []httpEvent `json:"events"`
+	}
+	if err := a.do(ctx, http.MethodGet, "/shipments/"+url.PathEscape(trackingNumber)+"/events", nil, &resp); err != nil {
+		return nil, err
+	}
+
+	events := make([]model.TrackingEvent, 0, len(resp.Events))
+	for _, e := range resp.Events {
+		status := e.Status
+		if mapped, ok := a.statusMap[status]; ok {
+			status = mapped
+		}
+		id := e.ID
+		if id == "" {
+			// Без ID событие опознается по коду и времени, чтобы повторный опрос не дублировал его
+			id = e.Status + "@" + e.OccurredAt.UTC().Format(time.RFC3339)
+		}
+		events = append(events, model.TrackingEvent{
+			ExternalID:  id,
+			Status:      status,
+			Description: e.Description,
+			Location:    e.Location,
+			OccurredAt:  e.OccurredAt,
+		})
+	}
+	return events, nil
+}
+
+func (a *HTTPAdapter) CancelShipment(ctx context.Context, trackingNumber string) error {
+	return a.do(ctx, http.MethodDelete, "/shipments/"+url.PathEscape(trackingNumber), nil, nil)
+}
+
+// do выполняет запрос к API перевозчика и разбирает JSON-ответ в out (если он не nil).
+func (a *HTTPAdapter) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, a.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if a.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+a.apiKey)
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrCarrierAPIFailed, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return ErrParcelNotFound
+	case resp.StatusCode == http.StatusConflict:
+		return ErrCancelRejected
+	case resp.StatusCode >= 300:
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%w: %s %s: %d %s", ErrCarrierAPIFailed, method, path, resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("%w: invalid response: %v", ErrCarrierAPIFailed, err)
+	}
+	return nil
+}
+
+// ParseStatusMap разбирает соответствие кодов перевозчика статусам отслеживания
+// из строки вида "ACCEPTED=accepted,COURIER=out_for_delivery". Пустые пары пропускаются.
+func ParseStatusMap(s string) map[string]string {
+	m := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		code, status, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if ok && code != "" && status != "" {
+			m[strings.TrimSpace(code)] = strings.TrimSpace(status)
+		}
+	}
+	return m
+}
@@ -0,0 +1,112 @@
+package carrier
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lamoda-seller-app/internal/model"
+	"github.com/lamoda-seller-app/internal/repository"
+)
+
+// pollBatch - сколько отправлений опрашивается за один запуск поллера
+const pollBatch = 200
+
+// Poller опрашивает перевозчиков о посылках в пути, сохраняет события отслеживания и
+// переводит отправления в shipped/delivered через ShipmentRepository.UpdateStatus - тот же путь,
+// что и ручная смена статуса, поэтому статус заказа, остатки и коды маркировки меняются так же.
+type Poller struct {
+	registry *Registry
+	repo     shipmentStore
+	interval time.Duration
+}
+
+// shipmentStore - методы repository.ShipmentRepository, которыми пользуется поллер
+type shipmentStore interface {
+	TrackedShipments(ctx context.Context, carriers []string, polledBefore time.Time, limit int) ([]model.Shipment, error)
+	SaveTrackingEvents(ctx context.Context, shipment *model.Shipment, events []model.TrackingEvent) (int, error)
+	UpdateStatus(ctx context.Context, userID, orderID, shipmentID uuid.UUID, status, comment string) (*model.Shipment, *model.Order, error)
+}
+
+func NewPoller(registry *Registry, repo *repository.ShipmentRepository, interval time.Duration) *Poller {
+	return &Poller{registry: registry, repo: repo, interval: interval}
+}
+
+// Poll опрашивает одну порцию отправлений и возвращает количество отправлений, сменивших статус.
+// Ошибка одного перевозчика или отправления не прерывает опрос остальных.
+func (p *Poller) Poll(ctx context.Context) (int, error) {
+	// Отправления, опрошенные в прошлый запуск, пропускаются
+	shipments, err := p.repo.TrackedShipments(ctx, p.registry.Names(), time.Now().Add(-p.interval/2), pollBatch)
+	if err != nil {
+		return 0, err
+	}
+
+	changed := 0
+	for i := range shipments {
+		if ctx.Err() != nil {
+			return changed, ctx.Err()
+		}
+		ok, err := p.pollShipment(ctx, &shipments[i])
+		if err != nil {
+			log.Printf("❌ Carrier Poll: отправление %s (%s %s): %v",
+				shipments[i].Number, shipments[i].Carrier, shipments[i].TrackingNumber, err)
+			continue
+		}
+		if ok {
+			changed++
+		}
+	}
+	return changed, nil
+}
+
+func (p *Poller) pollShipment(ctx context.Context, shipment *model.Shipment) (bool, error) {
+	adapter, err := p.registry.Get(shipment.Carrier)
+	if err != nil {
+		return false, err
+	}
+	events, err := adapter.TrackingEvents(ctx, shipment.TrackingNumber)
+	if err != nil {
+		return false, err
+	}
+	if _, err := p.repo.SaveTrackingEvents(ctx, shipment, events); err != nil {
+		return false, err
+	}
+
+	target, last := trackingTarget(events)
+	if target == "" || target == shipment.Status {
+		return false, nil
+	}
+
+	// Посылка, сразу оказавшаяся врученной, проходит оба перехода, чтобы товар был списан со склада
+	steps := []string{target}
+	if shipment.Status == model.ShipmentStatusPending && target == model.ShipmentStatusDelivered {
+		steps = []string{model.ShipmentStatusShipped, model.ShipmentStatusDelivered}
+	}
+	for _, status := range steps {
+		comment := fmt.Sprintf("По данным перевозчика %s: %s", shipment.Carrier, last.Description)
+		if _, _, err := p.repo.UpdateStatus(ctx, shipment.UserID, shipment.OrderID, shipment.ID, status, comment); err != nil {
+			return false, err
+		}
+	}
+	log.Printf("🚚 Carrier Poll: отправление %s - %s", shipment.Number, target)
+	return true, nil
+}
+
+// trackingTarget возвращает статус отправления, до которого дошла посылка по событиям, и событие, давшее его.
+// Вручение важнее приема, поэтому порядок событий не важен.
+func trackingTarget(events []model.TrackingEvent) (string, model.TrackingEvent) {
+	var target string
+	var last model.TrackingEvent
+	for _, e := range events {
+		status := model.TrackingShipmentStatus(e.Status)
+		if status == "" || target == model.ShipmentStatusDelivered {
+			continue
+		}
+		if status == model.ShipmentStatusDelivered || target == "" || e.OccurredAt.After(last.OccurredAt) {
+			target, last = status, e
+		}
+	}
+	return target, last
+}
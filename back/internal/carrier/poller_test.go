@@ -0,0 +1,338 @@
+package carrier
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lamoda-seller-app/internal/model"
+)
+
+// memoryShipments - хранилище отправлений в памяти с теми же правилами, что у ShipmentRepository
+type memoryShipments struct {
+	mu        sync.Mutex
+	shipments map[uuid.UUID]*model.Shipment
+	events    map[uuid.UUID]map[string]model.TrackingEvent
+	history   map[uuid.UUID][]string
+}
+
+func newMemoryShipments(shipments ...model.Shipment) *memoryShipments {
+	m := &memoryShipments{
+		shipments: make(map[uuid.UUID]*model.Shipment),
+		events:    make(map[uuid.UUID]map[string]model.TrackingEvent),
+		history:   make(map[uuid.UUID][]string),
+	}
+	for i := range shipments {
+		s := shipments[i]
+		m.shipments[s.ID] = &s
+	}
+	return m
+}
+
+func (m *memoryShipments) TrackedShipments(_ context.Context, carriers []string, polledBefore time.Time, limit int) ([]model.Shipment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var result []model.Shipment
+	for _, s := range m.shipments {
+		if len(result) == limit || s.TrackingNumber == "" || !containsName(carriers, s.Carrier) {
+			continue
+		}
+		if s.Status != model.ShipmentStatusPending && s.Status != model.ShipmentStatusShipped {
+			continue
+		}
+		if s.TrackingPolledAt != nil && !s.TrackingPolledAt.Before(polledBefore) {
+			continue
+		}
+		result = append(result, *s)
+	}
+	return result, nil
+}
+
+func (m *memoryShipments) SaveTrackingEvents(_ context.Context, shipment *model.Shipment, events []model.TrackingEvent) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	saved := m.events[shipment.ID]
+	if saved == nil {
+		saved = make(map[string]model.TrackingEvent)
+		m.events[shipment.ID] = saved
+	}
+	added := 0
+	for _, e := range events {
+		if _, ok := saved[e.ExternalID]; !ok {
+			saved[e.ExternalID] = e
+			added++
+		}
+	}
+	now := time.Now()
+	m.shipments[shipment.ID].TrackingPolledAt = &now
+	return added, nil
+}
+
+func (m *memoryShipments) UpdateStatus(_ context.Context, _, _, shipmentID uuid.UUID, status, _ string) (*model.Shipment, *model.Order, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := m.shipments[shipmentID]
+	allowed := map[string]string{
+		model.ShipmentStatusPending: model.ShipmentStatusShipped,
+		model.ShipmentStatusShipped: model.ShipmentStatusDelivered,
+	}
+	if allowed[s.Status] != status {
+		return nil, nil, errors.New("invalid shipment status transition: " + s.Status + " -> " + status)
+	}
+	s.Status = status
+	m.history[shipmentID] = append(m.history[shipmentID], status)
+	return s, &model.Order{}, nil
+}
+
+func (m *memoryShipments) get(id uuid.UUID) (model.Shipment, []string, int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return *m.shipments[id], m.history[id], len(m.events[id])
+}
+
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// fakeCarrier запускает тестового перевозчика с управляемыми часами
+type fakeCarrier struct {
+	server  *FakeServer
+	adapter *HTTPAdapter
+	mu      sync.Mutex
+	now     time.Time
+}
+
+const fakeStep = time.Hour
+
+func newFakeCarrier(t *testing.T, wrap func(http.Handler) http.Handler) *fakeCarrier {
+	t.Helper()
+	fc := &fakeCarrier{server: NewFakeServer(fakeStep), now: time.Date(2026, 10, 1, 10, 0, 0, 0, time.UTC)}
+	fc.server.now = func() time.Time {
+		fc.mu.Lock()
+		defer fc.mu.Unlock()
+		return fc.now
+	}
+	var handler http.Handler = fc.server
+	if wrap != nil {
+		handler = wrap(handler)
+	}
+	ts := httptest.NewServer(handler)
+	t.Cleanup(ts.Close)
+	fc.adapter = NewHTTPAdapter(ts.URL, "", 5*time.Second, nil)
+	return fc
+}
+
+func (fc *fakeCarrier) advance(d time.Duration) {
+	fc.mu.Lock()
+	fc.now = fc.now.Add(d)
+	fc.mu.Unlock()
+}
+
+func (fc *fakeCarrier) register(t *testing.T, status string) model.Shipment {
+	t.Helper()
+	created, err := fc.adapter.CreateShipment(context.Background(), ShipmentRequest{Reference: "100123-1", OrderNumber: "100123"})
+	if err != nil {
+		t.Fatalf("CreateShipment: %v", err)
+	}
+	return model.Shipment{
+		ID:             uuid.New(),
+		UserID:         uuid.New(),
+		OrderID:        uuid.New(),
+		Number:         "100123-1",
+		Carrier:        "fake",
+		TrackingNumber: created.TrackingNumber,
+		Status:         status,
+	}
+}
+
+func newTestPoller(fc *fakeCarrier, store *memoryShipments) *Poller {
+	registry := NewRegistry()
+	registry.Register("fake", fc.adapter)
+	// Нулевой интервал: каждое отправление опрашивается в каждый запуск
+	return &Poller{registry: registry, repo: store, interval: 0}
+}
+
+func poll(t *testing.T, p *Poller, want int) {
+	t.Helper()
+	changed, err := p.Poll(context.Background())
+	if err != nil {
+		t.Fatalf("Poll: %v", err)
+	}
+	if changed != want {
+		t.Fatalf("Poll changed %d shipments, want %d", changed, want)
+	}
+}
+
+func TestPollerFollowsCarrierRoute(t *testing.T) {
+	fc := newFakeCarrier(t, nil)
+	shipment := fc.register(t, model.ShipmentStatusPending)
+	store := newMemoryShipments(shipment)
+	p := newTestPoller(fc, store)
+
+	// Только регистрация у перевозчика - посылка еще на складе
+	poll(t, p, 0)
+	if s, _, events := store.get(shipment.ID); s.Status != model.ShipmentStatusPending || events != 1 {
+		t.Fatalf("after created: status %s, events %d", s.Status, events)
+	}
+
+	// Принята перевозчиком - отгружена
+	fc.advance(fakeStep)
+	poll(t, p, 1)
+	// В пути и у курьера - статус отправления не меняется, события добавляются без дублей
+	fc.advance(2 * fakeStep)
+	poll(t, p, 0)
+	if s, _, events := store.get(shipment.ID); s.Status != model.ShipmentStatusShipped || events != 4 {
+		t.Fatalf("after out_for_delivery: status %s, events %d", s.Status, events)
+	}
+
+	fc.advance(fakeStep)
+	poll(t, p, 1)
+	s, history, events := store.get(shipment.ID)
+	if s.Status != model.ShipmentStatusDelivered || events != 5 {
+		t.Fatalf("after delivered: status %s, events %d", s.Status, events)
+	}
+	if len(history) != 2 || history[0] != model.ShipmentStatusShipped || history[1] != model.ShipmentStatusDelivered {
+		t.Fatalf("history = %v", history)
+	}
+
+	// Врученное отправление - конечное состояние, больше не опрашивается
+	fc.advance(fakeStep)
+	poll(t, p, 0)
+	if _, history, _ := store.get(shipment.ID); len(history) != 2 {
+		t.Fatalf("delivered shipment changed again: %v", history)
+	}
+}
+
+func TestPollerDeliveredPendingShipmentPassesShipped(t *testing.T) {
+	fc := newFakeCarrier(t, nil)
+	shipment := fc.register(t, model.ShipmentStatusPending)
+	store := newMemoryShipments(shipment)
+	p := newTestPoller(fc, store)
+
+	fc.advance(10 * fakeStep)
+	poll(t, p, 1)
+	s, history, _ := store.get(shipment.ID)
+	if s.Status != model.ShipmentStatusDelivered {
+		t.Fatalf("status = %s, want delivered", s.Status)
+	}
+	if len(history) != 2 || history[0] != model.ShipmentStatusShipped {
+		t.Fatalf("history = %v, want shipped then delivered", history)
+	}
+}
+
+func TestPollerCancelledParcelKeepsStatus(t *testing.T) {
+	fc := newFakeCarrier(t, nil)
+	shipment := fc.register(t, model.ShipmentStatusPending)
+	store := newMemoryShipments(shipment)
+	p := newTestPoller(fc, store)
+
+	if err := fc.adapter.CancelShipment(context.Background(), shipment.TrackingNumber); err != nil {
+		t.Fatalf("CancelShipment: %v", err)
+	}
+	fc.advance(10 * fakeStep)
+	poll(t, p, 0)
+	if s, history, events := store.get(shipment.ID); s.Status != model.ShipmentStatusPending || len(history) != 0 || events != 2 {
+		t.Fatalf("cancelled parcel: status %s, history %v, events %d", s.Status, history, events)
+	}
+
+	// Принятую посылку отменить нельзя
+	accepted := fc.register(t, model.ShipmentStatusPending)
+	fc.advance(fakeStep)
+	if err := fc.adapter.CancelShipment(context.Background(), accepted.TrackingNumber); !errors.Is(err, ErrCancelRejected) {
+		t.Fatalf("CancelShipment after accept: %v, want %v", err, ErrCancelRejected)
+	}
+}
+
+func TestPollerRetriesFailedShipments(t *testing.T) {
+	var mu sync.Mutex
+	failures := 2
+	fc := newFakeCarrier(t, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			fail := r.Method == http.MethodGet && failures > 0
+			if fail {
+				failures--
+			}
+			mu.Unlock()
+			if fail {
+				http.Error(w, "temporarily unavailable", http.StatusServiceUnavailable)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	})
+	shipment := fc.register(t, model.ShipmentStatusPending)
+	unknown := model.Shipment{ID: uuid.New(), Carrier: "fake", TrackingNumber: "FAKE-MISSING", Status: model.ShipmentStatusPending}
+	store := newMemoryShipments(shipment, unknown)
+	p := newTestPoller(fc, store)
+	fc.advance(fakeStep)
+
+	// Ошибки перевозчика не прерывают опрос и не отмечают отправления опрошенными
+	poll(t, p, 0)
+	if s, _, events := store.get(shipment.ID); s.TrackingPolledAt != nil || events != 0 {
+		t.Fatalf("failed poll marked shipment: polled %v, events %d", s.TrackingPolledAt, events)
+	}
+
+	// Следующие запуски повторяют опрос, пока перевозчик не ответит
+	for i := 0; i < 3; i++ {
+		if _, err := p.Poll(context.Background()); err != nil {
+			t.Fatalf("Poll: %v", err)
+		}
+	}
+	if s, _, _ := store.get(shipment.ID); s.Status != model.ShipmentStatusShipped {
+		t.Fatalf("status after retries = %s, want shipped", s.Status)
+	}
+	if s, _, _ := store.get(unknown.ID); s.Status != model.ShipmentStatusPending || s.TrackingPolledAt != nil {
+		t.Fatalf("unknown parcel: status %s, polled %v", s.Status, s.TrackingPolledAt)
+	}
+}
+
+func TestHTTPAdapterMapsCarrierStatuses(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"events": [
+			{"id": "1", "status": "ACC", "occurred_at": "2026-10-01T10:00:00Z"},
+			{"status": "DLV", "description": "Вручено", "occurred_at": "2026-10-02T10:00:00Z"},
+			{"id": "3", "status": "weird", "occurred_at": "2026-10-02T11:00:00Z"}
+		]}`))
+	}))
+	defer ts.Close()
+
+	adapter := NewHTTPAdapter(ts.URL, "", 5*time.Second, ParseStatusMap("ACC=accepted, DLV=delivered,,bad"))
+	events, err := adapter.TrackingEvents(context.Background(), "T1")
+	if err != nil {
+		t.Fatalf("TrackingEvents: %v", err)
+	}
+	want := []struct{ id, status string }{
+		{"1", model.TrackingStatusAccepted},
+		{"DLV@2026-10-02T10:00:00Z", model.TrackingStatusDelivered},
+		{"3", "weird"},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("got %d events, want %d", len(events), len(want))
+	}
+	for i, w := range want {
+		if events[i].ExternalID != w.id || events[i].Status != w.status {
+			t.Errorf("event %d = %s/%s, want %s/%s", i, events[i].ExternalID, events[i].Status, w.id, w.status)
+		}
+	}
+
+	// Вручение важнее приема независимо от порядка событий, неизвестные коды не меняют статус
+	target, last := trackingTarget([]model.TrackingEvent{events[1], events[0], events[2]})
+	if target != model.ShipmentStatusDelivered || last.Description != "Вручено" {
+		t.Errorf("trackingTarget = %s (%s), want delivered", target, last.Description)
+	}
+	if target, _ := trackingTarget([]model.TrackingEvent{events[2]}); target != "" {
+		t.Errorf("trackingTarget for unknown status = %q, want empty", target)
+	}
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
 
 	"github.com/joho/godotenv"
 )
@@ -15,6 +16,40 @@ type Config struct {
 	DBName         string
 	ServerPort     string
 	JWTSecret      string
+
+	// Сколько дней удаленный товар хранится в корзине и может быть восстановлен
+	ProductTrashRetentionDays int
+	// Как часто (в секундах) планировщик применяет запланированные изменения цен
+	PriceSchedulerIntervalSeconds int
+	// Как часто (в минутах) пересчитываются продажи товаров за 30 дней
+	SalesRollupIntervalMinutes int
+	// Как часто (в минутах) остатки сверяются с точками заказа для оповещений
+	StockAlertsIntervalMinutes int
+	// Каталог, куда заглушка ГИС МТ "Честный ЗНАК" складывает отправленные документы
+	MarkingPortalDir string
+	// Перевозчик с HTTP API: название (поле carrier отправления), адрес API, ключ и
+	// соответствие его кодов статусов нашим ("ACCEPTED=accepted,DONE=delivered"). Без адреса не подключается
+	CarrierName      string
+	CarrierAPIURL    string
+	CarrierAPIKey    string
+	CarrierStatusMap string
+	// Как часто (в минутах) опрашиваются перевозчики о посылках в пути
+	CarrierPollIntervalMinutes int
+	// Как часто (в секундах) разбирается очередь вебхуков и сколько ждать ответа адреса
+	WebhookDispatchIntervalSeconds int
+	WebhookTimeoutSeconds          int
+	// Сколько дней хранятся разосланные события вебхуков и журнал их доставок
+	WebhookRetentionDays int
+	// SMTP-сервер для писем по уведомлениям. Без хоста письма пишутся в лог
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUser     string
+	SMTPPassword string
+	MailFrom     string
+	// Адрес фронтенда: ссылки в письмах ведут на его страницы
+	AppBaseURL string
+	// Как часто (в секундах) отправляются письма по уведомлениям
+	MailIntervalSeconds int
 }
 
 func Load() *Config {
@@ -31,6 +66,27 @@ func Load() *Config {
 		DBName:         getEnv("DB_NAME", "lamoda_db"),
 		ServerPort:     getEnv("SERVER_PORT", "8080"),
 		JWTSecret:      getEnv("JWT_SECRET", "supersecretkey"),
+
+		ProductTrashRetentionDays:      getEnvInt("PRODUCT_TRASH_RETENTION_DAYS", 30),
+		PriceSchedulerIntervalSeconds:  getEnvInt("PRICE_SCHEDULER_INTERVAL_SECONDS", 60),
+		SalesRollupIntervalMinutes:     getEnvInt("SALES_ROLLUP_INTERVAL_MINUTES", 15),
+		StockAlertsIntervalMinutes:     getEnvInt("STOCK_ALERTS_INTERVAL_MINUTES", 10),
+		MarkingPortalDir:               getEnv("MARKING_PORTAL_DIR", "./marking_outbox"),
+		CarrierName:                    getEnv("CARRIER_NAME", "fake"),
+		CarrierAPIURL:                  getEnv("CARRIER_API_URL", ""),
+		CarrierAPIKey:                  getEnv("CARRIER_API_KEY", ""),
+		CarrierStatusMap:               getEnv("CARRIER_STATUS_MAP", ""),
+		CarrierPollIntervalMinutes:     getEnvInt("CARRIER_POLL_INTERVAL_MINUTES", 5),
+		WebhookDispatchIntervalSeconds: getEnvInt("WEBHOOK_DISPATCH_INTERVAL_SECONDS", 10),
+		WebhookTimeoutSeconds:          getEnvInt("WEBHOOK_TIMEOUT_SECONDS", 10),
+		WebhookRetentionDays:           getEnvInt("WEBHOOK_RETENTION_DAYS", 30),
+		SMTPHost:                       getEnv("SMTP_HOST", ""),
+		SMTPPort:                       getEnvInt("SMTP_PORT", 587),
+		SMTPUser:                       getEnv("SMTP_USER", ""),
+		SMTPPassword:                   getEnv("SMTP_PASSWORD", ""),
+		MailFrom:                       getEnv("MAIL_FROM", "noreply@localhost"),
+		AppBaseURL:                     getEnv("APP_BASE_URL", "http://localhost:5173"),
+		MailIntervalSeconds:            getEnvInt("MAIL_INTERVAL_SECONDS", 30),
 	}
 }
 
@@ -40,3 +96,15 @@ func getEnv(key, defaultVal string) string {
 	}
 	return defaultVal
 }
+
+// getEnvInt читает положительное целое: интервалы, сроки хранения, порты. Ноль, отрицательное
+// или нечисловое значение заменяется значением по умолчанию.
+func getEnvInt(key string, defaultVal int) int {
+	if value, exists := os.LookupEnv(key); exists {
+		if n, err := strconv.Atoi(value); err == nil && n > 0 {
+			return n
+		}
+		log.Printf("⚠️  Invalid value for %s: %q, using default %d", key, value, defaultVal)
+	}
+	return defaultVal
+}
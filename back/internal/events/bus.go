@@ -0,0 +1,146 @@
+// Package events доставляет события продавца подключенным клиентам потока живых обновлений.
+// События пишутся в журнал webhook_events триггерами БД; Listener получает о них уведомления
+// Postgres (LISTEN/NOTIFY), поэтому событие, возникшее на любом экземпляре сервера,
+// приходит клиентам всех экземпляров.
+package events
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lamoda-seller-app/internal/model"
+)
+
+// subscriptionBuffer - сколько событий может ждать отправки клиенту. Если клиент не успевает,
+// подписка закрывается, и клиент переподключается с Last-Event-ID, дочитывая пропущенное из журнала.
+const subscriptionBuffer = 64
+
+// Event - событие продавца в потоке
+type Event struct {
+	Seq       int64           `json:"seq"`
+	TxID      int64           `json:"-"`
+	UserID    uuid.UUID       `json:"-"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// FromModel преобразует запись журнала в событие потока.
+func FromModel(e model.WebhookEvent) Event {
+	return Event{Seq: e.Seq, TxID: e.TxID, UserID: e.UserID, Type: e.Type, CreatedAt: e.CreatedAt, Data: e.Payload}
+}
+
+// Subscription - подписка клиента на события продавца. Канал C закрывается при отписке,
+// при переполнении буфера и при остановке шины.
+type Subscription struct {
+	C      <-chan Event
+	ch     chan Event
+	userID uuid.UUID
+}
+
+// Bus - внутренняя шина событий процесса: раздает события подпискам продавца.
+type Bus struct {
+	mu     sync.RWMutex
+	subs   map[uuid.UUID]map[*Subscription]struct{}
+	closed bool
+}
+
+func NewBus() *Bus {
+	return &Bus{subs: make(map[uuid.UUID]map[*Subscription]struct{})}
+}
+
+// Subscribe подписывает клиента на события продавца. После остановки шины возвращает закрытую подписку.
+func (b *Bus) Subscribe(userID uuid.UUID) *Subscription {
+	ch := make(chan Event, subscriptionBuffer)
+	sub := &Subscription{C: ch, ch: ch, userID: userID}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		close(ch)
+		return sub
+	}
+	if b.subs[userID] == nil {
+		b.subs[userID] = make(map[*Subscription]struct{})
+	}
+	b.subs[userID][sub] = struct{}{}
+	return sub
+}
+
+// Unsubscribe отменяет подписку. Повторный вызов безопасен.
+func (b *Bus) Unsubscribe(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.remove(sub)
+}
+
+// remove убирает подписку и закрывает ее канал. Вызывается под блокировкой записи.
+func (b *Bus) remove(sub *Subscription) {
+	subs, ok := b.subs[sub.userID]
+	if !ok {
+		return
+	}
+	if _, ok := subs[sub]; !ok {
+		return
+	}
+	delete(subs, sub)
+	if len(subs) == 0 {
+		delete(b.subs, sub.userID)
+	}
+	close(sub.ch)
+}
+
+// Publish отправляет событие подпискам его продавца, не блокируясь на медленных клиентах.
+func (b *Bus) Publish(event Event) {
+	b.mu.RLock()
+	var lagging []*Subscription
+	for sub := range b.subs[event.UserID] {
+		select {
+		case sub.ch <- event:
+		default:
+			lagging = append(lagging, sub)
+		}
+	}
+	b.mu.RUnlock()
+
+	if len(lagging) == 0 {
+		return
+	}
+	b.mu.Lock()
+	for _, sub := range lagging {
+		b.remove(sub)
+	}
+	b.mu.Unlock()
+}
+
+// HasSubscribers сообщает, подключен ли к этому экземпляру хоть один клиент продавца.
+func (b *Bus) HasSubscribers(userID uuid.UUID) bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.subs[userID]) > 0
+}
+
+// Subscribers возвращает продавцов, у которых есть подключенные клиенты.
+func (b *Bus) Subscribers() []uuid.UUID {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	users := make([]uuid.UUID, 0, len(b.subs))
+	for userID := range b.subs {
+		users = append(users, userID)
+	}
+	return users
+}
+
+// Close закрывает все подписки, чтобы открытые потоки завершились при остановке сервера.
+func (b *Bus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for _, subs := range b.subs {
+		for sub := range subs {
+			b.remove(sub)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package events
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lamoda-seller-app/internal/model"
+	"github.com/lamoda-seller-app/internal/repository"
+	"github.com/lib/pq"
+)
+
+const (
+	// NotifyChannel - канал Postgres, в который триггер журнала webhook_events шлет "<user_id>:<seq>"
+	NotifyChannel = "seller_events"
+	catchUpLimit  = 1000
+	pingInterval  = 90 * time.Second
+)
+
+// Listener слушает уведомления Postgres о новых событиях и публикует их в шину.
+// Читаются только события продавцов, подключенных к этому экземпляру. После обрыва
+// соединения Listener дочитывает из журнала события, пришедшие за время переподключения.
+type Listener struct {
+	dsn  string
+	bus  *Bus
+	repo *repository.EventRepository
+}
+
+func NewListener(dsn string, bus *Bus, repo *repository.EventRepository) *Listener {
+	return &Listener{dsn: dsn, bus: bus, repo: repo}
+}
+
+// Run слушает уведомления до отмены ctx.
+func (l *Listener) Run(ctx context.Context) {
+	listener := pq.NewListener(l.dsn, 5*time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("⚠️ Events Listener: соединение с БД: %v", err)
+		}
+	})
+	defer listener.Close()
+
+	if err := listener.Listen(NotifyChannel); err != nil {
+		log.Printf("❌ Events Listener: не удалось подписаться на %s: %v", NotifyChannel, err)
+		return
+	}
+	// Позиция журнала, с которой дочитываются события после обрыва соединения
+	watermark, err := l.repo.Watermark(ctx)
+	if err != nil {
+		log.Printf("❌ Events Listener: не удалось получить позицию журнала событий: %v", err)
+	}
+	log.Printf("📡 Events Listener: слушаю канал %s", NotifyChannel)
+
+	ping := time.NewTicker(pingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ping.C:
+			go listener.Ping()
+		case n := <-listener.Notify:
+			var seqs []int64
+			reconnected := n == nil
+			if n != nil {
+				seqs, reconnected = l.pending(n, listener.Notify)
+			}
+			if len(seqs) > 0 {
+				l.publish(l.repo.BySeq(ctx, seqs))
+			}
+			if reconnected {
+				// Соединение восстановлено: уведомления за время обрыва потеряны.
+				// Уже доставленные события тоже могут попасть в выборку, потоки отсеют их по seq
+				next, err := l.repo.Watermark(ctx)
+				if users := l.bus.Subscribers(); len(users) > 0 {
+					l.publish(l.repo.Since(ctx, users, watermark, 0, catchUpLimit))
+				}
+				if err == nil {
+					watermark = next
+				}
+			}
+		}
+	}
+}
+
+func (l *Listener) publish(events []model.WebhookEvent, err error) {
+	if err != nil {
+		log.Printf("❌ Events Listener: ошибка чтения журнала событий: %v", err)
+		return
+	}
+	for _, e := range events {
+		l.bus.Publish(FromModel(e))
+	}
+}
+
+// pending собирает номера событий из первого уведомления и всех, что уже ждут в канале,
+// чтобы прочитать их одним запросом. События продавцов без подключенных клиентов пропускаются.
+// reconnected сообщает, что среди уведомлений был сигнал переподключения.
+func (l *Listener) pending(first *pq.Notification, notify <-chan *pq.Notification) (seqs []int64, reconnected bool) {
+	for n := first; ; {
+		if userID, seq, ok := parseNotification(n.Extra); ok {
+			if l.bus.HasSubscribers(userID) {
+				seqs = append(seqs, seq)
+			}
+		}
+		if len(seqs) >= catchUpLimit {
+			return seqs, false
+		}
+		select {
+		case n = <-notify:
+			if n == nil {
+				return seqs, true
+			}
+		default:
+			return seqs, false
+		}
+	}
+}
+
+func parseNotification(payload string) (uuid.UUID, int64, bool) {
+	rawUser, rawSeq, ok := strings.Cut(payload, ":")
+	if !ok {
+		return uuid.Nil, 0, false
+	}
+	userID, err := uuid.Parse(rawUser)
+	if err != nil {
+		return uuid.Nil, 0, false
+	}
+	seq, err := strconv.ParseInt(rawSeq, 10, 64)
+	if err != nil {
+		return uuid.Nil, 0, false
+	}
+	return userID, seq, true
+}
@@ -0,0 +1,253 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lamoda-seller-app/internal/model"
+	"github.com/lamoda-seller-app/internal/repository"
+	"github.com/lamoda-seller-app/internal/validation"
+	"gorm.io/gorm"
+)
+
+// CategoryHandler обрабатывает HTTP запросы, связанные со справочником категорий.
+type CategoryHandler struct {
+	repo *repository.CategoryRepository
+}
+
+func NewCategoryHandler(repo *repository.CategoryRepository) *CategoryHandler {
+	return &CategoryHandler{repo: repo}
+}
+
+// --- Обработчики ---
+
+// ListCategories GET /api/categories
+func (h *CategoryHandler) ListCategories(c *gin.Context) {
+	categories, err := h.repo.Tree(c.Request.Context())
+	if err != nil {
+		log.Printf("❌ Categories ListCategories: ошибка получения категорий: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get categories"})
+		return
+	}
+	if lang := c.Query("lang"); lang != "" {
+		for i := range categories {
+			categories[i].Localize(lang)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"categories": categories})
+}
+
+// GetCategory GET /api/categories/{id}
+func (h *CategoryHandler) GetCategory(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid category ID format"})
+		return
+	}
+
+	category, err := h.repo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "category not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error: " + err.Error()})
+		return
+	}
+	if lang := c.Query("lang"); lang != "" {
+		category.Localize(lang)
+	}
+
+	c.JSON(http.StatusOK, category)
+}
+
+// CreateCategory POST /api/categories
+func (h *CategoryHandler) CreateCategory(c *gin.Context) {
+	var req model.CategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid input: " + err.Error()})
+		return
+	}
+
+	category := model.Category{
+		ID:        uuid.New(),
+		ParentID:  req.ParentID,
+		Slug:      req.Slug,
+		Name:      req.Name,
+		Names:     req.Names,
+		SortOrder: req.SortOrder,
+	}
+	if category.Names == nil {
+		category.Names = model.LocalizedNames{}
+	}
+
+	if err := h.repo.Create(c.Request.Context(), &category); err != nil {
+		log.Printf("❌ Categories CreateCategory: ошибка создания категории: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create category: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":  "Категория успешно создана",
+		"category": category,
+	})
+}
+
+// UpdateCategory PUT /api/categories/{id}
+// Слаг менять нельзя (409): по нему на категорию ссылаются товары.
+func (h *CategoryHandler) UpdateCategory(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid category ID format"})
+		return
+	}
+
+	var req model.CategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid input: " + err.Error()})
+		return
+	}
+
+	category, err := h.repo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "category not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error: " + err.Error()})
+		return
+	}
+
+	category.ParentID = req.ParentID
+	category.Slug = req.Slug
+	category.Name = req.Name
+	category.SortOrder = req.SortOrder
+	if req.Names != nil {
+		category.Names = req.Names
+	}
+
+	if err := h.repo.Update(c.Request.Context(), category); err != nil {
+		if errors.Is(err, repository.ErrCategoryCycle) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, repository.ErrCategorySlugChange) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "parent category not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update category: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Категория успешно обновлена",
+		"category": category,
+	})
+}
+
+// DeleteCategory DELETE /api/categories/{id}
+func (h *CategoryHandler) DeleteCategory(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid category ID format"})
+		return
+	}
+
+	if err := h.repo.Delete(c.Request.Context(), id); err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "category not found"})
+		case errors.Is(err, repository.ErrCategoryHasChildren), errors.Is(err, repository.ErrCategoryInUse):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete category: " + err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Категория успешно удалена"})
+}
+
+// GetCategorySchema GET /api/categories/{id}/schema
+// Возвращает итоговую схему атрибутов с учетом наследования от родительских категорий.
+func (h *CategoryHandler) GetCategorySchema(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid category ID format"})
+		return
+	}
+
+	category, err := h.repo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "category not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error: " + err.Error()})
+		return
+	}
+
+	schema, err := h.repo.ResolveSchema(c.Request.Context(), category.Slug)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to resolve category schema: " + err.Error()})
+		return
+	}
+	if lang := c.Query("lang"); lang != "" {
+		for i := range schema {
+			if name := schema[i].Names.Get(lang); name != "" {
+				schema[i].Name = name
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"category":   category.Slug,
+		"attributes": schema,
+	})
+}
+
+// UpdateCategorySchema PUT /api/categories/{id}/schema
+// Заменяет собственные атрибуты категории (унаследованные не затрагиваются).
+func (h *CategoryHandler) UpdateCategorySchema(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid category ID format"})
+		return
+	}
+
+	var req model.CategorySchemaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid input: " + err.Error()})
+		return
+	}
+	if errs := validation.ValidateCategorySchema(req.Attributes); errs.HasErrors() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "category schema is invalid", "details": errs})
+		return
+	}
+
+	if _, err := h.repo.GetByID(c.Request.Context(), id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "category not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error: " + err.Error()})
+		return
+	}
+
+	if err := h.repo.ReplaceAttributes(c.Request.Context(), id, req.Attributes); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update category schema: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Схема атрибутов успешно обновлена",
+		"attributes": req.Attributes,
+	})
+}
@@ -0,0 +1,197 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lamoda-seller-app/internal/model"
+)
+
+// GetRFMAnalytics GET /api/analytics/rfm?period=&category=&segment=&limit=&offset=
+func (h *AnalyticsHandler) GetRFMAnalytics(c *gin.Context) {
+	var params model.RFMRequestParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query parameters: " + err.Error()})
+		return
+	}
+	if !validRFMSegment(params.Segment) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unknown segment"})
+		return
+	}
+
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	response, err := h.repo.GetRFM(c.Request.Context(), userID, params)
+	if err != nil {
+		log.Printf("Error in GetRFM repo call: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get rfm analytics"})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// ExportRFMAnalytics GET /api/analytics/rfm/export?format=csv|json&period=&category=&segment=
+// Выгружает всех покупателей выборки с баллами и сегментами.
+func (h *AnalyticsHandler) ExportRFMAnalytics(c *gin.Context) {
+	var params model.RFMRequestParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query parameters: " + err.Error()})
+		return
+	}
+	if !validRFMSegment(params.Segment) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unknown segment"})
+		return
+	}
+	params.All = true
+
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	response, err := h.repo.GetRFM(c.Request.Context(), userID, params)
+	if err != nil {
+		log.Printf("Error in GetRFM repo call: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get rfm analytics"})
+		return
+	}
+
+	filename := "rfm_" + time.Now().Format("2006-01-02")
+	writeAnalyticsExport(c, filename, response, func(w *csv.Writer) {
+		w.Write([]string{"ID покупателя", "Имя", "Email", "Последний заказ", "Дней с заказа", "Заказов", "Сумма", "R", "F", "M", "RFM", "Сегмент"})
+		for _, row := range response.Customers {
+			w.Write([]string{
+				row.CustomerID.String(), csvText(row.Name), csvText(row.Email), row.LastOrderAt.Format("2006-01-02"),
+				strconv.Itoa(row.RecencyDays), strconv.Itoa(row.Frequency), strconv.FormatFloat(row.Monetary, 'f', 2, 64),
+				strconv.Itoa(row.RScore), strconv.Itoa(row.FScore), strconv.Itoa(row.MScore), row.RFMScore, row.Segment,
+			})
+		}
+	})
+}
+
+// GetCohortAnalytics GET /api/analytics/cohorts?period=&category=
+func (h *AnalyticsHandler) GetCohortAnalytics(c *gin.Context) {
+	var params model.CohortRequestParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query parameters: " + err.Error()})
+		return
+	}
+
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	response, err := h.repo.GetCohorts(c.Request.Context(), userID, params)
+	if err != nil {
+		log.Printf("Error in GetCohorts repo call: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get cohort analytics"})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// ExportCohortAnalytics GET /api/analytics/cohorts/export?format=csv|json&period=&category=
+// В csv строка - когорта, столбцы - доля вернувшихся покупателей по месяцам после первого заказа.
+func (h *AnalyticsHandler) ExportCohortAnalytics(c *gin.Context) {
+	var params model.CohortRequestParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query parameters: " + err.Error()})
+		return
+	}
+
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	response, err := h.repo.GetCohorts(c.Request.Context(), userID, params)
+	if err != nil {
+		log.Printf("Error in GetCohorts repo call: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get cohort analytics"})
+		return
+	}
+
+	filename := "cohorts_" + time.Now().Format("2006-01-02")
+	writeAnalyticsExport(c, filename, response, func(w *csv.Writer) {
+		header := []string{"Когорта", "Покупателей"}
+		for offset := range response.Average {
+			header = append(header, "М"+strconv.Itoa(offset)+", %")
+		}
+		w.Write(header)
+
+		rateRow := func(label string, customers int, cells []model.CohortCell) []string {
+			row := []string{label, strconv.Itoa(customers)}
+			for _, cell := range cells {
+				row = append(row, strconv.FormatFloat(cell.Rate, 'f', 1, 64))
+			}
+			return row
+		}
+		for _, cohort := range response.Cohorts {
+			w.Write(rateRow(cohort.Month, cohort.Customers, cohort.Retention))
+		}
+		total := 0
+		for _, cohort := range response.Cohorts {
+			total += cohort.Customers
+		}
+		w.Write(rateRow("Среднее", total, response.Average))
+	})
+}
+
+// writeAnalyticsExport отдает отчет файлом: csv (по умолчанию, разделитель ";") или json.
+func writeAnalyticsExport(c *gin.Context, filename string, report interface{}, writeCSV func(w *csv.Writer)) {
+	switch c.DefaultQuery("format", "csv") {
+	case "json":
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to build export: " + err.Error()})
+			return
+		}
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.json"`, filename))
+		c.Data(http.StatusOK, "application/json", data)
+	case "csv":
+		var buf bytes.Buffer
+		w := csv.NewWriter(&buf)
+		w.Comma = ';'
+		writeCSV(w)
+		w.Flush()
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.csv"`, filename))
+		c.Data(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be csv or json"})
+	}
+}
+
+// csvText обезвреживает текст, введенный покупателем, для выгрузки в csv: ячейку, начинающуюся
+// с = + - @ (или табуляции и перевода строки), Excel выполнил бы как формулу.
+func csvText(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+func validRFMSegment(segment string) bool {
+	if segment == "" {
+		return true
+	}
+	for _, s := range model.RFMSegments {
+		if s == segment {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lamoda-seller-app/internal/middleware"
+	"github.com/lamoda-seller-app/internal/model"
+	"github.com/lamoda-seller-app/internal/repository"
+	"gorm.io/gorm"
+)
+
+// CustomerHandler обрабатывает запросы к справочнику покупателей продавца.
+type CustomerHandler struct {
+	repo      *repository.CustomerRepository
+	orderRepo *repository.OrderRepository
+}
+
+func NewCustomerHandler(repo *repository.CustomerRepository, orderRepo *repository.OrderRepository) *CustomerHandler {
+	return &CustomerHandler{repo: repo, orderRepo: orderRepo}
+}
+
+// ListCustomers GET /api/customers?search=&is_regular=&min_orders=&sort_by=&sort_order=&limit=&offset=
+func (h *CustomerHandler) ListCustomers(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+
+	filter := model.CustomerFilter{
+		Search:    c.Query("search"),
+		SortBy:    c.Query("sort_by"),
+		SortOrder: c.Query("sort_order"),
+	}
+	if raw := c.Query("is_regular"); raw != "" {
+		isRegular, err := strconv.ParseBool(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid is_regular value"})
+			return
+		}
+		filter.IsRegular = &isRegular
+	}
+	filter.MinOrders, _ = strconv.Atoi(c.Query("min_orders"))
+	limit, offset := offsetPage(c)
+
+	customers, total, err := h.repo.List(c.Request.Context(), userID, filter, limit, offset)
+	if err != nil {
+		log.Printf("❌ Customers ListCustomers: ошибка получения покупателей: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve customers: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"customers":  customers,
+		"pagination": offsetPagination(total, limit, offset),
+	})
+}
+
+// GetCustomer GET /api/customers/:id
+func (h *CustomerHandler) GetCustomer(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+
+	customer, ok := h.customer(c, userID)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, customer)
+}
+
+// ListCustomerOrders GET /api/customers/:id/orders
+// История заказов покупателя. Принимает те же фильтры, сортировку и пагинацию, что и GET /api/orders.
+func (h *CustomerHandler) ListCustomerOrders(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+
+	customer, ok := h.customer(c, userID)
+	if !ok {
+		return
+	}
+	page, err := parsePageRequest(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	params := orderListParams(c.Request.URL.Query(), userID)
+	params.CustomerID = customer.ID
+	params.Limit, params.Offset = page.Limit, page.Offset
+	params.Cursor, params.Keyset = page.Cursor, page.Keyset
+
+	orders, summary, total, pageInfo, err := h.orderRepo.List(c.Request.Context(), params)
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		log.Printf("❌ Customers ListCustomerOrders: ошибка получения заказов: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve orders: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, ListOrdersAPIResponse{
+		Orders:     orders,
+		Summary:    summary,
+		Pagination: paginationResponse(page, total, pageInfo),
+	})
+}
+
+// customer загружает покупателя из параметра :id и сам отвечает на ошибку.
+func (h *CustomerHandler) customer(c *gin.Context, userID uuid.UUID) (*model.Customer, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid customer ID format"})
+		return nil, false
+	}
+	customer, err := h.repo.GetByID(c.Request.Context(), userID, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "customer not found"})
+			return nil, false
+		}
+		log.Printf("❌ Customers: ошибка получения покупателя %s: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error: " + err.Error()})
+		return nil, false
+	}
+	return customer, true
+}
@@ -0,0 +1,290 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lamoda-seller-app/internal/events"
+	"github.com/lamoda-seller-app/internal/middleware"
+	"github.com/lamoda-seller-app/internal/model"
+	"github.com/lamoda-seller-app/internal/repository"
+)
+
+const (
+	streamReplayLimit   = 500              // Сколько пропущенных событий дочитывается при переподключении
+	streamHeartbeat     = 25 * time.Second // Комментарий-пинг, чтобы прокси не закрывали молчащее соединение
+	streamRetryMillis   = 3000             // Через сколько браузер переподключается после обрыва
+	dashboardRefreshLag = 2 * time.Second  // Пачка событий о заказах пересчитывает дашборд один раз
+	streamTouchInterval = time.Hour        // Как часто открытый поток продлевает отметку об использовании
+	streamTicketTTL     = 30 * time.Second // Сколько выданный билет ждет первого подключения
+)
+
+// EventStreamHandler отдает поток живых обновлений продавца (Server-Sent Events).
+type EventStreamHandler struct {
+	bus           *events.Bus
+	repo          *repository.EventRepository
+	dashboardRepo repository.DashboardRepositoryInterface
+}
+
+func NewEventStreamHandler(bus *events.Bus, repo *repository.EventRepository, dashboardRepo repository.DashboardRepositoryInterface) *EventStreamHandler {
+	return &EventStreamHandler{bus: bus, repo: repo, dashboardRepo: dashboardRepo}
+}
+
+// IssueTicket POST /api/events/stream-ticket
+// Выдает билет на подключение к потоку: GET /api/events/stream?ticket=...
+// Билет нужно использовать в течение 30 секунд. Пока поток открыт, билет продлевается, и EventSource
+// переподключается с ним сам, передавая Last-Event-ID; билет истекает через 2 минуты после закрытия
+// потока. Если переподключение получило 401, клиент берет новый билет и подключается заново
+// с параметром last_event_id, равным последнему полученному id.
+func (h *EventStreamHandler) IssueTicket(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	ticket, expiresAt, err := h.repo.IssueStreamTicket(c.Request.Context(), userID, streamTicketTTL)
+	if err != nil {
+		log.Printf("❌ Events Stream: ошибка выдачи билета: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to issue stream ticket: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, model.StreamTicketResponse{Ticket: ticket, ExpiresAt: expiresAt})
+}
+
+// Stream GET /api/events/stream
+// События: order.created, order.status_changed, stock.low, product.updated, return.created
+// и dashboard.updated - показатели за сегодня, при подключении и после событий о заказах и возвратах.
+// id событий - позиция в журнале: браузер передает ее при переподключении в Last-Event-ID,
+// и поток продолжается с пропущенных событий; если их слишком много, приходит resync.
+// После переподключения события могут повториться - повтор узнается по seq.
+// EventSource не умеет передавать заголовки, поэтому вместо токена можно передать
+// параметр ticket (см. IssueTicket).
+func (h *EventStreamHandler) Stream(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	ctx := c.Request.Context()
+
+	lastID, err := lastEventID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid Last-Event-ID"})
+		return
+	}
+
+	// Поток живет дольше WriteTimeout сервера
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		log.Printf("⚠️ Events Stream: не удалось снять таймаут записи: %v", err)
+	}
+
+	ticket := c.GetString(middleware.StreamTicketKey)
+
+	// Без отметки об использовании потока события product.updated не пишутся в журнал
+	if err := h.repo.TouchStream(ctx, userID); err != nil {
+		log.Printf("⚠️ Events Stream: не удалось отметить подключение: %v", err)
+	}
+	touchedAt := time.Now()
+
+	// Подписка до чтения журнала, чтобы не потерять события между ними
+	sub := h.bus.Subscribe(userID)
+	defer h.bus.Unsubscribe(sub)
+
+	header := c.Writer.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	header.Set("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	fmt.Fprintf(c.Writer, "retry: %d\n\n", streamRetryMillis)
+
+	// cursor - позиция журнала, до которой клиент получил все события
+	sent := sentEvents{}
+	cursor := lastID
+	if lastID > 0 {
+		cursor, err = h.catchUp(ctx, c, userID, lastID, sent)
+	} else {
+		cursor, err = h.repo.Watermark(ctx)
+	}
+	if err != nil {
+		log.Printf("❌ Events Stream: ошибка чтения журнала: %v", err)
+		return
+	}
+	writeStreamCursor(c, cursor)
+	h.sendDashboard(ctx, c, userID)
+	c.Writer.Flush()
+	log.Printf("📡 Events Stream: клиент продавца %s подключен (Last-Event-ID %d)", userID, lastID)
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+	var dashboardDue <-chan time.Time
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-sub.C:
+			if !ok {
+				// Клиент не успевал читать или сервер останавливается - браузер переподключится
+				return
+			}
+			if !sent.add(event) {
+				continue // Уже отправлено из журнала
+			}
+			writeStreamEvent(c, cursor, event.Type, event)
+			switch event.Type {
+			case model.WebhookEventOrderCreated, model.WebhookEventOrderStatusChanged, model.WebhookEventReturnCreated:
+				if dashboardDue == nil {
+					dashboardDue = time.After(dashboardRefreshLag)
+				}
+			}
+		case <-dashboardDue:
+			dashboardDue = nil
+			h.sendDashboard(ctx, c, userID)
+		case <-heartbeat.C:
+			// Дочитываем журнал и сдвигаем позицию: уведомление о событии могло прийти
+			// раньше подписки или потеряться при переподключении Listener к БД
+			if next, err := h.catchUp(ctx, c, userID, cursor, sent); err != nil {
+				log.Printf("⚠️ Events Stream: ошибка чтения журнала: %v", err)
+			} else {
+				sent.forget(cursor)
+				cursor = next
+				writeStreamCursor(c, cursor)
+			}
+			fmt.Fprint(c.Writer, ": ping\n\n")
+			if ticket != "" {
+				// Билет продлевается, чтобы браузер мог переподключиться с ним после обрыва
+				if _, err := h.repo.UseStreamTicket(ctx, ticket); err != nil {
+					log.Printf("⚠️ Events Stream: не удалось продлить билет: %v", err)
+				}
+			}
+			if time.Since(touchedAt) >= streamTouchInterval {
+				if err := h.repo.TouchStream(ctx, userID); err != nil {
+					log.Printf("⚠️ Events Stream: не удалось отметить подключение: %v", err)
+				}
+				touchedAt = time.Now()
+			}
+		}
+		c.Writer.Flush()
+	}
+}
+
+// catchUp отправляет клиенту события журнала начиная с позиции from, которых он еще не получил,
+// и возвращает новую позицию. Если пропущенных событий больше streamReplayLimit, вместо них
+// отправляется resync. События отправляются с id = from: если поток оборвется посередине,
+// клиент дочитает их заново.
+func (h *EventStreamHandler) catchUp(ctx context.Context, c *gin.Context, userID uuid.UUID, from int64, sent sentEvents) (int64, error) {
+	// Граница берется до чтения: все события транзакций до нее будут прочитаны
+	watermark, err := h.repo.Watermark(ctx)
+	if err != nil {
+		return from, err
+	}
+
+	var missed []events.Event
+	var afterSeq int64
+	for {
+		page, err := h.repo.Since(ctx, []uuid.UUID{userID}, from, afterSeq, streamReplayLimit)
+		if err != nil {
+			return from, err
+		}
+		for _, e := range page {
+			afterSeq = e.Seq
+			if event := events.FromModel(e); sent.add(event) {
+				missed = append(missed, event)
+			}
+		}
+		if len(missed) >= streamReplayLimit {
+			// Клиент перезагрузит данные целиком и продолжит с текущей позиции
+			writeStreamEvent(c, watermark, model.LiveEventResync, gin.H{"reason": "too many missed events"})
+			return watermark, nil
+		}
+		if len(page) < streamReplayLimit {
+			break
+		}
+	}
+	for _, event := range missed {
+		writeStreamEvent(c, from, event.Type, event)
+	}
+	return watermark, nil
+}
+
+// sentEvents - события, уже отправленные клиенту: номер события -> номер транзакции.
+// Событие может прийти и из шины, и при дочитывании журнала; повтор отсеивается по номеру.
+type sentEvents map[int64]int64
+
+// add запоминает событие и сообщает, что оно еще не отправлялось.
+func (s sentEvents) add(event events.Event) bool {
+	if _, ok := s[event.Seq]; ok {
+		return false
+	}
+	s[event.Seq] = event.TxID
+	return true
+}
+
+// forget забывает события транзакций до позиции watermark: дочитывание журнала с более поздней
+// позиции их не вернет. Вызывается с предыдущей позицией, чтобы шина успела доставить запоздавшие.
+func (s sentEvents) forget(watermark int64) {
+	for seq, txID := range s {
+		if txID < watermark {
+			delete(s, seq)
+		}
+	}
+}
+
+// sendDashboard отправляет показатели дашборда за сегодня. Ошибка не прерывает поток.
+func (h *EventStreamHandler) sendDashboard(ctx context.Context, c *gin.Context, userID uuid.UUID) {
+	now := time.Now().UTC()
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	data, err := h.dashboardRepo.GetAggregatedData(ctx, userID, start, now)
+	if err != nil {
+		log.Printf("❌ Events Stream: ошибка расчета дашборда: %v", err)
+		return
+	}
+	writeStreamEvent(c, 0, model.LiveEventDashboard, model.LiveDashboard{
+		Date:          start.Format(dateFormat),
+		Revenue:       data.Revenue,
+		Orders:        data.OrdersCount,
+		ItemsSold:     data.ItemsSoldCount,
+		AvgOrderValue: safeDivide(data.Revenue, float64(data.OrdersCount)),
+		Returns:       data.ReturnsCount,
+		UpdatedAt:     now,
+	})
+}
+
+// writeStreamEvent пишет событие в формате SSE. События без позиции (id = 0) отправляются без поля id,
+// чтобы не сбивать Last-Event-ID браузера.
+func writeStreamEvent(c *gin.Context, id int64, event string, data interface{}) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		log.Printf("❌ Events Stream: ошибка сериализации события %s: %v", event, err)
+		return
+	}
+	if id > 0 {
+		fmt.Fprintf(c.Writer, "id: %d\n", id)
+	}
+	fmt.Fprintf(c.Writer, "event: %s\ndata: %s\n\n", event, payload)
+}
+
+// writeStreamCursor сообщает клиенту новую позицию в журнале без события: браузер запоминает id
+// для Last-Event-ID, даже если у сообщения нет данных.
+func writeStreamCursor(c *gin.Context, cursor int64) {
+	if cursor > 0 {
+		fmt.Fprintf(c.Writer, "id: %d\n\n", cursor)
+	}
+}
+
+// lastEventID читает позицию последнего полученного события: заголовок Last-Event-ID
+// (браузер передает его сам при переподключении) или параметр last_event_id.
+func lastEventID(c *gin.Context) (int64, error) {
+	raw := c.GetHeader("Last-Event-ID")
+	if raw == "" {
+		raw = c.Query("last_event_id")
+	}
+	if raw == "" {
+		return 0, nil
+	}
+	id, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || id < 0 {
+		return 0, fmt.Errorf("invalid event id %q", raw)
+	}
+	return id, nil
+}
@@ -0,0 +1,394 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lamoda-seller-app/internal/barcode"
+	"github.com/lamoda-seller-app/internal/middleware"
+	"github.com/lamoda-seller-app/internal/model"
+	"github.com/lamoda-seller-app/internal/render"
+	"github.com/lamoda-seller-app/internal/repository"
+	"gorm.io/gorm"
+)
+
+// LabelHandler обрабатывает запросы к штрихкодам и печати этикеток.
+type LabelHandler struct {
+	repo        *repository.LabelRepository
+	orderRepo   *repository.OrderRepository
+	markingRepo *repository.MarkingRepository
+	userRepo    *repository.UserRepository
+}
+
+func NewLabelHandler(repo *repository.LabelRepository, orderRepo *repository.OrderRepository, markingRepo *repository.MarkingRepository, userRepo *repository.UserRepository) *LabelHandler {
+	return &LabelHandler{repo: repo, orderRepo: orderRepo, markingRepo: markingRepo, userRepo: userRepo}
+}
+
+// --- Штрихкоды ---
+
+// ValidateBarcode GET /api/barcodes/validate?code=
+// Проверяет длину и контрольную цифру GTIN и возвращает его в формате хранения и для печати.
+func (h *LabelHandler) ValidateBarcode(c *gin.Context) {
+	code := strings.TrimSpace(c.Query("code"))
+	if code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "code is required"})
+		return
+	}
+
+	gtin, err := barcode.NormalizeGTIN(code)
+	if err != nil {
+		response := gin.H{"valid": false, "error": err.Error()}
+		// Подсказываем правильную контрольную цифру, если ошибка только в ней
+		if errors.Is(err, barcode.ErrGTINCheckDigit) {
+			check, _ := barcode.CheckDigit(code[:len(code)-1])
+			response["expected_check_digit"] = string(check)
+		}
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	response := gin.H{"valid": true, "gtin": gtin}
+	if ean, ok := barcode.EAN13(gtin); ok {
+		response["ean13"] = ean
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// GenerateBarcodes POST /api/barcodes/generate
+// Выпускает EAN-13 из диапазона префикса GS1 продавца вариантам товаров, у которых нет GTIN.
+func (h *LabelHandler) GenerateBarcodes(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+
+	var req model.GenerateBarcodesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid input: " + err.Error()})
+		return
+	}
+
+	generated, err := h.repo.GenerateGTINs(c.Request.Context(), userID, req.ProductIDs)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrGS1PrefixMissing):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		case errors.Is(err, barcode.ErrGS1Range):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			log.Printf("❌ Labels GenerateBarcodes: ошибка выпуска штрихкодов: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate barcodes: " + err.Error()})
+		}
+		return
+	}
+	if generated == nil {
+		generated = []model.GeneratedBarcode{}
+	}
+
+	log.Printf("✅ Labels GenerateBarcodes: выпущено %d штрихкодов", len(generated))
+	c.JSON(http.StatusOK, gin.H{
+		"message":  fmt.Sprintf("Выпущено штрихкодов: %d", len(generated)),
+		"barcodes": generated,
+	})
+}
+
+// --- Этикетки ---
+
+// PrintVariantLabels POST /api/labels/variants
+// Печатает этикетки вариантов. PDF содержит по странице на каждую копию, PNG и SVG - одну этикетку.
+func (h *LabelHandler) PrintVariantLabels(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+
+	var req model.PrintVariantLabelsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid input: " + err.Error()})
+		return
+	}
+
+	options, ok := labelOptions(c, req.Format, req.Size, render.VariantLabelSize, req.DPI)
+	if !ok {
+		return
+	}
+	symbology, err := render.ParseSymbology(req.Symbology)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ids := make([]uuid.UUID, len(req.Items))
+	total := 0
+	for i, item := range req.Items {
+		ids[i] = item.VariantID
+		total += max(item.Copies, 1)
+	}
+	if total > model.MaxLabelsPerRequest {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("too many labels, at most %d per request", model.MaxLabelsPerRequest)})
+		return
+	}
+
+	variants, err := h.repo.Variants(c.Request.Context(), userID, ids)
+	if err != nil {
+		if errors.Is(err, repository.ErrVariantNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve variants: " + err.Error()})
+		return
+	}
+
+	var pages []render.Page
+	for i, variant := range variants {
+		page, err := render.BuildVariantLabel(variantLabel(variant), options.size, symbology)
+		if err != nil {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": fmt.Sprintf("variant %s: %v", variant.SKU, err)})
+			return
+		}
+		for n := 0; n < max(req.Items[i].Copies, 1); n++ {
+			pages = append(pages, page)
+		}
+	}
+
+	h.writeLabels(c, options, pages, "labels")
+}
+
+// GetProductLabels GET /api/products/{id}/labels?format=pdf|png|svg&variant_id=&size=58x40&symbology=&dpi=
+// Печатает по этикетке на каждый вариант товара или на один вариант, если задан variant_id.
+func (h *LabelHandler) GetProductLabels(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+
+	productID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product ID format"})
+		return
+	}
+	variantID, err := optionalUUIDQuery(c.Request.URL.Query(), "variant_id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	dpi, _ := strconv.Atoi(c.Query("dpi"))
+	options, ok := labelOptions(c, c.Query("format"), c.Query("size"), render.VariantLabelSize, dpi)
+	if !ok {
+		return
+	}
+	symbology, err := render.ParseSymbology(c.Query("symbology"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	variants, err := h.repo.ProductVariants(c.Request.Context(), userID, productID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve variants: " + err.Error()})
+		return
+	}
+	if variantID != nil {
+		var selected []model.LabelVariant
+		for _, v := range variants {
+			if v.VariantID == *variantID {
+				selected = append(selected, v)
+			}
+		}
+		variants = selected
+	}
+	if len(variants) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "product variants not found"})
+		return
+	}
+
+	pages := make([]render.Page, 0, len(variants))
+	for _, variant := range variants {
+		page, err := render.BuildVariantLabel(variantLabel(variant), options.size, symbology)
+		if err != nil {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": fmt.Sprintf("variant %s: %v", variant.SKU, err)})
+			return
+		}
+		pages = append(pages, page)
+	}
+
+	h.writeLabels(c, options, pages, "labels-"+variants[0].SKU)
+}
+
+// GetPackingLabel GET /api/orders/{order_id}/packing-label?format=pdf|png|svg&size=100x150&dpi=
+// Упаковочная этикетка: номер заказа и трек-номер штрихкодами, получатель и адрес.
+func (h *LabelHandler) GetPackingLabel(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+
+	orderID, err := uuid.Parse(c.Param("order_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid order ID format"})
+		return
+	}
+	dpi, _ := strconv.Atoi(c.Query("dpi"))
+	options, ok := labelOptions(c, c.Query("format"), c.Query("size"), render.PackingLabelSize, dpi)
+	if !ok {
+		return
+	}
+
+	order, err := h.orderRepo.GetByID(c.Request.Context(), orderID, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "order not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error: " + err.Error()})
+		return
+	}
+	seller, err := h.userRepo.GetByID(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error: " + err.Error()})
+		return
+	}
+
+	items := 0
+	for _, item := range order.Items {
+		items += item.Quantity
+	}
+	page, err := render.BuildPackingLabel(render.PackingLabel{
+		Sender:      seller.Name,
+		OrderNumber: order.OrderNumber,
+		Tracking:    order.Delivery.TrackingNumber,
+		Delivery:    order.Delivery.Type,
+		Customer:    order.Customer.Name,
+		Phone:       order.Customer.Phone,
+		Address:     formatAddress(order.Delivery.Address),
+		Items:       items,
+		Date:        order.Date.Format("02.01.2006"),
+	}, options.size)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "failed to build label: " + err.Error()})
+		return
+	}
+
+	h.writeLabels(c, options, []render.Page{page}, "packing-"+order.OrderNumber)
+}
+
+// PrintMarkingLabels POST /api/labels/marking
+// Печатает коды маркировки "Честного ЗНАКа" в виде GS1 Data Matrix, по этикетке на код.
+func (h *LabelHandler) PrintMarkingLabels(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+
+	var req model.PrintMarkingLabelsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid input: " + err.Error()})
+		return
+	}
+	options, ok := labelOptions(c, req.Format, req.Size, render.VariantLabelSize, req.DPI)
+	if !ok {
+		return
+	}
+
+	codes, err := h.markingRepo.CodesByIDs(c.Request.Context(), userID, req.CodeIDs)
+	if err != nil {
+		if errors.Is(err, repository.ErrMarkingCodeNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve marking codes: " + err.Error()})
+		return
+	}
+
+	pages := make([]render.Page, 0, len(codes))
+	for _, code := range codes {
+		parts, err := model.ParseMarkingCode(code.Code)
+		if err != nil {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": fmt.Sprintf("code %s: %v", code.CIS, err)})
+			return
+		}
+		page, err := render.BuildMarkingLabel(render.MarkingLabel{
+			Title:      code.ProductName,
+			Attributes: joinNonEmpty(", ", code.Color, code.Size),
+			Code:       parts.Code,
+			GTIN:       parts.GTIN,
+			Serial:     parts.Serial,
+		}, options.size)
+		if err != nil {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": fmt.Sprintf("code %s: %v", code.CIS, err)})
+			return
+		}
+		pages = append(pages, page)
+	}
+
+	h.writeLabels(c, options, pages, "marking-labels")
+}
+
+// --- Вспомогательные функции ---
+
+type labelOutput struct {
+	format render.Format
+	size   render.LabelSize
+	dpi    int
+}
+
+// labelOptions проверяет формат, размер и разрешение. При ошибке сам пишет ответ и возвращает false.
+func labelOptions(c *gin.Context, format, size string, defaultSize render.LabelSize, dpi int) (labelOutput, bool) {
+	f, err := render.ParseFormat(format)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return labelOutput{}, false
+	}
+	s, err := render.ParseLabelSize(size, defaultSize)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return labelOutput{}, false
+	}
+	if dpi != 0 && (dpi < 72 || dpi > 600) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "dpi must be between 72 and 600"})
+		return labelOutput{}, false
+	}
+	return labelOutput{format: f, size: s, dpi: dpi}, true
+}
+
+func (h *LabelHandler) writeLabels(c *gin.Context, options labelOutput, pages []render.Page, name string) {
+	var buf bytes.Buffer
+	if err := render.Write(&buf, options.format, pages, options.dpi); err != nil {
+		log.Printf("❌ Labels writeLabels: ошибка формирования файла: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to render labels: " + err.Error()})
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`inline; filename="%s.%s"`, name, options.format))
+	c.Data(http.StatusOK, options.format.ContentType(), buf.Bytes())
+}
+
+func variantLabel(v model.LabelVariant) render.VariantLabel {
+	return render.VariantLabel{
+		Title:      joinNonEmpty(" ", v.Brand, v.Name),
+		Attributes: joinNonEmpty(", ", v.Color, v.Size),
+		SKU:        v.SKU,
+		GTIN:       v.GTIN,
+		Price:      labelPrice(v.Price, v.Currency),
+	}
+}
+
+// labelPrice - цена для этикетки в целых рублях; без цены строка не печатается.
+func labelPrice(price float64, currency string) string {
+	if price <= 0 {
+		return ""
+	}
+	return render.FormatMoney(price, currency, false)
+}
+
+func formatAddress(a model.Address) string {
+	house, apartment := a.House, a.Apartment
+	if house != "" {
+		house = "д. " + house
+	}
+	if apartment != "" {
+		apartment = "кв. " + apartment
+	}
+	return joinNonEmpty(", ", a.PostalCode, a.City, a.Street, house, apartment)
+}
+
+func joinNonEmpty(sep string, parts ...string) string {
+	var nonEmpty []string
+	for _, p := range parts {
+		if p = strings.TrimSpace(p); p != "" {
+			nonEmpty = append(nonEmpty, p)
+		}
+	}
+	return strings.Join(nonEmpty, sep)
+}
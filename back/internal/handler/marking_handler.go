@@ -0,0 +1,525 @@
+package handler
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lamoda-seller-app/internal/marking"
+	"github.com/lamoda-seller-app/internal/middleware"
+	"github.com/lamoda-seller-app/internal/model"
+	"github.com/lamoda-seller-app/internal/repository"
+	"gorm.io/gorm"
+)
+
+// MarkingHandler обрабатывает запросы к кодам маркировки "Честного ЗНАКа".
+type MarkingHandler struct {
+	repo   *repository.MarkingRepository
+	portal marking.Portal
+}
+
+func NewMarkingHandler(repo *repository.MarkingRepository, portal marking.Portal) *MarkingHandler {
+	return &MarkingHandler{repo: repo, portal: portal}
+}
+
+// ImportMarkingCodes POST /api/marking/batches
+// Принимает файл с кодами (multipart, поле file, по коду в строке) или JSON со списком codes.
+// Вариант задается полем variant_id или определяется по GTIN кодов.
+func (h *MarkingHandler) ImportMarkingCodes(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+
+	var req repository.ImportMarkingCodes
+	var rawCodes []string
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "file is required: " + err.Error()})
+			return
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read file: " + err.Error()})
+			return
+		}
+		defer file.Close()
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			// В выгрузках ГИС МТ код может быть первой колонкой таблицы
+			line, _, _ := strings.Cut(scanner.Text(), "\t")
+			rawCodes = append(rawCodes, line)
+		}
+		if err := scanner.Err(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read file: " + err.Error()})
+			return
+		}
+
+		req.FileName = fileHeader.Filename
+		req.GTIN = c.PostForm("gtin")
+		if raw := c.PostForm("variant_id"); raw != "" {
+			variantID, err := uuid.Parse(raw)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid variant ID format"})
+				return
+			}
+			req.VariantID = &variantID
+		}
+	} else {
+		var body model.ImportMarkingCodesRequest
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid input: " + err.Error()})
+			return
+		}
+		rawCodes = body.Codes
+		req.VariantID, req.GTIN = body.VariantID, body.GTIN
+	}
+
+	var rejected []model.MarkingCodeRejection
+	for i, raw := range rawCodes {
+		if strings.TrimSpace(raw) == "" {
+			continue
+		}
+		parts, err := model.ParseMarkingCode(raw)
+		if err != nil {
+			rejected = append(rejected, model.MarkingCodeRejection{Line: i + 1, Code: strings.TrimSpace(raw), Error: err.Error()})
+			continue
+		}
+		req.Codes = append(req.Codes, parts)
+		req.Lines = append(req.Lines, i+1)
+	}
+	if len(req.Codes) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "no valid marking codes found", "rejected": rejected})
+		return
+	}
+	if len(req.Codes) > model.MaxMarkingCodesPerImport {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("too many codes in one batch, max %d", model.MaxMarkingCodesPerImport)})
+		return
+	}
+	if req.VariantID == nil && req.GTIN == "" {
+		req.GTIN = req.Codes[0].GTIN
+	}
+
+	batch, err := h.repo.Import(c.Request.Context(), userID, &userID, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrVariantNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "variant not found: specify variant_id or set the variant GTIN"})
+		case errors.Is(err, repository.ErrGTINMismatch), errors.Is(err, repository.ErrGTINInUse):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			log.Printf("❌ Marking ImportMarkingCodes: ошибка загрузки кодов: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to import marking codes: " + err.Error()})
+		}
+		return
+	}
+	batch.Rejected = append(rejected, batch.Rejected...)
+
+	log.Printf("✅ Marking ImportMarkingCodes: загружено кодов: %d, повторов: %d, отклонено: %d",
+		batch.Imported, batch.Duplicates, len(batch.Rejected))
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Коды маркировки загружены",
+		"batch":   batch,
+	})
+}
+
+// ListMarkingBatches GET /api/marking/batches?variant_id=
+func (h *MarkingHandler) ListMarkingBatches(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	limit, offset := offsetPage(c)
+
+	variantID, err := optionalUUIDQuery(c.Request.URL.Query(), "variant_id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid variant_id"})
+		return
+	}
+
+	batches, total, err := h.repo.ListBatches(c.Request.Context(), userID, variantID, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve batches: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"batches":    batches,
+		"pagination": offsetPagination(total, limit, offset),
+	})
+}
+
+// ListMarkingCodes GET /api/marking/codes?status=&variant_id=&product_id=&batch_id=&order_id=&report_id=&search=
+func (h *MarkingHandler) ListMarkingCodes(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	q := c.Request.URL.Query()
+
+	params := repository.ListMarkingCodesParams{
+		UserID: userID,
+		Status: q.Get("status"),
+		Search: strings.TrimSpace(q.Get("search")),
+	}
+	params.Limit, params.Offset = offsetPage(c)
+
+	for key, target := range map[string]**uuid.UUID{
+		"variant_id": &params.VariantID,
+		"product_id": &params.ProductID,
+		"batch_id":   &params.BatchID,
+		"order_id":   &params.OrderID,
+		"report_id":  &params.ReportID,
+	} {
+		value, err := optionalUUIDQuery(q, key)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + key})
+			return
+		}
+		*target = value
+	}
+
+	codes, total, err := h.repo.ListCodes(c.Request.Context(), params)
+	if err != nil {
+		log.Printf("❌ Marking ListMarkingCodes: ошибка получения кодов: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve marking codes: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"codes":      codes,
+		"pagination": offsetPagination(total, params.Limit, params.Offset),
+	})
+}
+
+// GetMarkingSummary GET /api/marking/summary
+// Коды по статусам для каждого маркируемого варианта и нехватка свободных кодов под остаток.
+func (h *MarkingHandler) GetMarkingSummary(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+
+	summary, err := h.repo.Summary(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to build marking summary: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"variants": summary})
+}
+
+// WithdrawMarkingCodes POST /api/marking/codes/withdraw
+func (h *MarkingHandler) WithdrawMarkingCodes(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+
+	var req model.WithdrawMarkingCodesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid input: " + err.Error()})
+		return
+	}
+
+	updated, err := h.repo.Withdraw(c.Request.Context(), userID, req.CodeIDs, req.Reason)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to withdraw marking codes: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Коды выведены из оборота",
+		"updated": updated,
+		"skipped": int64(len(req.CodeIDs)) - updated, // Назначенные, отгруженные и чужие коды не выводятся
+	})
+}
+
+// ReintroduceMarkingCodes POST /api/marking/codes/reintroduce
+func (h *MarkingHandler) ReintroduceMarkingCodes(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+
+	var req model.MarkingCodeIDsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid input: " + err.Error()})
+		return
+	}
+
+	updated, err := h.repo.Reintroduce(c.Request.Context(), userID, req.CodeIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to reintroduce marking codes: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Коды возвращены в оборот",
+		"updated": updated,
+		"skipped": int64(len(req.CodeIDs)) - updated,
+	})
+}
+
+// --- Коды в заказе ---
+
+// GetOrderMarkingCodes GET /api/orders/{order_id}/marking-codes
+func (h *MarkingHandler) GetOrderMarkingCodes(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+
+	orderID, err := uuid.Parse(c.Param("order_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid order ID format"})
+		return
+	}
+
+	codes, err := h.repo.OrderCodes(c.Request.Context(), orderID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve marking codes: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"codes": codes})
+}
+
+// AssignOrderMarkingCodes POST /api/orders/{order_id}/marking-codes
+// Назначает позициям заказа коды, отсканированные при сборке. Единицам без кода
+// коды назначаются автоматически при отгрузке.
+func (h *MarkingHandler) AssignOrderMarkingCodes(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+
+	orderID, err := uuid.Parse(c.Param("order_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid order ID format"})
+		return
+	}
+
+	var req model.AssignMarkingCodesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid input: " + err.Error()})
+		return
+	}
+
+	codes := make(map[uuid.UUID][]string, len(req.Items))
+	for _, item := range req.Items {
+		for _, raw := range item.Codes {
+			parts, err := model.ParseMarkingCode(raw)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid marking code %q: %v", raw, err)})
+				return
+			}
+			codes[item.OrderItemID] = append(codes[item.OrderItemID], parts.CIS)
+		}
+	}
+
+	assigned, err := h.repo.AssignToOrder(c.Request.Context(), userID, orderID, codes)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "order not found"})
+		case errors.Is(err, repository.ErrOrderItemNotFound), errors.Is(err, repository.ErrMarkingCodeNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, repository.ErrMarkingCodeUnavailable), errors.Is(err, repository.ErrMarkingCodeWrongVariant),
+			errors.Is(err, repository.ErrTooManyMarkingCodes), errors.Is(err, repository.ErrOrderAlreadyShipped):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			log.Printf("❌ Marking AssignOrderMarkingCodes: ошибка назначения кодов: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to assign marking codes: " + err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Коды маркировки назначены",
+		"codes":   assigned,
+	})
+}
+
+// UnassignOrderMarkingCode DELETE /api/orders/{order_id}/marking-codes/{code_id}
+func (h *MarkingHandler) UnassignOrderMarkingCode(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+
+	orderID, err := uuid.Parse(c.Param("order_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid order ID format"})
+		return
+	}
+	codeID, err := uuid.Parse(c.Param("code_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid code ID format"})
+		return
+	}
+
+	if err := h.repo.Unassign(c.Request.Context(), userID, orderID, codeID); err != nil {
+		if errors.Is(err, repository.ErrMarkingCodeNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "assigned marking code not found in the order"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to unassign marking code: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Код маркировки снят с заказа"})
+}
+
+// --- Отчеты о выводе из оборота ---
+
+// CreateMarkingReport POST /api/marking/reports
+func (h *MarkingHandler) CreateMarkingReport(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+
+	var req model.CreateMarkingReportRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid input: " + err.Error()})
+			return
+		}
+	}
+
+	report, err := h.repo.CreateReport(c.Request.Context(), userID, req)
+	if err != nil {
+		if errors.Is(err, repository.ErrNothingToReport) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
+		log.Printf("❌ Marking CreateMarkingReport: ошибка формирования отчета: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create report: " + err.Error()})
+		return
+	}
+
+	log.Printf("✅ Marking CreateMarkingReport: сформирован отчет %s, кодов: %d", report.Number, report.CodesCount)
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Отчет о выводе из оборота сформирован",
+		"report":  report,
+	})
+}
+
+// ListMarkingReports GET /api/marking/reports
+func (h *MarkingHandler) ListMarkingReports(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	limit, offset := offsetPage(c)
+
+	reports, total, err := h.repo.ListReports(c.Request.Context(), userID, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve reports: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"reports":    reports,
+		"pagination": offsetPagination(total, limit, offset),
+	})
+}
+
+// GetMarkingReport GET /api/marking/reports/{id}
+func (h *MarkingHandler) GetMarkingReport(c *gin.Context) {
+	report, ok := h.loadReport(c)
+	if !ok {
+		return
+	}
+
+	lines, err := h.repo.ReportLines(c.Request.Context(), report.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve report lines: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"report": report, "lines": lines})
+}
+
+// ExportMarkingReport GET /api/marking/reports/{id}/export?format=json|csv
+// json - документ для загрузки в ГИС МТ, csv - таблица кодов с ценами для сверки.
+func (h *MarkingHandler) ExportMarkingReport(c *gin.Context) {
+	report, ok := h.loadReport(c)
+	if !ok {
+		return
+	}
+
+	doc, lines, err := h.repo.ReportDocument(c.Request.Context(), report)
+	if err != nil {
+		h.reportError(c, "ExportMarkingReport", err)
+		return
+	}
+
+	switch c.DefaultQuery("format", "json") {
+	case "json":
+		data, err := json.MarshalIndent(doc, "", "  ")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to build document: " + err.Error()})
+			return
+		}
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.json"`, report.Number))
+		c.Data(http.StatusOK, "application/json", data)
+	case "csv":
+		var buf bytes.Buffer
+		w := csv.NewWriter(&buf)
+		w.Comma = ';'
+		w.Write([]string{"КИ", "Цена за единицу, коп.", "Номер заказа", "Дата отгрузки"})
+		for _, line := range lines {
+			w.Write([]string{line.CIS, strconv.FormatInt(line.PriceKopecks, 10), line.OrderNumber, line.ShippedAt.Format("2006-01-02")})
+		}
+		w.Flush()
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.csv"`, report.Number))
+		c.Data(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json or csv"})
+	}
+}
+
+// SubmitMarkingReport POST /api/marking/reports/{id}/submit
+func (h *MarkingHandler) SubmitMarkingReport(c *gin.Context) {
+	report, ok := h.loadReport(c)
+	if !ok {
+		return
+	}
+	if report.Status == model.MarkingReportSubmitted {
+		c.JSON(http.StatusConflict, gin.H{"error": "report has already been submitted"})
+		return
+	}
+
+	doc, _, err := h.repo.ReportDocument(c.Request.Context(), report)
+	if err != nil {
+		h.reportError(c, "SubmitMarkingReport", err)
+		return
+	}
+
+	documentID, err := h.portal.SubmitReport(c.Request.Context(), *doc)
+	if err != nil {
+		log.Printf("❌ Marking SubmitMarkingReport: ГИС МТ не принял документ: %v", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "failed to submit report: " + err.Error()})
+		return
+	}
+	if err := h.repo.MarkSubmitted(c.Request.Context(), report, documentID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update report: " + err.Error()})
+		return
+	}
+
+	log.Printf("✅ Marking SubmitMarkingReport: отчет %s отправлен, документ %s", report.Number, documentID)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Отчет отправлен в Честный ЗНАК",
+		"report":  report,
+	})
+}
+
+func (h *MarkingHandler) loadReport(c *gin.Context) (*model.MarkingReport, bool) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid report ID format"})
+		return nil, false
+	}
+
+	report, err := h.repo.GetReport(c.Request.Context(), id, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "report not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error: " + err.Error()})
+		return nil, false
+	}
+	return report, true
+}
+
+func (h *MarkingHandler) reportError(c *gin.Context, method string, err error) {
+	if errors.Is(err, repository.ErrSellerINNMissing) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	log.Printf("❌ Marking %s: ошибка формирования документа: %v", method, err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to build document: " + err.Error()})
+}
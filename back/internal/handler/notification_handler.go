@@ -0,0 +1,169 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lamoda-seller-app/internal/middleware"
+	"github.com/lamoda-seller-app/internal/model"
+	"github.com/lamoda-seller-app/internal/repository"
+)
+
+// NotificationHandler обрабатывает запросы к центру уведомлений продавца.
+type NotificationHandler struct {
+	repo *repository.NotificationRepository
+}
+
+func NewNotificationHandler(repo *repository.NotificationRepository) *NotificationHandler {
+	return &NotificationHandler{repo: repo}
+}
+
+// ListNotifications GET /api/notifications?category=&unread=true&limit=&offset=
+// Вместе со списком возвращает число непрочитанных уведомлений (с учетом фильтра категории).
+func (h *NotificationHandler) ListNotifications(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+
+	filter := model.NotificationFilter{
+		Category:   c.Query("category"),
+		UnreadOnly: c.Query("unread") == "true",
+	}
+	if filter.Category != "" && !validNotificationCategory(filter.Category) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unknown notification category"})
+		return
+	}
+	limit, offset := offsetPage(c)
+
+	notifications, total, unread, err := h.repo.List(c.Request.Context(), userID, filter, limit, offset)
+	if err != nil {
+		log.Printf("❌ Notifications ListNotifications: ошибка получения уведомлений: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve notifications: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"notifications": notifications,
+		"unread_count":  unread,
+		"pagination":    offsetPagination(total, limit, offset),
+	})
+}
+
+// GetUnreadCounts GET /api/notifications/unread-count
+// Число непрочитанных уведомлений всего и по категориям - для бейджа.
+func (h *NotificationHandler) GetUnreadCounts(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+
+	counts, err := h.repo.UnreadCounts(c.Request.Context(), userID)
+	if err != nil {
+		log.Printf("❌ Notifications GetUnreadCounts: ошибка подсчета: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to count notifications: " + err.Error()})
+		return
+	}
+	var total int64
+	for _, n := range counts {
+		total += n
+	}
+	c.JSON(http.StatusOK, gin.H{"total": total, "categories": counts})
+}
+
+// MarkNotificationsRead POST /api/notifications/read
+func (h *NotificationHandler) MarkNotificationsRead(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+
+	var req model.MarkNotificationsReadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid input: " + err.Error()})
+		return
+	}
+
+	marked, err := h.repo.MarkRead(c.Request.Context(), userID, uniqueUUIDs(req.IDs))
+	if err != nil {
+		log.Printf("❌ Notifications MarkNotificationsRead: ошибка обновления: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to mark notifications: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Уведомления отмечены прочитанными", "marked": marked})
+}
+
+// MarkAllNotificationsRead POST /api/notifications/read-all?category=
+func (h *NotificationHandler) MarkAllNotificationsRead(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+
+	category := c.Query("category")
+	if category != "" && !validNotificationCategory(category) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unknown notification category"})
+		return
+	}
+
+	marked, err := h.repo.MarkAllRead(c.Request.Context(), userID, category)
+	if err != nil {
+		log.Printf("❌ Notifications MarkAllNotificationsRead: ошибка обновления: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to mark notifications: " + err.Error()})
+		return
+	}
+
+	log.Printf("✅ Notifications MarkAllNotificationsRead: прочитано %d уведомлений", marked)
+	c.JSON(http.StatusOK, gin.H{"message": "Все уведомления отмечены прочитанными", "marked": marked})
+}
+
+// GetNotificationPreferences GET /api/notifications/preferences
+func (h *NotificationHandler) GetNotificationPreferences(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+
+	prefs, err := h.repo.Preferences(c.Request.Context(), userID)
+	if err != nil {
+		log.Printf("❌ Notifications GetNotificationPreferences: ошибка получения настроек: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve preferences: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"preferences": prefs})
+}
+
+// UpdateNotificationPreferences PUT /api/notifications/preferences
+// Для каждой категории - только в приложении (in_app) или в приложении и на email (in_app_email).
+func (h *NotificationHandler) UpdateNotificationPreferences(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+
+	var req model.UpdateNotificationPreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid input: " + err.Error()})
+		return
+	}
+
+	// Повторная категория в одном запросе сломала бы upsert - берется последняя
+	byCategory := make(map[string]int, len(req.Preferences))
+	prefs := make([]model.NotificationPreferenceRequest, 0, len(req.Preferences))
+	for _, p := range req.Preferences {
+		if i, ok := byCategory[p.Category]; ok {
+			prefs[i] = p
+			continue
+		}
+		byCategory[p.Category] = len(prefs)
+		prefs = append(prefs, p)
+	}
+
+	ctx := c.Request.Context()
+	if err := h.repo.UpdatePreferences(ctx, userID, prefs); err != nil {
+		log.Printf("❌ Notifications UpdateNotificationPreferences: ошибка сохранения настроек: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update preferences: " + err.Error()})
+		return
+	}
+	updated, err := h.repo.Preferences(ctx, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve preferences: " + err.Error()})
+		return
+	}
+
+	log.Printf("✅ Notifications UpdateNotificationPreferences: обновлено категорий %d", len(prefs))
+	c.JSON(http.StatusOK, gin.H{"message": "Настройки уведомлений сохранены", "preferences": updated})
+}
+
+func validNotificationCategory(category string) bool {
+	for _, c := range model.NotificationCategories {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
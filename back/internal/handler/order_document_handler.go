@@ -0,0 +1,205 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lamoda-seller-app/internal/middleware"
+	"github.com/lamoda-seller-app/internal/model"
+	"github.com/lamoda-seller-app/internal/render"
+	"github.com/lamoda-seller-app/internal/repository"
+)
+
+// OrderDocumentHandler формирует документы по заказам: упаковочные листы и счета в PDF.
+type OrderDocumentHandler struct {
+	repo      *repository.OrderDocumentRepository
+	orderRepo *repository.OrderRepository
+	userRepo  *repository.UserRepository
+}
+
+func NewOrderDocumentHandler(repo *repository.OrderDocumentRepository, orderRepo *repository.OrderRepository, userRepo *repository.UserRepository) *OrderDocumentHandler {
+	return &OrderDocumentHandler{repo: repo, orderRepo: orderRepo, userRepo: userRepo}
+}
+
+// ListOrderDocuments GET /api/orders/{order_id}/documents
+// Возвращает документы, уже выданные по заказу, с их номерами.
+func (h *OrderDocumentHandler) ListOrderDocuments(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+
+	orderID, err := uuid.Parse(c.Param("order_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid order ID format"})
+		return
+	}
+
+	docs, err := h.repo.ListByOrder(c.Request.Context(), userID, orderID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve documents: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"documents": docs})
+}
+
+// GetOrderDocument GET /api/orders/{order_id}/documents/{type}, type - packing-slip или invoice
+// При первом запросе документу присваивается номер, повторные запросы печатают его с тем же номером и датой.
+func (h *OrderDocumentHandler) GetOrderDocument(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+
+	orderID, err := uuid.Parse(c.Param("order_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid order ID format"})
+		return
+	}
+	docType, ok := model.ParseOrderDocumentType(c.Param("type"))
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "document type must be packing-slip or invoice"})
+		return
+	}
+
+	h.writeDocuments(c, userID, docType, []uuid.UUID{orderID})
+}
+
+// BatchOrderDocuments POST /api/orders/documents
+// Объединяет документы одного вида по нескольким заказам в один PDF, например для печати всей сборки за день.
+func (h *OrderDocumentHandler) BatchOrderDocuments(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+
+	var req model.BatchOrderDocumentsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid input: " + err.Error()})
+		return
+	}
+	docType, _ := model.ParseOrderDocumentType(req.Type)
+
+	h.writeDocuments(c, userID, docType, uniqueUUIDs(req.OrderIDs))
+}
+
+func (h *OrderDocumentHandler) writeDocuments(c *gin.Context, userID uuid.UUID, docType string, orderIDs []uuid.UUID) {
+	ctx := c.Request.Context()
+
+	seller, err := h.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error: " + err.Error()})
+		return
+	}
+
+	docs, err := h.repo.Issue(ctx, userID, docType, orderIDs, func(orderID uuid.UUID) (model.OrderDocumentData, error) {
+		order, err := h.orderRepo.GetByID(ctx, orderID, userID)
+		if err != nil {
+			return model.OrderDocumentData{}, err
+		}
+		return orderDocumentData(order, seller), nil
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrOrderNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		log.Printf("❌ OrderDocuments writeDocuments: ошибка выдачи номеров: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to issue documents: " + err.Error()})
+		return
+	}
+
+	var pages []render.Page
+	for _, doc := range docs {
+		data := renderOrderDocument(&doc)
+		var docPages []render.Page
+		if docType == model.OrderDocumentInvoice {
+			docPages, err = render.BuildInvoice(data)
+		} else {
+			docPages, err = render.BuildPackingSlip(data)
+		}
+		if err != nil {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": fmt.Sprintf("order %s: %v", data.OrderNumber, err)})
+			return
+		}
+		pages = append(pages, docPages...)
+	}
+
+	var buf bytes.Buffer
+	if err := render.WritePDF(&buf, pages); err != nil {
+		log.Printf("❌ OrderDocuments writeDocuments: ошибка формирования PDF: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to render documents: " + err.Error()})
+		return
+	}
+
+	name := docs[0].Number
+	if len(docs) > 1 {
+		name = fmt.Sprintf("%s-%s-%d", model.OrderDocumentPrefix(docType), time.Now().Format("20060102"), len(docs))
+	}
+	log.Printf("✅ OrderDocuments writeDocuments: сформировано документов: %d (%s)", len(docs), docType)
+	c.Header("Content-Disposition", fmt.Sprintf(`inline; filename="%s.pdf"`, name))
+	c.Data(http.StatusOK, render.FormatPDF.ContentType(), buf.Bytes())
+}
+
+// orderDocumentData собирает содержимое документа из заказа; сохраняется при выдаче номера.
+func orderDocumentData(order *model.Order, seller *model.User) model.OrderDocumentData {
+	data := model.OrderDocumentData{
+		SellerName:  seller.Name,
+		SellerINN:   seller.INN,
+		OrderNumber: order.OrderNumber,
+		OrderDate:   order.Date,
+		Customer:    order.Customer.Name,
+		Phone:       order.Customer.Phone,
+		Email:       order.Customer.Email,
+		Address:     formatAddress(order.Delivery.Address),
+		Delivery:    order.Delivery.Type,
+		Tracking:    order.Delivery.TrackingNumber,
+		Payment:     joinNonEmpty(", ", order.Payment.Method, order.Payment.Status),
+		Notes:       order.Notes,
+		Subtotal:    order.Totals.Subtotal,
+		Discount:    order.Totals.Discount,
+		Shipping:    order.Totals.Delivery,
+		Total:       order.Totals.Total,
+		Currency:    "RUB",
+	}
+	for _, item := range order.Items {
+		data.Lines = append(data.Lines, model.OrderDocumentLine{
+			SKU:        item.SKU,
+			Name:       joinNonEmpty(" ", item.Brand, item.Name),
+			Attributes: joinNonEmpty(", ", item.Color, item.Size),
+			Quantity:   item.Quantity,
+			Price:      item.Price,
+			Discount:   item.Discount,
+			Total:      item.Total,
+		})
+	}
+	return data
+}
+
+// renderOrderDocument готовит сохраненное содержимое к печати. Дата документа - дата выдачи номера.
+func renderOrderDocument(doc *model.OrderDocument) render.OrderDocument {
+	d := doc.Data
+	data := render.OrderDocument{
+		Number:      doc.Number,
+		Date:        doc.IssuedAt.Format("02.01.2006"),
+		SellerName:  d.SellerName,
+		SellerINN:   d.SellerINN,
+		OrderNumber: d.OrderNumber,
+		OrderDate:   d.OrderDate.Format("02.01.2006"),
+		Customer:    d.Customer,
+		Phone:       d.Phone,
+		Email:       d.Email,
+		Address:     d.Address,
+		Delivery:    d.Delivery,
+		Tracking:    d.Tracking,
+		Payment:     d.Payment,
+		Notes:       d.Notes,
+		Subtotal:    d.Subtotal,
+		Discount:    d.Discount,
+		Shipping:    d.Shipping,
+		Total:       d.Total,
+		Currency:    d.Currency,
+	}
+	for _, l := range d.Lines {
+		data.Lines = append(data.Lines, render.DocumentLine(l))
+	}
+	return data
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"log"
 	"net/http"
+	"net/url"
 	"strconv"
 	"time"
 
@@ -44,48 +45,26 @@ func (h *OrderHandler) ListOrders(c *gin.Context) {
 
 	// --- Парсинг параметров ---
 	log.Printf("📋 Orders ListOrders: парсинг параметров запроса")
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
-	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
-	minAmount, _ := strconv.ParseFloat(c.DefaultQuery("min_amount", "0"), 64)
-	maxAmount, _ := strconv.ParseFloat(c.DefaultQuery("max_amount", "0"), 64)
-
-	var dateFrom, dateTo *time.Time
-	if df := c.Query("date_from"); df != "" {
-		if t, err := time.Parse(time.RFC3339, df); err == nil {
-			dateFrom = &t
-		}
-	}
-	if dt := c.Query("date_to"); dt != "" {
-		if t, err := time.Parse(time.RFC3339, dt); err == nil {
-			dateTo = &t
-		}
-	}
-
-	customerID, _ := uuid.Parse(c.Query("customer_id"))
-	productID, _ := uuid.Parse(c.Query("product_id"))
-
-	params := repository.ListOrdersParams{
-		UserID:     userID,
-		Status:     c.Query("status"),
-		DateFrom:   dateFrom,
-		DateTo:     dateTo,
-		CustomerID: customerID,
-		ProductID:  productID,
-		MinAmount:  minAmount,
-		MaxAmount:  maxAmount,
-		SortBy:     c.Query("sort_by"),
-		SortOrder:  c.DefaultQuery("sort_order", "asc"),
-		Limit:      limit,
-		Offset:     offset,
+	page, err := parsePageRequest(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
+	params := orderListParams(c.Request.URL.Query(), userID)
+	params.Limit, params.Offset = page.Limit, page.Offset
+	params.Cursor, params.Keyset = page.Cursor, page.Keyset
 
 	log.Printf("🔍 Orders ListOrders: параметры поиска - статус: %s, лимит: %d, смещение: %d",
 		params.Status, params.Limit, params.Offset)
 
 	// --- Получение данных из репозитория ---
 	log.Printf("🔍 Orders ListOrders: запрос данных из репозитория")
-	orders, summary, total, err := h.repo.List(c.Request.Context(), params)
+	orders, summary, total, pageInfo, err := h.repo.List(c.Request.Context(), params)
 	if err != nil {
+		if errors.Is(err, repository.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		log.Printf("❌ Orders ListOrders: ошибка получения заказов: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve orders: " + err.Error()})
 		return
@@ -99,15 +78,9 @@ func (h *OrderHandler) ListOrders(c *gin.Context) {
 
 	// --- Формирование ответа ---
 	response := ListOrdersAPIResponse{
-		Orders:  orders,
-		Summary: summary,
-		Pagination: PaginationResponse{
-			Total:   total,
-			Limit:   limit,
-			Offset:  offset,
-			HasNext: total > int64(limit+offset),
-			HasPrev: offset > 0,
-		},
+		Orders:     orders,
+		Summary:    summary,
+		Pagination: paginationResponse(page, total, pageInfo),
 	}
 
 	log.Printf("✅ Orders ListOrders: успешно сформирован ответ")
@@ -171,15 +144,44 @@ func (h *OrderHandler) UpdateOrderStatus(c *gin.Context) {
 
 	log.Printf("📝 Orders UpdateOrderStatus: новый статус: %s, комментарий: %s", req.Status, req.Comment)
 
-	// TODO: Добавить валидацию допустимых переходов статусов (например, из 'new' можно в 'confirmed', но не в 'delivered')
-
-	updatedOrder, err := h.repo.UpdateStatus(c.Request.Context(), orderID, userID, req.Status, req.Comment, req.EstimatedDeliveryDate)
+	// Допустимые переходы проверяются в репозитории (model.CanTransitionOrder), отмена - в Cancel
+	var updatedOrder *model.Order
+	if req.Status == model.OrderStatusCancelled {
+		// Отмена через смену статуса проходит тот же путь, что и POST /cancel: резервы, возврат, история
+		updatedOrder, _, err = h.repo.Cancel(c.Request.Context(), orderID, userID, model.CancelOrderRequest{
+			Reason:    model.CancelReasonOther,
+			Initiator: model.CancelInitiatorSeller,
+			Comment:   req.Comment,
+		})
+	} else {
+		updatedOrder, err = h.repo.UpdateStatus(c.Request.Context(), orderID, userID, req.Status, req.Comment, req.EstimatedDeliveryDate)
+	}
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			log.Printf("❌ Orders UpdateOrderStatus: заказ не найден или нет доступа")
 			c.JSON(http.StatusNotFound, gin.H{"error": "order not found or you don't have permission to update it"})
 			return
 		}
+		if errors.Is(err, repository.ErrInsufficientStock) {
+			log.Printf("❌ Orders UpdateOrderStatus: недостаточно остатка для резервирования")
+			c.JSON(http.StatusConflict, gin.H{"error": "not enough stock to reserve the order: " + err.Error()})
+			return
+		}
+		if errors.Is(err, repository.ErrMarkingCodesShortage) {
+			log.Printf("❌ Orders UpdateOrderStatus: не хватает кодов маркировки для отгрузки")
+			c.JSON(http.StatusConflict, gin.H{"error": "cannot ship the order: " + err.Error()})
+			return
+		}
+		if errors.Is(err, repository.ErrInvalidTransition) {
+			log.Printf("❌ Orders UpdateOrderStatus: недопустимый переход статуса: %v", err)
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, repository.ErrOrderNotCancellable) {
+			log.Printf("❌ Orders UpdateOrderStatus: заказ уже нельзя отменить: %v", err)
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
 		log.Printf("❌ Orders UpdateOrderStatus: ошибка обновления статуса: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update order status: " + err.Error()})
 		return
@@ -195,3 +197,93 @@ func (h *OrderHandler) UpdateOrderStatus(c *gin.Context) {
 		},
 	})
 }
+
+// CancelOrder POST /api/orders/{order_id}/cancel
+// Отменяет заказ целиком или отдельные позиции (items) с указанием причины и инициатора.
+func (h *OrderHandler) CancelOrder(c *gin.Context) {
+	log.Printf("📦 Orders CancelOrder: начало обработки запроса")
+
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+
+	orderID, err := uuid.Parse(c.Param("order_id"))
+	if err != nil {
+		log.Printf("❌ Orders CancelOrder: неверный формат ID заказа: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid order ID format"})
+		return
+	}
+
+	var req model.CancelOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("❌ Orders CancelOrder: ошибка парсинга JSON: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid input: " + err.Error()})
+		return
+	}
+
+	log.Printf("📝 Orders CancelOrder: заказ ID: %s, причина: %s, инициатор: %s, позиций: %d",
+		orderID, req.Reason, req.Initiator, len(req.Items))
+
+	order, cancellation, err := h.repo.Cancel(c.Request.Context(), orderID, userID, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			log.Printf("❌ Orders CancelOrder: заказ не найден или нет доступа")
+			c.JSON(http.StatusNotFound, gin.H{"error": "order not found or you don't have permission to cancel it"})
+		case errors.Is(err, repository.ErrOrderNotCancellable):
+			log.Printf("❌ Orders CancelOrder: заказ уже нельзя отменить: %v", err)
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, repository.ErrInsufficientFunds):
+			log.Printf("❌ Orders CancelOrder: на балансе не хватает средств для удержания выплаты")
+			c.JSON(http.StatusConflict, gin.H{"error": "insufficient balance to reverse the seller payout"})
+		case errors.Is(err, repository.ErrOrderItemNotFound), errors.Is(err, repository.ErrCancelQuantityTooBig):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			log.Printf("❌ Orders CancelOrder: ошибка отмены заказа: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to cancel order: " + err.Error()})
+		}
+		return
+	}
+
+	log.Printf("✅ Orders CancelOrder: заказ %s, полная отмена: %t, возврат: %.2f, списано с продавца: %.2f",
+		order.OrderNumber, cancellation.FullCancel, cancellation.RefundAmount, cancellation.SellerAmount)
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "Отмена заказа выполнена",
+		"order":        order,
+		"cancellation": cancellation,
+	})
+}
+
+// orderListParams разбирает фильтры и сортировку списка заказов из query-параметров.
+// Неверные значения фильтров игнорируются, как и раньше.
+func orderListParams(q url.Values, userID uuid.UUID) repository.ListOrdersParams {
+	minAmount, _ := strconv.ParseFloat(q.Get("min_amount"), 64)
+	maxAmount, _ := strconv.ParseFloat(q.Get("max_amount"), 64)
+
+	var dateFrom, dateTo *time.Time
+	if df := q.Get("date_from"); df != "" {
+		if t, err := time.Parse(time.RFC3339, df); err == nil {
+			dateFrom = &t
+		}
+	}
+	if dt := q.Get("date_to"); dt != "" {
+		if t, err := time.Parse(time.RFC3339, dt); err == nil {
+			dateTo = &t
+		}
+	}
+
+	customerID, _ := uuid.Parse(q.Get("customer_id"))
+	productID, _ := uuid.Parse(q.Get("product_id"))
+
+	params := repository.ListOrdersParams{
+		UserID:     userID,
+		Status:     q.Get("status"),
+		DateFrom:   dateFrom,
+		DateTo:     dateTo,
+		CustomerID: customerID,
+		ProductID:  productID,
+		MinAmount:  minAmount,
+		MaxAmount:  maxAmount,
+		SortBy:     q.Get("sort_by"),
+		SortOrder:  q.Get("sort_order"),
+	}
+	return params
+}
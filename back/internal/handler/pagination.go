@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lamoda-seller-app/internal/repository"
+)
+
+const (
+	defaultPageLimit = 20
+	maxOffsetLimit   = 100  // Максимальный размер страницы в режиме OFFSET
+	maxKeysetLimit   = 1000 // В keyset-режиме страницы могут быть больше, например для выгрузок
+)
+
+// pageRequest - параметры пагинации списка.
+// Keyset-режим включается параметром cursor (следующие страницы) или pagination=cursor (первая страница).
+type pageRequest struct {
+	Limit  int
+	Offset int
+	Cursor *repository.Cursor
+	Keyset bool
+}
+
+// parsePageRequest разбирает параметры limit, offset, cursor и pagination.
+func parsePageRequest(c *gin.Context) (pageRequest, error) {
+	req := pageRequest{Keyset: c.Query("pagination") == "cursor"}
+	if raw := c.Query("cursor"); raw != "" {
+		cursor, err := repository.DecodeCursor(raw)
+		if err != nil {
+			return req, err
+		}
+		req.Cursor = cursor
+		req.Keyset = true
+	}
+
+	maxLimit := maxOffsetLimit
+	if req.Keyset {
+		maxLimit = maxKeysetLimit
+	}
+	req.Limit, _ = strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultPageLimit)))
+	if req.Limit <= 0 || req.Limit > maxLimit {
+		req.Limit = defaultPageLimit
+	}
+
+	if !req.Keyset {
+		req.Offset, _ = strconv.Atoi(c.DefaultQuery("offset", "0"))
+		if req.Offset < 0 {
+			req.Offset = 0
+		}
+	}
+	return req, nil
+}
+
+// paginationResponse формирует блок pagination ответа.
+func paginationResponse(req pageRequest, total int64, page *repository.PageInfo) PaginationResponse {
+	return PaginationResponse{
+		Total:      total,
+		Limit:      req.Limit,
+		Offset:     req.Offset,
+		HasNext:    page.HasNext,
+		HasPrev:    page.HasPrev,
+		NextCursor: page.NextCursor,
+		PrevCursor: page.PrevCursor,
+	}
+}
+
+// offsetPage разбирает limit и offset для простых списков без keyset-пагинации.
+func offsetPage(c *gin.Context) (limit, offset int) {
+	limit, _ = strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultPageLimit)))
+	offset, _ = strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if limit <= 0 || limit > maxOffsetLimit {
+		limit = defaultPageLimit
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return limit, offset
+}
+
+func offsetPagination(total int64, limit, offset int) PaginationResponse {
+	return PaginationResponse{
+		Total:   total,
+		Limit:   limit,
+		Offset:  offset,
+		HasNext: total > int64(limit+offset),
+		HasPrev: offset > 0,
+	}
+}
@@ -0,0 +1,195 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lamoda-seller-app/internal/middleware"
+	"github.com/lamoda-seller-app/internal/model"
+	"github.com/lamoda-seller-app/internal/repository"
+	"gorm.io/gorm"
+)
+
+// PriceHandler обрабатывает HTTP запросы, связанные с историей цен и запланированными изменениями цен.
+type PriceHandler struct {
+	repo *repository.PriceRepository
+}
+
+func NewPriceHandler(repo *repository.PriceRepository) *PriceHandler {
+	return &PriceHandler{repo: repo}
+}
+
+// --- Обработчики ---
+
+// GetPriceTimeline GET /api/products/{id}/prices
+// Возвращает историю цены и продажи по интервалам действия каждой цены.
+func (h *PriceHandler) GetPriceTimeline(c *gin.Context) {
+	productID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product ID format"})
+		return
+	}
+
+	var params model.PriceTimelineRequestParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid query parameters: " + err.Error()})
+		return
+	}
+	if params.Period == "" {
+		params.Period = "90d"
+	}
+
+	var variantID *uuid.UUID
+	if params.VariantID != "" {
+		id, err := uuid.Parse(params.VariantID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid variant ID format"})
+			return
+		}
+		variantID = &id
+	}
+
+	timeline, err := h.repo.Timeline(c.Request.Context(), productID, variantID, params.Period)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "product not found"})
+		case errors.Is(err, repository.ErrVariantNotInProduct):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			log.Printf("❌ Prices GetPriceTimeline: ошибка построения динамики цены: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to build price timeline: " + err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, timeline)
+}
+
+// ListPriceSchedules GET /api/products/{id}/price-schedules
+func (h *PriceHandler) ListPriceSchedules(c *gin.Context) {
+	productID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product ID format"})
+		return
+	}
+
+	schedules, err := h.repo.ListSchedules(c.Request.Context(), productID, c.Query("status"))
+	if err != nil {
+		log.Printf("❌ Prices ListPriceSchedules: ошибка получения изменений цены: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve price schedules: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"schedules": schedules})
+}
+
+// CreatePriceSchedule POST /api/products/{id}/price-schedules
+func (h *PriceHandler) CreatePriceSchedule(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+
+	productID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product ID format"})
+		return
+	}
+
+	var req model.PriceScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid input: " + err.Error()})
+		return
+	}
+
+	switch {
+	case req.Price == nil && req.DiscountPercent == nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "either price or discount_percent is required"})
+		return
+	case req.VariantID != nil && req.DiscountPercent != nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "discount can only be scheduled for the whole product"})
+		return
+	case req.EndsAt != nil && !req.EndsAt.After(req.StartsAt):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ends_at must be after starts_at"})
+		return
+	case req.EndsAt != nil && !req.EndsAt.After(time.Now()):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ends_at must be in the future"})
+		return
+	case req.RevertOnEnd && req.EndsAt == nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "revert_on_end requires ends_at"})
+		return
+	}
+
+	schedule := model.PriceSchedule{
+		ID:              uuid.New(),
+		ProductID:       productID,
+		VariantID:       req.VariantID,
+		UserID:          &userID,
+		Price:           req.Price,
+		DiscountPercent: req.DiscountPercent,
+		StartsAt:        req.StartsAt,
+		EndsAt:          req.EndsAt,
+		RevertOnEnd:     req.RevertOnEnd,
+		Status:          model.PriceScheduleStatusPending,
+		Comment:         req.Comment,
+	}
+
+	if err := h.repo.CreateSchedule(c.Request.Context(), &schedule); err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "product not found"})
+		case errors.Is(err, repository.ErrVariantNotInProduct):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, repository.ErrPriceScheduleOverlap):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			log.Printf("❌ Prices CreatePriceSchedule: ошибка планирования изменения цены: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create price schedule: " + err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":  "Изменение цены запланировано",
+		"schedule": schedule,
+	})
+}
+
+// CancelPriceSchedule DELETE /api/products/{id}/price-schedules/{schedule_id}
+// Еще не начавшееся изменение отменяется, действующее - завершается досрочно.
+func (h *PriceHandler) CancelPriceSchedule(c *gin.Context) {
+	productID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product ID format"})
+		return
+	}
+	scheduleID, err := uuid.Parse(c.Param("schedule_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid schedule ID format"})
+		return
+	}
+
+	schedule, err := h.repo.CancelSchedule(c.Request.Context(), productID, scheduleID)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "price schedule not found"})
+		case errors.Is(err, repository.ErrPriceScheduleFinished):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to cancel price schedule: " + err.Error()})
+		}
+		return
+	}
+
+	message := "Изменение цены отменено"
+	if schedule.Status == model.PriceScheduleStatusActive {
+		message = "Изменение цены будет завершено при следующем запуске планировщика"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":  message,
+		"schedule": schedule,
+	})
+}
@@ -0,0 +1,298 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lamoda-seller-app/internal/middleware"
+	"github.com/lamoda-seller-app/internal/model"
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+// BulkUpdateProducts POST /api/products/bulk
+// Применяет одну операцию к списку товаров или ко всем товарам, подходящим под фильтр.
+// Все изменения сохраняются в одной транзакции; товары, к которым операция неприменима,
+// пропускаются с указанием причины. С preview=true возвращает изменения без сохранения.
+func (h *ProductHandler) BulkUpdateProducts(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+
+	var req model.BulkProductRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid input: " + err.Error()})
+		return
+	}
+
+	apply, err := h.bulkOperation(c, userID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ids, ok := h.bulkTargets(c, userID, &req)
+	if !ok {
+		return
+	}
+	log.Printf("📦 Products BulkUpdateProducts: операция %s для %d товаров (preview: %t)", req.Operation, len(ids), req.Preview)
+
+	results, err := h.repo.Bulk(c.Request.Context(), userID, ids, req.Preview, apply)
+	if err != nil {
+		log.Printf("❌ Products BulkUpdateProducts: ошибка массового изменения: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to apply bulk operation: " + err.Error()})
+		return
+	}
+
+	summary := model.SummarizeBulk(results)
+	message := "Предпросмотр массового изменения"
+	if !req.Preview {
+		message = "Массовое изменение выполнено"
+		action := model.ProductActionBulk
+		if req.Operation == model.BulkOpArchive {
+			action = model.ProductActionArchive
+		}
+		for _, r := range results {
+			if r.Result != model.BulkResultUpdated {
+				continue
+			}
+			h.recordVersion(c, r.ProductID, action, "Массовая операция: "+req.Operation)
+			if req.Operation == model.BulkOpAdjustPrice || req.Operation == model.BulkOpSetDiscount {
+				h.recordPrices(c, r.ProductID, model.PriceSourceBulk)
+			}
+		}
+	}
+
+	log.Printf("✅ Products BulkUpdateProducts: изменено %d, без изменений %d, пропущено %d, не найдено %d",
+		summary.Updated, summary.Unchanged, summary.Skipped, summary.NotFound)
+	c.JSON(http.StatusOK, gin.H{
+		"message":   message,
+		"operation": req.Operation,
+		"preview":   req.Preview,
+		"summary":   summary,
+		"results":   results,
+	})
+}
+
+// bulkTargets определяет товары массовой операции: явный список ID или фильтр списка товаров.
+// При ошибке сам пишет ответ.
+func (h *ProductHandler) bulkTargets(c *gin.Context, userID uuid.UUID, req *model.BulkProductRequest) ([]uuid.UUID, bool) {
+	if (len(req.ProductIDs) > 0) == (req.Filter != nil) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "specify either product_ids or filter"})
+		return nil, false
+	}
+
+	if len(req.ProductIDs) > 0 {
+		seen := make(map[uuid.UUID]struct{}, len(req.ProductIDs))
+		ids := make([]uuid.UUID, 0, len(req.ProductIDs))
+		for _, id := range req.ProductIDs {
+			if _, dup := seen[id]; dup {
+				continue
+			}
+			seen[id] = struct{}{}
+			ids = append(ids, id)
+		}
+		if len(ids) > model.MaxBulkProducts {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("too many products, maximum is %d", model.MaxBulkProducts)})
+			return nil, false
+		}
+		return ids, true
+	}
+
+	params, err := productListParams(url.Values(req.Filter))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid filter: " + err.Error()})
+		return nil, false
+	}
+	if params.LowStockThreshold, err = h.repo.LowStockThreshold(c.Request.Context(), userID); err != nil {
+		log.Printf("⚠️ Products bulkTargets: не удалось получить порог остатка продавца: %v", err)
+	}
+
+	ids, err := h.repo.BulkTargetIDs(c.Request.Context(), userID, params, model.MaxBulkProducts+1)
+	if err != nil {
+		log.Printf("❌ Products bulkTargets: ошибка выбора товаров по фильтру: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to select products: " + err.Error()})
+		return nil, false
+	}
+	if len(ids) > model.MaxBulkProducts {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("filter matches more than %d products, narrow it down", model.MaxBulkProducts)})
+		return nil, false
+	}
+	return ids, true
+}
+
+// bulkOperation проверяет параметры операции и возвращает функцию, изменяющую один товар.
+// Ошибка функции означает, что операция к товару неприменима, и товар будет пропущен.
+func (h *ProductHandler) bulkOperation(c *gin.Context, userID uuid.UUID, req *model.BulkProductRequest) (func(*model.Product) error, error) {
+	ctx := c.Request.Context()
+
+	switch req.Operation {
+	case model.BulkOpSetStatus:
+		if req.Status == "" {
+			return nil, errors.New("status is required for set_status")
+		}
+		return func(p *model.Product) error { return h.bulkSetStatus(ctx, p, req.Status) }, nil
+
+	case model.BulkOpArchive:
+		return func(p *model.Product) error { return h.bulkSetStatus(ctx, p, model.ProductStatusArchived) }, nil
+
+	case model.BulkOpAdjustPrice:
+		if req.Value == nil || req.PriceMode == "" {
+			return nil, errors.New("price_mode and value are required for adjust_price")
+		}
+		mode, value := req.PriceMode, *req.Value
+		if mode == model.PriceAdjustPercent && value <= -100 {
+			return nil, errors.New("percent adjustment must be greater than -100")
+		}
+		return func(p *model.Product) error {
+			price := adjustPrice(p.Price, mode, value)
+			if price <= 0 {
+				return fmt.Errorf("price would become %.2f", price)
+			}
+			p.Price = price
+			// Нулевая цена варианта означает цену товара, такие варианты не трогаем
+			for i := range p.Variants {
+				if p.Variants[i].Price == 0 {
+					continue
+				}
+				variantPrice := adjustPrice(p.Variants[i].Price, mode, value)
+				if variantPrice <= 0 {
+					return fmt.Errorf("price of variant %s would become %.2f", p.Variants[i].SKU, variantPrice)
+				}
+				p.Variants[i].Price = variantPrice
+			}
+			return nil
+		}, nil
+
+	case model.BulkOpSetDiscount:
+		if req.Value == nil || *req.Value < 0 || *req.Value > 100 {
+			return nil, errors.New("value between 0 and 100 is required for set_discount")
+		}
+		discount := *req.Value
+		return func(p *model.Product) error {
+			p.DiscountPercent = discount
+			return nil
+		}, nil
+
+	case model.BulkOpAddTags, model.BulkOpRemoveTags:
+		tags := normalizeTags(req.Tags)
+		if len(tags) == 0 {
+			return nil, fmt.Errorf("tags are required for %s", req.Operation)
+		}
+		add := req.Operation == model.BulkOpAddTags
+		return func(p *model.Product) error {
+			p.Tags = changeTags(p.Tags, tags, add)
+			return nil
+		}, nil
+
+	case model.BulkOpSetSupplier:
+		supplierID := req.SupplierID
+		if supplierID != nil {
+			if _, err := h.supplierRepo.GetByID(ctx, *supplierID, userID); err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return nil, errors.New("supplier not found")
+				}
+				return nil, fmt.Errorf("failed to check supplier: %w", err)
+			}
+		}
+		return func(p *model.Product) error {
+			p.SupplierID = supplierID
+			return nil
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown operation '%s'", req.Operation)
+}
+
+// bulkSetStatus переводит товар в статус по тем же правилам, что и действия publish/archive/unarchive.
+func (h *ProductHandler) bulkSetStatus(ctx context.Context, p *model.Product, status string) error {
+	if p.Status == status {
+		return nil
+	}
+	if !model.CanTransitionProduct(p.Status, status) {
+		return fmt.Errorf("product in status '%s' cannot be moved to '%s'", p.Status, status)
+	}
+	if status == model.ProductStatusActive || status == model.ProductStatusReady {
+		if readiness := h.readiness(ctx, p); !readiness.Ready {
+			return fmt.Errorf("product is not ready: %s", strings.Join(readiness.Missing, ", "))
+		}
+	}
+
+	// Те же отметки времени, что и в ProductRepository.SetStatus
+	now := time.Now()
+	switch status {
+	case model.ProductStatusActive:
+		p.PublishedAt, p.ArchivedAt = &now, nil
+	case model.ProductStatusArchived:
+		p.ArchivedAt = &now
+	case model.ProductStatusDraft:
+		p.ArchivedAt = nil
+	}
+	p.Status = status
+	return nil
+}
+
+// adjustPrice изменяет цену на сумму или процент с округлением до копеек.
+func adjustPrice(price float64, mode string, value float64) float64 {
+	if mode == model.PriceAdjustPercent {
+		price *= 1 + value/100
+	} else {
+		price += value
+	}
+	return math.Round(price*100) / 100
+}
+
+// normalizeTags убирает пробелы, пустые значения и повторы.
+func normalizeTags(tags []string) []string {
+	seen := make(map[string]struct{}, len(tags))
+	result := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		if _, dup := seen[tag]; dup {
+			continue
+		}
+		seen[tag] = struct{}{}
+		result = append(result, tag)
+	}
+	return result
+}
+
+// changeTags добавляет теги, которых еще нет у товара, или удаляет указанные.
+func changeTags(current pq.StringArray, tags []string, add bool) pq.StringArray {
+	set := make(map[string]struct{}, len(tags))
+	for _, tag := range tags {
+		set[tag] = struct{}{}
+	}
+
+	result := pq.StringArray{}
+	for _, tag := range current {
+		if _, ok := set[tag]; ok {
+			if add {
+				delete(set, tag) // Уже есть у товара
+			} else {
+				continue
+			}
+		}
+		result = append(result, tag)
+	}
+	if add {
+		for _, tag := range tags {
+			if _, ok := set[tag]; ok {
+				result = append(result, tag)
+			}
+		}
+	}
+	if len(result) == 0 && current == nil {
+		return nil // Не превращаем отсутствие тегов в пустой массив
+	}
+	return result
+}
@@ -1,38 +1,51 @@
 package handler
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"net/url"
 	"path/filepath"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/lamoda-seller-app/internal/middleware"
 	"github.com/lamoda-seller-app/internal/model"
 	"github.com/lamoda-seller-app/internal/repository"
+	"github.com/lamoda-seller-app/internal/validation"
 	"gorm.io/gorm"
 )
 
 // ProductHandler обрабатывает HTTP запросы, связанные с продуктами.
 type ProductHandler struct {
-	repo *repository.ProductRepository
+	repo           *repository.ProductRepository
+	categoryRepo   *repository.CategoryRepository
+	historyRepo    *repository.ProductHistoryRepository
+	priceRepo      *repository.PriceRepository
+	supplierRepo   *repository.SupplierRepository
+	trashRetention time.Duration // Сколько удаленный товар можно восстановить
 	// В реальном приложении сюда бы добавился ImageService для загрузки файлов
 }
 
-func NewProductHandler(repo *repository.ProductRepository) *ProductHandler {
-	return &ProductHandler{repo: repo}
+func NewProductHandler(repo *repository.ProductRepository, categoryRepo *repository.CategoryRepository, historyRepo *repository.ProductHistoryRepository, priceRepo *repository.PriceRepository, supplierRepo *repository.SupplierRepository, trashRetention time.Duration) *ProductHandler {
+	return &ProductHandler{repo: repo, categoryRepo: categoryRepo, historyRepo: historyRepo, priceRepo: priceRepo, supplierRepo: supplierRepo, trashRetention: trashRetention}
 }
 
 // --- Структуры ответов API ---
 
 type PaginationResponse struct {
-	Total   int64 `json:"total"`
-	Limit   int   `json:"limit"`
-	Offset  int   `json:"offset"`
-	HasNext bool  `json:"has_next"`
-	HasPrev bool  `json:"has_prev"`
+	Total      int64  `json:"total"`
+	Limit      int    `json:"limit"`
+	Offset     int    `json:"offset"`
+	HasNext    bool   `json:"has_next"`
+	HasPrev    bool   `json:"has_prev"`
+	NextCursor string `json:"next_cursor,omitempty"` // Непрозрачные курсоры для keyset-пагинации (см. pagination.go)
+	PrevCursor string `json:"prev_cursor,omitempty"`
 }
 
 type ListProductsAPIResponse struct {
@@ -49,29 +62,22 @@ func (h *ProductHandler) ListProducts(c *gin.Context) {
 
 	// --- Парсинг параметров ---
 	log.Printf("📋 Products ListProducts: парсинг параметров запроса")
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
-	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
-	minPrice, _ := strconv.ParseFloat(c.DefaultQuery("min_price", "0"), 64)
-	maxPrice, _ := strconv.ParseFloat(c.DefaultQuery("max_price", "0"), 64)
-
-	if limit <= 0 || limit > 100 {
-		limit = 20
+	page, err := parsePageRequest(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
-	if offset < 0 {
-		offset = 0
+	params, err := productListParams(c.Request.URL.Query())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
+	params.Limit, params.Offset = page.Limit, page.Offset
+	params.Cursor, params.Keyset = page.Cursor, page.Keyset
 
-	params := repository.ListProductsParams{
-		Limit:       limit,
-		Offset:      offset,
-		Search:      c.Query("search"),
-		Category:    c.Query("category"),
-		Brand:       c.Query("brand"),
-		MinPrice:    minPrice,
-		MaxPrice:    maxPrice,
-		StockStatus: c.Query("stock_status"),
-		SortBy:      c.Query("sort_by"),
-		SortOrder:   c.DefaultQuery("sort_order", "asc"),
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	if params.LowStockThreshold, err = h.repo.LowStockThreshold(c.Request.Context(), userID); err != nil {
+		log.Printf("⚠️ Products ListProducts: не удалось получить порог остатка продавца: %v", err)
 	}
 
 	log.Printf("🔍 Products ListProducts: параметры поиска - поиск: '%s', категория: '%s', бренд: '%s', лимит: %d",
@@ -79,8 +85,12 @@ func (h *ProductHandler) ListProducts(c *gin.Context) {
 
 	// --- Получение данных ---
 	log.Printf("🔍 Products ListProducts: запрос данных из репозитория")
-	products, total, filters, err := h.repo.List(c.Request.Context(), params)
+	products, total, filters, pageInfo, err := h.repo.List(c.Request.Context(), params)
 	if err != nil {
+		if errors.Is(err, repository.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		log.Printf("❌ Products ListProducts: ошибка получения продуктов: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve products: " + err.Error()})
 		return
@@ -90,15 +100,9 @@ func (h *ProductHandler) ListProducts(c *gin.Context) {
 
 	// --- Формирование ответа ---
 	response := ListProductsAPIResponse{
-		Products: products,
-		Pagination: PaginationResponse{
-			Total:   total,
-			Limit:   limit,
-			Offset:  offset,
-			HasNext: total > int64(limit+offset),
-			HasPrev: offset > 0,
-		},
-		Filters: filters,
+		Products:   products,
+		Pagination: paginationResponse(page, total, pageInfo),
+		Filters:    filters,
 	}
 
 	log.Printf("✅ Products ListProducts: успешно сформирован ответ")
@@ -118,7 +122,13 @@ func (h *ProductHandler) GetProductByID(c *gin.Context) {
 
 	log.Printf("🔍 Products GetProductByID: поиск продукта ID: %s", id)
 
-	product, err := h.repo.GetByID(c.Request.Context(), id)
+	// include_deleted=true позволяет открыть удаленный товар, например из истории заказа
+	getProduct := h.repo.GetByID
+	if includeDeleted, _ := strconv.ParseBool(c.Query("include_deleted")); includeDeleted {
+		getProduct = h.repo.GetByIDWithDeleted
+	}
+
+	product, err := getProduct(c.Request.Context(), id)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			log.Printf("❌ Products GetProductByID: продукт не найден")
@@ -142,14 +152,28 @@ func (h *ProductHandler) CreateProduct(c *gin.Context) {
 		return
 	}
 
+	if !h.validateProduct(c, &req, nil) {
+		return
+	}
+
 	// req.ID будет нулевым, GORM сгенерирует новый UUID
 	req.ID = uuid.New() // Явно генерируем, чтобы вернуть в ответе
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	req.UserID = &userID
+
+	// Новый товар всегда начинает жизненный цикл как черновик
+	req.Status = model.ProductStatusDraft
+	req.PublishedAt, req.ArchivedAt = nil, nil
+	req.Status = h.draftOrReady(c.Request.Context(), &req)
 
 	if err := h.repo.Create(c.Request.Context(), &req); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create product: " + err.Error()})
 		return
 	}
 
+	h.recordVersion(c, req.ID, model.ProductActionCreate, "")
+	h.recordPrices(c, req.ID, model.PriceSourceManual)
+
 	// Получаем созданный товар со всеми полями для ответа
 	createdProduct, err := h.repo.GetByID(c.Request.Context(), req.ID)
 	if err != nil {
@@ -184,6 +208,11 @@ func (h *ProductHandler) UpdateProduct(c *gin.Context) {
 		return
 	}
 
+	// Статус меняется только через действия жизненного цикла (publish/archive)
+	previousStatus := product.Status
+	publishedAt, archivedAt := product.PublishedAt, product.ArchivedAt
+	storedBarcodes := validation.StoredBarcodes(product)
+
 	// Привязываем JSON к СУЩЕСТВУЮЩЕМУ объекту.
 	// Это обновит только те поля, которые пришли в запросе.
 	if err := c.ShouldBindJSON(&product); err != nil {
@@ -191,14 +220,34 @@ func (h *ProductHandler) UpdateProduct(c *gin.Context) {
 		return
 	}
 
+	if product.Status != previousStatus {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status cannot be changed directly, use the publish/archive actions"})
+		return
+	}
+	product.PublishedAt, product.ArchivedAt = publishedAt, archivedAt
+
 	// ID не должен меняться
 	product.ID = id
 
+	if !h.validateProduct(c, product, storedBarcodes) {
+		return
+	}
+
+	product.Status = h.draftOrReady(c.Request.Context(), product)
+
 	if err := h.repo.Update(c.Request.Context(), product); err != nil {
+		if errors.Is(err, repository.ErrInsufficientStock) || errors.Is(err, repository.ErrNoDefaultWarehouse) {
+			// Остаток разнесен по складам: уменьшить его можно только до зарезервированного количества
+			c.JSON(http.StatusConflict, gin.H{"error": "failed to update stock: " + err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update product: " + err.Error()})
 		return
 	}
 
+	h.recordVersion(c, id, model.ProductActionUpdate, "")
+	h.recordPrices(c, id, model.PriceSourceManual)
+
 	// Получаем обновленный продукт для ответа
 	updatedProduct, _ := h.repo.GetByID(c.Request.Context(), id)
 
@@ -217,19 +266,94 @@ func (h *ProductHandler) DeleteProduct(c *gin.Context) {
 		return
 	}
 
-	// Перед удалением можно проверить, существует ли товар, чтобы вернуть 404,
-	// но DELETE к несуществующему ресурсу часто считают идемпотентной операцией.
-	// Здесь мы просто пытаемся удалить.
+	// Товар переносится в корзину (мягкое удаление) и может быть восстановлен в течение срока хранения.
 	if err := h.repo.Delete(c.Request.Context(), id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "product not found"})
+			return
+		}
 		// Ошибка может возникнуть, например, из-за проблем с подключением к БД.
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete product: " + err.Error()})
 		return
 	}
 
-	// Успешный ответ на DELETE - это 204 No Content с пустым телом.
-	// Можно также вернуть 200 OK с сообщением.
-	c.JSON(http.StatusOK, gin.H{"message": "Товар успешно удален"})
-	// или c.Status(http.StatusNoContent)
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Товар перемещен в корзину",
+		"purge_at": time.Now().Add(h.trashRetention),
+	})
+}
+
+// ListTrash GET /api/products/trash
+func (h *ProductHandler) ListTrash(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	products, total, err := h.repo.ListDeleted(c.Request.Context(), time.Now().Add(-h.trashRetention), limit, offset)
+	if err != nil {
+		log.Printf("❌ Products ListTrash: ошибка получения корзины: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve deleted products: " + err.Error()})
+		return
+	}
+
+	type trashItem struct {
+		model.Product
+		PurgeAt time.Time `json:"purge_at"`
+	}
+	items := make([]trashItem, 0, len(products))
+	for _, p := range products {
+		items = append(items, trashItem{Product: p, PurgeAt: p.DeletedAt.Time.Add(h.trashRetention)})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"products": items,
+		"pagination": PaginationResponse{
+			Total:   total,
+			Limit:   limit,
+			Offset:  offset,
+			HasNext: total > int64(limit+offset),
+			HasPrev: offset > 0,
+		},
+	})
+}
+
+// RestoreProduct POST /api/products/{id}/restore
+func (h *ProductHandler) RestoreProduct(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product ID format"})
+		return
+	}
+
+	if err := h.repo.Restore(c.Request.Context(), id, time.Now().Add(-h.trashRetention)); err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "deleted product not found"})
+		case errors.Is(err, repository.ErrRestoreWindowExpired):
+			c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+		case errors.Is(err, repository.ErrSKUTaken):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to restore product: " + err.Error()})
+		}
+		return
+	}
+
+	restoredProduct, err := h.repo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve restored product: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Товар восстановлен",
+		"product": restoredProduct,
+	})
 }
 
 // UploadImages POST /api/products/{id}/images
@@ -286,19 +410,135 @@ func (h *ProductHandler) UploadImages(c *gin.Context) {
 		uploadedImages = append(uploadedImages, image)
 	}
 
+	// Новые изображения могут сделать черновик готовым к публикации
+	if err := h.refreshReadyStatus(c.Request.Context(), productID); err != nil {
+		log.Printf("⚠️ Products UploadImages: не удалось пересчитать готовность товара: %v", err)
+	}
+	h.recordVersion(c, productID, model.ProductActionImages, "")
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Изображения успешно загружены",
 		"images":  uploadedImages,
 	})
 }
 
+// readiness выполняет проверку готовности товара к публикации. Размерная сетка берется
+// из схемы атрибутов категории товара (атрибут size, с наследованием от родительских категорий).
+func (h *ProductHandler) readiness(ctx context.Context, product *model.Product) model.ProductReadiness {
+	var sizeChart []string
+	slug := product.Subcategory
+	if slug == "" {
+		slug = product.Category
+	}
+	if slug != "" {
+		chart, err := h.categoryRepo.SizeChart(ctx, slug)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("⚠️ Products readiness: не удалось получить размерную сетку категории %s: %v", slug, err)
+		}
+		sizeChart = chart
+	}
+	return model.CheckReadiness(product, sizeChart)
+}
+
+// draftOrReady возвращает статус черновика с учетом проверки готовности.
+// Статусы active и archived не затрагиваются.
+func (h *ProductHandler) draftOrReady(ctx context.Context, product *model.Product) string {
+	if product.Status != model.ProductStatusDraft && product.Status != model.ProductStatusReady {
+		return product.Status
+	}
+	if h.readiness(ctx, product).Ready {
+		return model.ProductStatusReady
+	}
+	return model.ProductStatusDraft
+}
+
+// refreshReadyStatus пересчитывает статус draft/ready для сохраненного товара.
+func (h *ProductHandler) refreshReadyStatus(ctx context.Context, id uuid.UUID) error {
+	product, err := h.repo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if status := h.draftOrReady(ctx, product); status != product.Status {
+		return h.repo.SetStatus(ctx, id, status)
+	}
+	return nil
+}
+
+// validateProduct проверяет штрихкоды, категорию товара и значения атрибутов по схеме категории.
+// Штрихкоды из storedBarcodes уже сохранены у товара и повторно не проверяются.
+// При ошибке сам пишет ответ и возвращает false.
+func (h *ProductHandler) validateProduct(c *gin.Context, product *model.Product, storedBarcodes map[string]bool) bool {
+	if errs := validation.ValidateProductBarcodes(product, storedBarcodes); errs.HasErrors() {
+		log.Printf("❌ Products validateProduct: штрихкоды не прошли проверку: %v", errs)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "product barcodes are invalid", "details": errs})
+		return false
+	}
+
+	if product.Category == "" {
+		if len(product.Attributes) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "attributes require a category"})
+			return false
+		}
+		return true
+	}
+
+	slugs := []string{product.Category}
+	if product.Subcategory != "" {
+		slugs = append(slugs, product.Subcategory)
+	}
+	found := make([]*model.Category, 0, len(slugs))
+	for _, slug := range slugs {
+		category, err := h.categoryRepo.GetBySlug(c.Request.Context(), slug)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unknown category '%s'", slug)})
+				return false
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error: " + err.Error()})
+			return false
+		}
+		found = append(found, category)
+	}
+	// Подкатегория должна лежать внутри выбранной категории
+	if len(found) == 2 {
+		inside, err := h.categoryRepo.IsDescendant(c.Request.Context(), found[1].ID, found[0].ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error: " + err.Error()})
+			return false
+		}
+		if !inside {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("subcategory '%s' does not belong to category '%s'", product.Subcategory, product.Category)})
+			return false
+		}
+	}
+
+	// Схема берется от самой специфичной категории (с учетом наследования)
+	schema, err := h.categoryRepo.ResolveSchema(c.Request.Context(), slugs[len(slugs)-1])
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to resolve category schema: " + err.Error()})
+		return false
+	}
+
+	if errs := validation.ValidateProductAttributes(product.Attributes, schema); errs.HasErrors() {
+		log.Printf("❌ Products validateProduct: атрибуты не прошли проверку: %v", errs)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "product attributes are invalid", "details": errs})
+		return false
+	}
+	return true
+}
+
 // GetCategories GET /api/products/categories
 func (h *ProductHandler) GetCategories(c *gin.Context) {
-	categories, err := h.repo.GetCategories(c.Request.Context())
+	categories, err := h.categoryRepo.Tree(c.Request.Context())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get categories"})
 		return
 	}
+	if lang := c.Query("lang"); lang != "" {
+		for i := range categories {
+			categories[i].Localize(lang)
+		}
+	}
 	c.JSON(http.StatusOK, gin.H{"categories": categories})
 }
 
@@ -322,3 +562,407 @@ func (h *ProductHandler) GetSizeChart(c *gin.Context) {
 
 	c.JSON(http.StatusOK, sizeChart)
 }
+
+// GetProductReadiness GET /api/products/{id}/readiness
+func (h *ProductHandler) GetProductReadiness(c *gin.Context) {
+	product, ok := h.loadProduct(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":    product.Status,
+		"readiness": h.readiness(c.Request.Context(), product),
+	})
+}
+
+// PublishProduct POST /api/products/{id}/publish
+func (h *ProductHandler) PublishProduct(c *gin.Context) {
+	product, ok := h.loadProduct(c)
+	if !ok {
+		return
+	}
+
+	if !model.CanTransitionProduct(product.Status, model.ProductStatusActive) {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("product in status '%s' cannot be published", product.Status)})
+		return
+	}
+
+	readiness := h.readiness(c.Request.Context(), product)
+	if !readiness.Ready {
+		log.Printf("❌ Products PublishProduct: товар %s не готов к публикации: %v", product.ID, readiness.Missing)
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":     "product is not ready to be published",
+			"readiness": readiness,
+		})
+		return
+	}
+
+	h.changeStatus(c, product, model.ProductStatusActive, model.ProductActionPublish, "Товар опубликован")
+}
+
+// ArchiveProduct POST /api/products/{id}/archive
+func (h *ProductHandler) ArchiveProduct(c *gin.Context) {
+	product, ok := h.loadProduct(c)
+	if !ok {
+		return
+	}
+
+	if !model.CanTransitionProduct(product.Status, model.ProductStatusArchived) {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("product in status '%s' cannot be archived", product.Status)})
+		return
+	}
+
+	h.changeStatus(c, product, model.ProductStatusArchived, model.ProductActionArchive, "Товар перенесен в архив")
+}
+
+// UnarchiveProduct POST /api/products/{id}/unarchive
+// Возвращает товар из архива в черновики (или сразу в ready, если карточка готова).
+func (h *ProductHandler) UnarchiveProduct(c *gin.Context) {
+	product, ok := h.loadProduct(c)
+	if !ok {
+		return
+	}
+
+	if product.Status != model.ProductStatusArchived {
+		c.JSON(http.StatusConflict, gin.H{"error": "only archived products can be restored"})
+		return
+	}
+
+	product.Status = model.ProductStatusDraft
+	h.changeStatus(c, product, h.draftOrReady(c.Request.Context(), product), model.ProductActionUnarchive, "Товар возвращен из архива")
+}
+
+// loadProduct разбирает ID из пути и загружает товар. При ошибке сам пишет ответ.
+func (h *ProductHandler) loadProduct(c *gin.Context) (*model.Product, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product ID format"})
+		return nil, false
+	}
+
+	product, err := h.repo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "product not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error: " + err.Error()})
+		return nil, false
+	}
+	return product, true
+}
+
+// changeStatus сохраняет новый статус товара и возвращает обновленную карточку.
+func (h *ProductHandler) changeStatus(c *gin.Context, product *model.Product, status, action, message string) {
+	if err := h.repo.SetStatus(c.Request.Context(), product.ID, status); err != nil {
+		log.Printf("❌ Products changeStatus: ошибка смены статуса: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to change product status: " + err.Error()})
+		return
+	}
+	h.recordVersion(c, product.ID, action, "")
+
+	updatedProduct, err := h.repo.GetByID(c.Request.Context(), product.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve product: " + err.Error()})
+		return
+	}
+
+	log.Printf("✅ Products changeStatus: товар %s переведен в статус %s", product.ID, status)
+	c.JSON(http.StatusOK, gin.H{
+		"message": message,
+		"product": updatedProduct,
+	})
+}
+
+// recordVersion сохраняет версию карточки после изменения.
+// Ошибка записи истории не отменяет само изменение, поэтому только логируется.
+func (h *ProductHandler) recordVersion(c *gin.Context, productID uuid.UUID, action, comment string) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	if _, err := h.historyRepo.Record(c.Request.Context(), productID, &userID, action, comment); err != nil {
+		log.Printf("⚠️ Products recordVersion: не удалось сохранить версию товара %s: %v", productID, err)
+	}
+}
+
+// recordPrices добавляет в историю цен изменившиеся цены товара и вариантов.
+// Как и recordVersion, не отменяет изменение при ошибке.
+func (h *ProductHandler) recordPrices(c *gin.Context, productID uuid.UUID, source string) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	if err := h.priceRepo.RecordChanges(c.Request.Context(), productID, source, &userID); err != nil {
+		log.Printf("⚠️ Products recordPrices: не удалось сохранить историю цен товара %s: %v", productID, err)
+	}
+}
+
+// GetProductHistory GET /api/products/{id}/history
+func (h *ProductHandler) GetProductHistory(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product ID format"})
+		return
+	}
+
+	versions, err := h.historyRepo.List(c.Request.Context(), id)
+	if err != nil {
+		log.Printf("❌ Products GetProductHistory: ошибка получения истории: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve product history: " + err.Error()})
+		return
+	}
+	if len(versions) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "product history not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"product_id": id,
+		"versions":   versions,
+	})
+}
+
+// GetProductVersion GET /api/products/{id}/history/{version}
+func (h *ProductHandler) GetProductVersion(c *gin.Context) {
+	version, ok := h.loadVersion(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"version":  version,
+		"snapshot": version.Snapshot,
+	})
+}
+
+// RestoreProductVersion POST /api/products/{id}/history/{version}/restore
+// Откатывает карточку товара к сохраненной версии. Статус жизненного цикла и остатки не откатываются.
+func (h *ProductHandler) RestoreProductVersion(c *gin.Context) {
+	version, ok := h.loadVersion(c)
+	if !ok {
+		return
+	}
+
+	current, ok := h.loadProduct(c)
+	if !ok {
+		return
+	}
+
+	restored := model.Product(version.Snapshot)
+	restored.ID = current.ID
+	restored.UserID = current.UserID
+	restored.SupplierID = current.SupplierID
+	restored.Status = current.Status
+	restored.PublishedAt = current.PublishedAt
+	restored.ArchivedAt = current.ArchivedAt
+	restored.CreatedAt = current.CreatedAt
+	restored.DeletedAt = current.DeletedAt
+	// Остатки не откатываются: проверка готовности видит текущие (см. ProductRepository.Replace)
+	currentVariants := make(map[uuid.UUID]model.ProductVariant, len(current.Variants))
+	for _, v := range current.Variants {
+		currentVariants[v.ID] = v
+	}
+	for i := range restored.Variants {
+		v := currentVariants[restored.Variants[i].ID]
+		restored.Variants[i].Stock, restored.Variants[i].Reserved = v.Stock, v.Reserved
+	}
+
+	// Схема категории могла измениться с момента сохранения версии
+	if !h.validateProduct(c, &restored, validation.StoredBarcodes(current)) {
+		return
+	}
+	restored.Status = h.draftOrReady(c.Request.Context(), &restored)
+
+	if err := h.repo.Replace(c.Request.Context(), &restored); err != nil {
+		if errors.Is(err, repository.ErrVariantInUse) {
+			c.JSON(http.StatusConflict, gin.H{"error": "cannot remove variants missing from the version: " + err.Error()})
+			return
+		}
+		log.Printf("❌ Products RestoreProductVersion: ошибка отката: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to restore product version: " + err.Error()})
+		return
+	}
+
+	h.recordVersion(c, current.ID, model.ProductActionRollback, fmt.Sprintf("Восстановлена версия %d", version.Version))
+	h.recordPrices(c, current.ID, model.PriceSourceRollback)
+
+	updatedProduct, err := h.repo.GetByID(c.Request.Context(), current.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve product: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": fmt.Sprintf("Товар восстановлен до версии %d", version.Version),
+		"product": updatedProduct,
+	})
+}
+
+// loadVersion разбирает ID товара и номер версии из пути и загружает версию. При ошибке сам пишет ответ.
+func (h *ProductHandler) loadVersion(c *gin.Context) (*model.ProductVersion, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product ID format"})
+		return nil, false
+	}
+	number, err := strconv.Atoi(c.Param("version"))
+	if err != nil || number <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid version number"})
+		return nil, false
+	}
+
+	version, err := h.historyRepo.Get(c.Request.Context(), id, number)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "product version not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error: " + err.Error()})
+		return nil, false
+	}
+	return version, true
+}
+
+// productListParams разбирает фильтры и сортировку списка товаров из query-параметров.
+// Используется и для сохраненных представлений (см. view_handler.go), поэтому не зависит от gin.Context.
+func productListParams(q url.Values) (repository.ListProductsParams, error) {
+	minPrice, _ := strconv.ParseFloat(q.Get("min_price"), 64)
+	maxPrice, _ := strconv.ParseFloat(q.Get("max_price"), 64)
+
+	params := repository.ListProductsParams{
+		Search:      q.Get("search"),
+		Category:    q.Get("category"),
+		Subcategory: q.Get("subcategory"),
+		Brand:       q.Get("brand"),
+		Statuses:    listQuery(q, "status"),
+		Tags:        listQuery(q, "tags"),
+		Sizes:       listQuery(q, "sizes"),
+		Colors:      listQuery(q, "colors"),
+		MinPrice:    minPrice,
+		MaxPrice:    maxPrice,
+		StockStatus: q.Get("stock_status"),
+		SortBy:      q.Get("sort_by"),
+		SortOrder:   q.Get("sort_order"),
+	}
+
+	// Необязательные фильтры: при неверном значении возвращаем ошибку с именем параметра
+	var parseErr error
+	var err error
+	check := func(key string, err error) {
+		if err != nil && parseErr == nil {
+			parseErr = fmt.Errorf("invalid %s", key)
+		}
+	}
+	params.MinSales, err = optionalIntQuery(q, "min_sales")
+	check("min_sales", err)
+	params.MaxSales, err = optionalIntQuery(q, "max_sales")
+	check("max_sales", err)
+	params.MinMargin, err = optionalFloatQuery(q, "min_margin")
+	check("min_margin", err)
+	params.MaxMargin, err = optionalFloatQuery(q, "max_margin")
+	check("max_margin", err)
+	params.MinDiscount, err = optionalFloatQuery(q, "min_discount")
+	check("min_discount", err)
+	params.MaxDiscount, err = optionalFloatQuery(q, "max_discount")
+	check("max_discount", err)
+	params.IsBestseller, err = optionalBoolQuery(q, "is_bestseller")
+	check("is_bestseller", err)
+	params.IsNew, err = optionalBoolQuery(q, "is_new")
+	check("is_new", err)
+	params.CreatedFrom, err = optionalTimeQuery(q, "created_from", false)
+	check("created_from", err)
+	params.CreatedTo, err = optionalTimeQuery(q, "created_to", true)
+	check("created_to", err)
+	params.UpdatedFrom, err = optionalTimeQuery(q, "updated_from", false)
+	check("updated_from", err)
+	params.UpdatedTo, err = optionalTimeQuery(q, "updated_to", true)
+	check("updated_to", err)
+	if raw := q.Get("supplier_id"); raw != "" {
+		supplierID, err := uuid.Parse(raw)
+		check("supplier_id", err)
+		params.SupplierID = &supplierID
+	}
+	return params, parseErr
+
+}
+
+// optionalIntQuery возвращает целочисленный query-параметр или nil, если он не передан.
+func optionalIntQuery(q url.Values, key string) (*int, error) {
+	raw := q.Get(key)
+	if raw == "" {
+		return nil, nil
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil {
+		return nil, err
+	}
+	return &value, nil
+}
+
+// optionalFloatQuery возвращает дробный query-параметр или nil, если он не передан.
+func optionalFloatQuery(q url.Values, key string) (*float64, error) {
+	raw := q.Get(key)
+	if raw == "" {
+		return nil, nil
+	}
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &value, nil
+}
+
+// optionalBoolQuery возвращает логический query-параметр или nil, если он не передан.
+func optionalBoolQuery(q url.Values, key string) (*bool, error) {
+	raw := q.Get(key)
+	if raw == "" {
+		return nil, nil
+	}
+	value, err := strconv.ParseBool(raw)
+	if err != nil {
+		return nil, err
+	}
+	return &value, nil
+}
+
+// optionalUUIDQuery возвращает query-параметр с UUID или nil, если он не передан.
+func optionalUUIDQuery(q url.Values, key string) (*uuid.UUID, error) {
+	raw := q.Get(key)
+	if raw == "" {
+		return nil, nil
+	}
+	value, err := uuid.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	return &value, nil
+}
+
+// optionalTimeQuery принимает дату в RFC3339 или YYYY-MM-DD.
+// Для конца диапазона (endOfDay) дата без времени означает конец этого дня.
+func optionalTimeQuery(q url.Values, key string, endOfDay bool) (*time.Time, error) {
+	raw := q.Get(key)
+	if raw == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return &t, nil
+	}
+	t, err := time.Parse("2006-01-02", raw)
+	if err != nil {
+		return nil, err
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return &t, nil
+}
+
+// listQuery разбирает список значений: ?tags=a,b или ?tags=a&tags=b.
+func listQuery(q url.Values, key string) []string {
+	var values []string
+	for _, raw := range q[key] {
+		for _, v := range strings.Split(raw, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+	}
+	return values
+}
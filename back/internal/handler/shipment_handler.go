@@ -0,0 +1,318 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lamoda-seller-app/internal/carrier"
+	"github.com/lamoda-seller-app/internal/middleware"
+	"github.com/lamoda-seller-app/internal/model"
+	"github.com/lamoda-seller-app/internal/repository"
+	"gorm.io/gorm"
+)
+
+// ShipmentHandler обрабатывает запросы по отправлениям (посылкам) заказа.
+type ShipmentHandler struct {
+	repo      *repository.ShipmentRepository
+	orderRepo *repository.OrderRepository
+	carriers  *carrier.Registry
+}
+
+func NewShipmentHandler(repo *repository.ShipmentRepository, orderRepo *repository.OrderRepository, carriers *carrier.Registry) *ShipmentHandler {
+	return &ShipmentHandler{repo: repo, orderRepo: orderRepo, carriers: carriers}
+}
+
+// ListShipments GET /api/orders/{order_id}/shipments
+func (h *ShipmentHandler) ListShipments(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+
+	orderID, ok := orderIDParam(c)
+	if !ok {
+		return
+	}
+
+	shipments, err := h.repo.ListByOrder(c.Request.Context(), userID, orderID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "order not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve shipments: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"shipments": shipments})
+}
+
+// CreateShipment POST /api/orders/{order_id}/shipments
+// Создает посылку с частью позиций заказа, например с одного склада или без задержанной позиции.
+func (h *ShipmentHandler) CreateShipment(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+
+	orderID, ok := orderIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req model.CreateShipmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid input: " + err.Error()})
+		return
+	}
+
+	shipment, err := h.repo.Create(c.Request.Context(), userID, orderID, req)
+	if err != nil {
+		writeShipmentError(c, "CreateShipment", err)
+		return
+	}
+
+	log.Printf("✅ Shipments CreateShipment: создано отправление %s, позиций: %d", shipment.Number, len(shipment.Items))
+	c.JSON(http.StatusCreated, gin.H{
+		"message":  "Отправление успешно создано",
+		"shipment": shipment,
+	})
+}
+
+// UpdateShipment PUT /api/orders/{order_id}/shipments/{shipment_id}
+func (h *ShipmentHandler) UpdateShipment(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+
+	orderID, shipmentID, ok := shipmentParams(c)
+	if !ok {
+		return
+	}
+
+	var req model.UpdateShipmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid input: " + err.Error()})
+		return
+	}
+
+	shipment, err := h.repo.Update(c.Request.Context(), userID, orderID, shipmentID, req)
+	if err != nil {
+		writeShipmentError(c, "UpdateShipment", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Отправление успешно обновлено",
+		"shipment": shipment,
+	})
+}
+
+// UpdateShipmentStatus PUT /api/orders/{order_id}/shipments/{shipment_id}/status
+// Статус заказа пересчитывается по отправлениям: часть отгружена - partially_shipped, все - in_transit.
+func (h *ShipmentHandler) UpdateShipmentStatus(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+
+	orderID, shipmentID, ok := shipmentParams(c)
+	if !ok {
+		return
+	}
+
+	var req model.UpdateShipmentStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid input: " + err.Error()})
+		return
+	}
+
+	// Посылку, зарегистрированную у перевозчика, сначала отменяем у него
+	if req.Status == model.ShipmentStatusCancelled {
+		if !h.cancelAtCarrier(c, userID, orderID, shipmentID) {
+			return
+		}
+	}
+
+	shipment, order, err := h.repo.UpdateStatus(c.Request.Context(), userID, orderID, shipmentID, req.Status, req.Comment)
+	if err != nil {
+		writeShipmentError(c, "UpdateShipmentStatus", err)
+		return
+	}
+
+	log.Printf("✅ Shipments UpdateShipmentStatus: отправление %s - %s, заказ %s - %s",
+		shipment.Number, shipment.Status, order.OrderNumber, order.Status)
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Статус отправления успешно обновлен",
+		"shipment": shipment,
+		"order": gin.H{
+			"id":     order.ID,
+			"status": order.Status,
+		},
+	})
+}
+
+// RegisterShipment POST /api/orders/{order_id}/shipments/{shipment_id}/register
+// Регистрирует посылку у ее перевозчика и сохраняет полученный трек-номер. Дальше статусы
+// посылки обновляет поллер по событиям отслеживания.
+func (h *ShipmentHandler) RegisterShipment(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	ctx := c.Request.Context()
+
+	orderID, shipmentID, ok := shipmentParams(c)
+	if !ok {
+		return
+	}
+
+	shipment, err := h.repo.GetByID(ctx, userID, orderID, shipmentID)
+	if err != nil {
+		writeShipmentError(c, "RegisterShipment", err)
+		return
+	}
+	if shipment.Status != model.ShipmentStatusPending || shipment.TrackingNumber != "" {
+		c.JSON(http.StatusConflict, gin.H{"error": "shipment is already registered or shipped"})
+		return
+	}
+	adapter, err := h.carriers.Get(shipment.Carrier)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "carrier " + shipment.Carrier + ": " + err.Error()})
+		return
+	}
+	order, err := h.orderRepo.GetByID(ctx, orderID, userID)
+	if err != nil {
+		writeShipmentError(c, "RegisterShipment", err)
+		return
+	}
+
+	created, err := adapter.CreateShipment(ctx, carrierShipmentRequest(order, shipment))
+	if err != nil {
+		log.Printf("❌ Shipments RegisterShipment: перевозчик %s: %v", shipment.Carrier, err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "failed to register shipment with carrier: " + err.Error()})
+		return
+	}
+
+	shipment, err = h.repo.Update(ctx, userID, orderID, shipmentID, model.UpdateShipmentRequest{TrackingNumber: &created.TrackingNumber})
+	if err != nil {
+		writeShipmentError(c, "RegisterShipment", err)
+		return
+	}
+
+	log.Printf("✅ Shipments RegisterShipment: отправление %s зарегистрировано у %s, трек-номер %s",
+		shipment.Number, shipment.Carrier, shipment.TrackingNumber)
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Отправление зарегистрировано у перевозчика",
+		"shipment":  shipment,
+		"label_url": created.LabelURL,
+	})
+}
+
+// ListTrackingEvents GET /api/orders/{order_id}/tracking
+// Возвращает события отслеживания всех посылок заказа.
+func (h *ShipmentHandler) ListTrackingEvents(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+
+	orderID, ok := orderIDParam(c)
+	if !ok {
+		return
+	}
+
+	events, err := h.repo.ListTrackingEvents(c.Request.Context(), userID, orderID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve tracking events: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"events": events})
+}
+
+// cancelAtCarrier отменяет у перевозчика посылку с трек-номером. Возвращает false, если ответ уже записан.
+func (h *ShipmentHandler) cancelAtCarrier(c *gin.Context, userID, orderID, shipmentID uuid.UUID) bool {
+	shipment, err := h.repo.GetByID(c.Request.Context(), userID, orderID, shipmentID)
+	if err != nil {
+		writeShipmentError(c, "UpdateShipmentStatus", err)
+		return false
+	}
+	if shipment.TrackingNumber == "" || shipment.Status != model.ShipmentStatusPending {
+		return true
+	}
+	adapter, err := h.carriers.Get(shipment.Carrier)
+	if err != nil {
+		// Трек-номер введен вручную, у перевозчика отменять нечего
+		return true
+	}
+
+	err = adapter.CancelShipment(c.Request.Context(), shipment.TrackingNumber)
+	switch {
+	case err == nil, errors.Is(err, carrier.ErrParcelNotFound):
+		return true
+	case errors.Is(err, carrier.ErrCancelRejected):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		log.Printf("❌ Shipments UpdateShipmentStatus: перевозчик %s: %v", shipment.Carrier, err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "failed to cancel shipment at carrier: " + err.Error()})
+	}
+	return false
+}
+
+// carrierShipmentRequest собирает данные посылки для перевозчика из заказа и отправления.
+func carrierShipmentRequest(order *model.Order, shipment *model.Shipment) carrier.ShipmentRequest {
+	req := carrier.ShipmentRequest{
+		Reference:   shipment.Number,
+		OrderNumber: order.OrderNumber,
+		Recipient: carrier.Recipient{
+			Name:  order.Customer.Name,
+			Phone: order.Customer.Phone,
+			Email: order.Customer.Email,
+		},
+		DeliveryType: order.Delivery.Type,
+		Address:      order.Delivery.Address,
+	}
+	items := make(map[uuid.UUID]model.OrderItem, len(order.Items))
+	for _, item := range order.Items {
+		items[item.ID] = item
+	}
+	for _, si := range shipment.Items {
+		item := items[si.OrderItemID]
+		unitPrice := item.Price
+		if item.Quantity > 0 {
+			unitPrice = item.Total / float64(item.Quantity)
+		}
+		req.Items = append(req.Items, carrier.ParcelItem{
+			SKU:      item.SKU,
+			Name:     item.Name,
+			Quantity: si.Quantity,
+			Price:    unitPrice,
+		})
+		req.DeclaredValue += unitPrice * float64(si.Quantity)
+	}
+	return req
+}
+
+func writeShipmentError(c *gin.Context, action string, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "order, shipment or warehouse not found"})
+	case errors.Is(err, repository.ErrOrderItemNotFound), errors.Is(err, repository.ErrShipmentQuantityExceeded):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, repository.ErrOrderNotShippable), errors.Is(err, repository.ErrShipmentStatusTransition),
+		errors.Is(err, repository.ErrInsufficientStock), errors.Is(err, repository.ErrMarkingCodesShortage):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		log.Printf("❌ Shipments %s: %v", action, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to process shipment: " + err.Error()})
+	}
+}
+
+func orderIDParam(c *gin.Context) (uuid.UUID, bool) {
+	orderID, err := uuid.Parse(c.Param("order_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid order ID format"})
+		return uuid.Nil, false
+	}
+	return orderID, true
+}
+
+func shipmentParams(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	orderID, ok := orderIDParam(c)
+	if !ok {
+		return uuid.Nil, uuid.Nil, false
+	}
+	shipmentID, err := uuid.Parse(c.Param("shipment_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid shipment ID format"})
+		return uuid.Nil, uuid.Nil, false
+	}
+	return orderID, shipmentID, true
+}
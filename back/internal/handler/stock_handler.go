@@ -0,0 +1,198 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lamoda-seller-app/internal/middleware"
+	"github.com/lamoda-seller-app/internal/model"
+	"github.com/lamoda-seller-app/internal/repository"
+	"gorm.io/gorm"
+)
+
+// StockHandler обрабатывает запросы к точкам заказа, оповещениям об остатках и отчету о дозаказе.
+type StockHandler struct {
+	repo *repository.StockRepository
+}
+
+func NewStockHandler(repo *repository.StockRepository) *StockHandler {
+	return &StockHandler{repo: repo}
+}
+
+// ListStockAlerts GET /api/stock/alerts?status=open|resolved|all&type=low_stock|out_of_stock
+func (h *StockHandler) ListStockAlerts(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	alertType := c.Query("type")
+	if alertType != "" && alertType != model.StockAlertLow && alertType != model.StockAlertOutOfStock {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "type must be 'low_stock' or 'out_of_stock'"})
+		return
+	}
+
+	alerts, total, err := h.repo.ListAlerts(c.Request.Context(), repository.ListStockAlertsParams{
+		UserID: userID,
+		Status: c.Query("status"),
+		Type:   alertType,
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		log.Printf("❌ Stock ListStockAlerts: ошибка получения оповещений: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve stock alerts: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"alerts": alerts,
+		"pagination": PaginationResponse{
+			Total:   total,
+			Limit:   limit,
+			Offset:  offset,
+			HasNext: total > int64(limit+offset),
+			HasPrev: offset > 0,
+		},
+	})
+}
+
+// AcknowledgeStockAlert POST /api/stock/alerts/{id}/acknowledge
+func (h *StockHandler) AcknowledgeStockAlert(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid alert ID format"})
+		return
+	}
+
+	if err := h.repo.AcknowledgeAlert(c.Request.Context(), id, userID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "stock alert not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to acknowledge stock alert: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Оповещение отмечено как просмотренное"})
+}
+
+// GetRestockReport GET /api/stock/restock?supplier_id=
+// Рекомендуемые количества к заказу по вариантам, сгруппированные по поставщикам.
+func (h *StockHandler) GetRestockReport(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+
+	var supplierID *uuid.UUID
+	if raw := c.Query("supplier_id"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid supplier ID format"})
+			return
+		}
+		supplierID = &id
+	}
+
+	groups, err := h.repo.RestockReport(c.Request.Context(), userID, supplierID)
+	if err != nil {
+		log.Printf("❌ Stock GetRestockReport: ошибка построения отчета: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to build restock report: " + err.Error()})
+		return
+	}
+
+	var totalUnits int
+	var totalCost float64
+	for _, g := range groups {
+		totalUnits += g.TotalUnits
+		totalCost += g.TotalCost
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"suppliers":   groups,
+		"total_units": totalUnits,
+		"total_cost":  totalCost,
+		"settings": gin.H{
+			"velocity_window_days":   model.SalesWindowDays,
+			"safety_stock_days":      model.SafetyStockDays,
+			"cover_days":             model.RestockCoverDays,
+			"default_lead_time_days": model.DefaultLeadTimeDays,
+		},
+	})
+}
+
+// GetProductStockLevels GET /api/products/{id}/stock-levels
+// Остатки вариантов товара вместе с точками заказа и скоростью продаж.
+func (h *StockHandler) GetProductStockLevels(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+
+	productID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product ID format"})
+		return
+	}
+
+	levels, err := h.repo.VariantLevels(c.Request.Context(), userID, productID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve stock levels: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"variants": levels})
+}
+
+// UpdateReorderPoint PUT /api/products/{id}/variants/{variant_id}/reorder-point
+func (h *StockHandler) UpdateReorderPoint(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+
+	productID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product ID format"})
+		return
+	}
+	variantID, err := uuid.Parse(c.Param("variant_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid variant ID format"})
+		return
+	}
+
+	var req model.UpdateReorderPointRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid input: " + err.Error()})
+		return
+	}
+
+	if err := h.repo.SetReorderPoint(c.Request.Context(), userID, productID, variantID, req); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "variant not found"})
+			return
+		}
+		log.Printf("❌ Stock UpdateReorderPoint: ошибка сохранения точки заказа: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update reorder point: " + err.Error()})
+		return
+	}
+
+	levels, err := h.repo.VariantLevels(c.Request.Context(), userID, productID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve stock levels: " + err.Error()})
+		return
+	}
+	// Архивные товары не попадают в расчет, тогда возвращаем только сообщение
+	for _, l := range levels {
+		if l.VariantID == variantID {
+			c.JSON(http.StatusOK, gin.H{"message": "Точка заказа успешно обновлена", "variant": l})
+			return
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Точка заказа успешно обновлена"})
+}
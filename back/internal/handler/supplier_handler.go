@@ -0,0 +1,282 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lamoda-seller-app/internal/middleware"
+	"github.com/lamoda-seller-app/internal/model"
+	"github.com/lamoda-seller-app/internal/repository"
+	"gorm.io/gorm"
+)
+
+// SupplierHandler обрабатывает HTTP запросы, связанные с поставщиками.
+type SupplierHandler struct {
+	repo *repository.SupplierRepository
+}
+
+func NewSupplierHandler(repo *repository.SupplierRepository) *SupplierHandler {
+	return &SupplierHandler{repo: repo}
+}
+
+// --- Обработчики ---
+
+// ListSuppliers GET /api/suppliers
+func (h *SupplierHandler) ListSuppliers(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+
+	suppliers, err := h.repo.List(c.Request.Context(), userID, c.Query("search"))
+	if err != nil {
+		log.Printf("❌ Suppliers ListSuppliers: ошибка получения поставщиков: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve suppliers: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"suppliers": suppliers})
+}
+
+// GetSupplier GET /api/suppliers/{id}
+func (h *SupplierHandler) GetSupplier(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid supplier ID format"})
+		return
+	}
+
+	supplier, err := h.repo.GetByID(c.Request.Context(), id, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "supplier not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, supplier)
+}
+
+// CreateSupplier POST /api/suppliers
+func (h *SupplierHandler) CreateSupplier(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+
+	var req model.SupplierRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid input: " + err.Error()})
+		return
+	}
+
+	supplier := model.Supplier{
+		ID:      uuid.New(),
+		UserID:  &userID,
+		Name:    req.Name,
+		Contact: req.Contact,
+		Email:   req.Email,
+		Phone:   req.Phone,
+		Notes:   req.Notes,
+
+		LeadTimeDays: model.DefaultLeadTimeDays,
+	}
+	if req.LeadTimeDays != nil {
+		supplier.LeadTimeDays = *req.LeadTimeDays
+	}
+
+	if err := h.repo.Create(c.Request.Context(), &supplier); err != nil {
+		log.Printf("❌ Suppliers CreateSupplier: ошибка создания поставщика: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create supplier: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":  "Поставщик успешно создан",
+		"supplier": supplier,
+	})
+}
+
+// UpdateSupplier PUT /api/suppliers/{id}
+func (h *SupplierHandler) UpdateSupplier(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid supplier ID format"})
+		return
+	}
+
+	var req model.SupplierRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid input: " + err.Error()})
+		return
+	}
+
+	supplier, err := h.repo.GetByID(c.Request.Context(), id, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "supplier not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error: " + err.Error()})
+		return
+	}
+
+	supplier.Name = req.Name
+	supplier.Contact = req.Contact
+	supplier.Email = req.Email
+	supplier.Phone = req.Phone
+	supplier.Notes = req.Notes
+	if req.LeadTimeDays != nil {
+		supplier.LeadTimeDays = *req.LeadTimeDays
+	}
+
+	if err := h.repo.Update(c.Request.Context(), supplier); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update supplier: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Поставщик успешно обновлен",
+		"supplier": supplier,
+	})
+}
+
+// DeleteSupplier DELETE /api/suppliers/{id}
+func (h *SupplierHandler) DeleteSupplier(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid supplier ID format"})
+		return
+	}
+
+	if err := h.repo.Delete(c.Request.Context(), id, userID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "supplier not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete supplier: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Поставщик успешно удален"})
+}
+
+// AssignProducts POST /api/suppliers/{id}/products
+func (h *SupplierHandler) AssignProducts(c *gin.Context) {
+	h.changeAssignment(c, true)
+}
+
+// UnassignProducts DELETE /api/suppliers/{id}/products
+func (h *SupplierHandler) UnassignProducts(c *gin.Context) {
+	h.changeAssignment(c, false)
+}
+
+// changeAssignment привязывает (assign == true) или отвязывает товары от поставщика.
+func (h *SupplierHandler) changeAssignment(c *gin.Context, assign bool) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid supplier ID format"})
+		return
+	}
+
+	var req model.AssignSupplierRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid input: " + err.Error()})
+		return
+	}
+
+	if _, err := h.repo.GetByID(c.Request.Context(), id, userID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "supplier not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error: " + err.Error()})
+		return
+	}
+
+	productIDs := uniqueUUIDs(req.ProductIDs)
+	if assign {
+		err = h.repo.AssignProducts(c.Request.Context(), userID, id, productIDs)
+	} else {
+		err = h.repo.UnassignProducts(c.Request.Context(), userID, id, productIDs)
+	}
+	if err != nil {
+		if errors.Is(err, repository.ErrProductsNotOwned) || errors.Is(err, repository.ErrProductsNotAssigned) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		log.Printf("❌ Suppliers changeAssignment: ошибка привязки товаров: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update products: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Привязка товаров к поставщику обновлена",
+		"products": len(productIDs),
+	})
+}
+
+// GetSuppliersReport GET /api/suppliers/report
+func (h *SupplierHandler) GetSuppliersReport(c *gin.Context) {
+	h.report(c, uuid.Nil)
+}
+
+// GetSupplierReport GET /api/suppliers/{id}/report
+func (h *SupplierHandler) GetSupplierReport(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid supplier ID format"})
+		return
+	}
+	h.report(c, id)
+}
+
+func (h *SupplierHandler) report(c *gin.Context, supplierID uuid.UUID) {
+	var params model.SupplierReportRequestParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query parameters: " + err.Error()})
+		return
+	}
+	if params.Period == "" {
+		params.Period = "30d"
+	}
+
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+
+	reports, err := h.repo.Report(c.Request.Context(), userID, supplierID, params.Period)
+	if err != nil {
+		log.Printf("❌ Suppliers report: ошибка построения отчета: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build supplier report"})
+		return
+	}
+	if supplierID != uuid.Nil && len(reports) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "supplier not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, model.SupplierReportResponse{
+		Period:    params.Period,
+		Suppliers: reports,
+	})
+}
+
+// uniqueUUIDs убирает повторяющиеся идентификаторы, сохраняя порядок.
+func uniqueUUIDs(ids []uuid.UUID) []uuid.UUID {
+	seen := make(map[uuid.UUID]struct{}, len(ids))
+	result := make([]uuid.UUID, 0, len(ids))
+	for _, id := range ids {
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		result = append(result, id)
+	}
+	return result
+}
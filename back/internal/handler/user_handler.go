@@ -11,6 +11,7 @@ import (
 	"gorm.io/gorm"
 
 	"github.com/lamoda-seller-app/internal/auth"
+	"github.com/lamoda-seller-app/internal/barcode"
 	"github.com/lamoda-seller-app/internal/middleware"
 	"github.com/lamoda-seller-app/internal/model"
 	"github.com/lamoda-seller-app/internal/repository"
@@ -51,7 +52,11 @@ type ChangePasswordRequest struct {
 }
 
 type UpdateProfileRequest struct {
-	Name string `json:"name" binding:"required"`
+	Name              string  `json:"name" binding:"required"`
+	LowStockThreshold *int    `json:"low_stock_threshold" binding:"omitempty,gte=0"` // Если не передан, не меняется; 0 - не выделять "мало на складе"
+	ReservationRule   string  `json:"reservation_rule" binding:"omitempty,oneof=priority single_warehouse most_available"`
+	INN               *string `json:"inn" binding:"omitempty,numeric,min=10,max=12"` // Для документов "Честного ЗНАКа"
+	GS1Prefix         *string `json:"gs1_prefix"`                                    // Для выпуска EAN-13, пустая строка сбрасывает
 }
 
 type LinkAccountRequest struct {
@@ -241,12 +246,16 @@ func (h *UserHandler) GetProfile(c *gin.Context) {
 
 	// Не возвращаем хешированный пароль в ответе
 	response := gin.H{
-		"id":              user.ID,
-		"name":            user.Name,
-		"email":           user.Email,
-		"balance_kopecks": user.BalanceKopecks,
-		"created_at":      user.CreatedAt,
-		"updated_at":      user.UpdatedAt,
+		"id":                  user.ID,
+		"name":                user.Name,
+		"email":               user.Email,
+		"balance_kopecks":     user.BalanceKopecks,
+		"low_stock_threshold": user.LowStockThreshold,
+		"reservation_rule":    user.ReservationRule,
+		"inn":                 user.INN,
+		"gs1_prefix":          user.GS1Prefix,
+		"created_at":          user.CreatedAt,
+		"updated_at":          user.UpdatedAt,
 	}
 
 	c.JSON(http.StatusOK, response)
@@ -269,6 +278,26 @@ func (h *UserHandler) UpdateProfile(c *gin.Context) {
 	}
 
 	user.Name = req.Name
+	if req.LowStockThreshold != nil {
+		user.LowStockThreshold = *req.LowStockThreshold
+	}
+	if req.ReservationRule != "" {
+		user.ReservationRule = req.ReservationRule
+	}
+	if req.INN != nil {
+		user.INN = *req.INN
+	}
+	if req.GS1Prefix != nil && *req.GS1Prefix != user.GS1Prefix {
+		if *req.GS1Prefix != "" {
+			if err := barcode.ValidateGS1Prefix(*req.GS1Prefix); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid input: " + err.Error()})
+				return
+			}
+		}
+		// Номера в новом диапазоне выдаются с начала
+		user.GS1Prefix = *req.GS1Prefix
+		user.GS1NextRef = 0
+	}
 
 	if err := h.repo.UpdateUser(c.Request.Context(), user); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update profile"})
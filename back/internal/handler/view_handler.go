@@ -0,0 +1,241 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lamoda-seller-app/internal/middleware"
+	"github.com/lamoda-seller-app/internal/model"
+	"github.com/lamoda-seller-app/internal/repository"
+	"gorm.io/gorm"
+)
+
+// ViewHandler обрабатывает запросы к сохраненным представлениям списков товаров и заказов.
+type ViewHandler struct {
+	repo        *repository.ViewRepository
+	productRepo *repository.ProductRepository
+	orderRepo   *repository.OrderRepository
+}
+
+func NewViewHandler(repo *repository.ViewRepository, productRepo *repository.ProductRepository, orderRepo *repository.OrderRepository) *ViewHandler {
+	return &ViewHandler{repo: repo, productRepo: productRepo, orderRepo: orderRepo}
+}
+
+// ListViews GET /api/views?list=products|orders
+// Возвращает свои и общие представления вместе с текущим количеством записей для бейджей.
+func (h *ViewHandler) ListViews(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+
+	list := c.Query("list")
+	if list != "" && list != model.ViewListProducts && list != model.ViewListOrders {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "list must be 'products' or 'orders'"})
+		return
+	}
+
+	views, err := h.repo.List(c.Request.Context(), userID, list)
+	if err != nil {
+		log.Printf("❌ Views ListViews: ошибка получения представлений: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve views: " + err.Error()})
+		return
+	}
+
+	for i := range views {
+		h.attachCount(c.Request.Context(), userID, &views[i])
+	}
+
+	log.Printf("✅ Views ListViews: найдено %d представлений", len(views))
+	c.JSON(http.StatusOK, gin.H{"views": views})
+}
+
+// GetView GET /api/views/{id}
+func (h *ViewHandler) GetView(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	view, ok := h.loadView(c, userID)
+	if !ok {
+		return
+	}
+	h.attachCount(c.Request.Context(), userID, view)
+	c.JSON(http.StatusOK, view)
+}
+
+// CreateView POST /api/views
+func (h *ViewHandler) CreateView(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+
+	var req model.SavedViewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid input: " + err.Error()})
+		return
+	}
+	if err := validateViewFilters(req.List, req.Filters); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid filters: " + err.Error()})
+		return
+	}
+
+	view := viewFromRequest(req)
+	view.ID = uuid.New()
+	view.UserID = userID
+	if err := h.repo.Create(c.Request.Context(), view); err != nil {
+		log.Printf("❌ Views CreateView: ошибка сохранения представления: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create view: " + err.Error()})
+		return
+	}
+	view.IsOwner = true
+	h.attachCount(c.Request.Context(), userID, view)
+
+	log.Printf("✅ Views CreateView: создано представление '%s' (%s)", view.Name, view.List)
+	c.JSON(http.StatusCreated, gin.H{"message": "Представление успешно сохранено", "view": view})
+}
+
+// UpdateView PUT /api/views/{id}
+// Изменять и удалять представление может только его владелец.
+func (h *ViewHandler) UpdateView(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	existing, ok := h.loadOwnView(c, userID)
+	if !ok {
+		return
+	}
+
+	var req model.SavedViewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid input: " + err.Error()})
+		return
+	}
+	if err := validateViewFilters(req.List, req.Filters); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid filters: " + err.Error()})
+		return
+	}
+
+	view := viewFromRequest(req)
+	view.ID = existing.ID
+	view.UserID = userID
+	view.CreatedAt = existing.CreatedAt
+	if err := h.repo.Update(c.Request.Context(), view); err != nil {
+		log.Printf("❌ Views UpdateView: ошибка обновления представления: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update view: " + err.Error()})
+		return
+	}
+
+	updated, err := h.repo.GetVisible(c.Request.Context(), view.ID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load view: " + err.Error()})
+		return
+	}
+	h.attachCount(c.Request.Context(), userID, updated)
+
+	log.Printf("✅ Views UpdateView: обновлено представление %s", view.ID)
+	c.JSON(http.StatusOK, gin.H{"message": "Представление успешно обновлено", "view": updated})
+}
+
+// DeleteView DELETE /api/views/{id}
+func (h *ViewHandler) DeleteView(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	view, ok := h.loadOwnView(c, userID)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.Delete(c.Request.Context(), view.ID, userID); err != nil {
+		log.Printf("❌ Views DeleteView: ошибка удаления представления: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete view: " + err.Error()})
+		return
+	}
+
+	log.Printf("✅ Views DeleteView: удалено представление %s", view.ID)
+	c.JSON(http.StatusOK, gin.H{"message": "Представление успешно удалено"})
+}
+
+// loadView загружает доступное пользователю представление по ID из пути.
+// При ошибке ответ уже отправлен.
+func (h *ViewHandler) loadView(c *gin.Context, userID uuid.UUID) (*model.SavedView, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid view ID format"})
+		return nil, false
+	}
+
+	view, err := h.repo.GetVisible(c.Request.Context(), id, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "view not found"})
+			return nil, false
+		}
+		log.Printf("❌ Views loadView: ошибка базы данных: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error: " + err.Error()})
+		return nil, false
+	}
+	return view, true
+}
+
+// loadOwnView как loadView, но для общих представлений других пользователей возвращает 403.
+func (h *ViewHandler) loadOwnView(c *gin.Context, userID uuid.UUID) (*model.SavedView, bool) {
+	view, ok := h.loadView(c, userID)
+	if !ok {
+		return nil, false
+	}
+	if !view.IsOwner {
+		c.JSON(http.StatusForbidden, gin.H{"error": repository.ErrViewNotOwner.Error()})
+		return nil, false
+	}
+	return view, true
+}
+
+// attachCount считает, сколько записей сейчас попадает в представление.
+// Общие представления считаются по данным текущего пользователя, а не владельца.
+// Ошибка подсчета не мешает вернуть само представление.
+func (h *ViewHandler) attachCount(ctx context.Context, userID uuid.UUID, view *model.SavedView) {
+	var count int64
+	var err error
+	q := url.Values(view.Filters)
+
+	switch view.List {
+	case model.ViewListProducts:
+		var params repository.ListProductsParams
+		if params, err = productListParams(q); err == nil {
+			if params.LowStockThreshold, err = h.productRepo.LowStockThreshold(ctx, userID); err == nil {
+				count, err = h.productRepo.Count(ctx, params)
+			}
+		}
+	case model.ViewListOrders:
+		count, err = h.orderRepo.Count(ctx, orderListParams(q, userID))
+	default:
+		return
+	}
+
+	if err != nil {
+		log.Printf("⚠️ Views attachCount: не удалось посчитать записи представления %s: %v", view.ID, err)
+		return
+	}
+	view.Count = &count
+}
+
+// validateViewFilters проверяет фильтры тем же разбором, что и query-параметры списка.
+func validateViewFilters(list string, filters model.ViewFilters) error {
+	if list == model.ViewListProducts {
+		_, err := productListParams(url.Values(filters))
+		return err
+	}
+	return nil
+}
+
+func viewFromRequest(req model.SavedViewRequest) *model.SavedView {
+	filters := req.Filters
+	if filters == nil {
+		filters = model.ViewFilters{}
+	}
+	return &model.SavedView{
+		List:      req.List,
+		Name:      req.Name,
+		Filters:   filters,
+		SortBy:    req.SortBy,
+		SortOrder: req.SortOrder,
+		Columns:   req.Columns,
+		IsDefault: req.IsDefault,
+		Shared:    req.Shared,
+	}
+}
@@ -0,0 +1,331 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lamoda-seller-app/internal/middleware"
+	"github.com/lamoda-seller-app/internal/model"
+	"github.com/lamoda-seller-app/internal/repository"
+	"gorm.io/gorm"
+)
+
+// WarehouseHandler обрабатывает запросы к складам, остаткам по складам и перемещениям.
+type WarehouseHandler struct {
+	repo *repository.WarehouseRepository
+}
+
+func NewWarehouseHandler(repo *repository.WarehouseRepository) *WarehouseHandler {
+	return &WarehouseHandler{repo: repo}
+}
+
+// ListWarehouses GET /api/warehouses
+func (h *WarehouseHandler) ListWarehouses(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+
+	warehouses, err := h.repo.List(c.Request.Context(), userID)
+	if err != nil {
+		log.Printf("❌ Warehouses ListWarehouses: ошибка получения складов: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve warehouses: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"warehouses": warehouses})
+}
+
+// GetWarehouse GET /api/warehouses/{id}
+func (h *WarehouseHandler) GetWarehouse(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+
+	id, ok := warehouseIDParam(c)
+	if !ok {
+		return
+	}
+
+	warehouse, err := h.repo.GetByID(c.Request.Context(), id, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "warehouse not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, warehouse)
+}
+
+// CreateWarehouse POST /api/warehouses
+func (h *WarehouseHandler) CreateWarehouse(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+
+	var req model.WarehouseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid input: " + err.Error()})
+		return
+	}
+
+	warehouse := warehouseFromRequest(req)
+	warehouse.ID = uuid.New()
+	warehouse.UserID = userID
+	if err := h.repo.Create(c.Request.Context(), warehouse); err != nil {
+		if errors.Is(err, repository.ErrDefaultWarehouseType) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		log.Printf("❌ Warehouses CreateWarehouse: ошибка создания склада: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create warehouse: " + err.Error()})
+		return
+	}
+
+	log.Printf("✅ Warehouses CreateWarehouse: создан склад '%s' (%s)", warehouse.Name, warehouse.Type)
+	c.JSON(http.StatusCreated, gin.H{
+		"message":   "Склад успешно создан",
+		"warehouse": warehouse,
+	})
+}
+
+// UpdateWarehouse PUT /api/warehouses/{id}
+func (h *WarehouseHandler) UpdateWarehouse(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+
+	id, ok := warehouseIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req model.WarehouseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid input: " + err.Error()})
+		return
+	}
+
+	warehouse := warehouseFromRequest(req)
+	warehouse.ID = id
+	warehouse.UserID = userID
+	if err := h.repo.Update(c.Request.Context(), warehouse); err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "warehouse not found"})
+		case errors.Is(err, repository.ErrDefaultWarehouseRequired):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, repository.ErrDefaultWarehouseType):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update warehouse: " + err.Error()})
+		}
+		return
+	}
+
+	updated, err := h.repo.GetByID(c.Request.Context(), id, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve warehouse: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Склад успешно обновлен",
+		"warehouse": updated,
+	})
+}
+
+// DeleteWarehouse DELETE /api/warehouses/{id}
+func (h *WarehouseHandler) DeleteWarehouse(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+
+	id, ok := warehouseIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.Delete(c.Request.Context(), id, userID); err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "warehouse not found"})
+		case errors.Is(err, repository.ErrWarehouseInUse), errors.Is(err, repository.ErrDefaultWarehouseRequired):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete warehouse: " + err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Склад успешно удален"})
+}
+
+// ListWarehouseStock GET /api/warehouses/stock?warehouse_id=&product_id=&variant_id=
+// GET /api/warehouses/{id}/stock - то же для одного склада.
+func (h *WarehouseHandler) ListWarehouseStock(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	q := c.Request.URL.Query()
+
+	params := repository.ListStockParams{UserID: userID}
+	params.Limit, params.Offset = offsetPage(c)
+
+	var err error
+	if c.Param("id") != "" {
+		id, ok := warehouseIDParam(c)
+		if !ok {
+			return
+		}
+		params.WarehouseID = &id
+	} else if params.WarehouseID, err = optionalUUIDQuery(q, "warehouse_id"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid warehouse_id"})
+		return
+	}
+	if params.ProductID, err = optionalUUIDQuery(q, "product_id"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product_id"})
+		return
+	}
+	if params.VariantID, err = optionalUUIDQuery(q, "variant_id"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid variant_id"})
+		return
+	}
+
+	stocks, total, err := h.repo.ListStock(c.Request.Context(), params)
+	if err != nil {
+		log.Printf("❌ Warehouses ListWarehouseStock: ошибка получения остатков: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve stock: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"stock":      stocks,
+		"pagination": offsetPagination(total, params.Limit, params.Offset),
+	})
+}
+
+// AdjustWarehouseStock POST /api/warehouses/{id}/adjust
+// Корректировка остатка варианта на складе (приемка, инвентаризация).
+func (h *WarehouseHandler) AdjustWarehouseStock(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+
+	id, ok := warehouseIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req model.AdjustStockRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid input: " + err.Error()})
+		return
+	}
+	if (req.Stock == nil) == (req.Delta == nil) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "specify either stock or delta"})
+		return
+	}
+
+	if err := h.repo.Adjust(c.Request.Context(), userID, &userID, id, req); err != nil {
+		h.stockError(c, "AdjustWarehouseStock", err)
+		return
+	}
+
+	stocks, _, err := h.repo.ListStock(c.Request.Context(), repository.ListStockParams{
+		UserID: userID, WarehouseID: &id, VariantID: &req.VariantID, Limit: 1,
+	})
+	if err != nil || len(stocks) == 0 {
+		c.JSON(http.StatusOK, gin.H{"message": "Остаток успешно изменен"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Остаток успешно изменен", "stock": stocks[0]})
+}
+
+// TransferStock POST /api/warehouses/transfers
+func (h *WarehouseHandler) TransferStock(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+
+	var req model.TransferStockRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid input: " + err.Error()})
+		return
+	}
+
+	movements, err := h.repo.Transfer(c.Request.Context(), userID, &userID, req)
+	if err != nil {
+		h.stockError(c, "TransferStock", err)
+		return
+	}
+
+	log.Printf("✅ Warehouses TransferStock: перемещено позиций: %d", len(movements))
+	c.JSON(http.StatusCreated, gin.H{
+		"message":   "Перемещение успешно выполнено",
+		"movements": movements,
+	})
+}
+
+// ListStockMovements GET /api/warehouses/movements?warehouse_id=&variant_id=&type=
+func (h *WarehouseHandler) ListStockMovements(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	q := c.Request.URL.Query()
+
+	params := repository.ListMovementsParams{UserID: userID, Type: q.Get("type")}
+	params.Limit, params.Offset = offsetPage(c)
+
+	var err error
+	if params.WarehouseID, err = optionalUUIDQuery(q, "warehouse_id"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid warehouse_id"})
+		return
+	}
+	if params.VariantID, err = optionalUUIDQuery(q, "variant_id"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid variant_id"})
+		return
+	}
+
+	movements, total, err := h.repo.ListMovements(c.Request.Context(), params)
+	if err != nil {
+		log.Printf("❌ Warehouses ListStockMovements: ошибка получения движений: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve stock movements: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"movements":  movements,
+		"pagination": offsetPagination(total, params.Limit, params.Offset),
+	})
+}
+
+// stockError переводит ошибки операций с остатками в HTTP-ответ.
+func (h *WarehouseHandler) stockError(c *gin.Context, method string, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "warehouse not found"})
+	case errors.Is(err, repository.ErrVariantNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, repository.ErrSameWarehouse):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, repository.ErrInsufficientStock):
+		c.JSON(http.StatusConflict, gin.H{"error": "not enough free stock: " + err.Error()})
+	default:
+		log.Printf("❌ Warehouses %s: ошибка изменения остатков: %v", method, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to change stock: %v", err)})
+	}
+}
+
+func warehouseIDParam(c *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid warehouse ID format"})
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func warehouseFromRequest(req model.WarehouseRequest) *model.Warehouse {
+	active := true
+	if req.IsActive != nil {
+		active = *req.IsActive
+	}
+	return &model.Warehouse{
+		Name:      req.Name,
+		Code:      req.Code,
+		Type:      req.Type,
+		Address:   req.Address,
+		Priority:  req.Priority,
+		IsDefault: req.IsDefault,
+		IsActive:  active,
+	}
+}
@@ -0,0 +1,245 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lamoda-seller-app/internal/middleware"
+	"github.com/lamoda-seller-app/internal/model"
+	"github.com/lamoda-seller-app/internal/repository"
+	"github.com/lamoda-seller-app/internal/webhook"
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+// WebhookHandler обрабатывает запросы к адресам вебхуков продавца и журналу доставок.
+type WebhookHandler struct {
+	repo *repository.WebhookRepository
+}
+
+func NewWebhookHandler(repo *repository.WebhookRepository) *WebhookHandler {
+	return &WebhookHandler{repo: repo}
+}
+
+// ListWebhookEventTypes GET /api/webhooks/event-types
+func (h *WebhookHandler) ListWebhookEventTypes(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"event_types": model.WebhookEventTypes})
+}
+
+// ListWebhookEndpoints GET /api/webhooks
+func (h *WebhookHandler) ListWebhookEndpoints(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+
+	endpoints, err := h.repo.ListEndpoints(c.Request.Context(), userID)
+	if err != nil {
+		log.Printf("❌ Webhooks ListWebhookEndpoints: ошибка получения адресов: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve webhooks: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"webhooks": endpoints})
+}
+
+// CreateWebhookEndpoint POST /api/webhooks
+// Ключ подписи возвращается только в этом ответе и при смене ключа.
+func (h *WebhookHandler) CreateWebhookEndpoint(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+
+	var req model.CreateWebhookEndpointRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid input: " + err.Error()})
+		return
+	}
+
+	if err := webhook.ValidateURL(req.URL); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid webhook URL: " + err.Error()})
+		return
+	}
+
+	secret, err := webhook.NewSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate secret: " + err.Error()})
+		return
+	}
+	endpoint := &model.WebhookEndpoint{
+		ID:          uuid.New(),
+		UserID:      userID,
+		URL:         req.URL,
+		Secret:      secret,
+		EventTypes:  pq.StringArray(uniqueStrings(req.EventTypes)),
+		Description: req.Description,
+		IsActive:    true,
+	}
+	if err := h.repo.CreateEndpoint(c.Request.Context(), endpoint); err != nil {
+		log.Printf("❌ Webhooks CreateWebhookEndpoint: ошибка сохранения адреса: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create webhook: " + err.Error()})
+		return
+	}
+
+	log.Printf("✅ Webhooks CreateWebhookEndpoint: подключен адрес %s", endpoint.URL)
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Вебхук успешно подключен",
+		"webhook": model.WebhookEndpointWithSecret{WebhookEndpoint: *endpoint, Secret: secret},
+	})
+}
+
+// UpdateWebhookEndpoint PUT /api/webhooks/{id}
+func (h *WebhookHandler) UpdateWebhookEndpoint(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid webhook ID format"})
+		return
+	}
+
+	var req model.UpdateWebhookEndpointRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid input: " + err.Error()})
+		return
+	}
+
+	if req.URL != nil {
+		if err := webhook.ValidateURL(*req.URL); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid webhook URL: " + err.Error()})
+			return
+		}
+	}
+
+	endpoint, err := h.repo.GetEndpoint(c.Request.Context(), userID, id)
+	if err != nil {
+		writeWebhookError(c, "UpdateWebhookEndpoint", err)
+		return
+	}
+	if req.URL != nil {
+		endpoint.URL = *req.URL
+	}
+	if req.EventTypes != nil {
+		endpoint.EventTypes = pq.StringArray(uniqueStrings(*req.EventTypes))
+	}
+	if req.Description != nil {
+		endpoint.Description = *req.Description
+	}
+	if req.IsActive != nil {
+		endpoint.IsActive = *req.IsActive
+	}
+	if req.RotateSecret {
+		if endpoint.Secret, err = webhook.NewSecret(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate secret: " + err.Error()})
+			return
+		}
+	}
+
+	if err := h.repo.UpdateEndpoint(c.Request.Context(), endpoint); err != nil {
+		writeWebhookError(c, "UpdateWebhookEndpoint", err)
+		return
+	}
+
+	log.Printf("✅ Webhooks UpdateWebhookEndpoint: обновлен адрес %s", endpoint.ID)
+	response := gin.H{"message": "Вебхук успешно обновлен", "webhook": endpoint}
+	if req.RotateSecret {
+		response["webhook"] = model.WebhookEndpointWithSecret{WebhookEndpoint: *endpoint, Secret: endpoint.Secret}
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// DeleteWebhookEndpoint DELETE /api/webhooks/{id}
+func (h *WebhookHandler) DeleteWebhookEndpoint(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid webhook ID format"})
+		return
+	}
+
+	if err := h.repo.DeleteEndpoint(c.Request.Context(), userID, id); err != nil {
+		writeWebhookError(c, "DeleteWebhookEndpoint", err)
+		return
+	}
+
+	log.Printf("✅ Webhooks DeleteWebhookEndpoint: удален адрес %s", id)
+	c.JSON(http.StatusOK, gin.H{"message": "Вебхук успешно удален"})
+}
+
+// ListWebhookDeliveries GET /api/webhooks/deliveries?endpoint_id=&event_type=&status=&limit=&offset=
+// Журнал доставок с ответом адреса на последнюю попытку.
+func (h *WebhookHandler) ListWebhookDeliveries(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+
+	endpointID, err := optionalUUIDQuery(c.Request.URL.Query(), "endpoint_id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid endpoint_id format"})
+		return
+	}
+	filter := model.WebhookDeliveryFilter{
+		EndpointID: endpointID,
+		EventType:  c.Query("event_type"),
+		Status:     c.Query("status"),
+	}
+	switch filter.Status {
+	case "", model.WebhookDeliveryPending, model.WebhookDeliverySucceeded, model.WebhookDeliveryFailed:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be 'pending', 'succeeded' or 'failed'"})
+		return
+	}
+	limit, offset := offsetPage(c)
+
+	deliveries, total, err := h.repo.ListDeliveries(c.Request.Context(), userID, filter, limit, offset)
+	if err != nil {
+		log.Printf("❌ Webhooks ListWebhookDeliveries: ошибка получения журнала: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve deliveries: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"deliveries": deliveries,
+		"pagination": offsetPagination(total, limit, offset),
+	})
+}
+
+// RedeliverWebhook POST /api/webhooks/deliveries/{id}/redeliver
+// Ставит завершенную доставку на повторную отправку с тем же ID события.
+func (h *WebhookHandler) RedeliverWebhook(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(uuid.UUID)
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid delivery ID format"})
+		return
+	}
+
+	delivery, err := h.repo.Redeliver(c.Request.Context(), userID, id)
+	if err != nil {
+		writeWebhookError(c, "RedeliverWebhook", err)
+		return
+	}
+
+	log.Printf("✅ Webhooks RedeliverWebhook: доставка %s поставлена в очередь", delivery.ID)
+	c.JSON(http.StatusAccepted, gin.H{"message": "Доставка поставлена в очередь", "delivery": delivery})
+}
+
+func writeWebhookError(c *gin.Context, op string, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "webhook not found"})
+	case errors.Is(err, repository.ErrDeliveryInProgress):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		log.Printf("❌ Webhooks %s: ошибка базы данных: %v", op, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error: " + err.Error()})
+	}
+}
+
+// uniqueStrings убирает повторяющиеся строки, сохраняя порядок.
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]struct{}, len(values))
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		if _, ok := seen[v]; ok {
+			continue
+		}
+		seen[v] = struct{}{}
+		result = append(result, v)
+	}
+	return result
+}
@@ -0,0 +1,86 @@
+// Package jobs запускает периодические фоновые задачи внутри процесса сервера.
+package jobs
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// Job описывает периодическую фоновую задачу.
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) error
+}
+
+// Runner запускает зарегистрированные задачи и останавливает их при завершении работы сервера.
+type Runner struct {
+	jobs   []Job
+	wg     sync.WaitGroup
+	cancel context.CancelFunc
+}
+
+func NewRunner() *Runner {
+	return &Runner{}
+}
+
+// Add регистрирует задачу. Задачи нужно добавлять до вызова Start.
+// Задача с неположительным интервалом не регистрируется: time.NewTicker с ним паникует.
+func (r *Runner) Add(job Job) {
+	if job.Interval <= 0 {
+		log.Printf("❌ Job %s: неверный интервал %s, задача не запущена", job.Name, job.Interval)
+		return
+	}
+	r.jobs = append(r.jobs, job)
+}
+
+// Start запускает каждую задачу в отдельной горутине. Первый запуск происходит сразу.
+func (r *Runner) Start(ctx context.Context) {
+	ctx, r.cancel = context.WithCancel(ctx)
+	for _, job := range r.jobs {
+		r.wg.Add(1)
+		go r.loop(ctx, job)
+	}
+	log.Printf("⏱️ Запущено фоновых задач: %d", len(r.jobs))
+}
+
+// Stop останавливает задачи и ждет завершения текущих запусков.
+func (r *Runner) Stop() {
+	if r.cancel == nil {
+		return
+	}
+	r.cancel()
+	r.wg.Wait()
+	log.Println("✅ Фоновые задачи остановлены")
+}
+
+func (r *Runner) loop(ctx context.Context, job Job) {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+
+	for {
+		r.runOnce(ctx, job)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runOnce выполняет задачу, перехватывая панику, чтобы одна задача не уронила сервер.
+func (r *Runner) runOnce(ctx context.Context, job Job) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			log.Printf("❌ Job %s: паника: %v", job.Name, rec)
+		}
+	}()
+
+	if err := job.Run(ctx); err != nil && ctx.Err() == nil {
+		log.Printf("❌ Job %s: ошибка выполнения: %v", job.Name, err)
+	}
+}
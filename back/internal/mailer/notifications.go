@@ -0,0 +1,66 @@
+package mailer
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/lamoda-seller-app/internal/model"
+	"github.com/lamoda-seller-app/internal/repository"
+)
+
+const (
+	emailBatch = 50               // Писем за один запуск
+	emailLease = 10 * time.Minute // Через сколько письмо, взятое упавшим сервером, отправляется снова
+)
+
+// NotificationSender отправляет письма по уведомлениям категорий с каналом in_app_email.
+// Ссылка уведомления в письме становится полной через baseURL приложения.
+type NotificationSender struct {
+	repo    *repository.NotificationRepository
+	mailer  Mailer
+	baseURL string
+}
+
+func NewNotificationSender(repo *repository.NotificationRepository, mailer Mailer, baseURL string) *NotificationSender {
+	return &NotificationSender{repo: repo, mailer: mailer, baseURL: strings.TrimRight(baseURL, "/")}
+}
+
+// Send отправляет порцию писем из очереди и возвращает количество отправленных.
+func (s *NotificationSender) Send(ctx context.Context) (int, error) {
+	notifications, err := s.repo.ClaimEmails(ctx, emailBatch, emailLease)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, n := range notifications {
+		if ctx.Err() != nil {
+			return sent, ctx.Err()
+		}
+		sendErr := s.mailer.Send(ctx, s.message(n))
+		if sendErr != nil {
+			log.Printf("❌ Mailer: письмо по уведомлению %s на %s не отправлено: %v", n.ID, n.UserEmail, sendErr)
+		} else {
+			sent++
+		}
+		if err := s.repo.RecordEmail(ctx, n.ID, sendErr); err != nil {
+			return sent, err
+		}
+	}
+	return sent, nil
+}
+
+func (s *NotificationSender) message(n model.Notification) Message {
+	var text strings.Builder
+	if n.Body != "" {
+		text.WriteString(n.Body)
+		text.WriteString("\n\n")
+	}
+	if n.Link != "" {
+		text.WriteString("Открыть: " + s.baseURL + n.Link + "\n\n")
+	}
+	text.WriteString("Каналы уведомлений можно изменить в настройках профиля.\n")
+	return Message{To: n.UserEmail, Subject: n.Title, Text: text.String()}
+}
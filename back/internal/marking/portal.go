@@ -0,0 +1,48 @@
+// Package marking отправляет документы в ГИС МТ "Честный ЗНАК".
+package marking
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lamoda-seller-app/internal/model"
+)
+
+// Portal - клиент ГИС МТ для отправки документов вывода из оборота.
+type Portal interface {
+	// SubmitReport отправляет документ и возвращает его идентификатор в ГИС МТ.
+	SubmitReport(ctx context.Context, doc model.MarkingReportDocument) (string, error)
+}
+
+// FilePortal - заглушка ГИС МТ: сохраняет документы JSON-файлами в каталог.
+// Файлы можно загрузить в личный кабинет "Честного ЗНАКа" вручную.
+type FilePortal struct {
+	dir string
+}
+
+func NewFilePortal(dir string) *FilePortal {
+	return &FilePortal{dir: dir}
+}
+
+func (p *FilePortal) SubmitReport(ctx context.Context, doc model.MarkingReportDocument) (string, error) {
+	if err := os.MkdirAll(p.dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create marking outbox: %w", err)
+	}
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	documentID := uuid.New().String()
+	name := fmt.Sprintf("%s_%s_%s.json", time.Now().Format("20060102-150405"), doc.DocumentNumber, documentID)
+	if err := os.WriteFile(filepath.Join(p.dir, name), data, 0o644); err != nil {
+		return "", fmt.Errorf("failed to write marking document: %w", err)
+	}
+	return documentID, nil
+}
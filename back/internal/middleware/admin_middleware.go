@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lamoda-seller-app/internal/repository"
+)
+
+// RequireAdmin пропускает только администраторов. Ставится после JWTAuthMiddleware
+// на маршруты, которые меняют общие для всех продавцов данные.
+func RequireAdmin(users *repository.UserRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.MustGet(UserIDKey).(uuid.UUID)
+		user, err := users.GetByID(c.Request.Context(), userID)
+		if err != nil {
+			log.Printf("❌ RequireAdmin: пользователь %s не найден: %v", userID, err)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+			return
+		}
+		if !user.IsAdmin {
+			log.Printf("❌ RequireAdmin: пользователь %s не администратор", userID)
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "administrator role required"})
+			return
+		}
+		c.Next()
+	}
+}
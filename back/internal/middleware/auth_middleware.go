@@ -1,16 +1,19 @@
 package middleware
 
 import (
+	"errors"
 	"log"
 	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/lamoda-seller-app/internal/auth"
+	"github.com/lamoda-seller-app/internal/repository"
 )
 
 const (
-	UserIDKey = "user_id"
+	UserIDKey       = "user_id"
+	StreamTicketKey = "stream_ticket" // Билет, которым авторизован поток живых обновлений
 )
 
 func JWTAuthMiddleware() gin.HandlerFunc {
@@ -60,3 +63,33 @@ func min(a, b int) int {
 	}
 	return b
 }
+
+// StreamTicketAuth авторизует поток живых обновлений билетом из параметра ticket
+// (POST /api/events/stream-ticket): EventSource в браузере не умеет передавать заголовки,
+// а JWT в адресе остался бы в журналах. Билет годится только для потока и продлевается,
+// пока поток открыт, поэтому переподключение EventSource по тому же адресу проходит.
+// Без параметра ticket работает как JWTAuthMiddleware.
+func StreamTicketAuth(events *repository.EventRepository) gin.HandlerFunc {
+	jwtAuth := JWTAuthMiddleware()
+	return func(c *gin.Context) {
+		ticket := c.Query("ticket")
+		if ticket == "" {
+			jwtAuth(c)
+			return
+		}
+		userID, err := events.UseStreamTicket(c.Request.Context(), ticket)
+		if errors.Is(err, repository.ErrStreamTicketInvalid) {
+			log.Printf("❌ Stream Auth: билет недействителен или истек")
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired stream ticket"})
+			return
+		}
+		if err != nil {
+			log.Printf("❌ Stream Auth: ошибка проверки билета: %v", err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to check stream ticket"})
+			return
+		}
+		c.Set(UserIDKey, userID)
+		c.Set(StreamTicketKey, ticket)
+		c.Next()
+	}
+}
@@ -0,0 +1,109 @@
+package model
+
+import (
+	"database/sql/driver"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// Типы значений атрибутов категории
+const (
+	AttributeTypeString    = "string"
+	AttributeTypeNumber    = "number"
+	AttributeTypeBoolean   = "boolean"
+	AttributeTypeEnum      = "enum"
+	AttributeTypeMultiEnum = "multi_enum"
+)
+
+// SizeAttributeCode - код атрибута схемы категории, задающего размерную сетку: допустимые значения
+// атрибута - размеры, которые могут быть у вариантов товаров категории.
+const SizeAttributeCode = "size"
+
+// LocalizedNames - названия на разных языках: {"ru": "Платья", "en": "Dresses"}
+type LocalizedNames map[string]string
+
+func (ln *LocalizedNames) Scan(value interface{}) error { return scanJSON(ln, value) }
+func (ln LocalizedNames) Value() (driver.Value, error)  { return valueJSON(ln) }
+
+// Get возвращает название на указанном языке или пустую строку.
+func (ln LocalizedNames) Get(lang string) string {
+	if ln == nil {
+		return ""
+	}
+	return ln[lang]
+}
+
+// ProductAttributes - значения атрибутов товара по кодам из схемы категории
+type ProductAttributes map[string]interface{}
+
+func (pa *ProductAttributes) Scan(value interface{}) error { return scanJSON(pa, value) }
+func (pa ProductAttributes) Value() (driver.Value, error) {
+	if pa == nil {
+		return valueJSON(map[string]interface{}{})
+	}
+	return valueJSON(pa)
+}
+
+// Category представляет узел иерархического справочника категорий.
+// Товары ссылаются на категорию по Slug (products.category / products.subcategory), поэтому слаг
+// задается при создании и больше не меняется.
+type Category struct {
+	ID        uuid.UUID      `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
+	ParentID  *uuid.UUID     `gorm:"type:uuid;index" json:"parent_id"`
+	Slug      string         `gorm:"type:varchar(100);not null;uniqueIndex" json:"slug"`
+	Name      string         `gorm:"type:varchar(255);not null" json:"name"`
+	Names     LocalizedNames `gorm:"type:jsonb" json:"names"`
+	SortOrder int            `gorm:"default:0" json:"sort_order"`
+	CreatedAt time.Time      `json:"created_date"`
+	UpdatedAt time.Time      `json:"updated_date"`
+
+	Attributes    []CategoryAttribute `gorm:"foreignKey:CategoryID" json:"attributes,omitempty"`
+	Subcategories []Category          `gorm:"-" json:"subcategories,omitempty"`
+}
+
+// Localize подставляет в Name название на указанном языке (если оно задано) рекурсивно для всего поддерева.
+func (c *Category) Localize(lang string) {
+	if name := c.Names.Get(lang); name != "" {
+		c.Name = name
+	}
+	for i := range c.Attributes {
+		if name := c.Attributes[i].Names.Get(lang); name != "" {
+			c.Attributes[i].Name = name
+		}
+	}
+	for i := range c.Subcategories {
+		c.Subcategories[i].Localize(lang)
+	}
+}
+
+// CategoryAttribute описывает атрибут в схеме категории, например длину рукава или высоту каблука.
+type CategoryAttribute struct {
+	ID            uuid.UUID      `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
+	CategoryID    uuid.UUID      `gorm:"type:uuid;not null;index" json:"category_id"`
+	Code          string         `gorm:"type:varchar(100);not null" json:"code" binding:"required"`
+	Name          string         `gorm:"type:varchar(255);not null" json:"name" binding:"required"`
+	Names         LocalizedNames `gorm:"type:jsonb" json:"names"`
+	Type          string         `gorm:"type:varchar(20);not null" json:"type" binding:"required,oneof=string number boolean enum multi_enum"`
+	Required      bool           `gorm:"default:false" json:"required"`
+	AllowedValues pq.StringArray `gorm:"type:text[]" json:"allowed_values,omitempty"`
+	Unit          string         `gorm:"type:varchar(20)" json:"unit,omitempty"`
+	SortOrder     int            `gorm:"default:0" json:"sort_order"`
+}
+
+// --- Структуры для запросов ---
+
+// CategoryRequest представляет тело запроса на создание/изменение категории
+type CategoryRequest struct {
+	ParentID  *uuid.UUID     `json:"parent_id"`
+	Slug      string         `json:"slug" binding:"required"`
+	Name      string         `json:"name" binding:"required"`
+	Names     LocalizedNames `json:"names"`
+	SortOrder int            `json:"sort_order"`
+}
+
+// CategorySchemaRequest представляет тело запроса на замену схемы атрибутов категории
+type CategorySchemaRequest struct {
+	Attributes []CategoryAttribute `json:"attributes" binding:"dive"`
+}
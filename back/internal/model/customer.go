@@ -0,0 +1,40 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Customer - покупатель в справочнике продавца. Строится триггерами БД из снимков customer в заказах:
+// покупатель определяется по CustomerID заказа, а для заказов без него - по email.
+// Статистика пересчитывается при изменении заказов и возвратов.
+type Customer struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
+	UserID     uuid.UUID  `gorm:"type:uuid;index" json:"-"`
+	CustomerID *uuid.UUID `gorm:"type:uuid" json:"customer_id"` // ID покупателя в маркетплейсе, если известен
+	Email      string     `gorm:"type:varchar(255)" json:"email"`
+	Name       string     `gorm:"type:varchar(255)" json:"name"`
+	Phone      string     `gorm:"type:varchar(50)" json:"phone"`
+
+	OrdersCount          int        `json:"orders_count"` // Заказы без отмененных
+	CancelledOrdersCount int        `json:"cancelled_orders_count"`
+	Revenue              float64    `gorm:"type:numeric(14,2)" json:"revenue"` // Сумма неотмененных заказов
+	AvgCheck             float64    `gorm:"type:numeric(14,2)" json:"avg_check"`
+	ReturnsCount         int        `json:"returns_count"` // Единиц товара в возвратах
+	ReturnedAmount       float64    `gorm:"type:numeric(14,2)" json:"returned_amount"`
+	IsRegular            bool       `gorm:"->" json:"is_regular"` // Больше одного заказа, вычисляется в БД
+	FirstOrderAt         *time.Time `json:"first_order_at"`
+	LastOrderAt          *time.Time `json:"last_order_at"`
+	CreatedAt            time.Time  `json:"created_date"`
+	UpdatedAt            time.Time  `json:"updated_date"`
+}
+
+// CustomerFilter - фильтры списка покупателей
+type CustomerFilter struct {
+	Search    string // Подстрока имени, email или телефона
+	IsRegular *bool
+	MinOrders int
+	SortBy    string // last_order (по умолчанию), first_order, orders, revenue, avg_check, returns, name
+	SortOrder string
+}
@@ -0,0 +1,101 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Сегменты RFM. Определяются по баллам давности (R), частоты (F) и суммы (M) от 1 до 5
+const (
+	RFMSegmentChampions      = "champions"       // Покупают часто, много и недавно
+	RFMSegmentLoyal          = "loyal"           // Покупают регулярно
+	RFMSegmentPotentialLoyal = "potential_loyal" // Недавние покупатели с несколькими заказами
+	RFMSegmentNew            = "new"             // Первый заказ был недавно
+	RFMSegmentNeedAttention  = "need_attention"  // Средние показатели, давно не покупали
+	RFMSegmentAtRisk         = "at_risk"         // Покупали часто, но давно
+	RFMSegmentCantLose       = "cant_lose"       // Лучшие покупатели в прошлом, перестали покупать
+	RFMSegmentHibernating    = "hibernating"     // Давно и мало
+)
+
+// RFMSegments - все сегменты в порядке отображения
+var RFMSegments = []string{
+	RFMSegmentChampions, RFMSegmentLoyal, RFMSegmentPotentialLoyal, RFMSegmentNew,
+	RFMSegmentNeedAttention, RFMSegmentAtRisk, RFMSegmentCantLose, RFMSegmentHibernating,
+}
+
+// --- Структуры для /api/analytics/rfm ---
+
+type RFMRequestParams struct {
+	Period   string `form:"period"` // 30d, 90d, 1y (по умолчанию), all_time - учитываемые заказы
+	Category string `form:"category"`
+	Segment  string `form:"segment"`
+	Limit    int    `form:"limit"`
+	Offset   int    `form:"offset"`
+	All      bool   `form:"-"` // Все покупатели без пагинации - для выгрузки
+}
+
+// CustomerRFM - показатели и баллы покупателя. Баллы - квинтили среди покупателей продавца:
+// 5 у самых недавних, частых и крупных покупателей
+type CustomerRFM struct {
+	CustomerID  uuid.UUID `json:"customer_id"`
+	Name        string    `json:"name"`
+	Email       string    `json:"email"`
+	LastOrderAt time.Time `json:"last_order_at"`
+	RecencyDays int       `json:"recency_days"`
+	Frequency   int       `json:"frequency"`
+	Monetary    float64   `json:"monetary"`
+	RScore      int       `json:"r_score"`
+	FScore      int       `json:"f_score"`
+	MScore      int       `json:"m_score"`
+	RFMScore    string    `json:"rfm_score"` // Например "545"
+	Segment     string    `json:"segment"`
+}
+
+type RFMSegmentSummary struct {
+	Segment        string  `json:"segment"`
+	Customers      int     `json:"customers"`
+	Share          float64 `json:"share"` // Доля покупателей, %
+	Revenue        float64 `json:"revenue"`
+	RevenueShare   float64 `json:"revenue_share"` // Доля выручки, %
+	AvgRecencyDays float64 `json:"avg_recency_days"`
+	AvgFrequency   float64 `json:"avg_frequency"`
+	AvgMonetary    float64 `json:"avg_monetary"`
+}
+
+type RFMResponse struct {
+	Period         string              `json:"period"`
+	Category       string              `json:"category"`
+	TotalCustomers int                 `json:"total_customers"`
+	Segments       []RFMSegmentSummary `json:"segments"`
+	Customers      []CustomerRFM       `json:"customers"`
+	Total          int                 `json:"total"` // Покупателей в выборке с учетом фильтра segment
+}
+
+// --- Структуры для /api/analytics/cohorts ---
+
+type CohortRequestParams struct {
+	Period   string `form:"period"` // 90d, 1y (по умолчанию), all_time - месяцы первых заказов
+	Category string `form:"category"`
+}
+
+// CohortCell - покупатели когорты, сделавшие заказ через MonthOffset месяцев после первого
+type CohortCell struct {
+	MonthOffset int     `json:"month_offset"`
+	Customers   int     `json:"customers"`
+	Rate        float64 `json:"rate"` // % от размера когорты
+}
+
+// Cohort - покупатели, впервые купившие в месяце Month
+type Cohort struct {
+	Month     string       `json:"month"` // 2006-01
+	Customers int          `json:"customers"`
+	Retention []CohortCell `json:"retention"`
+}
+
+type CohortResponse struct {
+	Period   string       `json:"period"`
+	Category string       `json:"category"`
+	Cohorts  []Cohort     `json:"cohorts"`
+	Average  []CohortCell `json:"average"` // Средневзвешенный по когортам, дожившим до смещения
+}
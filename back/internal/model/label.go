@@ -0,0 +1,59 @@
+package model
+
+import "github.com/google/uuid"
+
+// MaxLabelsPerRequest - сколько этикеток можно напечатать одним запросом (с учетом копий)
+const MaxLabelsPerRequest = 1000
+
+// LabelVariant - данные варианта для печати этикетки
+type LabelVariant struct {
+	VariantID uuid.UUID `json:"variant_id"`
+	ProductID uuid.UUID `json:"product_id"`
+	Brand     string    `json:"brand"`
+	Name      string    `json:"name"`
+	SKU       string    `gorm:"column:sku" json:"sku"`
+	Size      string    `json:"size"`
+	Color     string    `json:"color"`
+	GTIN      string    `gorm:"column:gtin" json:"gtin"`
+	Price     float64   `json:"price"`
+	Currency  string    `json:"currency"`
+}
+
+// GeneratedBarcode - номер EAN-13, выпущенный варианту из диапазона префикса GS1 продавца
+type GeneratedBarcode struct {
+	ProductID uuid.UUID `json:"product_id"`
+	VariantID uuid.UUID `json:"variant_id"`
+	SKU       string    `json:"sku"`
+	GTIN      string    `json:"gtin"`  // 14 цифр, как хранится у варианта
+	EAN13     string    `json:"ean13"` // Для печати
+}
+
+// --- Структуры для запросов/ответов, не являющиеся моделями БД ---
+
+// GenerateBarcodesRequest - выпуск EAN-13 для вариантов товаров, у которых еще нет GTIN
+type GenerateBarcodesRequest struct {
+	ProductIDs []uuid.UUID `json:"product_ids" binding:"required,min=1,max=500"`
+}
+
+// VariantLabelItem - вариант и количество копий этикетки
+type VariantLabelItem struct {
+	VariantID uuid.UUID `json:"variant_id" binding:"required"`
+	Copies    int       `json:"copies" binding:"omitempty,min=1,max=1000"` // По умолчанию 1
+}
+
+// PrintVariantLabelsRequest - печать этикеток вариантов
+type PrintVariantLabelsRequest struct {
+	Items     []VariantLabelItem `json:"items" binding:"required,min=1,max=500,dive"`
+	Size      string             `json:"size"`      // Например, "58x40" (мм)
+	Symbology string             `json:"symbology"` // ean13, code128, datamatrix; по умолчанию по данным варианта
+	Format    string             `json:"format"`    // pdf, png, svg
+	DPI       int                `json:"dpi" binding:"omitempty,min=72,max=600"`
+}
+
+// PrintMarkingLabelsRequest - печать этикеток с кодами маркировки
+type PrintMarkingLabelsRequest struct {
+	CodeIDs []uuid.UUID `json:"code_ids" binding:"required,min=1,max=1000"`
+	Size    string      `json:"size"`
+	Format  string      `json:"format"`
+	DPI     int         `json:"dpi" binding:"omitempty,min=72,max=600"`
+}
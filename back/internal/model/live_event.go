@@ -0,0 +1,40 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// События потока живых обновлений (GET /api/events/stream), которых нет в журнале webhook_events.
+// Они вычисляются при отправке и не возобновляются по Last-Event-ID.
+const (
+	LiveEventDashboard = "dashboard.updated" // Показатели дашборда за сегодня
+	LiveEventResync    = "resync"            // Пропущено слишком много событий, клиенту нужно перезагрузить данные
+)
+
+// LiveDashboard - показатели дашборда за текущие сутки (UTC) для потока живых обновлений.
+// Считаются так же, как в GET /api/dashboard/stats.
+type LiveDashboard struct {
+	Date          string    `json:"date"`
+	Revenue       float64   `json:"revenue"`
+	Orders        int64     `json:"orders"`
+	ItemsSold     int64     `json:"items_sold"`
+	AvgOrderValue float64   `json:"avg_order_value"`
+	Returns       int64     `json:"returns"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// StreamTicket - билет на подключение к потоку живых обновлений. EventSource
+// не передает заголовки, поэтому вместо JWT в адресе передается билет; хранится его SHA-256.
+type StreamTicket struct {
+	TokenHash string    `gorm:"primary_key"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null"`
+	ExpiresAt time.Time `gorm:"not null"`
+}
+
+// StreamTicketResponse - ответ POST /api/events/stream-ticket
+type StreamTicketResponse struct {
+	Ticket    string    `json:"ticket"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
@@ -0,0 +1,269 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lamoda-seller-app/internal/barcode"
+)
+
+// Статусы кода маркировки "Честного ЗНАКа"
+const (
+	MarkingStatusAvailable = "available" // Загружен и свободен
+	MarkingStatusAssigned  = "assigned"  // Назначен позиции заказа (отсканирован при сборке)
+	MarkingStatusShipped   = "shipped"   // Отгружен покупателю в составе заказа
+	MarkingStatusWithdrawn = "withdrawn" // Выведен из оборота не продажей (порча, утрата, уничтожение)
+	MarkingStatusReturned  = "returned"  // Возвращен покупателем
+)
+
+// Причины вывода кода из оборота не продажей (соответствуют действиям ГИС МТ)
+const (
+	MarkingWithdrawDamageLoss    = "damage_loss"    // Порча или утрата товара
+	MarkingWithdrawDestruction   = "destruction"    // Уничтожение
+	MarkingWithdrawEnterpriseUse = "enterprise_use" // Использование для собственных нужд
+	MarkingWithdrawOther         = "other"
+)
+
+// Статусы отчета о выводе из оборота
+const (
+	MarkingReportDraft     = "draft"     // Сформирован, можно выгрузить файл
+	MarkingReportSubmitted = "submitted" // Отправлен в ГИС МТ
+)
+
+// MaxMarkingCodesPerImport - сколько кодов можно загрузить одной партией
+const MaxMarkingCodesPerImport = 100000
+
+// markingGS - разделитель групп GS1 (FNC1) в коде маркировки
+const markingGS = "\x1d"
+
+// MarkingCodeParts - разобранный код маркировки
+type MarkingCodeParts struct {
+	GTIN   string // 14 цифр, AI 01
+	Serial string // Серийный номер, AI 21
+	CIS    string // Код идентификации: 01 + GTIN + 21 + серийный номер
+	Code   string // Полный код с криптохвостом, разделители групп - GS
+}
+
+// ParseMarkingCode разбирает код маркировки в том виде, в каком он приходит из файла ГИС МТ или со сканера.
+// Разделитель групп может быть символом GS, его текстовой записью (\x1d, \u001d, <GS>) или отсутствовать -
+// тогда серийный номер считается 13-символьным, как у кодов легкой промышленности и обуви.
+func ParseMarkingCode(raw string) (MarkingCodeParts, error) {
+	code := strings.TrimSpace(raw)
+	for _, gs := range []string{`\x1d`, `\u001d`, `<GS>`, "␝"} {
+		code = strings.ReplaceAll(code, gs, markingGS)
+	}
+	// Префикс символики со сканера и ведущий FNC1 не являются частью кода
+	code = strings.TrimPrefix(code, "]d2")
+	code = strings.TrimPrefix(code, "]C1")
+	code = strings.TrimPrefix(code, markingGS)
+
+	if len(code) < 18 || code[:2] != "01" {
+		return MarkingCodeParts{}, errors.New("code must start with AI 01 and GTIN")
+	}
+	gtin := code[2:16]
+	if err := barcode.ValidateGTIN(gtin); err != nil {
+		return MarkingCodeParts{}, err
+	}
+	if code[16:18] != "21" {
+		return MarkingCodeParts{}, errors.New("serial number (AI 21) is missing")
+	}
+
+	rest := code[18:]
+	serial := rest
+	if i := strings.Index(rest, markingGS); i >= 0 {
+		serial = rest[:i]
+	} else if len(rest) > 13 {
+		serial = rest[:13]
+		// Без разделителей восстанавливаем их перед AI 91 и 92
+		tail := rest[13:]
+		if strings.HasPrefix(tail, "91") && len(tail) >= 8 && tail[6:8] == "92" {
+			tail = tail[:6] + markingGS + tail[6:]
+		}
+		code = code[:18] + serial + markingGS + tail
+	}
+	if serial == "" || len(serial) > 20 {
+		return MarkingCodeParts{}, errors.New("serial number must be 1 to 20 characters long")
+	}
+	for _, r := range serial {
+		if r < 0x21 || r > 0x7e {
+			return MarkingCodeParts{}, fmt.Errorf("serial number contains invalid character %q", r)
+		}
+	}
+
+	return MarkingCodeParts{
+		GTIN:   gtin,
+		Serial: serial,
+		CIS:    "01" + gtin + "21" + serial,
+		Code:   code,
+	}, nil
+}
+
+// MarkingCodeBatch - партия загруженных кодов маркировки
+type MarkingCodeBatch struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
+	UserID     uuid.UUID  `gorm:"type:uuid;not null" json:"-"`
+	VariantID  uuid.UUID  `gorm:"type:uuid;not null" json:"variant_id"`
+	GTIN       string     `gorm:"column:gtin;type:varchar(14);not null" json:"gtin"`
+	FileName   string     `gorm:"type:varchar(255)" json:"file_name"`
+	Imported   int        `gorm:"not null;default:0" json:"imported"`
+	Duplicates int        `gorm:"not null;default:0" json:"duplicates"` // Коды, которые уже были загружены ранее
+	CreatedBy  *uuid.UUID `gorm:"type:uuid" json:"created_by"`
+	CreatedAt  time.Time  `json:"created_date"`
+
+	// --- Поля, которые не хранятся в БД ---
+	Rejected []MarkingCodeRejection `gorm:"-" json:"rejected,omitempty"`
+}
+
+// MarkingCodeRejection - строка файла, которую не удалось загрузить
+type MarkingCodeRejection struct {
+	Line  int    `json:"line"`
+	Code  string `json:"code"`
+	Error string `json:"error"`
+}
+
+// MarkingCode - код маркировки единицы товара
+type MarkingCode struct {
+	ID             uuid.UUID  `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
+	UserID         uuid.UUID  `gorm:"type:uuid;not null" json:"-"`
+	BatchID        uuid.UUID  `gorm:"type:uuid;not null" json:"batch_id"`
+	VariantID      uuid.UUID  `gorm:"type:uuid;not null" json:"variant_id"`
+	GTIN           string     `gorm:"column:gtin;type:varchar(14);not null" json:"gtin"`
+	CIS            string     `gorm:"column:cis;type:varchar(64);not null" json:"cis"`
+	Code           string     `gorm:"type:text;not null" json:"code"`
+	Status         string     `gorm:"type:varchar(20);not null;default:'available'" json:"status"`
+	OrderID        *uuid.UUID `gorm:"type:uuid" json:"order_id"`
+	OrderItemID    *uuid.UUID `gorm:"type:uuid" json:"order_item_id"`
+	ReportID       *uuid.UUID `gorm:"type:uuid" json:"report_id"`
+	WithdrawReason *string    `gorm:"type:varchar(50)" json:"withdraw_reason"`
+	AssignedAt     *time.Time `json:"assigned_at"`
+	ShippedAt      *time.Time `json:"shipped_at"`
+	WithdrawnAt    *time.Time `json:"withdrawn_at"`
+	ReturnedAt     *time.Time `json:"returned_at"`
+	CreatedAt      time.Time  `json:"created_date"`
+	UpdatedAt      time.Time  `json:"updated_date"`
+
+	// --- Поля, которые не хранятся в БД, а вычисляются ---
+	ProductID   uuid.UUID `gorm:"->;-:migration" json:"product_id"`
+	ProductName string    `gorm:"->;-:migration" json:"product_name,omitempty"`
+	SKU         string    `gorm:"column:sku;->;-:migration" json:"sku,omitempty"`
+	Size        string    `gorm:"->;-:migration" json:"size,omitempty"`
+	Color       string    `gorm:"->;-:migration" json:"color,omitempty"`
+	OrderNumber *string   `gorm:"->;-:migration" json:"order_number,omitempty"`
+}
+
+// MarkingVariantSummary - количество кодов варианта по статусам в сравнении с остатком
+type MarkingVariantSummary struct {
+	VariantID   uuid.UUID `json:"variant_id"`
+	ProductID   uuid.UUID `json:"product_id"`
+	ProductName string    `json:"product_name"`
+	SKU         string    `gorm:"column:sku" json:"sku"`
+	Size        string    `json:"size"`
+	Color       string    `json:"color"`
+	GTIN        string    `gorm:"column:gtin" json:"gtin"`
+	Stock       int       `json:"stock"`
+	Available   int       `json:"available"`
+	Assigned    int       `json:"assigned"`
+	Shipped     int       `json:"shipped"`
+	Withdrawn   int       `json:"withdrawn"`
+	Returned    int       `json:"returned"`
+	Shortage    int       `json:"shortage"` // Сколько единиц остатка не покрыто свободными кодами
+}
+
+// MarkingReport - отчет о выводе отгруженных кодов из оборота
+type MarkingReport struct {
+	ID               uuid.UUID  `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
+	UserID           uuid.UUID  `gorm:"type:uuid;not null" json:"-"`
+	Number           string     `gorm:"type:varchar(50);not null" json:"number"`
+	Status           string     `gorm:"type:varchar(20);not null;default:'draft'" json:"status"`
+	CodesCount       int        `gorm:"not null;default:0" json:"codes_count"`
+	TotalKopecks     int64      `gorm:"not null;default:0" json:"total_kopecks"`
+	PortalDocumentID *string    `gorm:"type:varchar(100)" json:"portal_document_id"`
+	SubmittedAt      *time.Time `json:"submitted_at"`
+	CreatedAt        time.Time  `json:"created_date"`
+}
+
+// MarkingReportLine - код в отчете о выводе из оборота
+type MarkingReportLine struct {
+	CIS          string    `gorm:"column:cis" json:"cis"`
+	PriceKopecks int64     `json:"price_kopecks"`
+	OrderNumber  string    `json:"order_number"`
+	ShippedAt    time.Time `json:"shipped_at"`
+}
+
+// MarkingReportDocument - документ "Вывод из оборота" (LK_RECEIPT) с видом "Дистанционная продажа"
+// в формате, который принимает ГИС МТ при загрузке JSON-файла или через API.
+type MarkingReportDocument struct {
+	INN            string                 `json:"inn"`
+	Action         string                 `json:"action"`
+	ActionDate     string                 `json:"action_date"`
+	DocumentType   string                 `json:"document_type"`
+	DocumentNumber string                 `json:"document_number"`
+	DocumentDate   string                 `json:"document_date"`
+	DocumentName   string                 `json:"primary_document_custom_name"`
+	Products       []MarkingReportProduct `json:"products"`
+}
+
+// MarkingReportProduct - строка документа вывода из оборота
+type MarkingReportProduct struct {
+	CIS         string `json:"cis"`
+	ProductCost int64  `json:"product_cost"` // Цена за единицу в копейках
+}
+
+// BuildMarkingReportDocument собирает документ вывода из оборота по строкам отчета.
+func BuildMarkingReportDocument(report MarkingReport, inn string, lines []MarkingReportLine) MarkingReportDocument {
+	date := report.CreatedAt.Format("2006-01-02")
+	doc := MarkingReportDocument{
+		INN:            inn,
+		Action:         "DISTANCE",
+		ActionDate:     date,
+		DocumentType:   "OTHER",
+		DocumentNumber: report.Number,
+		DocumentDate:   date,
+		DocumentName:   "Отчет о продажах через маркетплейс",
+		Products:       make([]MarkingReportProduct, 0, len(lines)),
+	}
+	for _, line := range lines {
+		doc.Products = append(doc.Products, MarkingReportProduct{CIS: line.CIS, ProductCost: line.PriceKopecks})
+	}
+	return doc
+}
+
+// --- Структуры запросов ---
+
+// ImportMarkingCodesRequest - загрузка партии кодов списком (альтернатива загрузке файла)
+type ImportMarkingCodesRequest struct {
+	VariantID *uuid.UUID `json:"variant_id"`
+	GTIN      string     `json:"gtin"`
+	Codes     []string   `json:"codes" binding:"required,min=1"`
+}
+
+// AssignMarkingCodesRequest - коды, отсканированные при сборке заказа
+type AssignMarkingCodesRequest struct {
+	Items []AssignMarkingCodesItem `json:"items" binding:"required,min=1,dive"`
+}
+
+type AssignMarkingCodesItem struct {
+	OrderItemID uuid.UUID `json:"order_item_id" binding:"required"`
+	Codes       []string  `json:"codes" binding:"required,min=1"`
+}
+
+// WithdrawMarkingCodesRequest - вывод кодов из оборота не продажей
+type WithdrawMarkingCodesRequest struct {
+	CodeIDs []uuid.UUID `json:"code_ids" binding:"required,min=1"`
+	Reason  string      `json:"reason" binding:"required,oneof=damage_loss destruction enterprise_use other"`
+}
+
+// MarkingCodeIDsRequest - список кодов для действия над ними
+type MarkingCodeIDsRequest struct {
+	CodeIDs []uuid.UUID `json:"code_ids" binding:"required,min=1"`
+}
+
+// CreateMarkingReportRequest - период отгрузок, коды из которого попадут в отчет.
+// Без периода в отчет попадают все отгруженные коды, еще не вошедшие в отчеты.
+type CreateMarkingReportRequest struct {
+	ShippedFrom *time.Time `json:"shipped_from"`
+	ShippedTo   *time.Time `json:"shipped_to"`
+}
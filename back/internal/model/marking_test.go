@@ -0,0 +1,50 @@
+package model
+
+import "testing"
+
+func TestParseMarkingCode(t *testing.T) {
+	const (
+		gtin   = "04006381333931"
+		serial = "abcDEF1234567"
+		cis    = "01" + gtin + "21" + serial
+	)
+	tests := []struct {
+		name    string
+		raw     string
+		code    string // Ожидаемый Code; пусто - ошибка разбора
+		wantErr bool
+	}{
+		{name: "with GS", raw: cis + "\x1d91EE06\x1d92AbCd", code: cis + "\x1d91EE06\x1d92AbCd"},
+		{name: "textual GS and scanner prefix", raw: "]d2" + cis + "<GS>91EE06<GS>92AbCd", code: cis + "\x1d91EE06\x1d92AbCd"},
+		{name: "no GS restores separators", raw: cis + "91EE0692AbCd", code: cis + "\x1d91EE06\x1d92AbCd"},
+		{name: "no GS tail without AI 92", raw: cis + "91EE06", code: cis + "\x1d91EE06"},
+		{name: "no GS 7-character tail", raw: cis + "91EE069", code: cis + "\x1d91EE069"},
+		{name: "CIS only", raw: cis, code: cis},
+		{name: "too short", raw: "0104006381333931", wantErr: true},
+		{name: "wrong AI", raw: "02" + gtin + "21" + serial, wantErr: true},
+		{name: "non-digit GTIN", raw: "01" + "0400638133393A" + "21" + serial, wantErr: true},
+		{name: "bad GTIN check digit", raw: "0104006381333932" + "21" + serial, wantErr: true},
+		{name: "missing AI 21", raw: "01" + gtin + "10" + serial, wantErr: true},
+		{name: "serial with space", raw: "01" + gtin + "21" + "abc def", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parts, err := ParseMarkingCode(tt.raw)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %+v", parts)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if parts.GTIN != gtin || parts.CIS != cis {
+				t.Errorf("GTIN/CIS = %s/%s, want %s/%s", parts.GTIN, parts.CIS, gtin, cis)
+			}
+			if parts.Code != tt.code {
+				t.Errorf("Code = %q, want %q", parts.Code, tt.code)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package model
+
+import (
+	"database/sql/driver"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Категории уведомлений. Для каждой продавец выбирает канал доставки
+const (
+	NotificationCategoryOrders   = "orders"
+	NotificationCategoryStock    = "stock"
+	NotificationCategoryReturns  = "returns"
+	NotificationCategoryBalance  = "balance"
+	NotificationCategoryWebhooks = "webhooks"
+)
+
+// NotificationCategories - все категории уведомлений
+var NotificationCategories = []string{
+	NotificationCategoryOrders,
+	NotificationCategoryStock,
+	NotificationCategoryReturns,
+	NotificationCategoryBalance,
+	NotificationCategoryWebhooks,
+}
+
+// Каналы доставки уведомлений
+const (
+	NotificationChannelInApp      = "in_app"       // Только центр уведомлений (по умолчанию)
+	NotificationChannelInAppEmail = "in_app_email" // Центр уведомлений и письмо на email продавца
+)
+
+// Статусы письма по уведомлению
+const (
+	NotificationEmailNone    = "none"    // Письмо не нужно
+	NotificationEmailPending = "pending" // Ждет отправки
+	NotificationEmailSending = "sending" // Взято отправителем
+	NotificationEmailSent    = "sent"
+	NotificationEmailFailed  = "failed" // Попытки исчерпаны
+)
+
+// NotificationData - дополнительные данные уведомления (ID заказа, товара и т.п.)
+type NotificationData map[string]interface{}
+
+func (d NotificationData) Value() (driver.Value, error) {
+	if d == nil {
+		return "{}", nil
+	}
+	return valueJSON(d)
+}
+
+func (d *NotificationData) Scan(value interface{}) error {
+	return scanJSON(d, value)
+}
+
+// Notification - уведомление продавца. Link - путь страницы приложения, например /orders/{id}
+type Notification struct {
+	ID            uuid.UUID        `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
+	UserID        uuid.UUID        `gorm:"type:uuid;not null;index" json:"-"`
+	Category      string           `gorm:"type:varchar(20);not null" json:"category"`
+	Type          string           `gorm:"type:varchar(50);not null" json:"type"`
+	Title         string           `gorm:"type:varchar(255);not null" json:"title"`
+	Body          string           `gorm:"type:text" json:"body"`
+	Link          string           `gorm:"type:varchar(500)" json:"link"`
+	Data          NotificationData `gorm:"type:jsonb" json:"data"`
+	ReadAt        *time.Time       `json:"read_at"`
+	EmailStatus   string           `gorm:"type:varchar(20);not null;default:'none'" json:"email_status"`
+	EmailAttempts int              `gorm:"not null;default:0" json:"-"`
+	EmailedAt     *time.Time       `json:"-"`
+	CreatedAt     time.Time        `json:"created_date"`
+
+	// --- Поля, которые не хранятся в БД, а вычисляются ---
+	UserEmail string `gorm:"->;-:migration" json:"-"` // Адрес для письма, заполняется при выборке очереди писем
+}
+
+// NotificationPreference - канал доставки уведомлений категории
+type NotificationPreference struct {
+	UserID    uuid.UUID `gorm:"type:uuid;primary_key" json:"-"`
+	Category  string    `gorm:"type:varchar(20);primary_key" json:"category"`
+	Channel   string    `gorm:"type:varchar(20);not null;default:'in_app'" json:"channel"`
+	UpdatedAt time.Time `json:"updated_date"`
+}
+
+// --- Структуры для запросов/ответов, не являющиеся моделями БД ---
+
+// NotificationFilter - фильтры списка уведомлений
+type NotificationFilter struct {
+	Category   string
+	UnreadOnly bool
+}
+
+// UpdateNotificationPreferencesRequest - тело запроса на изменение каналов по категориям
+type UpdateNotificationPreferencesRequest struct {
+	Preferences []NotificationPreferenceRequest `json:"preferences" binding:"required,min=1,dive"`
+}
+
+// NotificationPreferenceRequest - канал доставки для одной категории
+type NotificationPreferenceRequest struct {
+	Category string `json:"category" binding:"required,oneof=orders stock returns balance webhooks"`
+	Channel  string `json:"channel" binding:"required,oneof=in_app in_app_email"`
+}
+
+// MarkNotificationsReadRequest - тело запроса на отметку уведомлений прочитанными
+type MarkNotificationsReadRequest struct {
+	IDs []uuid.UUID `json:"ids" binding:"required,min=1,max=500"`
+}
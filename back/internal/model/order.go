@@ -96,6 +96,38 @@ type TotalsInfo struct {
 func (ti *TotalsInfo) Scan(value interface{}) error { return scanJSON(ti, value) }
 func (ti TotalsInfo) Value() (driver.Value, error)  { return valueJSON(ti) }
 
+// Статусы заказа
+const (
+	OrderStatusNew              = "new"
+	OrderStatusConfirmed        = "confirmed"         // Подтвержден продавцом, товар резервируется на складах
+	OrderStatusInTransit        = "in_transit"        // Отгружен, товар списывается со складов
+	OrderStatusPartiallyShipped = "partially_shipped" // Отгружена часть посылок, статус выводится из отправлений
+	OrderStatusDelivered        = "delivered"
+	OrderStatusReturned         = "returned"
+	OrderStatusCancelled        = "cancelled" // Резерв снимается
+)
+
+// orderTransitions описывает переходы статусов, которые продавец может выполнить вручную.
+// Складские операции привязаны к целевому статусу (резерв при confirmed, списание при in_transit),
+// поэтому пропустить шаг нельзя. partially_shipped выставляется по отправлениям, отмена идет через Cancel.
+var orderTransitions = map[string][]string{
+	OrderStatusNew:              {OrderStatusConfirmed, OrderStatusCancelled},
+	OrderStatusConfirmed:        {OrderStatusInTransit, OrderStatusCancelled},
+	OrderStatusPartiallyShipped: {OrderStatusInTransit},
+	OrderStatusInTransit:        {OrderStatusDelivered, OrderStatusReturned},
+	OrderStatusDelivered:        {OrderStatusReturned},
+}
+
+// CanTransitionOrder проверяет, разрешен ли переход заказа из статуса from в статус to.
+func CanTransitionOrder(from, to string) bool {
+	for _, allowed := range orderTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
 // --- Основные модели ---
 
 // Order представляет заказ
@@ -117,8 +149,10 @@ type Order struct {
 	Totals   TotalsInfo   `gorm:"type:jsonb" json:"totals"`
 
 	// Связи "один ко многим"
-	StatusHistory []StatusHistory `gorm:"foreignKey:OrderID" json:"status_history"`
-	Items         []OrderItem     `gorm:"foreignKey:OrderID" json:"items"`
+	StatusHistory []StatusHistory     `gorm:"foreignKey:OrderID" json:"status_history"`
+	Items         []OrderItem         `gorm:"foreignKey:OrderID" json:"items"`
+	Cancellations []OrderCancellation `gorm:"foreignKey:OrderID" json:"cancellations,omitempty"`
+	Shipments     []Shipment          `gorm:"foreignKey:OrderID" json:"shipments,omitempty"`
 }
 
 // OrderItem представляет товарную позицию в заказе
@@ -138,6 +172,9 @@ type OrderItem struct {
 	CostPrice float64   `json:"cost_price"`
 	Discount  float64   `json:"discount"`
 	Total     float64   `json:"total"`
+
+	CancelledQuantity int `gorm:"not null;default:0" json:"cancelled_quantity"` // Отменено единиц, Quantity уже уменьшено на это число
+	ShippedQuantity   int `gorm:"not null;default:0" json:"shipped_quantity"`   // Отгружено единиц (в посылках или всем заказом)
 }
 
 // StatusHistory представляет запись в истории изменения статуса заказа
@@ -0,0 +1,81 @@
+package model
+
+import (
+	"database/sql/driver"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Причины отмены заказа
+const (
+	CancelReasonCustomerRequest = "customer_request" // Покупатель передумал
+	CancelReasonOutOfStock      = "out_of_stock"     // Товара нет в наличии
+	CancelReasonPaymentFailed   = "payment_failed"   // Оплата не прошла
+	CancelReasonAddressInvalid  = "address_invalid"  // Доставка по адресу невозможна
+	CancelReasonDuplicate       = "duplicate"        // Повторный заказ
+	CancelReasonFraud           = "fraud"            // Подозрение на мошенничество
+	CancelReasonOther           = "other"
+)
+
+// Инициаторы отмены
+const (
+	CancelInitiatorSeller   = "seller"
+	CancelInitiatorCustomer = "customer"
+)
+
+// Статусы оплаты после возврата средств покупателю
+const (
+	PaymentStatusRefunded          = "refunded"
+	PaymentStatusPartiallyRefunded = "partially_refunded"
+)
+
+// OrderCancellation - запись об отмене заказа целиком или отдельных позиций.
+// RefundAmount - сколько возвращено покупателю, SellerAmount - сколько списано с баланса продавца.
+type OrderCancellation struct {
+	ID           uuid.UUID          `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
+	UserID       uuid.UUID          `gorm:"type:uuid;not null" json:"-"`
+	OrderID      uuid.UUID          `gorm:"type:uuid;not null;index" json:"order_id"`
+	Reason       string             `gorm:"type:varchar(50);not null" json:"reason"`
+	Initiator    string             `gorm:"type:varchar(20);not null" json:"initiator"`
+	Comment      string             `gorm:"type:text" json:"comment"`
+	FullCancel   bool               `gorm:"not null;default:false" json:"full_cancel"`
+	Items        CancelledItemsList `gorm:"type:jsonb;not null;default:'[]'" json:"items"`
+	RefundAmount float64            `gorm:"type:numeric(12,2);not null;default:0" json:"refund_amount"`
+	SellerAmount float64            `gorm:"type:numeric(12,2);not null;default:0" json:"seller_amount"`
+	CreatedAt    time.Time          `json:"created_date"`
+}
+
+// CancelledItem - отмененная часть позиции заказа
+type CancelledItem struct {
+	OrderItemID uuid.UUID `json:"order_item_id"`
+	Quantity    int       `json:"quantity"`
+	Amount      float64   `json:"amount"`
+}
+
+// CancelledItemsList - список отмененных позиций, хранимый в JSONB
+type CancelledItemsList []CancelledItem
+
+func (l *CancelledItemsList) Scan(value interface{}) error { return scanJSON(l, value) }
+func (l CancelledItemsList) Value() (driver.Value, error) {
+	if l == nil {
+		return "[]", nil
+	}
+	return valueJSON(l)
+}
+
+// --- Структуры для запросов/ответов, не являющиеся моделями БД ---
+
+// CancelOrderRequest - тело запроса на отмену заказа. Без Items отменяется весь заказ.
+type CancelOrderRequest struct {
+	Reason    string            `json:"reason" binding:"required,oneof=customer_request out_of_stock payment_failed address_invalid duplicate fraud other"`
+	Initiator string            `json:"initiator" binding:"required,oneof=seller customer"`
+	Comment   string            `json:"comment" binding:"max=1000"`
+	Items     []CancelOrderItem `json:"items" binding:"omitempty,dive"`
+}
+
+// CancelOrderItem - сколько единиц позиции отменить
+type CancelOrderItem struct {
+	OrderItemID uuid.UUID `json:"order_item_id" binding:"required"`
+	Quantity    int       `json:"quantity" binding:"required,min=1"`
+}
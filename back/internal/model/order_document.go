@@ -0,0 +1,95 @@
+package model
+
+import (
+	"database/sql/driver"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Виды документов по заказу
+const (
+	OrderDocumentPackingSlip = "packing_slip" // Упаковочный лист для сборщика
+	OrderDocumentInvoice     = "invoice"      // Счет для покупателя
+)
+
+// MaxOrdersPerDocumentBatch - сколько заказов можно объединить в один PDF
+const MaxOrdersPerDocumentBatch = 200
+
+// orderDocumentSlugs - вид документа в URL и его префикс номера
+var orderDocumentSlugs = map[string]struct{ Type, Prefix string }{
+	"packing-slip": {OrderDocumentPackingSlip, "PS"},
+	"invoice":      {OrderDocumentInvoice, "INV"},
+}
+
+// ParseOrderDocumentType возвращает вид документа по его обозначению в URL (packing-slip, invoice).
+func ParseOrderDocumentType(slug string) (string, bool) {
+	doc, ok := orderDocumentSlugs[slug]
+	return doc.Type, ok
+}
+
+// OrderDocumentPrefix возвращает префикс номера документа: PS - упаковочный лист, INV - счет.
+func OrderDocumentPrefix(docType string) string {
+	for _, doc := range orderDocumentSlugs {
+		if doc.Type == docType {
+			return doc.Prefix
+		}
+	}
+	return "DOC"
+}
+
+// OrderDocument - выданный документ по заказу. Номер присваивается при первой печати и не меняется.
+// Вместе с номером сохраняется содержимое документа: повторная печать не зависит от последующих правок заказа.
+type OrderDocument struct {
+	ID       uuid.UUID         `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
+	UserID   uuid.UUID         `gorm:"type:uuid;not null" json:"-"`
+	OrderID  uuid.UUID         `gorm:"type:uuid;not null" json:"order_id"`
+	Type     string            `gorm:"type:varchar(20);not null" json:"type"`
+	Number   string            `gorm:"type:varchar(50);not null" json:"number"`
+	Data     OrderDocumentData `gorm:"type:jsonb" json:"-"`
+	IssuedAt time.Time         `json:"issued_at"`
+}
+
+// OrderDocumentData - содержимое документа на момент выдачи номера
+type OrderDocumentData struct {
+	SellerName  string              `json:"seller_name"`
+	SellerINN   string              `json:"seller_inn"`
+	OrderNumber string              `json:"order_number"`
+	OrderDate   time.Time           `json:"order_date"`
+	Customer    string              `json:"customer"`
+	Phone       string              `json:"phone"`
+	Email       string              `json:"email"`
+	Address     string              `json:"address"`
+	Delivery    string              `json:"delivery"`
+	Tracking    string              `json:"tracking"`
+	Payment     string              `json:"payment"`
+	Notes       string              `json:"notes"`
+	Lines       []OrderDocumentLine `json:"lines"`
+	Subtotal    float64             `json:"subtotal"`
+	Discount    float64             `json:"discount"`
+	Shipping    float64             `json:"shipping"`
+	Total       float64             `json:"total"`
+	Currency    string              `json:"currency"`
+}
+
+// OrderDocumentLine - товарная позиция документа
+type OrderDocumentLine struct {
+	SKU        string  `json:"sku"`
+	Name       string  `json:"name"`
+	Attributes string  `json:"attributes"`
+	Quantity   int     `json:"quantity"`
+	Price      float64 `json:"price"`
+	Discount   float64 `json:"discount"`
+	Total      float64 `json:"total"`
+}
+
+func (d *OrderDocumentData) Scan(value interface{}) error { return scanJSON(d, value) }
+func (d OrderDocumentData) Value() (driver.Value, error)  { return valueJSON(d) }
+
+// --- Структуры для запросов/ответов, не являющиеся моделями БД ---
+
+// BatchOrderDocumentsRequest - документы одного вида для нескольких заказов в одном PDF
+type BatchOrderDocumentsRequest struct {
+	OrderIDs []uuid.UUID `json:"order_ids" binding:"required,min=1,max=200"`
+	Type     string      `json:"type" binding:"required,oneof=packing-slip invoice"`
+}
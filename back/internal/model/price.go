@@ -0,0 +1,116 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Источники изменения цены в истории
+const (
+	PriceSourceInitial  = "initial"  // Начальная точка для товаров, созданных до появления истории
+	PriceSourceManual   = "manual"   // Изменение через карточку товара
+	PriceSourceRollback = "rollback" // Откат карточки к версии
+	PriceSourceSchedule = "schedule" // Применение запланированного изменения
+	PriceSourceRevert   = "revert"   // Возврат цены по окончании запланированного изменения
+	PriceSourceBulk     = "bulk"     // Массовое изменение цен или скидок
+)
+
+// Статусы запланированного изменения цены: pending → active → completed
+const (
+	PriceScheduleStatusPending   = "pending"   // Ждет времени начала
+	PriceScheduleStatusActive    = "active"    // Применено, ждет окончания и возврата цены
+	PriceScheduleStatusCompleted = "completed" // Завершено
+	PriceScheduleStatusCancelled = "cancelled" // Отменено продавцом
+)
+
+// PriceHistory - запись истории цен товара или варианта.
+type PriceHistory struct {
+	ID              uuid.UUID  `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
+	ProductID       uuid.UUID  `gorm:"type:uuid;not null;index" json:"product_id"`
+	VariantID       *uuid.UUID `gorm:"type:uuid" json:"variant_id,omitempty"` // nil - цена товара целиком
+	Price           float64    `gorm:"not null" json:"price"`
+	CostPrice       float64    `json:"cost_price"`
+	DiscountPercent float64    `gorm:"default:0" json:"discount_percent"`
+	Source          string     `gorm:"type:varchar(50);not null" json:"source"`
+	ScheduleID      *uuid.UUID `gorm:"type:uuid" json:"schedule_id,omitempty"`
+	UserID          *uuid.UUID `gorm:"type:uuid" json:"user_id,omitempty"`
+	CreatedAt       time.Time  `json:"created_date"`
+}
+
+// TableName явно задает имя таблицы (GORM по умолчанию использовал бы price_histories)
+func (PriceHistory) TableName() string {
+	return "price_history"
+}
+
+// PriceSchedule - запланированное изменение цены и/или скидки.
+// Если задан VariantID, меняется только цена варианта (скидка задается на уровне товара).
+type PriceSchedule struct {
+	ID                      uuid.UUID  `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
+	ProductID               uuid.UUID  `gorm:"type:uuid;not null;index" json:"product_id"`
+	VariantID               *uuid.UUID `gorm:"type:uuid" json:"variant_id,omitempty"`
+	UserID                  *uuid.UUID `gorm:"type:uuid" json:"-"`
+	Price                   *float64   `json:"price,omitempty"`
+	DiscountPercent         *float64   `json:"discount_percent,omitempty"`
+	StartsAt                time.Time  `gorm:"not null" json:"starts_at"`
+	EndsAt                  *time.Time `json:"ends_at,omitempty"`
+	RevertOnEnd             bool       `gorm:"default:false" json:"revert_on_end"`
+	Status                  string     `gorm:"type:varchar(50);default:'pending'" json:"status"`
+	PreviousPrice           *float64   `json:"previous_price,omitempty"` // Цена до применения, нужна для возврата
+	PreviousDiscountPercent *float64   `json:"previous_discount_percent,omitempty"`
+	AppliedAt               *time.Time `json:"applied_at,omitempty"`
+	CompletedAt             *time.Time `json:"completed_at,omitempty"`
+	Comment                 string     `gorm:"type:text" json:"comment,omitempty"`
+	CreatedAt               time.Time  `json:"created_date"`
+	UpdatedAt               time.Time  `json:"updated_date"`
+}
+
+// --- Структуры для запросов/ответов, не являющиеся моделями БД ---
+
+// PriceScheduleRequest представляет тело запроса на планирование изменения цены
+type PriceScheduleRequest struct {
+	VariantID       *uuid.UUID `json:"variant_id"`
+	Price           *float64   `json:"price" binding:"omitempty,gt=0"`
+	DiscountPercent *float64   `json:"discount_percent" binding:"omitempty,gte=0,lt=100"`
+	StartsAt        time.Time  `json:"starts_at" binding:"required"`
+	EndsAt          *time.Time `json:"ends_at"`
+	RevertOnEnd     bool       `json:"revert_on_end"`
+	Comment         string     `json:"comment"`
+}
+
+// PriceTimelineRequestParams содержит параметры запроса динамики цены.
+type PriceTimelineRequestParams struct {
+	VariantID string `form:"variant_id"` // Если указан, учитывается собственная цена варианта и его продажи
+	Period    string `form:"period"`     // 7d, 30d, 90d, 1y, all_time
+}
+
+// DailySales - продажи за один день
+type DailySales struct {
+	Date      time.Time `json:"date"`
+	UnitsSold int64     `json:"units_sold"`
+	Revenue   float64   `json:"revenue"`
+}
+
+// PricePeriod - интервал, в течение которого действовала одна цена, и продажи за него
+type PricePeriod struct {
+	From            time.Time `json:"from"`
+	To              time.Time `json:"to"`
+	Price           float64   `json:"price"`
+	DiscountPercent float64   `json:"discount_percent"`
+	FinalPrice      float64   `json:"final_price"` // Цена с учетом скидки
+	UnitsSold       int64     `json:"units_sold"`
+	Revenue         float64   `json:"revenue"`
+	Days            float64   `json:"days"`
+	UnitsPerDay     float64   `json:"units_per_day"`
+}
+
+// PriceTimelineResponse - динамика цены товара/варианта в сопоставлении с продажами
+type PriceTimelineResponse struct {
+	ProductID uuid.UUID       `json:"product_id"`
+	VariantID *uuid.UUID      `json:"variant_id,omitempty"`
+	Period    string          `json:"period"`
+	History   []PriceHistory  `json:"history"`
+	Periods   []PricePeriod   `json:"periods"`
+	Sales     []DailySales    `json:"sales"`
+	Schedules []PriceSchedule `json:"schedules"` // Предстоящие и действующие изменения
+}
@@ -34,6 +34,19 @@ func (d Dimensions) Value() (driver.Value, error) {
 	return json.Marshal(d)
 }
 
+// SearchHighlight - фрагменты названия и описания с подсвеченными совпадениями поискового запроса (<mark>...</mark>).
+// Текст экранирован для HTML: кроме <mark> тегов во фрагментах нет.
+type SearchHighlight struct {
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+func (h *SearchHighlight) Scan(value interface{}) error { return scanJSON(h, value) }
+func (h SearchHighlight) Value() (driver.Value, error)  { return valueJSON(h) }
+
+// SalesWindowDays - длина скользящего окна для показателей продаж товара (SalesCount30d, Revenue30d)
+const SalesWindowDays = 30
+
 // --- Основные модели ---
 
 // Product представляет основную сущность товара.
@@ -53,7 +66,9 @@ type Product struct {
 	Rating            float64        `gorm:"default:0" json:"rating"`
 	ReviewsCount      int            `gorm:"default:0" json:"reviews_count"`
 	ReturnRate        float64        `gorm:"default:0" json:"return_rate"`
-	Status            string         `gorm:"type:varchar(50);default:'draft';index" json:"status"` // active, inactive, draft
+	Status            string         `gorm:"type:varchar(50);default:'draft';index" json:"status"` // draft, ready, active, archived (см. product_lifecycle.go)
+	PublishedAt       *time.Time     `json:"published_at,omitempty"`
+	ArchivedAt        *time.Time     `json:"archived_at,omitempty"`
 	SeasonalDemand    string         `gorm:"type:varchar(100)" json:"seasonal_demand"`
 	IsBestseller      bool           `gorm:"default:false" json:"is_bestseller"`
 	IsNew             bool           `gorm:"default:true" json:"is_new"`
@@ -62,9 +77,12 @@ type Product struct {
 	Material          string         `gorm:"type:varchar(255)" json:"material"`
 	CareInstructions  string         `gorm:"type:text" json:"care_instructions"`
 	CountryOrigin     string         `gorm:"type:varchar(100)" json:"country_origin"`
+	Attributes        ProductAttributes `gorm:"type:jsonb" json:"attributes"` // Значения атрибутов по схеме категории
 	SupplierID        *uuid.UUID     `gorm:"type:uuid" json:"-"` // Ссылка на поставщика
+	UserID            *uuid.UUID     `gorm:"type:uuid;index" json:"-"` // ID продавца, которому принадлежит товар
 	CreatedAt         time.Time      `json:"created_date"`
 	UpdatedAt         time.Time      `json:"updated_date"`
+	DeletedAt         gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"` // Мягкое удаление: товар скрыт из списков, но доступен истории заказов
 
 	// --- Связи ---
 	Supplier *Supplier        `gorm:"foreignKey:SupplierID" json:"supplier,omitempty"`
@@ -76,8 +94,10 @@ type Product struct {
 	MainImage       string  `gorm:"-" json:"main_image,omitempty"`
 	AvailableSizes  []string `gorm:"-" json:"available_sizes,omitempty"`
 	AvailableColors []string `gorm:"-" json:"available_colors,omitempty"`
-	SalesCount30d   int     `gorm:"-" json:"sales_count_30d,omitempty"` // Пример вычисляемого поля
-	Revenue30d      float64 `gorm:"-" json:"revenue_30d,omitempty"`     // Пример вычисляемого поля
+	SalesCount30d   int     `gorm:"column:sales_count_30d;->;-:migration" json:"sales_count_30d"` // Продано штук за 30 дней (из product_sales_30d)
+	Revenue30d      float64 `gorm:"column:revenue_30d;->;-:migration" json:"revenue_30d"`         // Выручка за 30 дней (из product_sales_30d)
+	SearchRank      float64          `gorm:"column:search_rank;->;-:migration" json:"search_rank,omitempty"`    // Релевантность при поиске
+	Highlight       *SearchHighlight `gorm:"column:search_highlight;->;-:migration" json:"highlight,omitempty"` // Подсветка совпадений при поиске
 }
 
 // ProductVariant представляет вариант товара (SKU).
@@ -93,6 +113,9 @@ type ProductVariant struct {
 	Price      float64    `json:"price"` // Может отличаться от основного
 	Weight     float64    `json:"weight"` // в граммах
 	Dimensions Dimensions `gorm:"type:jsonb" json:"dimensions"`
+	ReorderPoint *int     `json:"reorder_point"`  // Точка заказа, заданная вручную; nil - рассчитывается (см. stock.go)
+	LeadTimeDays *int     `json:"lead_time_days"` // Срок поставки варианта; nil - берется у поставщика
+	GTIN         string   `gorm:"column:gtin;type:varchar(14);index" json:"gtin"` // GTIN, на который выпускаются коды маркировки
 }
 
 // ProductImage представляет изображение товара.
@@ -105,20 +128,6 @@ type ProductImage struct {
 	Order     int       `gorm:"default:0" json:"order"`
 }
 
-// Supplier представляет поставщика.
-type Supplier struct {
-	ID      uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
-	Name    string    `gorm:"type:varchar(255);not null" json:"name"`
-	Contact string    `gorm:"type:varchar(255)" json:"contact"`
-}
-
-// Category представляет иерархическую структуру категорий
-type Category struct {
-	ID            string     `json:"id"`
-	Name          string     `json:"name"`
-	Subcategories []Category `json:"subcategories,omitempty"`
-}
-
 // SizeChart представляет размерную сетку для категории.
 type SizeChart struct {
 	Category string `json:"category"`
@@ -172,12 +181,8 @@ func (p *Product) AfterFind(tx *gorm.DB) (err error) {
 	for c := range colorSet {
 		p.AvailableColors = append(p.AvailableColors, c)
 	}
-	
-	// TODO: Данные по продажам (SalesCount30d, Revenue30d) должны загружаться
-	// отдельным запросом в репозитории/сервисе и заполняться здесь.
-	// Для примера оставим их нулевыми.
-	p.SalesCount30d = 45 // Заглушка
-	p.Revenue30d = 400500 // Заглушка
+
+	// Продажи за 30 дней (SalesCount30d, Revenue30d) выбираются репозиторием из таблицы product_sales_30d
 
 	return
 }
\ No newline at end of file
@@ -0,0 +1,82 @@
+package model
+
+import "github.com/google/uuid"
+
+// Операции массового изменения товаров
+const (
+	BulkOpSetStatus   = "set_status"
+	BulkOpAdjustPrice = "adjust_price"
+	BulkOpSetDiscount = "set_discount"
+	BulkOpAddTags     = "add_tags"
+	BulkOpRemoveTags  = "remove_tags"
+	BulkOpSetSupplier = "set_supplier"
+	BulkOpArchive     = "archive"
+)
+
+// Способы изменения цены в операции adjust_price
+const (
+	PriceAdjustAmount  = "amount"  // Цена меняется на value рублей (может быть отрицательным)
+	PriceAdjustPercent = "percent" // Цена меняется на value процентов
+)
+
+// MaxBulkProducts - максимальное количество товаров в одной массовой операции
+const MaxBulkProducts = 1000
+
+// Результаты обработки товара в массовой операции
+const (
+	BulkResultUpdated   = "updated"
+	BulkResultUnchanged = "unchanged"
+	BulkResultSkipped   = "skipped" // Операция неприменима к товару, причина в Error
+	BulkResultNotFound  = "not_found"
+)
+
+// BulkProductRequest - тело запроса POST /api/products/bulk.
+// Товары задаются либо списком ID, либо фильтром в формате query-параметров GET /api/products.
+type BulkProductRequest struct {
+	ProductIDs []uuid.UUID         `json:"product_ids"`
+	Filter     map[string][]string `json:"filter"`
+	Operation  string              `json:"operation" binding:"required,oneof=set_status adjust_price set_discount add_tags remove_tags set_supplier archive"`
+	Status     string              `json:"status" binding:"omitempty,oneof=draft ready active archived"` // Для set_status
+	PriceMode  string              `json:"price_mode" binding:"omitempty,oneof=amount percent"`          // Для adjust_price
+	Value      *float64            `json:"value"`                                                        // Для adjust_price и set_discount
+	Tags       []string            `json:"tags"`                                                         // Для add_tags и remove_tags
+	SupplierID *uuid.UUID          `json:"supplier_id"`                                                  // Для set_supplier, null снимает привязку
+	Preview    bool                `json:"preview"`                                                      // Только показать изменения, ничего не сохраняя
+}
+
+// BulkItemResult - результат массовой операции для одного товара
+type BulkItemResult struct {
+	ProductID uuid.UUID     `json:"product_id"`
+	SKU       string        `json:"sku,omitempty"`
+	Name      string        `json:"name,omitempty"`
+	Result    string        `json:"result"`
+	Error     string        `json:"error,omitempty"`
+	Changes   []FieldChange `json:"changes,omitempty"`
+}
+
+// BulkSummary - итоги массовой операции
+type BulkSummary struct {
+	Total     int `json:"total"`
+	Updated   int `json:"updated"`
+	Unchanged int `json:"unchanged"`
+	Skipped   int `json:"skipped"`
+	NotFound  int `json:"not_found"`
+}
+
+// SummarizeBulk подсчитывает итоги по результатам для отдельных товаров.
+func SummarizeBulk(results []BulkItemResult) BulkSummary {
+	summary := BulkSummary{Total: len(results)}
+	for _, r := range results {
+		switch r.Result {
+		case BulkResultUpdated:
+			summary.Updated++
+		case BulkResultUnchanged:
+			summary.Unchanged++
+		case BulkResultSkipped:
+			summary.Skipped++
+		case BulkResultNotFound:
+			summary.NotFound++
+		}
+	}
+	return summary
+}
@@ -0,0 +1,115 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Статусы жизненного цикла товара: draft → ready → active → archived
+const (
+	ProductStatusDraft    = "draft"    // Карточка заполняется
+	ProductStatusReady    = "ready"    // Карточка прошла проверку готовности и может быть опубликована
+	ProductStatusActive   = "active"   // Товар опубликован и продается
+	ProductStatusArchived = "archived" // Товар снят с продажи
+)
+
+// MinDescriptionLength - минимальная длина описания (в символах) для публикации товара
+const MinDescriptionLength = 100
+
+// productTransitions описывает разрешенные переходы между статусами.
+var productTransitions = map[string][]string{
+	ProductStatusDraft:    {ProductStatusReady, ProductStatusActive, ProductStatusArchived},
+	ProductStatusReady:    {ProductStatusDraft, ProductStatusActive, ProductStatusArchived},
+	ProductStatusActive:   {ProductStatusArchived},
+	ProductStatusArchived: {ProductStatusDraft},
+}
+
+// CanTransitionProduct проверяет, разрешен ли переход товара из статуса from в статус to.
+func CanTransitionProduct(from, to string) bool {
+	for _, allowed := range productTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadinessCheck - результат одной проверки готовности к публикации
+type ReadinessCheck struct {
+	Code    string `json:"code"`
+	Passed  bool   `json:"passed"`
+	Message string `json:"message,omitempty"`
+}
+
+// ProductReadiness - сводный результат проверки готовности товара к публикации
+type ProductReadiness struct {
+	Ready   bool             `json:"ready"`
+	Checks  []ReadinessCheck `json:"checks"`
+	Missing []string         `json:"missing"`
+}
+
+// CheckReadiness проверяет, готова ли карточка товара к публикации.
+// Товар должен быть загружен вместе с изображениями и вариантами. sizeChart - размерная сетка
+// категории товара (допустимые значения атрибута SizeAttributeCode); пустая - сетки нет.
+func CheckReadiness(p *Product, sizeChart []string) ProductReadiness {
+	var readiness ProductReadiness
+	add := func(code string, passed bool, message string) {
+		check := ReadinessCheck{Code: code, Passed: passed}
+		if !passed {
+			check.Message = message
+			readiness.Missing = append(readiness.Missing, code)
+		}
+		readiness.Checks = append(readiness.Checks, check)
+	}
+
+	hasMainImage := false
+	for _, img := range p.Images {
+		if img.IsMain {
+			hasMainImage = true
+			break
+		}
+	}
+	add("main_image", hasMainImage, "Загрузите главное изображение товара")
+
+	descriptionLength := utf8.RuneCountInString(p.Description)
+	add("description", descriptionLength >= MinDescriptionLength,
+		fmt.Sprintf("Описание должно содержать не менее %d символов (сейчас %d)", MinDescriptionLength, descriptionLength))
+
+	chart := make(map[string]bool, len(sizeChart))
+	for _, size := range sizeChart {
+		chart[strings.ToUpper(size)] = true
+	}
+	hasStock := false
+	var unknownSizes []string
+	for _, v := range p.Variants {
+		if v.Stock-v.Reserved > 0 {
+			hasStock = true
+		}
+		if !chart[strings.ToUpper(v.Size)] {
+			unknownSizes = append(unknownSizes, fmt.Sprintf("%q", v.Size))
+		}
+	}
+	add("variants_in_stock", hasStock, "Добавьте хотя бы один вариант с доступным остатком")
+	switch {
+	case len(sizeChart) == 0:
+		add("size_chart", false, "Для категории товара не задана размерная сетка")
+	case len(p.Variants) == 0:
+		add("size_chart", false, "Добавьте варианты с размерами из размерной сетки категории")
+	default:
+		add("size_chart", len(unknownSizes) == 0,
+			"Размеры вариантов не входят в размерную сетку категории: "+strings.Join(unknownSizes, ", "))
+	}
+
+	add("barcode", p.Barcode != "", "Укажите штрихкод товара")
+
+	finalPrice := p.Price * (1 - p.DiscountPercent/100)
+	add("price_above_cost", p.Price > 0 && finalPrice > p.CostPrice,
+		fmt.Sprintf("Цена с учетом скидки (%.2f) должна быть выше себестоимости (%.2f)", finalPrice, p.CostPrice))
+
+	readiness.Ready = len(readiness.Missing) == 0
+	if readiness.Missing == nil {
+		readiness.Missing = []string{}
+	}
+	return readiness
+}
@@ -0,0 +1,155 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Действия, после которых сохраняется версия товара
+const (
+	ProductActionCreate    = "create"
+	ProductActionUpdate    = "update"
+	ProductActionImages    = "images"
+	ProductActionPublish   = "publish"
+	ProductActionArchive   = "archive"
+	ProductActionUnarchive = "unarchive"
+	ProductActionRollback  = "rollback"
+	ProductActionBulk      = "bulk" // Массовая операция (POST /api/products/bulk)
+)
+
+// ProductSnapshot - полный снимок карточки товара вместе с вариантами и изображениями
+type ProductSnapshot Product
+
+func (ps *ProductSnapshot) Scan(value interface{}) error { return scanJSON(ps, value) }
+func (ps ProductSnapshot) Value() (driver.Value, error)  { return valueJSON(ps) }
+
+// ProductVersion - версия карточки товара, сохраненная после изменения
+type ProductVersion struct {
+	ID        uuid.UUID       `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
+	ProductID uuid.UUID       `gorm:"type:uuid;not null;index" json:"product_id"`
+	Version   int             `gorm:"not null" json:"version"`
+	UserID    *uuid.UUID      `gorm:"type:uuid" json:"user_id"`
+	Action    string          `gorm:"type:varchar(50);not null" json:"action"`
+	Comment   string          `gorm:"type:text" json:"comment,omitempty"`
+	Snapshot  ProductSnapshot `gorm:"type:jsonb" json:"-"` // Отдается отдельно в GET /history/{version}
+	CreatedAt time.Time       `json:"created_date"`
+
+	// --- Поля, которые не хранятся в БД, а вычисляются ---
+	UserName string        `gorm:"->;-:migration" json:"user_name,omitempty"`
+	Changes  []FieldChange `gorm:"-" json:"changes,omitempty"`
+}
+
+// FieldChange - изменение одного поля между двумя версиями
+type FieldChange struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old"`
+	New   interface{} `json:"new"`
+}
+
+// ignoredDiffFields - вычисляемые и служебные поля, которые не показываются в истории
+var ignoredDiffFields = map[string]struct{}{
+	"created_date":     {},
+	"updated_date":     {},
+	"deleted_at":       {},
+	"margin_percent":   {},
+	"main_image":       {},
+	"available_sizes":  {},
+	"available_colors": {},
+	"sales_count_30d":  {},
+	"revenue_30d":      {},
+}
+
+// DiffProductSnapshots возвращает список изменений полей между двумя снимками.
+// Варианты сопоставляются по SKU, изображения - по URL, поэтому изменения выглядят как "variants[CT001-M].stock".
+// Если old == nil, все поля нового снимка считаются добавленными.
+func DiffProductSnapshots(old, new *ProductSnapshot) []FieldChange {
+	oldFlat := flattenSnapshot(old)
+	newFlat := flattenSnapshot(new)
+
+	keys := make(map[string]struct{}, len(oldFlat)+len(newFlat))
+	for k := range oldFlat {
+		keys[k] = struct{}{}
+	}
+	for k := range newFlat {
+		keys[k] = struct{}{}
+	}
+
+	changes := []FieldChange{}
+	for k := range keys {
+		o, n := oldFlat[k], newFlat[k]
+		if reflect.DeepEqual(o, n) {
+			continue
+		}
+		changes = append(changes, FieldChange{Field: k, Old: o, New: n})
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes
+}
+
+// flattenSnapshot раскладывает снимок в плоский набор "путь → значение".
+func flattenSnapshot(s *ProductSnapshot) map[string]interface{} {
+	flat := make(map[string]interface{})
+	if s == nil {
+		return flat
+	}
+
+	raw, err := json.Marshal(s)
+	if err != nil {
+		return flat
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return flat
+	}
+
+	for key, value := range doc {
+		if _, ignored := ignoredDiffFields[key]; ignored {
+			continue
+		}
+		switch key {
+		case "variants":
+			flattenCollection(flat, key, value, "sku")
+		case "images":
+			flattenCollection(flat, key, value, "url")
+		case "supplier":
+			if m, ok := value.(map[string]interface{}); ok {
+				flat["supplier"] = m["id"]
+			} else {
+				flat["supplier"] = nil
+			}
+		default:
+			flat[key] = value
+		}
+	}
+	return flat
+}
+
+// flattenCollection раскладывает массив объектов, используя поле labelKey для читаемого имени элемента.
+func flattenCollection(flat map[string]interface{}, prefix string, value interface{}, labelKey string) {
+	items, ok := value.([]interface{})
+	if !ok {
+		return
+	}
+	for _, item := range items {
+		obj, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		label := fmt.Sprint(obj[labelKey])
+		if label == "" || label == "<nil>" {
+			label = fmt.Sprint(obj["id"])
+		}
+		for field, v := range obj {
+			if field == "id" {
+				continue
+			}
+			flat[fmt.Sprintf("%s[%s].%s", prefix, label, field)] = v
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package model
+
+import (
+	"database/sql/driver"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// Списки, для которых можно сохранять представления
+const (
+	ViewListProducts = "products"
+	ViewListOrders   = "orders"
+)
+
+// ViewFilters - фильтры представления в том же виде, что и query-параметры списка
+// (например, {"status": ["active"], "min_price": ["1000"]})
+type ViewFilters map[string][]string
+
+func (vf *ViewFilters) Scan(value interface{}) error { return scanJSON(vf, value) }
+func (vf ViewFilters) Value() (driver.Value, error)  { return valueJSON(vf) }
+
+// SavedView - именованное представление списка товаров или заказов
+type SavedView struct {
+	ID        uuid.UUID      `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
+	UserID    uuid.UUID      `gorm:"type:uuid;not null;index" json:"user_id"`
+	List      string         `gorm:"type:varchar(20);not null" json:"list"`
+	Name      string         `gorm:"type:varchar(255);not null" json:"name"`
+	Filters   ViewFilters    `gorm:"type:jsonb;not null" json:"filters"`
+	SortBy    string         `gorm:"type:varchar(50)" json:"sort_by,omitempty"`
+	SortOrder string         `gorm:"type:varchar(4)" json:"sort_order,omitempty"`
+	Columns   pq.StringArray `gorm:"type:text[]" json:"columns"`
+	IsDefault bool           `gorm:"not null;default:false" json:"is_default"`
+	Shared    bool           `gorm:"not null;default:false" json:"shared"`
+	CreatedAt time.Time      `json:"created_date"`
+	UpdatedAt time.Time      `json:"updated_date"`
+
+	// --- Поля, которые не хранятся в БД, а вычисляются ---
+	OwnerName string `gorm:"->;-:migration" json:"owner_name,omitempty"`
+	IsOwner   bool   `gorm:"-" json:"is_owner"`
+	Count     *int64 `gorm:"-" json:"count,omitempty"` // Текущее количество записей для бейджа
+}
+
+// SavedViewRequest - тело запроса на создание и изменение представления
+type SavedViewRequest struct {
+	List      string      `json:"list" binding:"required,oneof=products orders"`
+	Name      string      `json:"name" binding:"required,max=255"`
+	Filters   ViewFilters `json:"filters"`
+	SortBy    string      `json:"sort_by" binding:"max=50"`
+	SortOrder string      `json:"sort_order" binding:"omitempty,oneof=asc desc"`
+	Columns   []string    `json:"columns"`
+	IsDefault bool        `json:"is_default"`
+	Shared    bool        `json:"shared"`
+}
@@ -0,0 +1,70 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Статусы отправления
+const (
+	ShipmentStatusPending   = "pending"   // Собирается, товар еще на складе
+	ShipmentStatusShipped   = "shipped"   // Передано перевозчику, товар списан со склада
+	ShipmentStatusDelivered = "delivered" // Вручено покупателю
+	ShipmentStatusCancelled = "cancelled" // Отменено до отгрузки, позиции можно включить в другое отправление
+)
+
+// Shipment - посылка с частью позиций заказа. Заказ может уходить несколькими посылками
+// с разных складов или с разными перевозчиками, статус заказа выводится из статусов его отправлений.
+type Shipment struct {
+	ID               uuid.UUID      `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
+	UserID           uuid.UUID      `gorm:"type:uuid;not null" json:"-"`
+	OrderID          uuid.UUID      `gorm:"type:uuid;not null;index" json:"order_id"`
+	Number           string         `gorm:"type:varchar(120);not null" json:"number"` // Номер заказа и порядковый номер посылки: 100123-2
+	WarehouseID      *uuid.UUID     `gorm:"type:uuid" json:"warehouse_id"`
+	Carrier          string         `gorm:"type:varchar(100)" json:"carrier"`
+	TrackingNumber   string         `gorm:"type:varchar(100)" json:"tracking_number"`
+	Status           string         `gorm:"type:varchar(20);not null;default:'pending'" json:"status"`
+	ShippedAt        *time.Time     `json:"shipped_at"`
+	DeliveredAt      *time.Time     `json:"delivered_at"`
+	TrackingPolledAt *time.Time     `json:"tracking_polled_at"` // Последний опрос перевозчика
+	CreatedAt        time.Time      `json:"created_date"`
+	UpdatedAt        time.Time      `json:"updated_date"`
+	Items            []ShipmentItem `gorm:"foreignKey:ShipmentID" json:"items"`
+}
+
+// ShipmentItem - сколько единиц позиции заказа лежит в посылке
+type ShipmentItem struct {
+	ID          uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
+	ShipmentID  uuid.UUID `gorm:"type:uuid;not null;index" json:"-"`
+	OrderItemID uuid.UUID `gorm:"type:uuid;not null" json:"order_item_id"`
+	Quantity    int       `gorm:"not null" json:"quantity"`
+}
+
+// --- Структуры для запросов/ответов, не являющиеся моделями БД ---
+
+// CreateShipmentRequest - тело запроса на создание отправления
+type CreateShipmentRequest struct {
+	WarehouseID    *uuid.UUID            `json:"warehouse_id"`
+	Carrier        string                `json:"carrier" binding:"max=100"`
+	TrackingNumber string                `json:"tracking_number" binding:"max=100"`
+	Items          []ShipmentItemRequest `json:"items" binding:"required,min=1,dive"`
+}
+
+// ShipmentItemRequest - позиция заказа и количество единиц в посылке
+type ShipmentItemRequest struct {
+	OrderItemID uuid.UUID `json:"order_item_id" binding:"required"`
+	Quantity    int       `json:"quantity" binding:"required,min=1"`
+}
+
+// UpdateShipmentRequest - тело запроса на изменение перевозчика и трек-номера
+type UpdateShipmentRequest struct {
+	Carrier        *string `json:"carrier" binding:"omitempty,max=100"`
+	TrackingNumber *string `json:"tracking_number" binding:"omitempty,max=100"`
+}
+
+// UpdateShipmentStatusRequest - тело запроса на смену статуса отправления
+type UpdateShipmentStatusRequest struct {
+	Status  string `json:"status" binding:"required,oneof=shipped delivered cancelled"`
+	Comment string `json:"comment"`
+}
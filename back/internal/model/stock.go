@@ -0,0 +1,85 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Параметры расчета точки заказа.
+// Скорость продаж варианта считается за последние SalesWindowDays дней.
+// Точка заказа = скорость × (срок поставки + страховой запас), рекомендуемый заказ
+// доводит остаток до точки заказа плюс запас еще на RestockCoverDays дней продаж.
+const (
+	DefaultLeadTimeDays = 14
+	SafetyStockDays     = 7
+	RestockCoverDays    = 30
+)
+
+// Типы оповещений об остатках
+const (
+	StockAlertLow        = "low_stock"    // Доступный остаток не выше точки заказа
+	StockAlertOutOfStock = "out_of_stock" // Доступного остатка нет
+)
+
+// StockAlert - оповещение об остатке варианта.
+// Открытое оповещение (ResolvedAt == nil) у варианта может быть только одно;
+// оно закрывается автоматически, когда остаток поднимается выше точки заказа.
+type StockAlert struct {
+	ID             uuid.UUID  `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
+	UserID         uuid.UUID  `gorm:"type:uuid;not null;index" json:"-"`
+	ProductID      uuid.UUID  `gorm:"type:uuid;not null" json:"product_id"`
+	VariantID      uuid.UUID  `gorm:"type:uuid;not null" json:"variant_id"`
+	Type           string     `gorm:"type:varchar(20);not null" json:"type"`
+	Available      int        `gorm:"not null" json:"available"`
+	ReorderPoint   int        `gorm:"not null" json:"reorder_point"`
+	CreatedAt      time.Time  `json:"created_date"`
+	UpdatedAt      time.Time  `json:"updated_date"`
+	AcknowledgedAt *time.Time `json:"acknowledged_at,omitempty"`
+	ResolvedAt     *time.Time `json:"resolved_at,omitempty"`
+
+	// --- Поля, которые не хранятся в БД, а вычисляются ---
+	ProductName string `gorm:"->;-:migration" json:"product_name"`
+	SKU         string `gorm:"column:sku;->;-:migration" json:"sku"`
+	Size        string `gorm:"->;-:migration" json:"size"`
+	Color       string `gorm:"->;-:migration" json:"color"`
+}
+
+// VariantStockLevel - остаток варианта вместе с рассчитанной точкой заказа и рекомендацией
+type VariantStockLevel struct {
+	ProductID          uuid.UUID  `json:"product_id"`
+	ProductName        string     `json:"product_name"`
+	VariantID          uuid.UUID  `json:"variant_id"`
+	SKU                string     `gorm:"column:sku" json:"sku"`
+	Size               string     `json:"size"`
+	Color              string     `json:"color"`
+	Stock              int        `json:"stock"`
+	Reserved           int        `json:"reserved"`
+	Available          int        `json:"available"`
+	UnitsSold          int        `json:"units_sold"`     // Продано за SalesWindowDays дней
+	DailyVelocity      float64    `json:"daily_velocity"` // Штук в день
+	LeadTimeDays       int        `json:"lead_time_days"`
+	ReorderPoint       int        `json:"reorder_point"`
+	ReorderPointManual bool       `json:"reorder_point_manual"`
+	SuggestedQuantity  int        `json:"suggested_quantity"`
+	DaysOfStock        *float64   `json:"days_of_stock,omitempty"` // На сколько дней хватит остатка; nil, если продаж не было
+	CostPrice          float64    `json:"cost_price"`
+	SupplierID         *uuid.UUID `json:"supplier_id"`
+	SupplierName       string     `json:"supplier_name,omitempty"`
+}
+
+// RestockGroup - рекомендации к заказу у одного поставщика
+type RestockGroup struct {
+	SupplierID   *uuid.UUID          `json:"supplier_id"` // nil - товары без поставщика
+	SupplierName string              `json:"supplier_name"`
+	Items        []VariantStockLevel `json:"items"`
+	TotalUnits   int                 `json:"total_units"`
+	TotalCost    float64             `json:"total_cost"` // По себестоимости
+}
+
+// UpdateReorderPointRequest - ручная настройка точки заказа варианта.
+// null в поле возвращает автоматический расчет.
+type UpdateReorderPointRequest struct {
+	ReorderPoint *int `json:"reorder_point" binding:"omitempty,min=0"`
+	LeadTimeDays *int `json:"lead_time_days" binding:"omitempty,min=0"`
+}
@@ -0,0 +1,68 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Supplier представляет поставщика продавца.
+type Supplier struct {
+	ID           uuid.UUID  `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
+	UserID       *uuid.UUID `gorm:"type:uuid;index" json:"-"` // ID продавца, которому принадлежит поставщик
+	Name         string     `gorm:"type:varchar(255);not null" json:"name"`
+	Contact      string     `gorm:"type:varchar(255)" json:"contact"`
+	Email        string     `gorm:"type:varchar(255)" json:"email"`
+	Phone        string     `gorm:"type:varchar(50)" json:"phone"`
+	Notes        string     `gorm:"type:text" json:"notes"`
+	LeadTimeDays int        `gorm:"not null;default:14" json:"lead_time_days"` // Срок поставки в днях, используется для точки заказа
+	CreatedAt    time.Time  `json:"created_date"`
+	UpdatedAt    time.Time  `json:"updated_date"`
+
+	// --- Поля, которые не хранятся в БД, а вычисляются ---
+	ProductsCount int64 `gorm:"->;-:migration" json:"products_count"`
+}
+
+// --- Структуры для запросов/ответов, не являющиеся моделями БД ---
+
+// SupplierRequest представляет тело запроса на создание/изменение поставщика
+type SupplierRequest struct {
+	Name         string `json:"name" binding:"required"`
+	Contact      string `json:"contact"`
+	Email        string `json:"email" binding:"omitempty,email"`
+	Phone        string `json:"phone"`
+	Notes        string `json:"notes"`
+	LeadTimeDays *int   `json:"lead_time_days" binding:"omitempty,min=0"`
+}
+
+// AssignSupplierRequest представляет тело запроса на привязку товаров к поставщику
+type AssignSupplierRequest struct {
+	ProductIDs []uuid.UUID `json:"product_ids" binding:"required,min=1"`
+}
+
+// SupplierReportRequestParams содержит параметры запроса отчета по поставщикам.
+type SupplierReportRequestParams struct {
+	Period string `form:"period"` // 7d, 30d, 90d, 1y, all_time
+}
+
+// SupplierReport - показатели одного поставщика за период
+type SupplierReport struct {
+	SupplierID     uuid.UUID `json:"supplier_id"`
+	Name           string    `json:"name"`
+	ProductsCount  int64     `json:"products_count"`
+	SKUCount       int64     `json:"sku_count"`        // Количество вариантов (SKU)
+	StockUnits     int64     `json:"stock_units"`      // Остаток в штуках
+	StockValueCost float64   `json:"stock_value_cost"` // Стоимость остатка по себестоимости
+	UnitsSold      int64     `json:"units_sold"`
+	Revenue        float64   `json:"revenue"`
+	Profit         float64   `json:"profit"`
+	MarginPercent  float64   `json:"margin_percent"`
+	UnitsReturned  int64     `json:"units_returned"`
+	ReturnRate     float64   `json:"return_rate"`
+}
+
+// SupplierReportResponse — полная структура ответа отчета по поставщикам.
+type SupplierReportResponse struct {
+	Period    string           `json:"period"`
+	Suppliers []SupplierReport `json:"suppliers"`
+}
@@ -0,0 +1,45 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Статусы событий отслеживания, к которым адаптеры перевозчиков приводят свои коды
+const (
+	TrackingStatusCreated        = "created"          // Перевозчик зарегистрировал отправление
+	TrackingStatusAccepted       = "accepted"         // Посылка принята от продавца
+	TrackingStatusInTransit      = "in_transit"       // В пути
+	TrackingStatusOutForDelivery = "out_for_delivery" // Передана курьеру
+	TrackingStatusDelivered      = "delivered"        // Вручена покупателю
+	TrackingStatusReturning      = "returning"        // Возвращается продавцу
+	TrackingStatusCancelled      = "cancelled"        // Отменена у перевозчика
+	TrackingStatusException      = "exception"        // Задержка или проблема с доставкой
+)
+
+// TrackingEvent - событие отслеживания посылки, полученное от перевозчика
+type TrackingEvent struct {
+	ID          uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
+	UserID      uuid.UUID `gorm:"type:uuid;not null" json:"-"`
+	OrderID     uuid.UUID `gorm:"type:uuid;not null;index" json:"order_id"`
+	ShipmentID  uuid.UUID `gorm:"type:uuid;not null" json:"shipment_id"`
+	ExternalID  string    `gorm:"type:varchar(100);not null" json:"external_id"` // ID события у перевозчика, повторы не сохраняются
+	Status      string    `gorm:"type:varchar(30);not null" json:"status"`
+	Description string    `gorm:"type:text" json:"description"`
+	Location    string    `gorm:"type:varchar(255)" json:"location"`
+	OccurredAt  time.Time `gorm:"not null" json:"occurred_at"`
+	CreatedAt   time.Time `json:"created_date"`
+}
+
+// TrackingShipmentStatus возвращает статус отправления, который следует из события перевозчика,
+// или пустую строку, если событие статус не меняет.
+func TrackingShipmentStatus(eventStatus string) string {
+	switch eventStatus {
+	case TrackingStatusAccepted, TrackingStatusInTransit, TrackingStatusOutForDelivery:
+		return ShipmentStatusShipped
+	case TrackingStatusDelivered:
+		return ShipmentStatusDelivered
+	}
+	return ""
+}
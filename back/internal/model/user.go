@@ -7,6 +7,9 @@ import (
 	"gorm.io/gorm"
 )
 
+// DefaultLowStockThreshold - порог "мало на складе" по умолчанию
+const DefaultLowStockThreshold = 10
+
 type User struct {
 	ID             uuid.UUID `gorm:"type:uuid;primary_key;" json:"id"`
 	Name           string    `gorm:"size:255;not null" json:"name"`
@@ -15,9 +18,20 @@ type User struct {
 	// --- НОВОЕ ПОЛЕ ---
 	// Храним баланс в копейках, чтобы избежать проблем с float.
 	// `not null;default:0` гарантирует, что у новых пользователей баланс будет 0.
-	BalanceKopecks int64     `gorm:"not null;default:0" json:"balance_kopecks"`
-	CreatedAt      time.Time `json:"created_at"`
-	UpdatedAt      time.Time `json:"-"` // Скроем UpdatedAt из JSON для чистоты
+	BalanceKopecks int64 `gorm:"not null;default:0" json:"balance_kopecks"`
+	// Порог остатка, при котором товар считается "мало на складе" (фильтр stock_status=low_stock)
+	LowStockThreshold int `gorm:"not null;default:10" json:"low_stock_threshold"`
+	// Правило выбора склада при резервировании заказов (см. warehouse.go)
+	ReservationRule string `gorm:"type:varchar(20);not null;default:'priority'" json:"reservation_rule"`
+	// Администратор ведет общий справочник категорий. Назначается только в БД
+	IsAdmin bool `gorm:"not null;default:false" json:"is_admin"`
+	// ИНН продавца для документов "Честного ЗНАКа"
+	INN string `gorm:"column:inn;type:varchar(12)" json:"inn"`
+	// Префикс компании GS1 и следующий номер товара в его диапазоне для выпуска EAN-13
+	GS1Prefix  string    `gorm:"column:gs1_prefix;type:varchar(11)" json:"gs1_prefix"`
+	GS1NextRef int64     `gorm:"column:gs1_next_ref;not null;default:0" json:"-"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"-"` // Скроем UpdatedAt из JSON для чистоты
 }
 
 func (user *User) BeforeCreate(tx *gorm.DB) (err error) {
@@ -0,0 +1,134 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Типы складов
+const (
+	WarehouseTypeOwn     = "own"     // Собственный склад продавца
+	WarehouseTypeFBO     = "fbo"     // Склад Lamoda (FBO)
+	WarehouseTypeTransit = "transit" // Товары в пути; с него не резервируется
+)
+
+// Типы движений остатков
+const (
+	StockMovementTransfer   = "transfer"   // Перемещение между складами
+	StockMovementAdjustment = "adjustment" // Корректировка (приемка, инвентаризация, правка из карточки)
+	StockMovementShipment   = "shipment"   // Отгрузка заказа со склада
+	StockMovementInitial    = "initial"    // Начальный остаток варианта на складе
+)
+
+// Правила выбора склада при резервировании заказа
+const (
+	ReservationRulePriority        = "priority"         // Склады по приоритету, позиция может делиться между складами
+	ReservationRuleSingleWarehouse = "single_warehouse" // Склад, где позиция есть целиком; если такого нет - как priority
+	ReservationRuleMostAvailable   = "most_available"   // Сначала склады с наибольшим доступным остатком
+)
+
+// Warehouse - склад продавца
+type Warehouse struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null;index" json:"-"`
+	Name      string    `gorm:"type:varchar(255);not null" json:"name"`
+	Code      *string   `gorm:"type:varchar(50)" json:"code"`
+	Type      string    `gorm:"type:varchar(20);not null" json:"type"`
+	Address   string    `gorm:"type:text" json:"address"`
+	Priority  int       `gorm:"not null;default:0" json:"priority"`
+	IsDefault bool      `gorm:"not null;default:false" json:"is_default"` // Сюда попадают остатки, заданные в карточке товара
+	IsActive  bool      `gorm:"not null;default:true" json:"is_active"`
+	CreatedAt time.Time `json:"created_date"`
+	UpdatedAt time.Time `json:"updated_date"`
+
+	// --- Поля, которые не хранятся в БД, а вычисляются ---
+	StockUnits    int64 `gorm:"->;-:migration" json:"stock_units"`
+	ReservedUnits int64 `gorm:"->;-:migration" json:"reserved_units"`
+}
+
+// Reservable сообщает, можно ли резервировать заказы с этого склада.
+func (w *Warehouse) Reservable() bool {
+	return w.IsActive && w.Type != WarehouseTypeTransit
+}
+
+// WarehouseStock - остаток варианта на складе
+type WarehouseStock struct {
+	WarehouseID uuid.UUID `gorm:"type:uuid;primaryKey" json:"warehouse_id"`
+	VariantID   uuid.UUID `gorm:"type:uuid;primaryKey" json:"variant_id"`
+	Stock       int       `gorm:"not null;default:0" json:"stock"`
+	Reserved    int       `gorm:"not null;default:0" json:"reserved"`
+	UpdatedAt   time.Time `json:"updated_date"`
+
+	// --- Поля, которые не хранятся в БД, а вычисляются ---
+	WarehouseName string    `gorm:"->;-:migration" json:"warehouse_name,omitempty"`
+	ProductID     uuid.UUID `gorm:"->;-:migration" json:"product_id"`
+	ProductName   string    `gorm:"->;-:migration" json:"product_name,omitempty"`
+	SKU           string    `gorm:"column:sku;->;-:migration" json:"sku,omitempty"`
+	Size          string    `gorm:"->;-:migration" json:"size,omitempty"`
+	Color         string    `gorm:"->;-:migration" json:"color,omitempty"`
+}
+
+// StockMovement - движение остатка варианта между складами или со склада/на склад
+type StockMovement struct {
+	ID              uuid.UUID  `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
+	UserID          uuid.UUID  `gorm:"type:uuid;not null" json:"-"`
+	VariantID       uuid.UUID  `gorm:"type:uuid;not null" json:"variant_id"`
+	FromWarehouseID *uuid.UUID `gorm:"type:uuid" json:"from_warehouse_id"`
+	ToWarehouseID   *uuid.UUID `gorm:"type:uuid" json:"to_warehouse_id"`
+	Quantity        int        `gorm:"not null" json:"quantity"`
+	Type            string     `gorm:"type:varchar(20);not null" json:"type"`
+	OrderID         *uuid.UUID `gorm:"type:uuid" json:"order_id,omitempty"`
+	Comment         string     `gorm:"type:text" json:"comment,omitempty"`
+	CreatedBy       *uuid.UUID `gorm:"type:uuid" json:"created_by,omitempty"`
+	CreatedAt       time.Time  `json:"created_date"`
+
+	// --- Поля, которые не хранятся в БД, а вычисляются ---
+	SKU               string `gorm:"column:sku;->;-:migration" json:"sku"`
+	FromWarehouseName string `gorm:"->;-:migration" json:"from_warehouse_name,omitempty"`
+	ToWarehouseName   string `gorm:"->;-:migration" json:"to_warehouse_name,omitempty"`
+}
+
+// StockReservation - часть позиции заказа, зарезервированная на складе
+type StockReservation struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
+	OrderID     uuid.UUID  `gorm:"type:uuid;not null;index" json:"order_id"`
+	OrderItemID uuid.UUID  `gorm:"type:uuid;not null" json:"order_item_id"`
+	VariantID   uuid.UUID  `gorm:"type:uuid;not null" json:"variant_id"`
+	WarehouseID *uuid.UUID `gorm:"type:uuid" json:"warehouse_id"` // nil - остаток варианта еще не разнесен по складам
+	Quantity    int        `gorm:"not null" json:"quantity"`
+	CreatedAt   time.Time  `json:"created_date"`
+}
+
+// --- Структуры для запросов/ответов, не являющиеся моделями БД ---
+
+// WarehouseRequest - тело запроса на создание/изменение склада
+type WarehouseRequest struct {
+	Name      string  `json:"name" binding:"required,max=255"`
+	Code      *string `json:"code" binding:"omitempty,max=50"`
+	Type      string  `json:"type" binding:"required,oneof=own fbo transit"`
+	Address   string  `json:"address"`
+	Priority  int     `json:"priority"`
+	IsDefault bool    `json:"is_default"`
+	IsActive  *bool   `json:"is_active"` // По умолчанию склад активен
+}
+
+// AdjustStockRequest - корректировка остатка варианта на складе.
+// Задается либо новый остаток (Stock), либо изменение (Delta).
+type AdjustStockRequest struct {
+	VariantID uuid.UUID `json:"variant_id" binding:"required"`
+	Stock     *int      `json:"stock" binding:"omitempty,min=0"`
+	Delta     *int      `json:"delta"`
+	Comment   string    `json:"comment"`
+}
+
+// TransferStockRequest - перемещение остатка между складами
+type TransferStockRequest struct {
+	FromWarehouseID uuid.UUID `json:"from_warehouse_id" binding:"required"`
+	ToWarehouseID   uuid.UUID `json:"to_warehouse_id" binding:"required"`
+	Items           []struct {
+		VariantID uuid.UUID `json:"variant_id" binding:"required"`
+		Quantity  int       `json:"quantity" binding:"required,min=1"`
+	} `json:"items" binding:"required,min=1,dive"`
+	Comment string `json:"comment"`
+}
@@ -0,0 +1,126 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// Типы событий вебхуков. События пишутся в webhook_events триггерами БД (миграция 22)
+const (
+	WebhookEventOrderCreated       = "order.created"
+	WebhookEventOrderStatusChanged = "order.status_changed"
+	WebhookEventProductUpdated     = "product.updated"
+	WebhookEventStockLow           = "stock.low"
+	WebhookEventReturnCreated      = "return.created"
+)
+
+// WebhookEventTypes - все типы событий, на которые можно подписаться
+var WebhookEventTypes = []string{
+	WebhookEventOrderCreated,
+	WebhookEventOrderStatusChanged,
+	WebhookEventProductUpdated,
+	WebhookEventStockLow,
+	WebhookEventReturnCreated,
+}
+
+// Статусы доставки вебхука
+const (
+	WebhookDeliveryPending   = "pending"   // Ждет первой или повторной попытки
+	WebhookDeliverySucceeded = "succeeded" // Адрес ответил 2xx
+	WebhookDeliveryFailed    = "failed"    // Попытки исчерпаны, можно отправить повторно вручную
+)
+
+// WebhookEndpoint - адрес продавца, на который отправляются события.
+// Пустой список EventTypes означает подписку на все события.
+type WebhookEndpoint struct {
+	ID          uuid.UUID      `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
+	UserID      uuid.UUID      `gorm:"type:uuid;not null;index" json:"-"`
+	URL         string         `gorm:"type:varchar(2048);not null" json:"url"`
+	Secret      string         `gorm:"type:varchar(100);not null" json:"-"` // Ключ подписи HMAC-SHA256, показывается только при создании
+	EventTypes  pq.StringArray `gorm:"type:text[]" json:"event_types"`
+	Description string         `gorm:"type:varchar(255)" json:"description"`
+	IsActive    bool           `gorm:"not null;default:true" json:"is_active"`
+	CreatedAt   time.Time      `json:"created_date"`
+	UpdatedAt   time.Time      `json:"updated_date"`
+}
+
+// Subscribed сообщает, подписан ли адрес на события типа eventType.
+func (e *WebhookEndpoint) Subscribed(eventType string) bool {
+	if len(e.EventTypes) == 0 {
+		return true
+	}
+	for _, t := range e.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookEvent - событие в исходящей очереди (outbox). Записывается в одной транзакции
+// с изменением заказа, товара, остатка или возврата; DispatchedAt заполняется,
+// когда по событию созданы доставки на адреса продавца. Те же события уходят в поток SSE.
+type WebhookEvent struct {
+	ID           uuid.UUID       `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
+	Seq          int64           `gorm:"->" json:"seq"`           // Порядковый номер; по нему клиент потока SSE узнает повторы
+	TxID         int64           `gorm:"->;column:txid" json:"-"` // Номер транзакции, записавшей событие; по нему продолжается поток
+	UserID       uuid.UUID       `gorm:"type:uuid;not null" json:"-"`
+	Type         string          `gorm:"type:varchar(50);not null" json:"type"`
+	Payload      json.RawMessage `gorm:"type:jsonb;not null" json:"payload"`
+	CreatedAt    time.Time       `json:"created_date"`
+	DispatchedAt *time.Time      `json:"dispatched_at"`
+}
+
+// WebhookDelivery - доставка события на адрес: число попыток, ответ последней попытки
+// и время следующей. Повторы идут с экспоненциальной задержкой (см. internal/webhook).
+type WebhookDelivery struct {
+	ID             uuid.UUID        `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
+	UserID         uuid.UUID        `gorm:"type:uuid;not null" json:"-"`
+	EndpointID     uuid.UUID        `gorm:"type:uuid;not null" json:"endpoint_id"`
+	EventID        uuid.UUID        `gorm:"type:uuid;not null" json:"event_id"`
+	Status         string           `gorm:"type:varchar(20);not null;default:'pending'" json:"status"`
+	Attempts       int              `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt  time.Time        `json:"next_attempt_at"`
+	LastAttemptAt  *time.Time       `json:"last_attempt_at"`
+	ResponseStatus *int             `json:"response_status"`
+	ResponseBody   string           `gorm:"type:text" json:"response_body"`
+	Error          string           `gorm:"type:text" json:"error"`
+	CreatedAt      time.Time        `json:"created_date"`
+	UpdatedAt      time.Time        `json:"updated_date"`
+	Event          *WebhookEvent    `gorm:"foreignKey:EventID" json:"event,omitempty"`
+	Endpoint       *WebhookEndpoint `gorm:"foreignKey:EndpointID" json:"endpoint,omitempty"`
+}
+
+// --- Структуры для запросов/ответов, не являющиеся моделями БД ---
+
+// CreateWebhookEndpointRequest - тело запроса на подключение адреса
+type CreateWebhookEndpointRequest struct {
+	URL         string   `json:"url" binding:"required,url,max=2048"`
+	EventTypes  []string `json:"event_types" binding:"omitempty,dive,oneof=order.created order.status_changed product.updated stock.low return.created"`
+	Description string   `json:"description" binding:"max=255"`
+}
+
+// UpdateWebhookEndpointRequest - тело запроса на изменение адреса. RotateSecret выпускает новый ключ подписи
+type UpdateWebhookEndpointRequest struct {
+	URL          *string   `json:"url" binding:"omitempty,url,max=2048"`
+	EventTypes   *[]string `json:"event_types" binding:"omitempty,dive,oneof=order.created order.status_changed product.updated stock.low return.created"`
+	Description  *string   `json:"description" binding:"omitempty,max=255"`
+	IsActive     *bool     `json:"is_active"`
+	RotateSecret bool      `json:"rotate_secret"`
+}
+
+// WebhookEndpointWithSecret - адрес с ключом подписи, отдается при создании и смене ключа
+type WebhookEndpointWithSecret struct {
+	WebhookEndpoint
+	Secret string `json:"secret"`
+}
+
+// WebhookDeliveryFilter - фильтры журнала доставок
+type WebhookDeliveryFilter struct {
+	EndpointID *uuid.UUID
+	EventType  string
+	Status     string
+}
@@ -0,0 +1,345 @@
+package render
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/lamoda-seller-app/internal/barcode"
+)
+
+// PageA4 - размер страницы документов
+var PageA4 = LabelSize{210, 297}
+
+const (
+	docMargin     = 15.0
+	docFooter     = 10.0 // Место под номер страницы
+	docTextSize   = 9.0
+	docLineHeight = docTextSize * mmPerPt * 1.35
+	docCellPad    = 1.2
+)
+
+// OrderDocument - данные упаковочного листа или счета по заказу
+type OrderDocument struct {
+	Number      string // Номер документа
+	Date        string // Дата выдачи
+	SellerName  string
+	SellerINN   string
+	OrderNumber string
+	OrderDate   string
+	Customer    string
+	Phone       string
+	Email       string
+	Address     string
+	Delivery    string
+	Tracking    string
+	Payment     string
+	Notes       string
+	Lines       []DocumentLine
+	Subtotal    float64
+	Discount    float64
+	Shipping    float64
+	Total       float64
+	Currency    string
+}
+
+// DocumentLine - товарная позиция документа
+type DocumentLine struct {
+	SKU        string
+	Name       string
+	Attributes string
+	Quantity   int
+	Price      float64
+	Discount   float64
+	Total      float64
+}
+
+// BuildPackingSlip строит упаковочный лист: состав заказа для сборщика с отметками о сборке.
+func BuildPackingSlip(d OrderDocument) ([]Page, error) {
+	b := newDocBuilder(fmt.Sprintf("Упаковочный лист № %s", d.Number))
+	if err := b.header(fmt.Sprintf("Упаковочный лист № %s от %s", d.Number, d.Date), d.OrderNumber); err != nil {
+		return nil, err
+	}
+
+	b.fields([][2]string{
+		{"Заказ", fmt.Sprintf("№ %s от %s", d.OrderNumber, d.OrderDate)},
+		{"Продавец", d.SellerName},
+		{"Получатель", joinFields(", ", d.Customer, d.Phone)},
+		{"Адрес", d.Address},
+		{"Доставка", d.Delivery},
+		{"Трек-номер", d.Tracking},
+	})
+
+	columns := []docColumn{
+		{"№", 8, AlignCenter},
+		{"Артикул", 34, AlignLeft},
+		{"Наименование", 72, AlignLeft},
+		{"Цвет, размер", 32, AlignLeft},
+		{"Кол-во", 16, AlignRight},
+		{"Собрано", 18, AlignCenter},
+	}
+	rows := make([][]string, len(d.Lines))
+	units := 0
+	for i, line := range d.Lines {
+		rows[i] = []string{strconv.Itoa(i + 1), line.SKU, line.Name, line.Attributes, strconv.Itoa(line.Quantity), ""}
+		units += line.Quantity
+	}
+	b.table(columns, rows)
+
+	b.gap(2)
+	b.paragraph(fmt.Sprintf("Всего позиций: %d, единиц товара: %d", len(d.Lines), units), true)
+	if d.Notes != "" {
+		b.paragraph("Комментарий к заказу: "+d.Notes, false)
+	}
+	b.gap(10)
+	b.ensure(docLineHeight)
+	b.page.Add(Text{X: docMargin, Y: b.y, Size: docTextSize, Value: "Собрал: ______________________"})
+	b.page.Add(Text{X: docMargin + 95, Y: b.y, Size: docTextSize, Value: "Проверил: ______________________"})
+	return b.finish(), nil
+}
+
+// BuildInvoice строит счет покупателю с суммами по позициям, итогами и суммой прописью.
+func BuildInvoice(d OrderDocument) ([]Page, error) {
+	b := newDocBuilder(fmt.Sprintf("Счет № %s", d.Number))
+	if err := b.header(fmt.Sprintf("Счет № %s от %s", d.Number, d.Date), d.OrderNumber); err != nil {
+		return nil, err
+	}
+
+	seller := d.SellerName
+	if d.SellerINN != "" {
+		seller += ", ИНН " + d.SellerINN
+	}
+	b.fields([][2]string{
+		{"Продавец", seller},
+		{"Покупатель", joinFields(", ", d.Customer, d.Email, d.Phone)},
+		{"Основание", fmt.Sprintf("Заказ № %s от %s", d.OrderNumber, d.OrderDate)},
+		{"Адрес доставки", d.Address},
+		{"Оплата", d.Payment},
+	})
+
+	columns := []docColumn{
+		{"№", 8, AlignCenter},
+		{"Наименование", 60, AlignLeft},
+		{"Артикул", 30, AlignLeft},
+		{"Кол-во", 16, AlignRight},
+		{"Цена", 22, AlignRight},
+		{"Скидка", 20, AlignRight},
+		{"Сумма", 24, AlignRight},
+	}
+	rows := make([][]string, len(d.Lines))
+	for i, line := range d.Lines {
+		name := line.Name
+		if line.Attributes != "" {
+			name += " (" + line.Attributes + ")"
+		}
+		rows[i] = []string{
+			strconv.Itoa(i + 1), name, line.SKU, strconv.Itoa(line.Quantity),
+			FormatMoney(line.Price, d.Currency, true), FormatMoney(line.Discount, d.Currency, true), FormatMoney(line.Total, d.Currency, true),
+		}
+	}
+	b.table(columns, rows)
+
+	b.gap(2)
+	totals := [][2]string{
+		{"Сумма товаров:", FormatMoney(d.Subtotal, d.Currency, true)},
+		{"Скидка:", FormatMoney(d.Discount, d.Currency, true)},
+		{"Доставка:", FormatMoney(d.Shipping, d.Currency, true)},
+	}
+	right := PageA4.Width - docMargin
+	for _, t := range totals {
+		b.ensure(docLineHeight)
+		b.y += docLineHeight
+		b.page.Add(Text{X: right - 30, Y: b.y, Size: docTextSize, Align: AlignRight, Value: t[0]})
+		b.page.Add(Text{X: right, Y: b.y, Size: docTextSize, Align: AlignRight, Value: t[1]})
+	}
+	b.ensure(docLineHeight * 1.3)
+	b.y += docLineHeight * 1.3
+	b.page.Add(Text{X: right - 30, Y: b.y, Size: docTextSize + 1, Bold: true, Align: AlignRight, Value: "Итого к оплате:"})
+	b.page.Add(Text{X: right, Y: b.y, Size: docTextSize + 1, Bold: true, Align: AlignRight, Value: FormatMoney(d.Total, d.Currency, true)})
+
+	b.gap(3)
+	b.paragraph(fmt.Sprintf("Всего наименований %d, на сумму %s", len(d.Lines), FormatMoney(d.Total, d.Currency, true)), false)
+	if d.Currency == "" || d.Currency == "RUB" {
+		b.paragraph(AmountInWords(d.Total), true)
+	}
+	return b.finish(), nil
+}
+
+// --- Верстка многостраничных документов ---
+
+type docColumn struct {
+	title string
+	width float64
+	align Align
+}
+
+// docBuilder раскладывает блоки документа по страницам A4 сверху вниз,
+// добавляя новую страницу, когда очередной блок не помещается.
+type docBuilder struct {
+	title string // Заголовок продолжения на следующих страницах
+	pages []*Page
+	page  *Page
+	y     float64
+}
+
+func newDocBuilder(title string) *docBuilder {
+	b := &docBuilder{title: title}
+	b.newPage()
+	return b
+}
+
+func (b *docBuilder) newPage() {
+	b.page = &Page{Width: PageA4.Width, Height: PageA4.Height}
+	b.pages = append(b.pages, b.page)
+	b.y = docMargin
+	if len(b.pages) > 1 {
+		b.y += docLineHeight
+		b.page.Add(Text{X: docMargin, Y: b.y, Size: docTextSize - 1, Value: b.title + " (продолжение)"})
+		b.y += docLineHeight
+	}
+}
+
+// ensure начинает новую страницу, если блок высотой h не помещается на текущую.
+func (b *docBuilder) ensure(h float64) bool {
+	if b.y+h > PageA4.Height-docMargin-docFooter {
+		b.newPage()
+		return true
+	}
+	return false
+}
+
+func (b *docBuilder) gap(h float64) {
+	b.y += h
+}
+
+// header выводит заголовок документа и штрихкод номера заказа справа.
+func (b *docBuilder) header(title, orderNumber string) error {
+	code, err := barcode.EncodeCode128(orderNumber)
+	if err != nil {
+		return err
+	}
+	codeWidth, codeHeight := 55.0, 10.0
+	right := PageA4.Width - docMargin
+	addLinear(b.page, code, right-codeWidth, b.y, codeWidth, codeHeight+3)
+
+	titleSize := 14.0
+	for _, line := range Wrap(title, titleSize, true, PageA4.Width-2*docMargin-codeWidth-5, 2) {
+		b.y += titleSize * mmPerPt * 1.3
+		b.page.Add(Text{X: docMargin, Y: b.y, Size: titleSize, Bold: true, Value: line})
+	}
+	b.y = max(b.y, docMargin+codeHeight+3) + 6
+	return nil
+}
+
+// fields выводит пары "название: значение"; пустые значения пропускаются.
+func (b *docBuilder) fields(pairs [][2]string) {
+	labelWidth := 32.0
+	valueWidth := PageA4.Width - 2*docMargin - labelWidth
+	for _, pair := range pairs {
+		if pair[1] == "" {
+			continue
+		}
+		lines := Wrap(pair[1], docTextSize, false, valueWidth, 0)
+		b.ensure(float64(len(lines)) * docLineHeight)
+		for i, line := range lines {
+			b.y += docLineHeight
+			if i == 0 {
+				b.page.Add(Text{X: docMargin, Y: b.y, Size: docTextSize, Bold: true, Value: pair[0] + ":"})
+			}
+			b.page.Add(Text{X: docMargin + labelWidth, Y: b.y, Size: docTextSize, Value: line})
+		}
+	}
+	b.gap(4)
+}
+
+// paragraph выводит текст с переносом по словам на всю ширину страницы.
+func (b *docBuilder) paragraph(text string, bold bool) {
+	for _, line := range Wrap(text, docTextSize, bold, PageA4.Width-2*docMargin, 0) {
+		b.ensure(docLineHeight)
+		b.y += docLineHeight
+		b.page.Add(Text{X: docMargin, Y: b.y, Size: docTextSize, Bold: bold, Value: line})
+	}
+}
+
+// table выводит таблицу с рамками. Текст в ячейках переносится по словам,
+// шапка повторяется на каждой странице.
+func (b *docBuilder) table(columns []docColumn, rows [][]string) {
+	header := make([]string, len(columns))
+	for i, col := range columns {
+		header[i] = col.title
+	}
+	b.ensure(2 * (docLineHeight + 2*docCellPad))
+	b.tableRow(columns, header, true)
+	for _, row := range rows {
+		if b.ensure(b.rowHeight(columns, row, false)) {
+			b.tableRow(columns, header, true)
+		}
+		b.tableRow(columns, row, false)
+	}
+}
+
+func (b *docBuilder) cellLines(col docColumn, value string, bold bool) []string {
+	lines := Wrap(value, docTextSize, bold, col.width-2*docCellPad, 0)
+	if len(lines) == 0 {
+		lines = []string{""}
+	}
+	return lines
+}
+
+func (b *docBuilder) rowHeight(columns []docColumn, row []string, bold bool) float64 {
+	maxLines := 1
+	for i, col := range columns {
+		maxLines = max(maxLines, len(b.cellLines(col, row[i], bold)))
+	}
+	return float64(maxLines)*docLineHeight + 2*docCellPad
+}
+
+func (b *docBuilder) tableRow(columns []docColumn, row []string, bold bool) {
+	height := b.rowHeight(columns, row, bold)
+	x := docMargin
+	for i, col := range columns {
+		b.page.Add(Rect{X: x, Y: b.y, W: col.width, H: height, Line: 0.2})
+		tx := x + docCellPad
+		switch col.align {
+		case AlignCenter:
+			tx = x + col.width/2
+		case AlignRight:
+			tx = x + col.width - docCellPad
+		}
+		ty := b.y + docCellPad
+		for _, line := range b.cellLines(col, row[i], bold) {
+			ty += docLineHeight
+			b.page.Add(Text{X: tx, Y: ty - (docLineHeight - docTextSize*mmPerPt), Size: docTextSize, Bold: bold, Align: col.align, Value: line})
+		}
+		x += col.width
+	}
+	b.y += height
+}
+
+// finish добавляет номера страниц и возвращает страницы документа.
+func (b *docBuilder) finish() []Page {
+	pages := make([]Page, len(b.pages))
+	for i, page := range b.pages {
+		if len(b.pages) > 1 {
+			page.Add(Text{
+				X: PageA4.Width - docMargin, Y: PageA4.Height - docMargin, Size: docTextSize - 1, Align: AlignRight,
+				Value: fmt.Sprintf("Стр. %d из %d", i+1, len(b.pages)),
+			})
+		}
+		pages[i] = *page
+	}
+	return pages
+}
+
+func joinFields(sep string, parts ...string) string {
+	result := ""
+	for _, p := range parts {
+		if p == "" {
+			continue
+		}
+		if result != "" {
+			result += sep
+		}
+		result += p
+	}
+	return result
+}
@@ -0,0 +1,505 @@
+package render
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// Font - шрифт TrueType: метрики и контуры глифов для растеризации и встраивания в PDF.
+// Поддерживаются контуры glyf (в том числе составные глифы) и таблица cmap форматов 4 и 12.
+type Font struct {
+	Name       string // PostScript-имя для PDF
+	data       []byte
+	tables     map[string][]byte
+	unitsPerEm int
+	ascent     int
+	descent    int
+	capHeight  int
+	bbox       [4]int
+	numGlyphs  int
+	longLoca   bool
+	advances   []uint16
+	cmap       map[rune]uint16
+}
+
+var errFontFormat = errors.New("unsupported font format")
+
+// ParseFont разбирает файл шрифта TrueType.
+func ParseFont(name string, data []byte) (*Font, error) {
+	if len(data) < 12 {
+		return nil, errFontFormat
+	}
+	f := &Font{Name: name, data: data, tables: make(map[string][]byte)}
+
+	numTables := int(binary.BigEndian.Uint16(data[4:]))
+	for i := 0; i < numTables; i++ {
+		rec := 12 + 16*i
+		if rec+16 > len(data) {
+			return nil, errFontFormat
+		}
+		tag := string(data[rec : rec+4])
+		offset := int(binary.BigEndian.Uint32(data[rec+8:]))
+		length := int(binary.BigEndian.Uint32(data[rec+12:]))
+		if offset+length > len(data) {
+			return nil, fmt.Errorf("font table %q is out of bounds", tag)
+		}
+		f.tables[tag] = data[offset : offset+length]
+	}
+	for _, tag := range []string{"head", "hhea", "maxp", "hmtx", "loca", "glyf", "cmap"} {
+		if _, ok := f.tables[tag]; !ok {
+			return nil, fmt.Errorf("font table %q is missing", tag)
+		}
+	}
+
+	head := f.tables["head"]
+	f.unitsPerEm = int(binary.BigEndian.Uint16(head[18:]))
+	for i := 0; i < 4; i++ {
+		f.bbox[i] = int(int16(binary.BigEndian.Uint16(head[36+2*i:])))
+	}
+	f.longLoca = binary.BigEndian.Uint16(head[50:]) == 1
+
+	hhea := f.tables["hhea"]
+	f.ascent = int(int16(binary.BigEndian.Uint16(hhea[4:])))
+	f.descent = int(int16(binary.BigEndian.Uint16(hhea[6:])))
+	numHMetrics := int(binary.BigEndian.Uint16(hhea[34:]))
+
+	f.numGlyphs = int(binary.BigEndian.Uint16(f.tables["maxp"][4:]))
+	hmtx := f.tables["hmtx"]
+	f.advances = make([]uint16, f.numGlyphs)
+	for i := 0; i < f.numGlyphs; i++ {
+		if i < numHMetrics {
+			f.advances[i] = binary.BigEndian.Uint16(hmtx[4*i:])
+		} else {
+			f.advances[i] = f.advances[numHMetrics-1]
+		}
+	}
+
+	f.capHeight = f.ascent
+	if os2, ok := f.tables["OS/2"]; ok && len(os2) >= 90 && binary.BigEndian.Uint16(os2) >= 2 {
+		f.capHeight = int(int16(binary.BigEndian.Uint16(os2[88:])))
+	}
+
+	if err := f.parseCmap(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *Font) parseCmap() error {
+	cmap := f.tables["cmap"]
+	f.cmap = make(map[rune]uint16)
+
+	var format4, format12 []byte
+	n := int(binary.BigEndian.Uint16(cmap[2:]))
+	for i := 0; i < n; i++ {
+		platform := binary.BigEndian.Uint16(cmap[4+8*i:])
+		encoding := binary.BigEndian.Uint16(cmap[6+8*i:])
+		offset := int(binary.BigEndian.Uint32(cmap[8+8*i:]))
+		if offset >= len(cmap) {
+			continue
+		}
+		sub := cmap[offset:]
+		switch format := binary.BigEndian.Uint16(sub); {
+		case format == 12 && (platform == 3 && encoding == 10 || platform == 0):
+			format12 = sub
+		case format == 4 && (platform == 3 && encoding == 1 || platform == 0):
+			format4 = sub
+		}
+	}
+
+	switch {
+	case format12 != nil:
+		groups := int(binary.BigEndian.Uint32(format12[12:]))
+		for g := 0; g < groups; g++ {
+			rec := format12[16+12*g:]
+			start := binary.BigEndian.Uint32(rec)
+			end := binary.BigEndian.Uint32(rec[4:])
+			glyph := binary.BigEndian.Uint32(rec[8:])
+			for c := start; c <= end && c-start < 0x10000; c++ {
+				f.cmap[rune(c)] = uint16(glyph + c - start)
+			}
+		}
+	case format4 != nil:
+		segX2 := int(binary.BigEndian.Uint16(format4[6:]))
+		ends := format4[14:]
+		starts := format4[16+segX2:]
+		deltas := format4[16+2*segX2:]
+		rangeOffsets := format4[16+3*segX2:]
+		for s := 0; s < segX2/2; s++ {
+			end := binary.BigEndian.Uint16(ends[2*s:])
+			start := binary.BigEndian.Uint16(starts[2*s:])
+			delta := binary.BigEndian.Uint16(deltas[2*s:])
+			rangeOffset := int(binary.BigEndian.Uint16(rangeOffsets[2*s:]))
+			for c := uint32(start); c <= uint32(end) && c != 0xFFFF; c++ {
+				var glyph uint16
+				if rangeOffset == 0 {
+					glyph = uint16(c) + delta
+				} else {
+					pos := 16 + 3*segX2 + 2*s + rangeOffset + 2*int(c-uint32(start))
+					if pos+2 > len(format4) {
+						continue
+					}
+					glyph = binary.BigEndian.Uint16(format4[pos:])
+					if glyph != 0 {
+						glyph += delta
+					}
+				}
+				if glyph != 0 {
+					f.cmap[rune(c)] = glyph
+				}
+			}
+		}
+	default:
+		return errors.New("font has no Unicode cmap")
+	}
+	return nil
+}
+
+// GlyphIndex возвращает номер глифа для символа; 0 - глиф отсутствует.
+func (f *Font) GlyphIndex(r rune) uint16 {
+	return f.cmap[r]
+}
+
+// advance возвращает ширину глифа в единицах шрифта.
+func (f *Font) advance(gid uint16) int {
+	if int(gid) >= len(f.advances) {
+		return 0
+	}
+	return int(f.advances[gid])
+}
+
+// Width возвращает ширину строки при кегле size, в тех же единицах, что и size.
+func (f *Font) Width(text string, size float64) float64 {
+	units := 0
+	for _, r := range text {
+		units += f.advance(f.GlyphIndex(r))
+	}
+	return float64(units) * size / float64(f.unitsPerEm)
+}
+
+func (f *Font) glyphData(gid uint16) []byte {
+	if int(gid) >= f.numGlyphs {
+		return nil
+	}
+	start, end := f.locaRange(int(gid))
+	glyf := f.tables["glyf"]
+	if start >= end || end > len(glyf) {
+		return nil
+	}
+	return glyf[start:end]
+}
+
+func (f *Font) locaRange(gid int) (int, int) {
+	loca := f.tables["loca"]
+	if f.longLoca {
+		return int(binary.BigEndian.Uint32(loca[4*gid:])), int(binary.BigEndian.Uint32(loca[4*gid+4:]))
+	}
+	return 2 * int(binary.BigEndian.Uint16(loca[2*gid:])), 2 * int(binary.BigEndian.Uint16(loca[2*gid+2:]))
+}
+
+// --- Контуры глифов ---
+
+type fontPoint struct {
+	x, y float64
+	on   bool
+}
+
+// Флаги составных глифов
+const (
+	compArgsAreWords = 0x0001
+	compArgsAreXY    = 0x0002
+	compHaveScale    = 0x0008
+	compMore         = 0x0020
+	compHaveXYScale  = 0x0040
+	compHave2x2      = 0x0080
+)
+
+// contours возвращает контуры глифа в единицах шрифта; ось Y направлена вверх.
+func (f *Font) contours(gid uint16, depth int) [][]fontPoint {
+	data := f.glyphData(gid)
+	if len(data) < 10 || depth > 8 {
+		return nil
+	}
+	n := int(int16(binary.BigEndian.Uint16(data)))
+	if n >= 0 {
+		return parseSimpleGlyph(data, n)
+	}
+
+	var result [][]fontPoint
+	p := 10
+	for {
+		if p+4 > len(data) {
+			break
+		}
+		flags := binary.BigEndian.Uint16(data[p:])
+		component := binary.BigEndian.Uint16(data[p+2:])
+		p += 4
+
+		var dx, dy float64
+		if flags&compArgsAreWords != 0 {
+			dx = float64(int16(binary.BigEndian.Uint16(data[p:])))
+			dy = float64(int16(binary.BigEndian.Uint16(data[p+2:])))
+			p += 4
+		} else {
+			dx = float64(int8(data[p]))
+			dy = float64(int8(data[p+1]))
+			p += 2
+		}
+		if flags&compArgsAreXY == 0 {
+			dx, dy = 0, 0 // Привязка по точкам не поддерживается, такие глифы в DejaVu не встречаются
+		}
+
+		a, b, c, d := 1.0, 0.0, 0.0, 1.0
+		f2dot14 := func(at int) float64 { return float64(int16(binary.BigEndian.Uint16(data[at:]))) / 16384 }
+		switch {
+		case flags&compHaveScale != 0:
+			a = f2dot14(p)
+			d = a
+			p += 2
+		case flags&compHaveXYScale != 0:
+			a, d = f2dot14(p), f2dot14(p+2)
+			p += 4
+		case flags&compHave2x2 != 0:
+			a, b, c, d = f2dot14(p), f2dot14(p+2), f2dot14(p+4), f2dot14(p+6)
+			p += 8
+		}
+
+		for _, contour := range f.contours(component, depth+1) {
+			transformed := make([]fontPoint, len(contour))
+			for i, pt := range contour {
+				transformed[i] = fontPoint{x: a*pt.x + c*pt.y + dx, y: b*pt.x + d*pt.y + dy, on: pt.on}
+			}
+			result = append(result, transformed)
+		}
+		if flags&compMore == 0 {
+			break
+		}
+	}
+	return result
+}
+
+func parseSimpleGlyph(data []byte, numContours int) [][]fontPoint {
+	p := 10
+	ends := make([]int, numContours)
+	for i := range ends {
+		ends[i] = int(binary.BigEndian.Uint16(data[p:]))
+		p += 2
+	}
+	if numContours == 0 {
+		return nil
+	}
+	numPoints := ends[numContours-1] + 1
+	p += 2 + int(binary.BigEndian.Uint16(data[p:])) // Инструкции хинтинга пропускаем
+
+	flags := make([]byte, 0, numPoints)
+	for len(flags) < numPoints && p < len(data) {
+		flag := data[p]
+		p++
+		flags = append(flags, flag)
+		if flag&0x08 != 0 && p < len(data) {
+			repeat := int(data[p])
+			p++
+			for i := 0; i < repeat; i++ {
+				flags = append(flags, flag)
+			}
+		}
+	}
+	if len(flags) < numPoints {
+		return nil
+	}
+
+	points := make([]fontPoint, numPoints)
+	readCoords := func(short, same byte, set func(i int, v float64)) bool {
+		v := 0
+		for i := 0; i < numPoints; i++ {
+			flag := flags[i]
+			switch {
+			case flag&short != 0:
+				if p >= len(data) {
+					return false
+				}
+				if flag&same != 0 {
+					v += int(data[p])
+				} else {
+					v -= int(data[p])
+				}
+				p++
+			case flag&same == 0:
+				if p+2 > len(data) {
+					return false
+				}
+				v += int(int16(binary.BigEndian.Uint16(data[p:])))
+				p += 2
+			}
+			set(i, float64(v))
+		}
+		return true
+	}
+	if !readCoords(0x02, 0x10, func(i int, v float64) { points[i].x = v }) ||
+		!readCoords(0x04, 0x20, func(i int, v float64) { points[i].y = v }) {
+		return nil
+	}
+	for i := range points {
+		points[i].on = flags[i]&0x01 != 0
+	}
+
+	contours := make([][]fontPoint, 0, numContours)
+	start := 0
+	for _, end := range ends {
+		if end >= start && end < numPoints {
+			contours = append(contours, points[start:end+1])
+		}
+		start = end + 1
+	}
+	return contours
+}
+
+// components возвращает глифы, из которых состоит составной глиф.
+func (f *Font) components(gid uint16) []uint16 {
+	data := f.glyphData(gid)
+	if len(data) < 10 || int16(binary.BigEndian.Uint16(data)) >= 0 {
+		return nil
+	}
+	var result []uint16
+	p := 10
+	for p+4 <= len(data) {
+		flags := binary.BigEndian.Uint16(data[p:])
+		result = append(result, binary.BigEndian.Uint16(data[p+2:]))
+		p += 4
+		if flags&compArgsAreWords != 0 {
+			p += 4
+		} else {
+			p += 2
+		}
+		switch {
+		case flags&compHaveScale != 0:
+			p += 2
+		case flags&compHaveXYScale != 0:
+			p += 4
+		case flags&compHave2x2 != 0:
+			p += 8
+		}
+		if flags&compMore == 0 {
+			break
+		}
+	}
+	return result
+}
+
+// --- Подмножество шрифта для PDF ---
+
+// subset возвращает файл шрифта, в котором оставлены только контуры нужных глифов.
+// Номера глифов не меняются, поэтому текст в PDF кодируется номерами глифов исходного шрифта.
+func (f *Font) subset(used map[uint16]bool) []byte {
+	keep := map[uint16]bool{0: true}
+	var visit func(gid uint16)
+	visit = func(gid uint16) {
+		if keep[gid] && gid != 0 {
+			return
+		}
+		keep[gid] = true
+		for _, c := range f.components(gid) {
+			visit(c)
+		}
+	}
+	for gid := range used {
+		visit(gid)
+	}
+
+	var glyf []byte
+	loca := make([]byte, 4*(f.numGlyphs+1))
+	for gid := 0; gid < f.numGlyphs; gid++ {
+		binary.BigEndian.PutUint32(loca[4*gid:], uint32(len(glyf)))
+		if keep[uint16(gid)] {
+			glyf = append(glyf, f.glyphData(uint16(gid))...)
+			for len(glyf)%4 != 0 {
+				glyf = append(glyf, 0)
+			}
+		}
+	}
+	binary.BigEndian.PutUint32(loca[4*f.numGlyphs:], uint32(len(glyf)))
+
+	head := append([]byte(nil), f.tables["head"]...)
+	binary.BigEndian.PutUint32(head[8:], 0)  // checkSumAdjustment пересчитывается ниже
+	binary.BigEndian.PutUint16(head[50:], 1) // Длинный формат loca
+
+	tables := map[string][]byte{
+		"head": head,
+		"hhea": f.tables["hhea"],
+		"maxp": f.tables["maxp"],
+		"hmtx": f.tables["hmtx"],
+		"loca": loca,
+		"glyf": glyf,
+	}
+	for _, tag := range []string{"cmap", "cvt ", "fpgm", "prep", "OS/2"} {
+		if t, ok := f.tables[tag]; ok {
+			tables[tag] = t
+		}
+	}
+	out := buildFontFile(tables)
+
+	adjustment := 0xB1B0AFBA - fontChecksum(out)
+	headOffset := fontTableOffset(out, "head")
+	binary.BigEndian.PutUint32(out[headOffset+8:], adjustment)
+	return out
+}
+
+func buildFontFile(tables map[string][]byte) []byte {
+	tags := make([]string, 0, len(tables))
+	for tag := range tables {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	n := len(tags)
+	searchRange, entrySelector := 1, 0
+	for searchRange*2 <= n {
+		searchRange *= 2
+		entrySelector++
+	}
+	searchRange *= 16
+
+	out := make([]byte, 12+16*n)
+	binary.BigEndian.PutUint32(out, 0x00010000)
+	binary.BigEndian.PutUint16(out[4:], uint16(n))
+	binary.BigEndian.PutUint16(out[6:], uint16(searchRange))
+	binary.BigEndian.PutUint16(out[8:], uint16(entrySelector))
+	binary.BigEndian.PutUint16(out[10:], uint16(n*16-searchRange))
+
+	for i, tag := range tags {
+		table := tables[tag]
+		rec := out[12+16*i:]
+		copy(rec, tag)
+		binary.BigEndian.PutUint32(rec[4:], fontChecksum(table))
+		binary.BigEndian.PutUint32(rec[8:], uint32(len(out)))
+		binary.BigEndian.PutUint32(rec[12:], uint32(len(table)))
+		out = append(out, table...)
+		for len(out)%4 != 0 {
+			out = append(out, 0)
+		}
+	}
+	return out
+}
+
+func fontTableOffset(file []byte, tag string) int {
+	n := int(binary.BigEndian.Uint16(file[4:]))
+	for i := 0; i < n; i++ {
+		rec := file[12+16*i:]
+		if string(rec[:4]) == tag {
+			return int(binary.BigEndian.Uint32(rec[8:]))
+		}
+	}
+	return 0
+}
+
+func fontChecksum(data []byte) uint32 {
+	var sum uint32
+	for i := 0; i < len(data); i += 4 {
+		var word [4]byte
+		copy(word[:], data[i:])
+		sum += binary.BigEndian.Uint32(word[:])
+	}
+	return sum
+}
@@ -0,0 +1,307 @@
+package render
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/lamoda-seller-app/internal/barcode"
+)
+
+// LabelSize - размер этикетки в мм
+type LabelSize struct {
+	Width  float64
+	Height float64
+}
+
+var (
+	VariantLabelSize = LabelSize{58, 40}
+	PackingLabelSize = LabelSize{100, 150}
+)
+
+// ParseLabelSize разбирает размер вида "58x40"; пустая строка означает размер по умолчанию.
+func ParseLabelSize(s string, def LabelSize) (LabelSize, error) {
+	if s == "" {
+		return def, nil
+	}
+	parts := strings.Split(strings.ToLower(s), "x")
+	if len(parts) != 2 {
+		return LabelSize{}, fmt.Errorf("invalid label size %q, expected WIDTHxHEIGHT in mm", s)
+	}
+	w, errW := strconv.ParseFloat(parts[0], 64)
+	h, errH := strconv.ParseFloat(parts[1], 64)
+	if errW != nil || errH != nil || w < 20 || h < 15 || w > 210 || h > 297 {
+		return LabelSize{}, fmt.Errorf("invalid label size %q, expected 20x15 to 210x297 mm", s)
+	}
+	return LabelSize{w, h}, nil
+}
+
+// Symbology - вид штрихкода на этикетке товара
+type Symbology string
+
+const (
+	SymbologyAuto       Symbology = ""
+	SymbologyEAN13      Symbology = "ean13"
+	SymbologyCode128    Symbology = "code128"
+	SymbologyDataMatrix Symbology = "datamatrix"
+)
+
+// ParseSymbology проверяет вид штрихкода; пустая строка - выбор по данным варианта.
+func ParseSymbology(s string) (Symbology, error) {
+	switch sym := Symbology(strings.ToLower(s)); sym {
+	case SymbologyAuto, SymbologyEAN13, SymbologyCode128, SymbologyDataMatrix:
+		return sym, nil
+	}
+	return "", fmt.Errorf("unsupported symbology %q, expected ean13, code128 or datamatrix", s)
+}
+
+var (
+	ErrNoBarcodeData = errors.New("variant has neither GTIN nor SKU")
+	ErrNotEAN13      = errors.New("variant GTIN cannot be printed as EAN-13")
+)
+
+const labelMargin = 2.0
+
+// VariantLabel - данные этикетки товара
+type VariantLabel struct {
+	Title      string // Бренд и название
+	Attributes string // Цвет, размер
+	SKU        string
+	GTIN       string // 14 цифр или пусто
+	Price      string
+}
+
+// BuildVariantLabel строит этикетку варианта товара. Без явного выбора печатается EAN-13,
+// если GTIN в него помещается, иначе GS1-128 по GTIN или Code 128 по артикулу.
+func BuildVariantLabel(l VariantLabel, size LabelSize, symbology Symbology) (Page, error) {
+	if symbology == SymbologyAuto {
+		symbology = SymbologyCode128
+		if _, ok := barcode.EAN13(l.GTIN); ok {
+			symbology = SymbologyEAN13
+		}
+	}
+
+	page := Page{Width: size.Width, Height: size.Height}
+	m := labelMargin
+	inner := size.Width - 2*m
+	titleSize := clamp(size.Height*0.2, 6, 11)
+	textSize := titleSize - 1
+
+	y := m + titleSize*mmPerPt
+	for _, line := range Wrap(l.Title, titleSize, true, inner, 2) {
+		page.Add(Text{X: m, Y: y, Size: titleSize, Bold: true, Value: line})
+		y += titleSize * mmPerPt * 1.2
+	}
+	if l.Attributes != "" {
+		page.Add(Text{X: m, Y: y, Size: textSize, Value: Fit(l.Attributes, textSize, false, inner)})
+		y += textSize * mmPerPt * 1.2
+	}
+
+	// Нижняя строка: артикул и цена
+	bottom := size.Height - m
+	footer := fmt.Sprintf("Арт. %s", l.SKU)
+	if l.SKU == "" {
+		footer = ""
+	}
+	if l.Price != "" {
+		page.Add(Text{X: size.Width - m, Y: bottom, Size: titleSize, Bold: true, Align: AlignRight, Value: l.Price})
+	}
+	if footer != "" {
+		priceWidth := TextWidth(l.Price, titleSize, true) + 2
+		page.Add(Text{X: m, Y: bottom, Size: textSize, Value: Fit(footer, textSize, false, inner-priceWidth)})
+	}
+	bottom -= titleSize*mmPerPt + 1
+
+	area := bottom - y
+	if area < 8 {
+		return Page{}, errors.New("label is too small for a barcode")
+	}
+
+	switch symbology {
+	case SymbologyEAN13:
+		code, ok := barcode.EAN13(l.GTIN)
+		if !ok {
+			return Page{}, ErrNotEAN13
+		}
+		bars, err := barcode.EncodeEAN13(code)
+		if err != nil {
+			return Page{}, err
+		}
+		addLinear(&page, bars, m, y, inner, area)
+	case SymbologyCode128:
+		var bars *barcode.Linear
+		var err error
+		switch {
+		case l.GTIN != "":
+			bars, err = barcode.EncodeGS1128("01" + l.GTIN)
+			if err == nil {
+				bars.Text = "(01)" + l.GTIN
+			}
+		case l.SKU != "":
+			bars, err = barcode.EncodeCode128(l.SKU)
+		default:
+			return Page{}, ErrNoBarcodeData
+		}
+		if err != nil {
+			return Page{}, err
+		}
+		addLinear(&page, bars, m, y, inner, area)
+	case SymbologyDataMatrix:
+		var matrix *barcode.Matrix
+		var err error
+		caption := l.SKU
+		switch {
+		case l.GTIN != "":
+			matrix, err = barcode.EncodeGS1DataMatrix("01" + l.GTIN)
+			caption = l.GTIN
+		case l.SKU != "":
+			matrix, err = barcode.EncodeDataMatrix([]byte(l.SKU))
+		default:
+			return Page{}, ErrNoBarcodeData
+		}
+		if err != nil {
+			return Page{}, err
+		}
+		side := math.Min(area, inner/2)
+		page.Add(MatrixCode{X: m, Y: y, Size: side, Code: matrix})
+		page.Add(Text{X: m + side + 2, Y: y + side/2, Size: textSize, Value: Fit(caption, textSize, false, inner-side-2)})
+	}
+	return page, nil
+}
+
+// addLinear размещает одномерный штрихкод с подписью по центру области, сохраняя свободные зоны.
+func addLinear(page *Page, code *barcode.Linear, x, y, width, height float64) {
+	textSize := clamp(height*1.2, 5, 9)
+	barsHeight := height - textSize*mmPerPt - 0.5
+	modules := float64(len(code.Modules) + 2*code.Quiet)
+	module := math.Min(width/modules, 0.5)
+	barsWidth := module * float64(len(code.Modules))
+	left := x + (width-barsWidth)/2
+
+	page.Add(Bars{X: left, Y: y, W: barsWidth, H: barsHeight, Code: code})
+	page.Add(Text{X: x + width/2, Y: y + height, Size: textSize, Align: AlignCenter, Value: Fit(code.Text, textSize, false, width)})
+}
+
+// MarkingLabel - этикетка с кодом маркировки "Честного ЗНАКа"
+type MarkingLabel struct {
+	Title      string
+	Attributes string
+	Code       string // Полный код маркировки с разделителями GS
+	GTIN       string
+	Serial     string
+}
+
+// BuildMarkingLabel строит этикетку с кодом маркировки в виде GS1 Data Matrix.
+func BuildMarkingLabel(l MarkingLabel, size LabelSize) (Page, error) {
+	matrix, err := barcode.EncodeGS1DataMatrix(l.Code)
+	if err != nil {
+		return Page{}, err
+	}
+
+	page := Page{Width: size.Width, Height: size.Height}
+	m := labelMargin
+	side := math.Min(size.Height-2*m, (size.Width-2*m)*0.55)
+	page.Add(MatrixCode{X: m, Y: m, Size: side, Code: matrix})
+
+	x := m + side + 2
+	width := size.Width - x - m
+	textSize := clamp(size.Height*0.17, 5, 9)
+	y := m + textSize*mmPerPt
+	for _, line := range Wrap(l.Title, textSize, true, width, 3) {
+		page.Add(Text{X: x, Y: y, Size: textSize, Bold: true, Value: line})
+		y += textSize * mmPerPt * 1.2
+	}
+	for _, line := range Wrap(l.Attributes, textSize, false, width, 2) {
+		page.Add(Text{X: x, Y: y, Size: textSize, Value: line})
+		y += textSize * mmPerPt * 1.2
+	}
+
+	small := math.Max(5, textSize-2)
+	bottom := m + side
+	page.Add(Text{X: x, Y: bottom - small*mmPerPt*1.2, Size: small, Value: Fit("(01)"+l.GTIN, small, false, width)})
+	page.Add(Text{X: x, Y: bottom, Size: small, Value: Fit("(21)"+l.Serial, small, false, width)})
+	return page, nil
+}
+
+// PackingLabel - данные упаковочной этикетки заказа
+type PackingLabel struct {
+	Sender      string
+	OrderNumber string
+	Tracking    string
+	Delivery    string
+	Customer    string
+	Phone       string
+	Address     string
+	Items       int
+	Date        string
+}
+
+// BuildPackingLabel строит упаковочную этикетку: номер заказа и трек-номер штрихкодами Code 128,
+// получатель, адрес и состав отправления.
+func BuildPackingLabel(l PackingLabel, size LabelSize) (Page, error) {
+	page := Page{Width: size.Width, Height: size.Height}
+	m := labelMargin * 2
+	inner := size.Width - 2*m
+	line := 0.4
+	page.Add(Rect{X: m / 2, Y: m / 2, W: size.Width - m, H: size.Height - m, Line: line})
+
+	y := m + 9*mmPerPt
+	if l.Sender != "" {
+		page.Add(Text{X: m, Y: y, Size: 9, Value: Fit("Отправитель: "+l.Sender, 9, false, inner)})
+		y += 9 * mmPerPt * 1.4
+	}
+	page.Add(Text{X: m, Y: y + 14*mmPerPt, Size: 14, Bold: true, Value: Fit("Заказ № "+l.OrderNumber, 14, true, inner)})
+	y += 14*mmPerPt*1.4 + 2
+
+	orderCode, err := barcode.EncodeCode128(l.OrderNumber)
+	if err != nil {
+		return Page{}, err
+	}
+	codeHeight := math.Min(22, size.Height*0.15)
+	addLinear(&page, orderCode, m, y, inner, codeHeight)
+	y += codeHeight + 3
+
+	page.Add(Rect{X: m / 2, Y: y, W: size.Width - m, H: line, Fill: true})
+	y += 3 + 9*mmPerPt
+	page.Add(Text{X: m, Y: y, Size: 9, Value: "Получатель"})
+	y += 12 * mmPerPt * 1.3
+	page.Add(Text{X: m, Y: y, Size: 12, Bold: true, Value: Fit(l.Customer, 12, true, inner)})
+	if l.Phone != "" {
+		y += 10 * mmPerPt * 1.4
+		page.Add(Text{X: m, Y: y, Size: 10, Value: Fit(l.Phone, 10, false, inner)})
+	}
+	for _, addr := range Wrap(l.Address, 10, false, inner, 4) {
+		y += 10 * mmPerPt * 1.4
+		page.Add(Text{X: m, Y: y, Size: 10, Value: addr})
+	}
+	y += 4
+
+	page.Add(Rect{X: m / 2, Y: y, W: size.Width - m, H: line, Fill: true})
+	y += 3 + 10*mmPerPt
+	page.Add(Text{X: m, Y: y, Size: 10, Value: fmt.Sprintf("Мест: 1   Товаров: %d", l.Items)})
+	page.Add(Text{X: size.Width - m, Y: y, Size: 10, Align: AlignRight, Value: l.Date})
+	if l.Delivery != "" {
+		y += 10 * mmPerPt * 1.4
+		page.Add(Text{X: m, Y: y, Size: 10, Value: Fit("Доставка: "+l.Delivery, 10, false, inner)})
+	}
+
+	if l.Tracking != "" {
+		trackCode, err := barcode.EncodeCode128(l.Tracking)
+		if err != nil {
+			return Page{}, err
+		}
+		bottom := size.Height - m
+		top := bottom - codeHeight
+		if top > y+9*mmPerPt*1.4+2 {
+			page.Add(Text{X: m, Y: top - 2, Size: 9, Value: "Трек-номер"})
+			addLinear(&page, trackCode, m, top, inner, codeHeight)
+		}
+	}
+	return page, nil
+}
+
+func clamp(v, lo, hi float64) float64 {
+	return math.Max(lo, math.Min(hi, v))
+}
@@ -0,0 +1,139 @@
+package render
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// FormatMoney форматирует сумму с разделением разрядов: "12 990,50 ₽".
+// Без копеек (fraction = false) сумма округляется до целых: "12 991 ₽".
+func FormatMoney(amount float64, currency string, fraction bool) string {
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	kopecks := int64(math.Round(amount * 100))
+	whole := kopecks / 100
+	if !fraction {
+		whole = int64(math.Round(amount))
+	}
+
+	s := sign + groupDigits(whole)
+	if fraction {
+		s += "," + pad2(kopecks%100)
+	}
+	switch currency {
+	case "", "RUB":
+		return s + " ₽"
+	}
+	return s + " " + currency
+}
+
+func groupDigits(n int64) string {
+	digits := strconv.FormatInt(n, 10)
+	var b strings.Builder
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteRune(' ')
+		}
+		b.WriteRune(d)
+	}
+	return b.String()
+}
+
+func pad2(n int64) string {
+	if n < 10 {
+		return "0" + strconv.FormatInt(n, 10)
+	}
+	return strconv.FormatInt(n, 10)
+}
+
+// --- Сумма прописью ---
+
+var (
+	wordsUnitsMale   = []string{"", "один", "два", "три", "четыре", "пять", "шесть", "семь", "восемь", "девять"}
+	wordsUnitsFemale = []string{"", "одна", "две", "три", "четыре", "пять", "шесть", "семь", "восемь", "девять"}
+	wordsTeens       = []string{"десять", "одиннадцать", "двенадцать", "тринадцать", "четырнадцать", "пятнадцать", "шестнадцать", "семнадцать", "восемнадцать", "девятнадцать"}
+	wordsTens        = []string{"", "", "двадцать", "тридцать", "сорок", "пятьдесят", "шестьдесят", "семьдесят", "восемьдесят", "девяносто"}
+	wordsHundreds    = []string{"", "сто", "двести", "триста", "четыреста", "пятьсот", "шестьсот", "семьсот", "восемьсот", "девятьсот"}
+)
+
+// wordsScale - разряды: формы для 1, 2-4 и 5-20 и род числительного
+var wordsScale = []struct {
+	forms  [3]string
+	female bool
+}{
+	{[3]string{"", "", ""}, false},
+	{[3]string{"тысяча", "тысячи", "тысяч"}, true},
+	{[3]string{"миллион", "миллиона", "миллионов"}, false},
+	{[3]string{"миллиард", "миллиарда", "миллиардов"}, false},
+}
+
+// AmountInWords возвращает сумму в рублях прописью, как ее пишут в счетах:
+// "Двенадцать тысяч девятьсот девяносто рублей 50 копеек".
+func AmountInWords(amount float64) string {
+	kopecks := int64(math.Round(math.Abs(amount) * 100))
+	rubles := kopecks / 100
+
+	var parts []string
+	if rubles == 0 {
+		parts = append(parts, "ноль")
+	}
+	for scale := len(wordsScale) - 1; scale >= 0; scale-- {
+		div := int64(math.Pow(1000, float64(scale)))
+		group := int((rubles / div) % 1000)
+		if group == 0 {
+			continue
+		}
+		parts = append(parts, tripletWords(group, wordsScale[scale].female)...)
+		if scale > 0 {
+			parts = append(parts, wordsScale[scale].forms[pluralForm(group)])
+		}
+	}
+	parts = append(parts, [3]string{"рубль", "рубля", "рублей"}[pluralForm(int(rubles%1000))])
+
+	s := strings.Join(parts, " ")
+	s = strings.ToUpper(string([]rune(s)[:1])) + string([]rune(s)[1:])
+	return s + " " + pad2(kopecks%100) + " " + [3]string{"копейка", "копейки", "копеек"}[pluralForm(int(kopecks%100))]
+}
+
+func tripletWords(n int, female bool) []string {
+	var words []string
+	if h := n / 100; h > 0 {
+		words = append(words, wordsHundreds[h])
+	}
+	rest := n % 100
+	switch {
+	case rest >= 10 && rest < 20:
+		words = append(words, wordsTeens[rest-10])
+	default:
+		if t := rest / 10; t > 0 {
+			words = append(words, wordsTens[t])
+		}
+		if u := rest % 10; u > 0 {
+			if female {
+				words = append(words, wordsUnitsFemale[u])
+			} else {
+				words = append(words, wordsUnitsMale[u])
+			}
+		}
+	}
+	return words
+}
+
+// pluralForm возвращает форму существительного после числа: 0 - "рубль", 1 - "рубля", 2 - "рублей".
+func pluralForm(n int) int {
+	n %= 100
+	if n >= 11 && n <= 19 {
+		return 2
+	}
+	switch n % 10 {
+	case 1:
+		return 0
+	case 2, 3, 4:
+		return 1
+	}
+	return 2
+}
@@ -0,0 +1,43 @@
+package render
+
+import "testing"
+
+func TestFormatMoney(t *testing.T) {
+	tests := []struct {
+		amount   float64
+		currency string
+		fraction bool
+		want     string
+	}{
+		{12990.5, "RUB", true, "12 990,50 ₽"},
+		{12990.5, "", false, "12 991 ₽"},
+		{1234567.891, "RUB", true, "1 234 567,89 ₽"},
+		{-999.99, "", true, "-999,99 ₽"},
+		{0, "USD", true, "0,00 USD"},
+	}
+	for _, tt := range tests {
+		if got := FormatMoney(tt.amount, tt.currency, tt.fraction); got != tt.want {
+			t.Errorf("FormatMoney(%v, %q, %v) = %q, want %q", tt.amount, tt.currency, tt.fraction, got, tt.want)
+		}
+	}
+}
+
+func TestAmountInWords(t *testing.T) {
+	tests := []struct {
+		amount float64
+		want   string
+	}{
+		{12990.5, "Двенадцать тысяч девятьсот девяносто рублей 50 копеек"},
+		{1001.01, "Одна тысяча один рубль 01 копейка"},
+		{2, "Два рубля 00 копеек"},
+		{0.22, "Ноль рублей 22 копейки"},
+		{111, "Сто одиннадцать рублей 00 копеек"},
+		{21000000, "Двадцать один миллион рублей 00 копеек"},
+		{2002000, "Два миллиона две тысячи рублей 00 копеек"},
+	}
+	for _, tt := range tests {
+		if got := AmountInWords(tt.amount); got != tt.want {
+			t.Errorf("AmountInWords(%v) = %q, want %q", tt.amount, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package render
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// WritePDF выводит страницы в PDF. Используемые глифы шрифтов встраиваются подмножеством
+// (CIDFontType2, Identity-H), поэтому текст на любом языке отображается без шрифтов в системе.
+func WritePDF(w io.Writer, pages []Page) error {
+	doc := &pdfDocument{}
+	fonts := []*pdfFont{{font: fontFor(false), name: "F1"}, {font: fontFor(true), name: "F2"}}
+	for _, f := range fonts {
+		f.used = make(map[uint16]rune)
+	}
+
+	doc.reserve() // 1 - каталог
+	doc.reserve() // 2 - дерево страниц
+
+	var contents []int
+	for _, page := range pages {
+		stream := pageContent(page, fonts)
+		contents = append(contents, doc.addStream("", stream))
+	}
+
+	resources := "<< /Font <<"
+	for _, f := range fonts {
+		if len(f.used) > 0 {
+			resources += fmt.Sprintf(" /%s %d 0 R", f.name, doc.addFont(f))
+		}
+	}
+	resources += " >> >>"
+
+	var kids []string
+	for i, page := range pages {
+		ref := doc.add(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Resources %s /Contents %d 0 R >>",
+			pdfNum(page.Width/mmPerPt), pdfNum(page.Height/mmPerPt), resources, contents[i]))
+		kids = append(kids, fmt.Sprintf("%d 0 R", ref))
+	}
+
+	doc.set(1, "<< /Type /Catalog /Pages 2 0 R >>")
+	doc.set(2, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+
+	_, err := w.Write(doc.bytes())
+	return err
+}
+
+type pdfFont struct {
+	font *Font
+	name string
+	used map[uint16]rune
+}
+
+type pdfDocument struct {
+	objects [][]byte
+}
+
+func (d *pdfDocument) reserve() int {
+	d.objects = append(d.objects, nil)
+	return len(d.objects)
+}
+
+func (d *pdfDocument) set(ref int, body string) {
+	d.objects[ref-1] = []byte(body)
+}
+
+func (d *pdfDocument) add(body string) int {
+	d.objects = append(d.objects, []byte(body))
+	return len(d.objects)
+}
+
+// addStream добавляет сжатый поток; extra - дополнительные ключи словаря потока.
+func (d *pdfDocument) addStream(extra string, data []byte) int {
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	zw.Write(data)
+	zw.Close()
+
+	var obj bytes.Buffer
+	fmt.Fprintf(&obj, "<< /Length %d /Filter /FlateDecode%s >>\nstream\n", buf.Len(), extra)
+	obj.Write(buf.Bytes())
+	obj.WriteString("\nendstream")
+	d.objects = append(d.objects, obj.Bytes())
+	return len(d.objects)
+}
+
+func (d *pdfDocument) addFont(f *pdfFont) int {
+	font := f.font
+	scale := func(v int) int { return v * 1000 / font.unitsPerEm }
+
+	gids := make([]int, 0, len(f.used))
+	for gid := range f.used {
+		gids = append(gids, int(gid))
+	}
+	sort.Ints(gids)
+
+	used := make(map[uint16]bool, len(gids))
+	for _, gid := range gids {
+		used[uint16(gid)] = true
+	}
+	file := font.subset(used)
+	fileRef := d.addStream(fmt.Sprintf(" /Length1 %d", len(file)), file)
+
+	// Тег подмножества из шести заглавных букв, как требует спецификация
+	tag := []byte("AAAAAA")
+	for i, gid := range gids {
+		tag[i%6] = byte('A' + (int(tag[i%6]-'A')+gid)%26)
+	}
+	baseFont := string(tag) + "+" + font.Name
+
+	descriptor := d.add(fmt.Sprintf("<< /Type /FontDescriptor /FontName /%s /Flags 32 /FontBBox [%d %d %d %d] "+
+		"/ItalicAngle 0 /Ascent %d /Descent %d /CapHeight %d /StemV 80 /FontFile2 %d 0 R >>",
+		baseFont, scale(font.bbox[0]), scale(font.bbox[1]), scale(font.bbox[2]), scale(font.bbox[3]),
+		scale(font.ascent), scale(font.descent), scale(font.capHeight), fileRef))
+
+	var widths strings.Builder
+	for _, gid := range gids {
+		fmt.Fprintf(&widths, "%d [%d] ", gid, scale(font.advance(uint16(gid))))
+	}
+	cidFont := d.add(fmt.Sprintf("<< /Type /Font /Subtype /CIDFontType2 /BaseFont /%s "+
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (Identity) /Supplement 0 >> "+
+		"/FontDescriptor %d 0 R /DW 1000 /W [%s] /CIDToGIDMap /Identity >>",
+		baseFont, descriptor, widths.String()))
+
+	toUnicode := d.addStream("", toUnicodeCMap(f.used, gids))
+
+	return d.add(fmt.Sprintf("<< /Type /Font /Subtype /Type0 /BaseFont /%s /Encoding /Identity-H "+
+		"/DescendantFonts [%d 0 R] /ToUnicode %d 0 R >>", baseFont, cidFont, toUnicode))
+}
+
+// toUnicodeCMap сопоставляет глифы символам, чтобы текст из PDF можно было копировать и искать.
+func toUnicodeCMap(used map[uint16]rune, gids []int) []byte {
+	var b bytes.Buffer
+	b.WriteString("/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n" +
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n" +
+		"/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n" +
+		"1 begincodespacerange\n<0000> <FFFF>\nendcodespacerange\n")
+	for start := 0; start < len(gids); start += 100 {
+		end := min(start+100, len(gids))
+		fmt.Fprintf(&b, "%d beginbfchar\n", end-start)
+		for _, gid := range gids[start:end] {
+			fmt.Fprintf(&b, "<%04X> <%s>\n", gid, utf16Hex(used[uint16(gid)]))
+		}
+		b.WriteString("endbfchar\n")
+	}
+	b.WriteString("endcmap\nCMapName currentdict /CMap defineresource pop\nend\nend\n")
+	return b.Bytes()
+}
+
+func utf16Hex(r rune) string {
+	if r < 0x10000 {
+		return fmt.Sprintf("%04X", r)
+	}
+	r -= 0x10000
+	return fmt.Sprintf("%04X%04X", 0xD800+(r>>10), 0xDC00+(r&0x3FF))
+}
+
+func (d *pdfDocument) bytes() []byte {
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, len(d.objects))
+	for i, obj := range d.objects {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n", i+1)
+		b.Write(obj)
+		b.WriteString("\nendobj\n")
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(d.objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(d.objects)+1, xref)
+	return b.Bytes()
+}
+
+// pageContent строит поток команд страницы. Координаты переводятся из мм в пункты,
+// ось Y разворачивается снизу вверх.
+func pageContent(page Page, fonts []*pdfFont) []byte {
+	var b bytes.Buffer
+	pt := func(mm float64) string { return pdfNum(mm / mmPerPt) }
+	y := func(mm float64) string { return pdfNum((page.Height - mm) / mmPerPt) }
+
+	b.WriteString("0 g 0 G\n")
+	for _, item := range page.Items {
+		switch it := item.(type) {
+		case Rect:
+			if it.Fill {
+				fmt.Fprintf(&b, "%s %s %s %s re f\n", pt(it.X), y(it.Y+it.H), pt(it.W), pt(it.H))
+			} else {
+				fmt.Fprintf(&b, "%s w %s %s %s %s re S\n", pt(it.Line), pt(it.X), y(it.Y+it.H), pt(it.W), pt(it.H))
+			}
+		case Bars:
+			if it.Code == nil || len(it.Code.Modules) == 0 {
+				continue
+			}
+			module := it.W / float64(len(it.Code.Modules))
+			for _, r := range runs(it.Code.Modules) {
+				fmt.Fprintf(&b, "%s %s %s %s re\n", pt(it.X+float64(r[0])*module), y(it.Y+it.H), pt(float64(r[1])*module), pt(it.H))
+			}
+			b.WriteString("f\n")
+		case MatrixCode:
+			if it.Code == nil {
+				continue
+			}
+			rows, cols := it.Code.Size()
+			if rows == 0 {
+				continue
+			}
+			module := it.Size / float64(max(rows, cols))
+			for row, cells := range it.Code.Cells {
+				for _, r := range runs(cells) {
+					fmt.Fprintf(&b, "%s %s %s %s re\n", pt(it.X+float64(r[0])*module), y(it.Y+float64(row+1)*module), pt(float64(r[1])*module), pt(module))
+				}
+			}
+			b.WriteString("f\n")
+		case Text:
+			f := fonts[0]
+			if it.Bold {
+				f = fonts[1]
+			}
+			gids, runes := textGlyphs(f.font, it.Value)
+			if len(gids) == 0 {
+				continue
+			}
+			var hex strings.Builder
+			for i, gid := range gids {
+				f.used[gid] = runes[i]
+				fmt.Fprintf(&hex, "%04X", gid)
+			}
+			fmt.Fprintf(&b, "BT /%s %s Tf %s %s Td <%s> Tj ET\n", f.name, pdfNum(it.Size), pt(textStart(it)), y(it.Y), hex.String())
+		}
+	}
+	return b.Bytes()
+}
+
+func pdfNum(v float64) string {
+	s := fmt.Sprintf("%.3f", v)
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	if s == "-0" || s == "" {
+		return "0"
+	}
+	return s
+}
@@ -0,0 +1,312 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+)
+
+// DefaultDPI - разрешение термопринтеров этикеток (8 точек на мм)
+const DefaultDPI = 203
+
+// WritePNG выводит страницу растровым изображением с разрешением dpi.
+// Модули штрихкодов выравниваются по целым пикселям, чтобы код читался сканером.
+func WritePNG(w io.Writer, page Page, dpi int) error {
+	if dpi <= 0 {
+		dpi = DefaultDPI
+	}
+	c := newCanvas(page, float64(dpi))
+	for _, item := range page.Items {
+		switch it := item.(type) {
+		case Rect:
+			c.rect(it)
+		case Bars:
+			c.bars(it)
+		case MatrixCode:
+			c.matrix(it)
+		case Text:
+			c.text(it)
+		}
+	}
+	return png.Encode(w, c.img)
+}
+
+type canvas struct {
+	img   *image.Gray
+	scale float64 // Пикселей на мм
+	dpi   float64
+}
+
+func newCanvas(page Page, dpi float64) *canvas {
+	scale := dpi / 25.4
+	w := int(math.Round(page.Width * scale))
+	h := int(math.Round(page.Height * scale))
+	img := image.NewGray(image.Rect(0, 0, w, h))
+	for i := range img.Pix {
+		img.Pix[i] = 0xFF
+	}
+	return &canvas{img: img, scale: scale, dpi: dpi}
+}
+
+func (c *canvas) px(mm float64) int {
+	return int(math.Round(mm * c.scale))
+}
+
+// fill закрашивает прямоугольник в пикселях.
+func (c *canvas) fill(x0, y0, x1, y1 int) {
+	r := image.Rect(x0, y0, x1, y1).Intersect(c.img.Rect)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			c.img.SetGray(x, y, color.Gray{})
+		}
+	}
+}
+
+func (c *canvas) rect(r Rect) {
+	x0, y0, x1, y1 := c.px(r.X), c.px(r.Y), c.px(r.X+r.W), c.px(r.Y+r.H)
+	if r.Fill {
+		c.fill(x0, y0, x1, y1)
+		return
+	}
+	line := max(1, c.px(r.Line))
+	c.fill(x0, y0, x1, y0+line)
+	c.fill(x0, y1-line, x1, y1)
+	c.fill(x0, y0, x0+line, y1)
+	c.fill(x1-line, y0, x1, y1)
+}
+
+// moduleSize подбирает целую ширину модуля в пикселях и отступ для центрирования кода в отведенной ширине.
+func (c *canvas) moduleSize(width float64, modules int) (int, int) {
+	available := width * c.scale
+	module := max(1, int(available/float64(modules)))
+	return module, int((available - float64(module*modules)) / 2)
+}
+
+func (c *canvas) bars(b Bars) {
+	if b.Code == nil || len(b.Code.Modules) == 0 {
+		return
+	}
+	module, offset := c.moduleSize(b.W, len(b.Code.Modules))
+	x, y0, y1 := c.px(b.X)+offset, c.px(b.Y), c.px(b.Y+b.H)
+	for _, r := range runs(b.Code.Modules) {
+		c.fill(x+r[0]*module, y0, x+(r[0]+r[1])*module, y1)
+	}
+}
+
+func (c *canvas) matrix(m MatrixCode) {
+	if m.Code == nil {
+		return
+	}
+	rows, cols := m.Code.Size()
+	if rows == 0 {
+		return
+	}
+	module, offset := c.moduleSize(m.Size, max(rows, cols))
+	x, y := c.px(m.X)+offset, c.px(m.Y)+offset
+	for row, cells := range m.Code.Cells {
+		for _, r := range runs(cells) {
+			c.fill(x+r[0]*module, y+row*module, x+(r[0]+r[1])*module, y+(row+1)*module)
+		}
+	}
+}
+
+// text растеризует строку со сглаживанием.
+func (c *canvas) text(t Text) {
+	font := fontFor(t.Bold)
+	gids, _ := textGlyphs(font, t.Value)
+	if len(gids) == 0 {
+		return
+	}
+	sizePx := t.Size / 72 * c.dpi
+	unit := sizePx / float64(font.unitsPerEm)
+
+	startX := textStart(t) * c.scale
+	baseline := t.Y * c.scale
+	left := int(math.Floor(startX)) - 2
+	top := int(math.Floor(baseline-float64(font.ascent)*unit)) - 2
+	width := int(math.Ceil(font.Width(t.Value, sizePx))) + 6
+	height := int(math.Ceil(float64(font.ascent-font.descent)*unit)) + 6
+
+	r := newRasterizer(width, height)
+	pen := startX - float64(left)
+	base := baseline - float64(top)
+	for _, gid := range gids {
+		for _, contour := range font.contours(gid, 0) {
+			r.contour(contour, func(p fontPoint) (float64, float64) {
+				return pen + p.x*unit, base - p.y*unit
+			})
+		}
+		pen += float64(font.advance(gid)) * unit
+	}
+
+	coverage := r.accumulate()
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			a := coverage[y*width+x]
+			if a <= 0 {
+				continue
+			}
+			ix, iy := left+x, top+y
+			if !(image.Point{ix, iy}.In(c.img.Rect)) {
+				continue
+			}
+			v := float32(c.img.GrayAt(ix, iy).Y) * (1 - a)
+			c.img.SetGray(ix, iy, color.Gray{Y: uint8(v + 0.5)})
+		}
+	}
+}
+
+// rasterizer закрашивает контуры по правилу ненулевого обхода с точным вычислением площади покрытия:
+// каждый отрезок добавляет в буфер приращения, а суммирование по строке дает покрытие пикселей.
+type rasterizer struct {
+	w, h int
+	acc  []float32
+}
+
+func newRasterizer(w, h int) *rasterizer {
+	return &rasterizer{w: w, h: h, acc: make([]float32, w*h+4)}
+}
+
+// contour добавляет замкнутый контур TrueType: внеконтурные точки - контрольные точки квадратичных кривых.
+func (r *rasterizer) contour(points []fontPoint, tr func(fontPoint) (float64, float64)) {
+	n := len(points)
+	if n < 2 {
+		return
+	}
+	// Начинаем с точки на контуре; если таких нет, с середины первых двух контрольных
+	start := -1
+	for i, p := range points {
+		if p.on {
+			start = i
+			break
+		}
+	}
+	var first fontPoint
+	if start < 0 {
+		first = fontPoint{x: (points[0].x + points[1].x) / 2, y: (points[0].y + points[1].y) / 2, on: true}
+		start = 0
+	} else {
+		first = points[start]
+		start++
+	}
+
+	cx, cy := tr(first)
+	var ctrl *fontPoint
+	for k := 0; k < n; k++ {
+		p := points[(start+k)%n]
+		if p.on {
+			if ctrl != nil {
+				cx, cy = r.quad(cx, cy, *ctrl, p, tr)
+				ctrl = nil
+			} else {
+				x, y := tr(p)
+				r.line(cx, cy, x, y)
+				cx, cy = x, y
+			}
+			continue
+		}
+		if ctrl != nil {
+			mid := fontPoint{x: (ctrl.x + p.x) / 2, y: (ctrl.y + p.y) / 2}
+			cx, cy = r.quad(cx, cy, *ctrl, mid, tr)
+		}
+		pc := p
+		ctrl = &pc
+	}
+	if ctrl != nil {
+		cx, cy = r.quad(cx, cy, *ctrl, first, tr)
+	}
+	fx, fy := tr(first)
+	r.line(cx, cy, fx, fy)
+}
+
+func (r *rasterizer) quad(x0, y0 float64, ctrl, end fontPoint, tr func(fontPoint) (float64, float64)) (float64, float64) {
+	x1, y1 := tr(ctrl)
+	x2, y2 := tr(end)
+	dist := math.Hypot(x0-2*x1+x2, y0-2*y1+y2)
+	steps := max(1, int(math.Sqrt(dist*4)))
+	px, py := x0, y0
+	for i := 1; i <= steps; i++ {
+		t := float64(i) / float64(steps)
+		mt := 1 - t
+		x := mt*mt*x0 + 2*mt*t*x1 + t*t*x2
+		y := mt*mt*y0 + 2*mt*t*y1 + t*t*y2
+		r.line(px, py, x, y)
+		px, py = x, y
+	}
+	return x2, y2
+}
+
+func (r *rasterizer) line(x0, y0, x1, y1 float64) {
+	if y0 == y1 {
+		return
+	}
+	clampX := func(x float64) float64 { return math.Max(0, math.Min(float64(r.w-2), x)) }
+	x0, x1 = clampX(x0), clampX(x1)
+
+	dir := float32(1)
+	if y0 > y1 {
+		dir = -1
+		x0, y0, x1, y1 = x1, y1, x0, y0
+	}
+	dxdy := (x1 - x0) / (y1 - y0)
+	x := x0
+	if y0 < 0 {
+		x -= y0 * dxdy
+	}
+	for y := max(0, int(y0)); y < min(r.h, int(math.Ceil(y1))); y++ {
+		row := y * r.w
+		dy := math.Min(float64(y+1), y1) - math.Max(float64(y), y0)
+		xnext := x + dxdy*dy
+		d := float32(dy) * dir
+		xa, xb := x, xnext
+		if xa > xb {
+			xa, xb = xb, xa
+		}
+		xaFloor := math.Floor(xa)
+		xai := int(xaFloor)
+		xbCeil := math.Ceil(xb)
+		xbi := int(xbCeil)
+		if xbi <= xai+1 {
+			xmf := float32(0.5*(x+xnext) - xaFloor)
+			r.acc[row+xai] += d - d*xmf
+			r.acc[row+xai+1] += d * xmf
+		} else {
+			s := float32(1 / (xb - xa))
+			xaf := float32(xa - xaFloor)
+			a0 := 0.5 * s * (1 - xaf) * (1 - xaf)
+			xbf := float32(xb - xbCeil + 1)
+			am := 0.5 * s * xbf * xbf
+			r.acc[row+xai] += d * a0
+			if xbi == xai+2 {
+				r.acc[row+xai+1] += d * (1 - a0 - am)
+			} else {
+				a1 := s * (1.5 - xaf)
+				r.acc[row+xai+1] += d * (a1 - a0)
+				for xi := xai + 2; xi < xbi-1; xi++ {
+					r.acc[row+xi] += d * s
+				}
+				a2 := a1 + float32(xbi-xai-3)*s
+				r.acc[row+xbi-1] += d * (1 - a2 - am)
+			}
+			r.acc[row+xbi] += d * am
+		}
+		x = xnext
+	}
+}
+
+// accumulate возвращает покрытие пикселей от 0 до 1.
+func (r *rasterizer) accumulate() []float32 {
+	out := make([]float32, r.w*r.h)
+	var sum float32
+	for i := range out {
+		sum += r.acc[i]
+		a := sum
+		if a < 0 {
+			a = -a
+		}
+		out[i] = min(a, 1)
+	}
+	return out
+}
@@ -0,0 +1,253 @@
+// Package render строит печатные документы и этикетки и выводит их в PDF, PNG или SVG.
+// Текст набирается встроенным шрифтом DejaVu Sans с поддержкой кириллицы.
+package render
+
+import (
+	"embed"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/lamoda-seller-app/internal/barcode"
+)
+
+//go:embed fonts/*.ttf
+var fontFiles embed.FS
+
+var (
+	fontsOnce   sync.Once
+	regularFont *Font
+	boldFont    *Font
+)
+
+func loadFonts() {
+	fontsOnce.Do(func() {
+		regularFont = mustLoadFont("fonts/DejaVuSans.ttf", "DejaVuSans")
+		boldFont = mustLoadFont("fonts/DejaVuSans-Bold.ttf", "DejaVuSans-Bold")
+	})
+}
+
+func mustLoadFont(path, name string) *Font {
+	data, err := fontFiles.ReadFile(path)
+	if err != nil {
+		panic(fmt.Sprintf("render: embedded font %s: %v", path, err))
+	}
+	font, err := ParseFont(name, data)
+	if err != nil {
+		panic(fmt.Sprintf("render: embedded font %s: %v", path, err))
+	}
+	return font
+}
+
+func fontFor(bold bool) *Font {
+	loadFonts()
+	if bold {
+		return boldFont
+	}
+	return regularFont
+}
+
+// Format - формат вывода документа
+type Format string
+
+const (
+	FormatPDF Format = "pdf"
+	FormatPNG Format = "png"
+	FormatSVG Format = "svg"
+)
+
+// ParseFormat проверяет формат вывода; пустая строка означает PDF.
+func ParseFormat(s string) (Format, error) {
+	switch Format(strings.ToLower(s)) {
+	case "", FormatPDF:
+		return FormatPDF, nil
+	case FormatPNG:
+		return FormatPNG, nil
+	case FormatSVG:
+		return FormatSVG, nil
+	}
+	return "", fmt.Errorf("unsupported format %q, expected pdf, png or svg", s)
+}
+
+// ContentType возвращает MIME-тип формата.
+func (f Format) ContentType() string {
+	switch f {
+	case FormatPNG:
+		return "image/png"
+	case FormatSVG:
+		return "image/svg+xml"
+	}
+	return "application/pdf"
+}
+
+// Write выводит страницы в выбранном формате. PNG и SVG содержат одну страницу,
+// поэтому для них выводится только первая.
+func Write(w io.Writer, format Format, pages []Page, dpi int) error {
+	if len(pages) == 0 {
+		return fmt.Errorf("nothing to render")
+	}
+	switch format {
+	case FormatPNG:
+		return WritePNG(w, pages[0], dpi)
+	case FormatSVG:
+		return WriteSVG(w, pages[0])
+	}
+	return WritePDF(w, pages)
+}
+
+// Page - страница документа или этикетка. Размеры и координаты в миллиметрах от левого верхнего угла.
+type Page struct {
+	Width  float64
+	Height float64
+	Items  []Item
+}
+
+// Add добавляет элементы на страницу.
+func (p *Page) Add(items ...Item) {
+	p.Items = append(p.Items, items...)
+}
+
+// Item - элемент страницы: Text, Rect, Bars или MatrixCode.
+type Item interface {
+	item()
+}
+
+// Align - выравнивание текста относительно X
+type Align int
+
+const (
+	AlignLeft Align = iota
+	AlignCenter
+	AlignRight
+)
+
+// Text - строка текста. Y - базовая линия, Size - кегль в пунктах.
+type Text struct {
+	X, Y  float64
+	Size  float64
+	Bold  bool
+	Align Align
+	Value string
+}
+
+// Rect - прямоугольник: залитый или контур толщиной Line.
+type Rect struct {
+	X, Y, W, H float64
+	Fill       bool
+	Line       float64
+}
+
+// Bars - одномерный штрихкод, растянутый на ширину W.
+type Bars struct {
+	X, Y, W, H float64
+	Code       *barcode.Linear
+}
+
+// MatrixCode - двумерный штрихкод со стороной Size.
+type MatrixCode struct {
+	X, Y, Size float64
+	Code       *barcode.Matrix
+}
+
+func (Text) item()       {}
+func (Rect) item()       {}
+func (Bars) item()       {}
+func (MatrixCode) item() {}
+
+const mmPerPt = 25.4 / 72
+
+// TextWidth возвращает ширину строки в миллиметрах.
+func TextWidth(value string, size float64, bold bool) float64 {
+	return fontFor(bold).Width(value, size) * mmPerPt
+}
+
+// Fit обрезает строку с многоточием, чтобы она поместилась в ширину maxWidth (мм).
+func Fit(value string, size float64, bold bool, maxWidth float64) string {
+	if TextWidth(value, size, bold) <= maxWidth {
+		return value
+	}
+	runes := []rune(value)
+	for len(runes) > 0 {
+		runes = runes[:len(runes)-1]
+		candidate := strings.TrimRight(string(runes), " ") + "…"
+		if TextWidth(candidate, size, bold) <= maxWidth {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// Wrap разбивает текст на строки по словам так, чтобы каждая помещалась в maxWidth (мм).
+// Если строк получается больше maxLines (при maxLines > 0), последняя обрезается с многоточием.
+func Wrap(value string, size float64, bold bool, maxWidth float64, maxLines int) []string {
+	var lines []string
+	current := ""
+	for _, word := range strings.Fields(value) {
+		candidate := word
+		if current != "" {
+			candidate = current + " " + word
+		}
+		if current == "" || TextWidth(candidate, size, bold) <= maxWidth {
+			current = candidate
+			continue
+		}
+		lines = append(lines, current)
+		current = word
+	}
+	if current != "" {
+		lines = append(lines, current)
+	}
+	for i := range lines {
+		lines[i] = Fit(lines[i], size, bold, maxWidth)
+	}
+	if maxLines > 0 && len(lines) > maxLines {
+		last := strings.Join(lines[maxLines-1:], " ")
+		lines = append(lines[:maxLines-1], Fit(last+" …", size, bold, maxWidth))
+	}
+	return lines
+}
+
+// textGlyphs возвращает номера глифов строки; символы без глифа пропускаются.
+func textGlyphs(font *Font, value string) ([]uint16, []rune) {
+	var gids []uint16
+	var runes []rune
+	for _, r := range value {
+		gid := font.GlyphIndex(r)
+		if gid == 0 && r != ' ' {
+			continue
+		}
+		gids = append(gids, gid)
+		runes = append(runes, r)
+	}
+	return gids, runes
+}
+
+// textStart возвращает X начала строки с учетом выравнивания, в мм.
+func textStart(t Text) float64 {
+	switch t.Align {
+	case AlignCenter:
+		return t.X - TextWidth(t.Value, t.Size, t.Bold)/2
+	case AlignRight:
+		return t.X - TextWidth(t.Value, t.Size, t.Bold)
+	}
+	return t.X
+}
+
+// runs возвращает отрезки подряд идущих true: начало и длина.
+func runs(cells []bool) [][2]int {
+	var result [][2]int
+	for i := 0; i < len(cells); {
+		if !cells[i] {
+			i++
+			continue
+		}
+		j := i
+		for j < len(cells) && cells[j] {
+			j++
+		}
+		result = append(result, [2]int{i, j - i})
+		i = j
+	}
+	return result
+}
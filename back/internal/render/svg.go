@@ -0,0 +1,79 @@
+package render
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WriteSVG выводит страницу векторным изображением; единица координат - миллиметр.
+// Текст выводится элементами <text>, шрифт берется из системы просмотра.
+func WriteSVG(w io.Writer, page Page) error {
+	b := bufio.NewWriter(w)
+	fmt.Fprintf(b, `<?xml version="1.0" encoding="UTF-8"?>`+"\n"+
+		`<svg xmlns="http://www.w3.org/2000/svg" width="%smm" height="%smm" viewBox="0 0 %s %s">`+"\n",
+		pdfNum(page.Width), pdfNum(page.Height), pdfNum(page.Width), pdfNum(page.Height))
+	fmt.Fprintf(b, `<rect width="%s" height="%s" fill="#fff"/>`+"\n", pdfNum(page.Width), pdfNum(page.Height))
+
+	rect := func(x, y, w, h float64) {
+		fmt.Fprintf(b, `<rect x="%s" y="%s" width="%s" height="%s"/>`+"\n", pdfNum(x), pdfNum(y), pdfNum(w), pdfNum(h))
+	}
+
+	for _, item := range page.Items {
+		switch it := item.(type) {
+		case Rect:
+			if it.Fill {
+				rect(it.X, it.Y, it.W, it.H)
+			} else {
+				fmt.Fprintf(b, `<rect x="%s" y="%s" width="%s" height="%s" fill="none" stroke="#000" stroke-width="%s"/>`+"\n",
+					pdfNum(it.X+it.Line/2), pdfNum(it.Y+it.Line/2), pdfNum(it.W-it.Line), pdfNum(it.H-it.Line), pdfNum(it.Line))
+			}
+		case Bars:
+			if it.Code == nil || len(it.Code.Modules) == 0 {
+				continue
+			}
+			module := it.W / float64(len(it.Code.Modules))
+			b.WriteString(`<g shape-rendering="crispEdges">` + "\n")
+			for _, r := range runs(it.Code.Modules) {
+				rect(it.X+float64(r[0])*module, it.Y, float64(r[1])*module, it.H)
+			}
+			b.WriteString("</g>\n")
+		case MatrixCode:
+			if it.Code == nil {
+				continue
+			}
+			rows, cols := it.Code.Size()
+			if rows == 0 {
+				continue
+			}
+			module := it.Size / float64(max(rows, cols))
+			b.WriteString(`<g shape-rendering="crispEdges">` + "\n")
+			for row, cells := range it.Code.Cells {
+				for _, r := range runs(cells) {
+					rect(it.X+float64(r[0])*module, it.Y+float64(row)*module, float64(r[1])*module, module)
+				}
+			}
+			b.WriteString("</g>\n")
+		case Text:
+			anchor := "start"
+			switch it.Align {
+			case AlignCenter:
+				anchor = "middle"
+			case AlignRight:
+				anchor = "end"
+			}
+			weight := ""
+			if it.Bold {
+				weight = ` font-weight="bold"`
+			}
+			var value strings.Builder
+			xml.EscapeText(&value, []byte(it.Value))
+			fmt.Fprintf(b, `<text x="%s" y="%s" font-family="DejaVu Sans, Arial, sans-serif" font-size="%s" text-anchor="%s"%s>%s</text>`+"\n",
+				pdfNum(it.X), pdfNum(it.Y), pdfNum(it.Size*mmPerPt), anchor, weight, value.String())
+		}
+	}
+	b.WriteString("</svg>\n")
+	return b.Flush()
+}
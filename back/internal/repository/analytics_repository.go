@@ -20,6 +20,8 @@ type AnalyticsRepo interface {
 	GetSizeDistribution(ctx context.Context, userID uuid.UUID, params model.SizeDistributionRequestParams) (*model.SizeDistributionResponse, error)
 	GetSeasonalTrends(ctx context.Context, userID uuid.UUID, params model.SeasonalTrendsRequestParams) (*model.SeasonalTrendsResponse, error)
 	GetReturnsAnalytics(ctx context.Context, userID uuid.UUID, params model.ReturnsAnalyticsRequestParams) (*model.ReturnsAnalyticsResponse, error)
+	GetRFM(ctx context.Context, userID uuid.UUID, params model.RFMRequestParams) (*model.RFMResponse, error)
+	GetCohorts(ctx context.Context, userID uuid.UUID, params model.CohortRequestParams) (*model.CohortResponse, error)
 }
 
 type analyticsRepository struct {
@@ -0,0 +1,222 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/lamoda-seller-app/internal/model"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrCategoryHasChildren = errors.New("category has subcategories")
+	ErrCategoryInUse       = errors.New("category is used by products")
+	ErrCategoryCycle       = errors.New("category cannot be its own ancestor")
+	ErrCategorySlugChange  = errors.New("category slug cannot be changed: products reference categories by slug")
+)
+
+// CategoryRepository инкапсулирует логику работы со справочником категорий.
+type CategoryRepository struct {
+	db *gorm.DB
+}
+
+func NewCategoryRepository(db *gorm.DB) *CategoryRepository {
+	return &CategoryRepository{db: db}
+}
+
+// Tree возвращает все категории в виде дерева (корневые категории с вложенными подкатегориями).
+func (r *CategoryRepository) Tree(ctx context.Context) ([]model.Category, error) {
+	var all []model.Category
+	err := r.db.WithContext(ctx).
+		Preload("Attributes", func(db *gorm.DB) *gorm.DB { return db.Order("sort_order ASC, code ASC") }).
+		Order("sort_order ASC, name ASC").
+		Find(&all).Error
+	if err != nil {
+		return nil, err
+	}
+	return buildCategoryTree(all, nil), nil
+}
+
+// buildCategoryTree рекурсивно собирает дочерние категории для parentID.
+func buildCategoryTree(all []model.Category, parentID *uuid.UUID) []model.Category {
+	var nodes []model.Category
+	for _, c := range all {
+		if (parentID == nil && c.ParentID == nil) || (parentID != nil && c.ParentID != nil && *c.ParentID == *parentID) {
+			id := c.ID
+			c.Subcategories = buildCategoryTree(all, &id)
+			nodes = append(nodes, c)
+		}
+	}
+	sort.SliceStable(nodes, func(i, j int) bool { return nodes[i].SortOrder < nodes[j].SortOrder })
+	return nodes
+}
+
+// GetByID возвращает категорию вместе с её собственными атрибутами.
+func (r *CategoryRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.Category, error) {
+	var category model.Category
+	err := r.db.WithContext(ctx).
+		Preload("Attributes", func(db *gorm.DB) *gorm.DB { return db.Order("sort_order ASC, code ASC") }).
+		First(&category, "id = ?", id).Error
+	return &category, err
+}
+
+// GetBySlug возвращает категорию по слагу.
+func (r *CategoryRepository) GetBySlug(ctx context.Context, slug string) (*model.Category, error) {
+	var category model.Category
+	err := r.db.WithContext(ctx).First(&category, "slug = ?", slug).Error
+	return &category, err
+}
+
+// IsDescendant проверяет, что категория id лежит внутри категории ancestorID (на любой глубине).
+func (r *CategoryRepository) IsDescendant(ctx context.Context, id, ancestorID uuid.UUID) (bool, error) {
+	var inside bool
+	err := r.db.WithContext(ctx).Raw(`
+		WITH RECURSIVE chain AS (
+			SELECT id, parent_id FROM categories WHERE id = ?
+			UNION ALL
+			SELECT c.id, c.parent_id FROM categories c JOIN chain ON c.id = chain.parent_id
+		)
+		SELECT EXISTS (SELECT 1 FROM chain WHERE parent_id = ?)`, id, ancestorID).
+		Scan(&inside).Error
+	return inside, err
+}
+
+// Create создает новую категорию.
+func (r *CategoryRepository) Create(ctx context.Context, category *model.Category) error {
+	return r.db.WithContext(ctx).Omit("Attributes").Create(category).Error
+}
+
+// Update обновляет категорию. Проверяет, что новый родитель не находится в поддереве категории.
+// Слаг после создания не меняется: по нему на категорию ссылаются товары (ErrCategorySlugChange).
+func (r *CategoryRepository) Update(ctx context.Context, category *model.Category) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var current model.Category
+		if err := tx.Select("slug").First(&current, "id = ?", category.ID).Error; err != nil {
+			return err
+		}
+		if current.Slug != category.Slug {
+			return ErrCategorySlugChange
+		}
+
+		for parentID := category.ParentID; parentID != nil; {
+			if *parentID == category.ID {
+				return ErrCategoryCycle
+			}
+			var parent model.Category
+			if err := tx.Select("id", "parent_id").First(&parent, "id = ?", *parentID).Error; err != nil {
+				return err
+			}
+			parentID = parent.ParentID
+		}
+
+		return tx.Model(category).Select("parent_id", "name", "names", "sort_order").Updates(category).Error
+	})
+}
+
+// Delete удаляет категорию, если у неё нет подкатегорий и товаров.
+func (r *CategoryRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var category model.Category
+		if err := tx.First(&category, "id = ?", id).Error; err != nil {
+			return err
+		}
+
+		var children int64
+		if err := tx.Model(&model.Category{}).Where("parent_id = ?", id).Count(&children).Error; err != nil {
+			return err
+		}
+		if children > 0 {
+			return ErrCategoryHasChildren
+		}
+
+		var products int64
+		if err := tx.Model(&model.Product{}).
+			Where("category = ? OR subcategory = ?", category.Slug, category.Slug).
+			Count(&products).Error; err != nil {
+			return err
+		}
+		if products > 0 {
+			return ErrCategoryInUse
+		}
+
+		return tx.Delete(&model.Category{}, "id = ?", id).Error
+	})
+}
+
+// ReplaceAttributes полностью заменяет собственную схему атрибутов категории.
+func (r *CategoryRepository) ReplaceAttributes(ctx context.Context, categoryID uuid.UUID, attributes []model.CategoryAttribute) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("category_id = ?", categoryID).Delete(&model.CategoryAttribute{}).Error; err != nil {
+			return err
+		}
+		if len(attributes) == 0 {
+			return nil
+		}
+		for i := range attributes {
+			attributes[i].ID = uuid.New()
+			attributes[i].CategoryID = categoryID
+		}
+		return tx.Create(&attributes).Error
+	})
+}
+
+// ResolveSchema возвращает итоговую схему атрибутов категории с учетом наследования от родителей.
+// Атрибут дочерней категории переопределяет одноименный атрибут родителя.
+func (r *CategoryRepository) ResolveSchema(ctx context.Context, slug string) ([]model.CategoryAttribute, error) {
+	category, err := r.GetBySlug(ctx, slug)
+	if err != nil {
+		return nil, err
+	}
+
+	// Собираем цепочку от категории до корня
+	chain := []uuid.UUID{category.ID}
+	for parentID := category.ParentID; parentID != nil; {
+		var parent model.Category
+		if err := r.db.WithContext(ctx).Select("id", "parent_id").First(&parent, "id = ?", *parentID).Error; err != nil {
+			return nil, err
+		}
+		chain = append(chain, parent.ID)
+		parentID = parent.ParentID
+	}
+
+	var attributes []model.CategoryAttribute
+	if err := r.db.WithContext(ctx).Where("category_id IN ?", chain).Order("sort_order ASC, code ASC").Find(&attributes).Error; err != nil {
+		return nil, err
+	}
+
+	// Идем от корня к листу, чтобы более специфичные атрибуты перезаписывали общие
+	depth := make(map[uuid.UUID]int, len(chain))
+	for i, id := range chain {
+		depth[id] = len(chain) - i
+	}
+	sort.SliceStable(attributes, func(i, j int) bool { return depth[attributes[i].CategoryID] < depth[attributes[j].CategoryID] })
+
+	byCode := make(map[string]int)
+	var schema []model.CategoryAttribute
+	for _, attr := range attributes {
+		if idx, ok := byCode[attr.Code]; ok {
+			schema[idx] = attr
+			continue
+		}
+		byCode[attr.Code] = len(schema)
+		schema = append(schema, attr)
+	}
+	return schema, nil
+}
+
+// SizeChart возвращает размерную сетку категории - допустимые значения атрибута size
+// из схемы с учетом наследования. Если атрибута нет, сетка пустая.
+func (r *CategoryRepository) SizeChart(ctx context.Context, slug string) ([]string, error) {
+	schema, err := r.ResolveSchema(ctx, slug)
+	if err != nil {
+		return nil, err
+	}
+	for _, attr := range schema {
+		if attr.Code == model.SizeAttributeCode {
+			return attr.AllowedValues, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrInvalidCursor = errors.New("invalid pagination cursor")
+
+// Cursor - позиция в списке для keyset-пагинации.
+// Хранит ключ сортировки и ID последней (или первой, для Prev) строки страницы.
+// Клиенту отдается в виде непрозрачной строки (см. EncodeCursor).
+type Cursor struct {
+	SortBy string    `json:"s"`
+	Desc   bool      `json:"d"`
+	Value  string    `json:"v"` // Значение ключа сортировки в текстовом виде, приводится к типу в SQL
+	ID     uuid.UUID `json:"i"`
+	Prev   bool      `json:"p,omitempty"` // Курсор указывает на предыдущую страницу
+}
+
+// PageInfo - курсоры соседних страниц
+type PageInfo struct {
+	NextCursor string
+	PrevCursor string
+	HasNext    bool
+	HasPrev    bool
+}
+
+// EncodeCursor кодирует курсор в непрозрачную строку для API.
+func EncodeCursor(c Cursor) string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// DecodeCursor разбирает строку курсора, полученную от клиента.
+func DecodeCursor(s string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c Cursor
+	if err := json.Unmarshal(raw, &c); err != nil || c.SortBy == "" || c.ID == uuid.Nil {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// keysetColumn описывает ключ сортировки для keyset-пагинации.
+type keysetColumn struct {
+	expr string        // SQL-выражение ключа (в WHERE нельзя использовать алиасы из SELECT)
+	args []interface{} // Аргументы выражения, если они есть
+	cast string        // Тип, к которому приводится значение из курсора
+}
+
+// keysetPage применяет сортировку и условие курсора к запросу.
+// Ключ дополняется ID, чтобы порядок был строгим даже при одинаковых значениях.
+// Для курсора Prev порядок обращается: строки выбираются назад от курсора и потом разворачиваются (см. finishKeysetPage).
+func keysetPage(query *gorm.DB, col keysetColumn, idColumn string, desc bool, cursor *Cursor, limit int) *gorm.DB {
+	backward := cursor != nil && cursor.Prev
+	scanDesc := desc != backward
+
+	if cursor != nil {
+		op := ">"
+		if scanDesc {
+			op = "<"
+		}
+		args := append(append([]interface{}{}, col.args...), cursor.Value, cursor.ID)
+		query = query.Where(fmt.Sprintf("(%s, %s) %s (CAST(? AS %s), ?)", col.expr, idColumn, op, col.cast), args...)
+	}
+
+	// Выбираем на одну строку больше, чтобы понять, есть ли следующая страница
+	return keysetOrder(query, col, idColumn, scanDesc).Limit(limit + 1)
+}
+
+// keysetOrder сортирует запрос по ключу и ID. Используется и в режиме OFFSET,
+// чтобы порядок строк совпадал с keyset-режимом.
+func keysetOrder(query *gorm.DB, col keysetColumn, idColumn string, desc bool) *gorm.DB {
+	direction := "ASC"
+	if desc {
+		direction = "DESC"
+	}
+	return query.Order(clause.OrderBy{Expression: clause.Expr{
+		SQL:                fmt.Sprintf("%s %s, %s %s", col.expr, direction, idColumn, direction),
+		Vars:               col.args,
+		WithoutParentheses: true,
+	}})
+}
+
+// finishKeysetPage обрезает лишнюю строку, восстанавливает прямой порядок для курсора Prev
+// и формирует курсоры соседних страниц. key возвращает значение ключа сортировки и ID строки.
+func finishKeysetPage[T any](rows []T, sortBy string, desc bool, cursor *Cursor, limit int, key func(*T) (string, uuid.UUID)) ([]T, *PageInfo) {
+	more := len(rows) > limit
+	if more {
+		rows = rows[:limit]
+	}
+	backward := cursor != nil && cursor.Prev
+	if backward {
+		for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+			rows[i], rows[j] = rows[j], rows[i]
+		}
+	}
+
+	info := &PageInfo{}
+	if backward {
+		info.HasPrev, info.HasNext = more, true
+	} else {
+		info.HasNext, info.HasPrev = more, cursor != nil
+	}
+
+	if len(rows) == 0 {
+		return rows, info
+	}
+	if info.HasNext {
+		v, id := key(&rows[len(rows)-1])
+		info.NextCursor = EncodeCursor(Cursor{SortBy: sortBy, Desc: desc, Value: v, ID: id})
+	}
+	if info.HasPrev {
+		v, id := key(&rows[0])
+		info.PrevCursor = EncodeCursor(Cursor{SortBy: sortBy, Desc: desc, Value: v, ID: id, Prev: true})
+	}
+	return rows, info
+}
+
+// offsetPageInfo формирует курсоры для страницы, полученной в режиме OFFSET,
+// чтобы клиент мог перейти на keyset-пагинацию с любой страницы.
+func offsetPageInfo[T any](rows []T, sortBy string, desc bool, total int64, limit, offset int, key func(*T) (string, uuid.UUID)) *PageInfo {
+	info := &PageInfo{
+		HasNext: total > int64(limit+offset),
+		HasPrev: offset > 0,
+	}
+	if len(rows) == 0 {
+		return info
+	}
+	if info.HasNext {
+		v, id := key(&rows[len(rows)-1])
+		info.NextCursor = EncodeCursor(Cursor{SortBy: sortBy, Desc: desc, Value: v, ID: id})
+	}
+	if info.HasPrev {
+		v, id := key(&rows[0])
+		info.PrevCursor = EncodeCursor(Cursor{SortBy: sortBy, Desc: desc, Value: v, ID: id, Prev: true})
+	}
+	return info
+}
+
+// Форматирование значений ключей сортировки для курсора без потери точности
+func cursorFloat(v float64) string  { return strconv.FormatFloat(v, 'f', -1, 64) }
+func cursorInt(v int64) string      { return strconv.FormatInt(v, 10) }
+func cursorTime(v time.Time) string { return v.UTC().Format(time.RFC3339Nano) }
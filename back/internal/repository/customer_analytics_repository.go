@@ -0,0 +1,260 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lamoda-seller-app/internal/model"
+)
+
+const (
+	defaultRFMLimit = 50
+	maxRFMLimit     = 1000
+)
+
+// customerOrdersCTE возвращает подзапрос заказов продавца с покупателем из справочника (orders.customer_ref)
+// и суммой заказа, без отмененных. С фильтром категории заказ учитывается, если в нем есть товары
+// категории, а сумма - только по этим товарам.
+func customerOrdersCTE(userID uuid.UUID, category string, from time.Time) (string, []interface{}) {
+	args := []interface{}{userID, from}
+	if category == "" {
+		return `
+			SELECT o.id, o.customer_ref, o.date, COALESCE((o.totals->>'total')::numeric, 0) AS amount
+			FROM orders o
+			WHERE o.user_id = ? AND o.date >= ? AND o.status <> 'cancelled' AND o.customer_ref IS NOT NULL`, args
+	}
+	return `
+			SELECT o.id, o.customer_ref, o.date, SUM(oi.total) AS amount
+			FROM orders o
+			JOIN order_items oi ON oi.order_id = o.id
+			JOIN products p ON p.id = oi.product_id
+			WHERE o.user_id = ? AND o.date >= ? AND o.status <> 'cancelled' AND o.customer_ref IS NOT NULL
+				AND p.category = ?
+			GROUP BY o.id`, append(args, category)
+}
+
+// --- RFM ---
+
+// GetRFM считает давность, частоту и сумму заказов каждого покупателя за период, баллы-квинтили
+// и сегменты. Балл - квинтиль середины группы одинаковых значений (CUME_DIST минус половина группы):
+// одинаковые значения получают одинаковый балл, а большая группа одинаковых значений (например,
+// покупатели с одним заказом) попадает в средние квинтили, а не целиком в первый или последний.
+func (r *analyticsRepository) GetRFM(ctx context.Context, userID uuid.UUID, params model.RFMRequestParams) (*model.RFMResponse, error) {
+	if params.Period == "" {
+		params.Period = "1y"
+	}
+	if params.Limit <= 0 || params.Limit > maxRFMLimit {
+		params.Limit = defaultRFMLimit
+	}
+	if params.Offset < 0 {
+		params.Offset = 0
+	}
+
+	base, args := customerOrdersCTE(userID, params.Category, getStartDate(params.Period))
+	var rows []model.CustomerRFM
+	err := r.db.WithContext(ctx).Raw(`
+		WITH base AS (`+base+`
+		), stats AS (
+			SELECT customer_ref, MAX(date) AS last_order_at, COUNT(*) AS frequency, SUM(amount) AS monetary
+			FROM base GROUP BY customer_ref
+		)
+		SELECT
+			c.id AS customer_id, c.name, c.email, s.last_order_at,
+			GREATEST(EXTRACT(DAY FROM NOW() - s.last_order_at), 0)::int AS recency_days,
+			s.frequency, s.monetary,
+			LEAST(5, 1 + FLOOR((CUME_DIST() OVER r - COUNT(*) OVER r_ties / (2.0 * COUNT(*) OVER ())) * 5))::int AS r_score,
+			LEAST(5, 1 + FLOOR((CUME_DIST() OVER f - COUNT(*) OVER f_ties / (2.0 * COUNT(*) OVER ())) * 5))::int AS f_score,
+			LEAST(5, 1 + FLOOR((CUME_DIST() OVER m - COUNT(*) OVER m_ties / (2.0 * COUNT(*) OVER ())) * 5))::int AS m_score
+		FROM stats s
+		JOIN customers c ON c.id = s.customer_ref
+		WINDOW
+			r AS (ORDER BY s.last_order_at), r_ties AS (PARTITION BY s.last_order_at),
+			f AS (ORDER BY s.frequency), f_ties AS (PARTITION BY s.frequency),
+			m AS (ORDER BY s.monetary), m_ties AS (PARTITION BY s.monetary)
+		ORDER BY s.monetary DESC, c.id`, args...).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to query rfm: %w", err)
+	}
+
+	summaries := make(map[string]*model.RFMSegmentSummary, len(model.RFMSegments))
+	for _, segment := range model.RFMSegments {
+		summaries[segment] = &model.RFMSegmentSummary{Segment: segment}
+	}
+	var totalRevenue float64
+	filtered := make([]model.CustomerRFM, 0, len(rows))
+	for i := range rows {
+		row := &rows[i]
+		row.RFMScore = fmt.Sprintf("%d%d%d", row.RScore, row.FScore, row.MScore)
+		row.Segment = rfmSegment(row.RScore, row.FScore, row.MScore)
+
+		s := summaries[row.Segment]
+		s.Customers++
+		s.Revenue += row.Monetary
+		s.AvgRecencyDays += float64(row.RecencyDays)
+		s.AvgFrequency += float64(row.Frequency)
+		totalRevenue += row.Monetary
+
+		if params.Segment == "" || params.Segment == row.Segment {
+			filtered = append(filtered, *row)
+		}
+	}
+
+	segments := make([]model.RFMSegmentSummary, 0, len(model.RFMSegments))
+	for _, segment := range model.RFMSegments {
+		s := summaries[segment]
+		if s.Customers > 0 {
+			n := float64(s.Customers)
+			s.Share = roundTo(n*100/float64(len(rows)), 1)
+			s.AvgRecencyDays = roundTo(s.AvgRecencyDays/n, 1)
+			s.AvgFrequency = roundTo(s.AvgFrequency/n, 2)
+			s.AvgMonetary = roundTo(s.Revenue/n, 2)
+		}
+		if totalRevenue > 0 {
+			s.RevenueShare = roundTo(s.Revenue*100/totalRevenue, 1)
+		}
+		segments = append(segments, *s)
+	}
+
+	page := filtered
+	if !params.All {
+		page = filtered[min(params.Offset, len(filtered)):min(params.Offset+params.Limit, len(filtered))]
+	}
+	return &model.RFMResponse{
+		Period:         params.Period,
+		Category:       params.Category,
+		TotalCustomers: len(rows),
+		Segments:       segments,
+		Customers:      page,
+		Total:          len(filtered),
+	}, nil
+}
+
+// rfmSegment относит покупателя к сегменту по баллам. Частота и сумма усредняются в один балл FM.
+func rfmSegment(r, f, m int) string {
+	fm := float64(f+m) / 2
+	switch {
+	case r >= 4 && fm >= 4:
+		return model.RFMSegmentChampions
+	case r >= 3 && fm >= 3.5:
+		return model.RFMSegmentLoyal
+	case r >= 4 && f == 1:
+		return model.RFMSegmentNew
+	case r >= 3 && fm >= 2:
+		return model.RFMSegmentPotentialLoyal
+	case r == 1 && fm >= 4:
+		return model.RFMSegmentCantLose
+	case r <= 2 && fm >= 3:
+		return model.RFMSegmentAtRisk
+	case r <= 2 && fm < 2:
+		return model.RFMSegmentHibernating
+	default:
+		return model.RFMSegmentNeedAttention
+	}
+}
+
+// --- Cohorts ---
+
+// GetCohorts строит матрицу удержания по месячным когортам: когорта - месяц первого заказа покупателя
+// (за все время, с учетом фильтра категории), ячейка - доля покупателей когорты с заказом
+// через N месяцев после первого. В ответ попадают когорты с первым заказом за период.
+func (r *analyticsRepository) GetCohorts(ctx context.Context, userID uuid.UUID, params model.CohortRequestParams) (*model.CohortResponse, error) {
+	if params.Period == "" {
+		params.Period = "1y"
+	}
+	startDate := getStartDate(params.Period)
+	startMonth := time.Date(startDate.Year(), startDate.Month(), 1, 0, 0, 0, 0, startDate.Location())
+
+	base, args := customerOrdersCTE(userID, params.Category, time.Time{})
+	var rows []struct {
+		Cohort      time.Time
+		MonthOffset int
+		Customers   int
+	}
+	err := r.db.WithContext(ctx).Raw(`
+		WITH base AS (`+base+`
+		), firsts AS (
+			SELECT customer_ref, date_trunc('month', MIN(date)) AS cohort
+			FROM base GROUP BY customer_ref
+		), activity AS (
+			SELECT DISTINCT b.customer_ref, f.cohort,
+				((EXTRACT(YEAR FROM b.date) - EXTRACT(YEAR FROM f.cohort)) * 12
+					+ EXTRACT(MONTH FROM b.date) - EXTRACT(MONTH FROM f.cohort))::int AS month_offset
+			FROM base b
+			JOIN firsts f ON f.customer_ref = b.customer_ref
+			WHERE f.cohort >= ?
+		)
+		SELECT cohort, month_offset, COUNT(*) AS customers
+		FROM activity
+		GROUP BY cohort, month_offset
+		ORDER BY cohort, month_offset`, append(args, startMonth)...).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to query cohorts: %w", err)
+	}
+
+	// Для каждой когорты заполняются все смещения до текущего месяца, в том числе без заказов
+	now := time.Now()
+	cohorts := []model.Cohort{}
+	index := map[string]int{}
+	for _, row := range rows {
+		month := row.Cohort.Format("2006-01")
+		i, ok := index[month]
+		if !ok {
+			elapsed := (now.Year()-row.Cohort.Year())*12 + int(now.Month()) - int(row.Cohort.Month())
+			retention := make([]model.CohortCell, elapsed+1)
+			for offset := range retention {
+				retention[offset].MonthOffset = offset
+			}
+			i = len(cohorts)
+			index[month] = i
+			cohorts = append(cohorts, model.Cohort{Month: month, Retention: retention})
+		}
+		cohort := &cohorts[i]
+		if row.MonthOffset == 0 {
+			cohort.Customers = row.Customers
+		}
+		if row.MonthOffset >= 0 && row.MonthOffset < len(cohort.Retention) {
+			cohort.Retention[row.MonthOffset].Customers = row.Customers
+		}
+	}
+
+	var sizes, active []int
+	for i := range cohorts {
+		cohort := &cohorts[i]
+		for offset := range cohort.Retention {
+			cell := &cohort.Retention[offset]
+			if cohort.Customers > 0 {
+				cell.Rate = roundTo(float64(cell.Customers)*100/float64(cohort.Customers), 1)
+			}
+			if offset >= len(sizes) {
+				sizes, active = append(sizes, 0), append(active, 0)
+			}
+			sizes[offset] += cohort.Customers
+			active[offset] += cell.Customers
+		}
+	}
+
+	average := make([]model.CohortCell, len(sizes))
+	for offset := range average {
+		average[offset] = model.CohortCell{MonthOffset: offset, Customers: active[offset]}
+		if sizes[offset] > 0 {
+			average[offset].Rate = roundTo(float64(active[offset])*100/float64(sizes[offset]), 1)
+		}
+	}
+
+	return &model.CohortResponse{
+		Period:   params.Period,
+		Category: params.Category,
+		Cohorts:  cohorts,
+		Average:  average,
+	}, nil
+}
+
+func roundTo(value float64, digits int) float64 {
+	p := math.Pow(10, float64(digits))
+	return math.Round(value*p) / p
+}
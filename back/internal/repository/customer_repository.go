@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/lamoda-seller-app/internal/model"
+	"gorm.io/gorm"
+)
+
+// CustomerRepository читает справочник покупателей продавца. Записи создаются и обновляются
+// триггерами БД по заказам (миграция 25_create_customers), поэтому методов записи здесь нет.
+type CustomerRepository struct {
+	db *gorm.DB
+}
+
+func NewCustomerRepository(db *gorm.DB) *CustomerRepository {
+	return &CustomerRepository{db: db}
+}
+
+// customerSortColumns - допустимые значения sort_by и соответствующие колонки
+var customerSortColumns = map[string]string{
+	"last_order":  "last_order_at",
+	"first_order": "first_order_at",
+	"orders":      "orders_count",
+	"revenue":     "revenue",
+	"avg_check":   "avg_check",
+	"returns":     "returns_count",
+	"name":        "lower(name)",
+}
+
+// List возвращает покупателей продавца по фильтру и их общее количество.
+func (r *CustomerRepository) List(ctx context.Context, userID uuid.UUID, filter model.CustomerFilter, limit, offset int) ([]model.Customer, int64, error) {
+	query := r.db.WithContext(ctx).Model(&model.Customer{}).Where("user_id = ?", userID)
+	if search := strings.TrimSpace(filter.Search); search != "" {
+		pattern := "%" + search + "%"
+		query = query.Where("(name ILIKE ? OR email ILIKE ? OR phone ILIKE ?)", pattern, pattern, pattern)
+	}
+	if filter.IsRegular != nil {
+		query = query.Where("is_regular = ?", *filter.IsRegular)
+	}
+	if filter.MinOrders > 0 {
+		query = query.Where("orders_count >= ?", filter.MinOrders)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	column, ok := customerSortColumns[filter.SortBy]
+	if !ok {
+		column = customerSortColumns["last_order"]
+	}
+	direction := "DESC NULLS LAST"
+	if strings.ToLower(filter.SortOrder) == "asc" {
+		direction = "ASC NULLS LAST"
+	}
+
+	var customers []model.Customer
+	err := query.Order(column + " " + direction).Order("id").
+		Limit(limit).Offset(offset).
+		Find(&customers).Error
+	return customers, total, err
+}
+
+// GetByID возвращает покупателя продавца.
+func (r *CustomerRepository) GetByID(ctx context.Context, userID, id uuid.UUID) (*model.Customer, error) {
+	var customer model.Customer
+	if err := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).First(&customer).Error; err != nil {
+		return nil, err
+	}
+	return &customer, nil
+}
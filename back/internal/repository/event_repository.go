@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lamoda-seller-app/internal/model"
+	"gorm.io/gorm"
+)
+
+var ErrStreamTicketInvalid = errors.New("stream ticket is invalid or expired")
+
+// EventRepository читает журнал событий продавцов (webhook_events) для потока живых обновлений.
+type EventRepository struct {
+	db *gorm.DB
+}
+
+func NewEventRepository(db *gorm.DB) *EventRepository {
+	return &EventRepository{db: db}
+}
+
+// Watermark возвращает границу журнала: все транзакции с номером меньше границы завершены,
+// поэтому события с txid меньше нее уже видны и новых среди них не появится.
+// Продолжать чтение журнала надо с границы, взятой до предыдущего чтения, а не с наибольшего seq:
+// seq выдается при вставке, и событие с меньшим номером может зафиксироваться позже.
+func (r *EventRepository) Watermark(ctx context.Context) (int64, error) {
+	var watermark int64
+	err := r.db.WithContext(ctx).Raw("SELECT pg_snapshot_xmin(pg_current_snapshot())::text::bigint").Scan(&watermark).Error
+	return watermark, err
+}
+
+// BySeq возвращает события с указанными номерами в порядке номеров.
+func (r *EventRepository) BySeq(ctx context.Context, seqs []int64) ([]model.WebhookEvent, error) {
+	var events []model.WebhookEvent
+	err := r.db.WithContext(ctx).Where("seq IN ?", seqs).Order("seq").Find(&events).Error
+	return events, err
+}
+
+// Since возвращает до limit событий продавцов userIDs из транзакций с номером не меньше watermark
+// и с номером события больше afterSeq (для чтения страницами), в порядке номеров.
+// Среди них могут быть уже полученные события, их узнают по seq.
+func (r *EventRepository) Since(ctx context.Context, userIDs []uuid.UUID, watermark, afterSeq int64, limit int) ([]model.WebhookEvent, error) {
+	var events []model.WebhookEvent
+	err := r.db.WithContext(ctx).
+		Where("user_id IN ? AND txid >= ? AND seq > ?", userIDs, watermark, afterSeq).
+		Order("seq").
+		Limit(limit).
+		Find(&events).Error
+	return events, err
+}
+
+// TouchStream отмечает, что продавец пользуется потоком: пока отметке меньше суток,
+// триггеры пишут в журнал и события product.updated, которые иначе нужны только вебхукам.
+func (r *EventRepository) TouchStream(ctx context.Context, userID uuid.UUID) error {
+	return r.db.WithContext(ctx).Model(&model.User{}).Where("id = ?", userID).
+		UpdateColumn("events_stream_at", time.Now()).Error
+}
+
+// StreamTicketIdleTTL - сколько билет на подключение к потоку действует после последнего использования.
+// Пока поток открыт, билет продлевается, поэтому автоматическое переподключение EventSource
+// по тому же адресу проходит; после долгого перерыва нужен новый билет.
+const StreamTicketIdleTTL = 2 * time.Minute
+
+// IssueStreamTicket выдает билет на подключение к потоку, действующий ttl до первого использования,
+// и время его истечения. Заодно удаляются просроченные билеты.
+func (r *EventRepository) IssueStreamTicket(ctx context.Context, userID uuid.UUID, ttl time.Duration) (string, time.Time, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", time.Time{}, err
+	}
+	ticket := base64.RawURLEncoding.EncodeToString(raw)
+	expiresAt := time.Now().Add(ttl)
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("expires_at <= NOW()").Delete(&model.StreamTicket{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&model.StreamTicket{
+			TokenHash: streamTicketHash(ticket),
+			UserID:    userID,
+			ExpiresAt: expiresAt,
+		}).Error
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return ticket, expiresAt, nil
+}
+
+// UseStreamTicket проверяет билет, продлевает его на StreamTicketIdleTTL и возвращает продавца,
+// которому он выдан. Открытый поток вызывает его периодически, чтобы билет не истек.
+// Просроченный или неизвестный билет - ErrStreamTicketInvalid.
+func (r *EventRepository) UseStreamTicket(ctx context.Context, ticket string) (uuid.UUID, error) {
+	var tickets []model.StreamTicket
+	err := r.db.WithContext(ctx).
+		Raw("UPDATE stream_tickets SET expires_at = ? WHERE token_hash = ? AND expires_at > NOW() RETURNING *",
+			time.Now().Add(StreamTicketIdleTTL), streamTicketHash(ticket)).
+		Scan(&tickets).Error
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if len(tickets) == 0 {
+		return uuid.Nil, ErrStreamTicketInvalid
+	}
+	return tickets[0].UserID, nil
+}
+
+func streamTicketHash(ticket string) string {
+	sum := sha256.Sum256([]byte(ticket))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/lamoda-seller-app/internal/barcode"
+	"github.com/lamoda-seller-app/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrGS1PrefixMissing = errors.New("GS1 company prefix is not set in the profile")
+
+// LabelRepository выпускает штрихкоды вариантам и собирает данные для печати этикеток.
+type LabelRepository struct {
+	db *gorm.DB
+}
+
+func NewLabelRepository(db *gorm.DB) *LabelRepository {
+	return &LabelRepository{db: db}
+}
+
+// GenerateGTINs выпускает EAN-13 из диапазона префикса GS1 продавца всем вариантам товаров, у которых нет GTIN.
+// Номера, уже занятые вариантами или штрихкодами товаров, пропускаются.
+// Если диапазон заканчивается, не выпускается ни один номер.
+func (r *LabelRepository) GenerateGTINs(ctx context.Context, userID uuid.UUID, productIDs []uuid.UUID) ([]model.GeneratedBarcode, error) {
+	var generated []model.GeneratedBarcode
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var user model.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, "id = ?", userID).Error; err != nil {
+			return err
+		}
+		if user.GS1Prefix == "" {
+			return ErrGS1PrefixMissing
+		}
+
+		var variants []model.ProductVariant
+		err := tx.Joins("JOIN products p ON p.id = product_variants.product_id").
+			Where("p.user_id = ? AND p.id IN ? AND p.deleted_at IS NULL", userID, productIDs).
+			Where("COALESCE(product_variants.gtin, '') = ''").
+			Order("p.created_at, product_variants.sku").
+			Clauses(clause.Locking{Strength: "UPDATE", Table: clause.Table{Name: "product_variants"}}).
+			Find(&variants).Error
+		if err != nil {
+			return err
+		}
+
+		next := user.GS1NextRef
+		for _, variant := range variants {
+			var ean string
+			for {
+				ean, err = barcode.GenerateEAN13(user.GS1Prefix, next)
+				if err != nil {
+					return err
+				}
+				next++
+
+				var used int64
+				err = tx.Raw(`SELECT (SELECT COUNT(*) FROM product_variants WHERE gtin = ?) +
+					(SELECT COUNT(*) FROM products WHERE barcode IN (?, ?))`, "0"+ean, ean, "0"+ean).Scan(&used).Error
+				if err != nil {
+					return err
+				}
+				if used == 0 {
+					break
+				}
+			}
+
+			gtin := "0" + ean
+			if err := tx.Model(&model.ProductVariant{}).Where("id = ?", variant.ID).Update("gtin", gtin).Error; err != nil {
+				return err
+			}
+			generated = append(generated, model.GeneratedBarcode{
+				ProductID: variant.ProductID,
+				VariantID: variant.ID,
+				SKU:       variant.SKU,
+				GTIN:      gtin,
+				EAN13:     ean,
+			})
+		}
+
+		return tx.Model(&model.User{}).Where("id = ?", userID).Update("gs1_next_ref", next).Error
+	})
+	return generated, err
+}
+
+// Variants возвращает данные для этикеток вариантов в порядке запроса.
+// Если какой-то вариант не найден у продавца, возвращается ErrVariantNotFound.
+func (r *LabelRepository) Variants(ctx context.Context, userID uuid.UUID, variantIDs []uuid.UUID) ([]model.LabelVariant, error) {
+	var rows []model.LabelVariant
+	if err := r.labelVariants(ctx, userID).Where("v.id IN ?", variantIDs).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]model.LabelVariant, len(rows))
+	for _, row := range rows {
+		byID[row.VariantID] = row
+	}
+
+	result := make([]model.LabelVariant, 0, len(variantIDs))
+	for _, id := range variantIDs {
+		row, ok := byID[id]
+		if !ok {
+			return nil, ErrVariantNotFound
+		}
+		result = append(result, row)
+	}
+	return result, nil
+}
+
+// ProductVariants возвращает данные для этикеток всех вариантов товара продавца.
+func (r *LabelRepository) ProductVariants(ctx context.Context, userID, productID uuid.UUID) ([]model.LabelVariant, error) {
+	var rows []model.LabelVariant
+	err := r.labelVariants(ctx, userID).Where("p.id = ?", productID).Order("v.sku").Scan(&rows).Error
+	return rows, err
+}
+
+func (r *LabelRepository) labelVariants(ctx context.Context, userID uuid.UUID) *gorm.DB {
+	return r.db.WithContext(ctx).Table("product_variants v").
+		Select(`v.id AS variant_id, p.id AS product_id, p.brand, p.name, v.sku, v.size, v.color,
+			COALESCE(v.gtin, '') AS gtin, CASE WHEN v.price > 0 THEN v.price ELSE p.price END AS price,
+			COALESCE(p.currency, 'RUB') AS currency`).
+		Joins("JOIN products p ON p.id = v.product_id").
+		Where("p.user_id = ? AND p.deleted_at IS NULL", userID)
+}
@@ -0,0 +1,703 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lamoda-seller-app/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrGTINMismatch            = errors.New("GTIN does not match the variant")
+	ErrGTINInUse               = errors.New("GTIN is already used by another variant")
+	ErrMarkingCodeNotFound     = errors.New("marking code not found")
+	ErrMarkingCodeUnavailable  = errors.New("marking code is not available")
+	ErrMarkingCodeWrongVariant = errors.New("marking code belongs to another variant")
+	ErrTooManyMarkingCodes     = errors.New("more marking codes than items in the order line")
+	ErrMarkingCodesShortage    = errors.New("not enough marking codes")
+	ErrOrderItemNotFound       = errors.New("order item not found")
+	ErrOrderAlreadyShipped     = errors.New("order has already been shipped")
+	ErrNothingToReport         = errors.New("no shipped marking codes to report")
+	ErrSellerINNMissing        = errors.New("seller INN is not set in the profile")
+)
+
+// MarkingRepository инкапсулирует работу с кодами маркировки "Честного ЗНАКа".
+type MarkingRepository struct {
+	db *gorm.DB
+}
+
+func NewMarkingRepository(db *gorm.DB) *MarkingRepository {
+	return &MarkingRepository{db: db}
+}
+
+// --- Загрузка кодов ---
+
+// ImportMarkingCodes - партия разобранных кодов для загрузки
+type ImportMarkingCodes struct {
+	VariantID *uuid.UUID // Вариант; если не задан, ищется по GTIN
+	GTIN      string
+	FileName  string
+	Codes     []model.MarkingCodeParts
+	Lines     []int // Номер строки файла для каждого кода, для сообщений об ошибках
+}
+
+// Import загружает партию кодов на вариант. Коды с чужим GTIN отклоняются, уже загруженные - пропускаются.
+// Если у варианта еще нет GTIN, он берется из партии.
+func (r *MarkingRepository) Import(ctx context.Context, userID uuid.UUID, actor *uuid.UUID, req ImportMarkingCodes) (*model.MarkingCodeBatch, error) {
+	var batch model.MarkingCodeBatch
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		gtin := req.GTIN
+		if gtin == "" && len(req.Codes) > 0 {
+			gtin = req.Codes[0].GTIN
+		}
+		variant, err := findMarkingVariant(tx, userID, req.VariantID, gtin)
+		if err != nil {
+			return err
+		}
+		if variant.GTIN != "" {
+			if req.GTIN != "" && req.GTIN != variant.GTIN {
+				return ErrGTINMismatch
+			}
+			gtin = variant.GTIN
+		} else {
+			var taken int64
+			err := tx.Model(&model.ProductVariant{}).
+				Joins("JOIN products p ON p.id = product_variants.product_id").
+				Where("p.user_id = ? AND product_variants.gtin = ? AND product_variants.id <> ?", userID, gtin, variant.ID).
+				Count(&taken).Error
+			if err != nil {
+				return err
+			}
+			if taken > 0 {
+				return ErrGTINInUse
+			}
+			if err := tx.Model(&model.ProductVariant{}).Where("id = ?", variant.ID).Update("gtin", gtin).Error; err != nil {
+				return err
+			}
+		}
+
+		batch = model.MarkingCodeBatch{
+			ID:        uuid.New(),
+			UserID:    userID,
+			VariantID: variant.ID,
+			GTIN:      gtin,
+			FileName:  req.FileName,
+			CreatedBy: actor,
+		}
+		if err := tx.Create(&batch).Error; err != nil {
+			return err
+		}
+
+		codes := make([]model.MarkingCode, 0, len(req.Codes))
+		seen := make(map[string]bool, len(req.Codes))
+		for i, parts := range req.Codes {
+			if parts.GTIN != gtin {
+				batch.Rejected = append(batch.Rejected, model.MarkingCodeRejection{
+					Line: req.Lines[i], Code: parts.CIS, Error: ErrGTINMismatch.Error(),
+				})
+				continue
+			}
+			if seen[parts.CIS] {
+				batch.Duplicates++
+				continue
+			}
+			seen[parts.CIS] = true
+			codes = append(codes, model.MarkingCode{
+				ID:        uuid.New(),
+				UserID:    userID,
+				BatchID:   batch.ID,
+				VariantID: variant.ID,
+				GTIN:      gtin,
+				CIS:       parts.CIS,
+				Code:      parts.Code,
+				Status:    model.MarkingStatusAvailable,
+			})
+		}
+
+		// Коды, загруженные раньше (в том числе другим продавцом), не перезаписываются
+		for start := 0; start < len(codes); start += 1000 {
+			end := min(start+1000, len(codes))
+			res := tx.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "cis"}}, DoNothing: true}).Create(codes[start:end])
+			if res.Error != nil {
+				return res.Error
+			}
+			batch.Imported += int(res.RowsAffected)
+		}
+		batch.Duplicates += len(codes) - batch.Imported
+
+		return tx.Model(&batch).Updates(map[string]interface{}{
+			"imported":   batch.Imported,
+			"duplicates": batch.Duplicates,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &batch, nil
+}
+
+// findMarkingVariant находит вариант продавца по ID или по GTIN.
+func findMarkingVariant(tx *gorm.DB, userID uuid.UUID, variantID *uuid.UUID, gtin string) (*model.ProductVariant, error) {
+	query := tx.Model(&model.ProductVariant{}).
+		Joins("JOIN products p ON p.id = product_variants.product_id").
+		Where("p.user_id = ?", userID)
+	if variantID != nil {
+		query = query.Where("product_variants.id = ?", *variantID)
+	} else {
+		query = query.Where("product_variants.gtin = ?", gtin)
+	}
+
+	var variant model.ProductVariant
+	err := query.Select("product_variants.*").Clauses(clause.Locking{Strength: "UPDATE", Table: clause.Table{Name: "product_variants"}}).
+		First(&variant).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrVariantNotFound
+	}
+	return &variant, err
+}
+
+// ListBatches возвращает загруженные партии кодов.
+func (r *MarkingRepository) ListBatches(ctx context.Context, userID uuid.UUID, variantID *uuid.UUID, limit, offset int) ([]model.MarkingCodeBatch, int64, error) {
+	query := r.db.WithContext(ctx).Model(&model.MarkingCodeBatch{}).Where("user_id = ?", userID)
+	if variantID != nil {
+		query = query.Where("variant_id = ?", *variantID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var batches []model.MarkingCodeBatch
+	err := query.Order("created_at DESC").Limit(limit).Offset(offset).Find(&batches).Error
+	return batches, total, err
+}
+
+// --- Просмотр кодов ---
+
+// ListMarkingCodesParams - параметры списка кодов
+type ListMarkingCodesParams struct {
+	UserID    uuid.UUID
+	Status    string
+	VariantID *uuid.UUID
+	ProductID *uuid.UUID
+	BatchID   *uuid.UUID
+	OrderID   *uuid.UUID
+	ReportID  *uuid.UUID
+	Search    string // По коду идентификации
+	Limit     int
+	Offset    int
+}
+
+// ListCodes возвращает коды маркировки продавца.
+func (r *MarkingRepository) ListCodes(ctx context.Context, params ListMarkingCodesParams) ([]model.MarkingCode, int64, error) {
+	query := r.withDetails(ctx).Where("marking_codes.user_id = ?", params.UserID)
+	if params.Status != "" {
+		query = query.Where("marking_codes.status = ?", params.Status)
+	}
+	if params.VariantID != nil {
+		query = query.Where("marking_codes.variant_id = ?", *params.VariantID)
+	}
+	if params.ProductID != nil {
+		query = query.Where("v.product_id = ?", *params.ProductID)
+	}
+	if params.BatchID != nil {
+		query = query.Where("marking_codes.batch_id = ?", *params.BatchID)
+	}
+	if params.OrderID != nil {
+		query = query.Where("marking_codes.order_id = ?", *params.OrderID)
+	}
+	if params.ReportID != nil {
+		query = query.Where("marking_codes.report_id = ?", *params.ReportID)
+	}
+	if params.Search != "" {
+		query = query.Where("marking_codes.cis LIKE ?", "%"+params.Search+"%")
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var codes []model.MarkingCode
+	err := query.Select(markingCodeDetailsSelect).
+		Order("marking_codes.created_at DESC, marking_codes.cis").
+		Limit(params.Limit).Offset(params.Offset).
+		Find(&codes).Error
+	return codes, total, err
+}
+
+// OrderCodes возвращает коды, назначенные позициям заказа.
+func (r *MarkingRepository) OrderCodes(ctx context.Context, orderID, userID uuid.UUID) ([]model.MarkingCode, error) {
+	var codes []model.MarkingCode
+	err := r.withDetails(ctx).Select(markingCodeDetailsSelect).
+		Where("marking_codes.user_id = ? AND marking_codes.order_id = ?", userID, orderID).
+		Order("marking_codes.order_item_id, marking_codes.cis").
+		Find(&codes).Error
+	return codes, err
+}
+
+// CodesByIDs возвращает коды продавца в порядке запроса, например для печати этикеток.
+func (r *MarkingRepository) CodesByIDs(ctx context.Context, userID uuid.UUID, ids []uuid.UUID) ([]model.MarkingCode, error) {
+	var codes []model.MarkingCode
+	err := r.withDetails(ctx).Select(markingCodeDetailsSelect).
+		Where("marking_codes.user_id = ? AND marking_codes.id IN ?", userID, ids).
+		Find(&codes).Error
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]model.MarkingCode, len(codes))
+	for _, code := range codes {
+		byID[code.ID] = code
+	}
+
+	result := make([]model.MarkingCode, 0, len(ids))
+	for _, id := range ids {
+		code, ok := byID[id]
+		if !ok {
+			return nil, ErrMarkingCodeNotFound
+		}
+		result = append(result, code)
+	}
+	return result, nil
+}
+
+func (r *MarkingRepository) withDetails(ctx context.Context) *gorm.DB {
+	return r.db.WithContext(ctx).Model(&model.MarkingCode{}).
+		Joins("JOIN product_variants v ON v.id = marking_codes.variant_id").
+		Joins("JOIN products p ON p.id = v.product_id").
+		Joins("LEFT JOIN orders o ON o.id = marking_codes.order_id")
+}
+
+const markingCodeDetailsSelect = "marking_codes.*, p.id AS product_id, p.name AS product_name, v.sku, v.size, v.color, o.order_number"
+
+// Summary возвращает количество кодов по статусам для каждого маркируемого варианта.
+func (r *MarkingRepository) Summary(ctx context.Context, userID uuid.UUID) ([]model.MarkingVariantSummary, error) {
+	var rows []model.MarkingVariantSummary
+	err := r.db.WithContext(ctx).Raw(`
+		SELECT v.id AS variant_id, p.id AS product_id, p.name AS product_name, v.sku, v.size, v.color,
+			COALESCE(v.gtin, '') AS gtin, v.stock,
+			COUNT(*) FILTER (WHERE mc.status = 'available') AS available,
+			COUNT(*) FILTER (WHERE mc.status = 'assigned') AS assigned,
+			COUNT(*) FILTER (WHERE mc.status = 'shipped') AS shipped,
+			COUNT(*) FILTER (WHERE mc.status = 'withdrawn') AS withdrawn,
+			COUNT(*) FILTER (WHERE mc.status = 'returned') AS returned,
+			GREATEST(v.stock - v.reserved - COUNT(*) FILTER (WHERE mc.status = 'available'), 0) AS shortage
+		FROM marking_codes mc
+		JOIN product_variants v ON v.id = mc.variant_id
+		JOIN products p ON p.id = v.product_id
+		WHERE mc.user_id = ?
+		GROUP BY v.id, p.id
+		ORDER BY shortage DESC, p.name, v.sku`, userID).
+		Scan(&rows).Error
+	return rows, err
+}
+
+// --- Действия с кодами ---
+
+// Withdraw выводит свободные или возвращенные коды из оборота. Возвращает количество выведенных кодов.
+func (r *MarkingRepository) Withdraw(ctx context.Context, userID uuid.UUID, ids []uuid.UUID, reason string) (int64, error) {
+	res := r.db.WithContext(ctx).Model(&model.MarkingCode{}).
+		Where("user_id = ? AND id IN ? AND status IN ?", userID, ids,
+			[]string{model.MarkingStatusAvailable, model.MarkingStatusReturned}).
+		Updates(map[string]interface{}{
+			"status":          model.MarkingStatusWithdrawn,
+			"withdraw_reason": reason,
+			"withdrawn_at":    time.Now(),
+		})
+	return res.RowsAffected, res.Error
+}
+
+// Reintroduce возвращает в оборот коды возвращенных товаров, чтобы их можно было продать снова.
+func (r *MarkingRepository) Reintroduce(ctx context.Context, userID uuid.UUID, ids []uuid.UUID) (int64, error) {
+	res := r.db.WithContext(ctx).Model(&model.MarkingCode{}).
+		Where("user_id = ? AND id IN ? AND status = ?", userID, ids, model.MarkingStatusReturned).
+		Updates(map[string]interface{}{
+			"status":        model.MarkingStatusAvailable,
+			"order_id":      nil,
+			"order_item_id": nil,
+			"assigned_at":   nil,
+			"shipped_at":    nil,
+		})
+	return res.RowsAffected, res.Error
+}
+
+// AssignToOrder назначает отсканированные коды позициям заказа. codes - коды идентификации по позициям.
+// Повторное назначение кода той же позиции ничего не меняет.
+func (r *MarkingRepository) AssignToOrder(ctx context.Context, userID, orderID uuid.UUID, codes map[uuid.UUID][]string) ([]model.MarkingCode, error) {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var order model.Order
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id = ?", userID).First(&order, orderID).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("order_id = ?", order.ID).Find(&order.Items).Error; err != nil {
+			return err
+		}
+		if orderShipped(order.Status) {
+			return ErrOrderAlreadyShipped
+		}
+
+		items := make(map[uuid.UUID]model.OrderItem, len(order.Items))
+		for _, item := range order.Items {
+			items[item.ID] = item
+		}
+
+		now := time.Now()
+		for itemID, cisList := range codes {
+			item, ok := items[itemID]
+			if !ok {
+				return ErrOrderItemNotFound
+			}
+
+			var found []model.MarkingCode
+			err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("user_id = ? AND cis IN ?", userID, cisList).Find(&found).Error
+			if err != nil {
+				return err
+			}
+			if len(found) != len(uniqueStrings(cisList)) {
+				return ErrMarkingCodeNotFound
+			}
+
+			var fresh []uuid.UUID
+			for _, code := range found {
+				if code.VariantID != item.VariantID {
+					return fmt.Errorf("%w: %s", ErrMarkingCodeWrongVariant, code.CIS)
+				}
+				if code.Status == model.MarkingStatusAssigned && code.OrderItemID != nil && *code.OrderItemID == item.ID {
+					continue
+				}
+				if code.Status != model.MarkingStatusAvailable {
+					return fmt.Errorf("%w: %s (%s)", ErrMarkingCodeUnavailable, code.CIS, code.Status)
+				}
+				fresh = append(fresh, code.ID)
+			}
+			if len(fresh) == 0 {
+				continue
+			}
+
+			var assigned int64
+			if err := tx.Model(&model.MarkingCode{}).Where("order_item_id = ?", item.ID).Count(&assigned).Error; err != nil {
+				return err
+			}
+			if int(assigned)+len(fresh) > item.Quantity {
+				return ErrTooManyMarkingCodes
+			}
+			if err := assignCodes(tx, fresh, &order, item, now); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.OrderCodes(ctx, orderID, userID)
+}
+
+// Unassign снимает назначенный код с позиции заказа до отгрузки.
+func (r *MarkingRepository) Unassign(ctx context.Context, userID, orderID, codeID uuid.UUID) error {
+	res := r.db.WithContext(ctx).Model(&model.MarkingCode{}).
+		Where("id = ? AND user_id = ? AND order_id = ? AND status = ?", codeID, userID, orderID, model.MarkingStatusAssigned).
+		Updates(releasedCodeColumns())
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrMarkingCodeNotFound
+	}
+	return nil
+}
+
+// --- Отчеты о выводе из оборота ---
+
+// CreateReport формирует отчет из отгруженных кодов, еще не вошедших в отчеты.
+func (r *MarkingRepository) CreateReport(ctx context.Context, userID uuid.UUID, req model.CreateMarkingReportRequest) (*model.MarkingReport, error) {
+	var report model.MarkingReport
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		query := tx.Model(&model.MarkingCode{}).
+			Where("user_id = ? AND status = ? AND report_id IS NULL", userID, model.MarkingStatusShipped)
+		if req.ShippedFrom != nil {
+			query = query.Where("shipped_at >= ?", *req.ShippedFrom)
+		}
+		if req.ShippedTo != nil {
+			query = query.Where("shipped_at <= ?", *req.ShippedTo)
+		}
+		var ids []uuid.UUID
+		if err := query.Clauses(clause.Locking{Strength: "UPDATE"}).Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return ErrNothingToReport
+		}
+
+		// Номер отчета: дата и порядковый номер за день
+		var sameDay int64
+		now := time.Now()
+		prefix := "KM-" + now.Format("20060102") + "-"
+		if err := tx.Model(&model.MarkingReport{}).Where("user_id = ? AND number LIKE ?", userID, prefix+"%").Count(&sameDay).Error; err != nil {
+			return err
+		}
+
+		report = model.MarkingReport{
+			ID:         uuid.New(),
+			UserID:     userID,
+			Number:     fmt.Sprintf("%s%03d", prefix, sameDay+1),
+			Status:     model.MarkingReportDraft,
+			CodesCount: len(ids),
+			CreatedAt:  now,
+		}
+		if err := tx.Create(&report).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.MarkingCode{}).Where("id IN ?", ids).Update("report_id", report.ID).Error; err != nil {
+			return err
+		}
+
+		lines, err := reportLines(tx, report.ID)
+		if err != nil {
+			return err
+		}
+		for _, line := range lines {
+			report.TotalKopecks += line.PriceKopecks
+		}
+		return tx.Model(&report).Update("total_kopecks", report.TotalKopecks).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// ListReports возвращает отчеты продавца.
+func (r *MarkingRepository) ListReports(ctx context.Context, userID uuid.UUID, limit, offset int) ([]model.MarkingReport, int64, error) {
+	query := r.db.WithContext(ctx).Model(&model.MarkingReport{}).Where("user_id = ?", userID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var reports []model.MarkingReport
+	err := query.Order("created_at DESC").Limit(limit).Offset(offset).Find(&reports).Error
+	return reports, total, err
+}
+
+// GetReport возвращает отчет продавца.
+func (r *MarkingRepository) GetReport(ctx context.Context, id, userID uuid.UUID) (*model.MarkingReport, error) {
+	var report model.MarkingReport
+	err := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).First(&report).Error
+	return &report, err
+}
+
+// ReportLines возвращает коды отчета с ценой реализации.
+func (r *MarkingRepository) ReportLines(ctx context.Context, reportID uuid.UUID) ([]model.MarkingReportLine, error) {
+	return reportLines(r.db.WithContext(ctx), reportID)
+}
+
+// ReportDocument собирает документ вывода из оборота для отправки в ГИС МТ.
+func (r *MarkingRepository) ReportDocument(ctx context.Context, report *model.MarkingReport) (*model.MarkingReportDocument, []model.MarkingReportLine, error) {
+	var inn string
+	if err := r.db.WithContext(ctx).Model(&model.User{}).Where("id = ?", report.UserID).Select("COALESCE(inn, '')").Row().Scan(&inn); err != nil {
+		return nil, nil, err
+	}
+	if inn == "" {
+		return nil, nil, ErrSellerINNMissing
+	}
+
+	lines, err := r.ReportLines(ctx, report.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	doc := model.BuildMarkingReportDocument(*report, inn, lines)
+	return &doc, lines, nil
+}
+
+// MarkSubmitted отмечает отчет отправленным в ГИС МТ.
+func (r *MarkingRepository) MarkSubmitted(ctx context.Context, report *model.MarkingReport, documentID string) error {
+	now := time.Now()
+	err := r.db.WithContext(ctx).Model(report).Updates(map[string]interface{}{
+		"status":             model.MarkingReportSubmitted,
+		"portal_document_id": documentID,
+		"submitted_at":       now,
+	}).Error
+	if err != nil {
+		return err
+	}
+	report.Status = model.MarkingReportSubmitted
+	report.PortalDocumentID = &documentID
+	report.SubmittedAt = &now
+	return nil
+}
+
+// reportLines - цена единицы берется из позиции заказа с учетом скидки.
+func reportLines(db *gorm.DB, reportID uuid.UUID) ([]model.MarkingReportLine, error) {
+	var lines []model.MarkingReportLine
+	err := db.Raw(`
+		SELECT mc.cis, mc.shipped_at, o.order_number,
+			ROUND(CASE WHEN oi.quantity > 0 AND oi.total > 0 THEN oi.total / oi.quantity ELSE oi.price END * 100)::bigint AS price_kopecks
+		FROM marking_codes mc
+		JOIN order_items oi ON oi.id = mc.order_item_id
+		JOIN orders o ON o.id = oi.order_id
+		WHERE mc.report_id = ?
+		ORDER BY mc.shipped_at, mc.cis`, reportID).
+		Scan(&lines).Error
+	return lines, err
+}
+
+// --- Коды при смене статуса заказа (внутри транзакции) ---
+
+// applyOrderMarking ведет коды маркировки вместе со статусом заказа:
+// in_transit - коды назначаются недостающим единицам и отгружаются, cancelled - назначенные коды освобождаются,
+// returned - отгруженные коды помечаются возвращенными.
+func applyOrderMarking(tx *gorm.DB, order *model.Order, status string) error {
+	switch status {
+	case model.OrderStatusInTransit:
+		return shipOrderCodes(tx, order)
+	case model.OrderStatusCancelled:
+		return tx.Model(&model.MarkingCode{}).
+			Where("order_id = ? AND status = ?", order.ID, model.MarkingStatusAssigned).
+			Updates(releasedCodeColumns()).Error
+	case model.OrderStatusReturned:
+		return tx.Model(&model.MarkingCode{}).
+			Where("order_id = ? AND status = ?", order.ID, model.MarkingStatusShipped).
+			Updates(map[string]interface{}{"status": model.MarkingStatusReturned, "returned_at": time.Now()}).Error
+	}
+	return nil
+}
+
+// shipOrderCodes назначает коды единицам, которые не отсканировали при сборке (в порядке загрузки),
+// и отгружает все коды заказа, кроме единиц, уже отгруженных отдельными посылками.
+func shipOrderCodes(tx *gorm.DB, order *model.Order) error {
+	quantities := make(map[uuid.UUID]int, len(order.Items))
+	for _, item := range order.Items {
+		quantities[item.ID] = item.Quantity - item.ShippedQuantity
+	}
+	return shipItemCodes(tx, order, quantities)
+}
+
+// shipItemCodes отгружает коды quantities единиц позиций заказа: сначала назначенные при сборке,
+// недостающие назначаются из свободных. Вариант считается маркируемым, если на него загружались коды.
+func shipItemCodes(tx *gorm.DB, order *model.Order, quantities map[uuid.UUID]int) error {
+	if len(order.Items) == 0 {
+		return nil
+	}
+	variantIDs := make([]uuid.UUID, 0, len(order.Items))
+	for _, item := range order.Items {
+		variantIDs = append(variantIDs, item.VariantID)
+	}
+	var marked []uuid.UUID
+	if err := tx.Model(&model.MarkingCode{}).Where("variant_id IN ?", variantIDs).Distinct().Pluck("variant_id", &marked).Error; err != nil {
+		return err
+	}
+	if len(marked) == 0 {
+		return nil
+	}
+	isMarked := make(map[uuid.UUID]bool, len(marked))
+	for _, id := range marked {
+		isMarked[id] = true
+	}
+
+	now := time.Now()
+	var ship []uuid.UUID
+	for _, item := range order.Items {
+		quantity := quantities[item.ID]
+		if !isMarked[item.VariantID] || quantity <= 0 {
+			continue
+		}
+		var assigned []uuid.UUID
+		err := tx.Model(&model.MarkingCode{}).
+			Where("order_item_id = ? AND status = ?", item.ID, model.MarkingStatusAssigned).
+			Order("assigned_at, id").Limit(quantity).Pluck("id", &assigned).Error
+		if err != nil {
+			return err
+		}
+		ship = append(ship, assigned...)
+		need := quantity - len(assigned)
+		if need <= 0 {
+			continue
+		}
+
+		var free []uuid.UUID
+		err = tx.Model(&model.MarkingCode{}).
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("variant_id = ? AND user_id = ? AND status = ?", item.VariantID, order.UserID, model.MarkingStatusAvailable).
+			Order("created_at, cis").Limit(need).
+			Pluck("id", &free).Error
+		if err != nil {
+			return err
+		}
+		if len(free) < need {
+			return fmt.Errorf("%w for %s: need %d, available %d", ErrMarkingCodesShortage, item.SKU, need, len(free))
+		}
+		if err := assignCodes(tx, free, order, item, now); err != nil {
+			return err
+		}
+		ship = append(ship, free...)
+	}
+	if len(ship) == 0 {
+		return nil
+	}
+
+	return tx.Model(&model.MarkingCode{}).
+		Where("id IN ?", ship).
+		Updates(map[string]interface{}{"status": model.MarkingStatusShipped, "shipped_at": now}).Error
+}
+
+func assignCodes(tx *gorm.DB, ids []uuid.UUID, order *model.Order, item model.OrderItem, at time.Time) error {
+	return tx.Model(&model.MarkingCode{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+		"status":        model.MarkingStatusAssigned,
+		"order_id":      order.ID,
+		"order_item_id": item.ID,
+		"assigned_at":   at,
+	}).Error
+}
+
+// releaseItemCodes возвращает в доступные коды, назначенные позиции сверх keep единиц,
+// например после частичной отмены. Первыми снимаются последние назначенные.
+func releaseItemCodes(tx *gorm.DB, itemID uuid.UUID, keep int) error {
+	var extra []uuid.UUID
+	err := tx.Model(&model.MarkingCode{}).
+		Where("order_item_id = ? AND status = ?", itemID, model.MarkingStatusAssigned).
+		Order("assigned_at, id").Offset(keep).Pluck("id", &extra).Error
+	if err != nil || len(extra) == 0 {
+		return err
+	}
+	return tx.Model(&model.MarkingCode{}).Where("id IN ?", extra).Updates(releasedCodeColumns()).Error
+}
+
+func releasedCodeColumns() map[string]interface{} {
+	return map[string]interface{}{
+		"status":        model.MarkingStatusAvailable,
+		"order_id":      nil,
+		"order_item_id": nil,
+		"assigned_at":   nil,
+	}
+}
+
+// orderShipped сообщает, что заказ уже отгружен или закрыт и коды ему назначать поздно.
+func orderShipped(status string) bool {
+	switch status {
+	case model.OrderStatusInTransit, model.OrderStatusDelivered, model.OrderStatusReturned, model.OrderStatusCancelled:
+		return true
+	}
+	return false
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	var out []string
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	return out
+}
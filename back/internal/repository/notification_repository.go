@@ -0,0 +1,183 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lamoda-seller-app/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxEmailAttempts - после стольких неудачных попыток письмо по уведомлению больше не отправляется
+const maxEmailAttempts = 3
+
+// NotificationRepository хранит уведомления продавцов, настройки каналов и очередь писем.
+type NotificationRepository struct {
+	db *gorm.DB
+}
+
+func NewNotificationRepository(db *gorm.DB) *NotificationRepository {
+	return &NotificationRepository{db: db}
+}
+
+// notify создает уведомление в транзакции tx через функцию БД create_notification,
+// которая учитывает канал категории и ставит письмо в очередь. Уведомление появляется
+// только вместе с изменением, о котором сообщает.
+func notify(tx *gorm.DB, userID uuid.UUID, category, notificationType, title, body, link string, data model.NotificationData) error {
+	if data == nil {
+		data = model.NotificationData{}
+	}
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return tx.Exec("SELECT create_notification(?, ?, ?, ?, ?, ?, ?::jsonb)",
+		userID, category, notificationType, title, body, link, string(payload)).Error
+}
+
+// List возвращает уведомления продавца, новые первыми, общее число и число непрочитанных по фильтру категории.
+func (r *NotificationRepository) List(ctx context.Context, userID uuid.UUID, filter model.NotificationFilter, limit, offset int) ([]model.Notification, int64, int64, error) {
+	query := r.db.WithContext(ctx).Model(&model.Notification{}).Where("user_id = ?", userID)
+	if filter.Category != "" {
+		query = query.Where("category = ?", filter.Category)
+	}
+
+	var unread int64
+	if err := query.Session(&gorm.Session{}).Where("read_at IS NULL").Count(&unread).Error; err != nil {
+		return nil, 0, 0, err
+	}
+	if filter.UnreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, 0, err
+	}
+
+	var notifications []model.Notification
+	err := query.Order("created_at DESC, id").Limit(limit).Offset(offset).Find(&notifications).Error
+	return notifications, total, unread, err
+}
+
+// UnreadCounts возвращает число непрочитанных уведомлений по категориям.
+func (r *NotificationRepository) UnreadCounts(ctx context.Context, userID uuid.UUID) (map[string]int64, error) {
+	var rows []struct {
+		Category string
+		Count    int64
+	}
+	err := r.db.WithContext(ctx).Model(&model.Notification{}).
+		Select("category, COUNT(*) AS count").
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Group("category").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int64, len(model.NotificationCategories))
+	for _, category := range model.NotificationCategories {
+		counts[category] = 0
+	}
+	for _, row := range rows {
+		counts[row.Category] = row.Count
+	}
+	return counts, nil
+}
+
+// MarkRead отмечает уведомления продавца прочитанными и возвращает, сколько из них было непрочитано.
+func (r *NotificationRepository) MarkRead(ctx context.Context, userID uuid.UUID, ids []uuid.UUID) (int64, error) {
+	result := r.db.WithContext(ctx).Model(&model.Notification{}).
+		Where("user_id = ? AND id IN ? AND read_at IS NULL", userID, ids).
+		Update("read_at", time.Now())
+	return result.RowsAffected, result.Error
+}
+
+// MarkAllRead отмечает прочитанными все уведомления продавца, или только категории, если она указана.
+func (r *NotificationRepository) MarkAllRead(ctx context.Context, userID uuid.UUID, category string) (int64, error) {
+	query := r.db.WithContext(ctx).Model(&model.Notification{}).Where("user_id = ? AND read_at IS NULL", userID)
+	if category != "" {
+		query = query.Where("category = ?", category)
+	}
+	result := query.Update("read_at", time.Now())
+	return result.RowsAffected, result.Error
+}
+
+// Preferences возвращает каналы всех категорий. Для категорий без настройки - только в приложении.
+func (r *NotificationRepository) Preferences(ctx context.Context, userID uuid.UUID) ([]model.NotificationPreference, error) {
+	var saved []model.NotificationPreference
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Find(&saved).Error; err != nil {
+		return nil, err
+	}
+	byCategory := make(map[string]model.NotificationPreference, len(saved))
+	for _, p := range saved {
+		byCategory[p.Category] = p
+	}
+
+	prefs := make([]model.NotificationPreference, 0, len(model.NotificationCategories))
+	for _, category := range model.NotificationCategories {
+		p, ok := byCategory[category]
+		if !ok {
+			p = model.NotificationPreference{UserID: userID, Category: category, Channel: model.NotificationChannelInApp}
+		}
+		prefs = append(prefs, p)
+	}
+	return prefs, nil
+}
+
+// UpdatePreferences сохраняет каналы указанных категорий.
+func (r *NotificationRepository) UpdatePreferences(ctx context.Context, userID uuid.UUID, prefs []model.NotificationPreferenceRequest) error {
+	rows := make([]model.NotificationPreference, 0, len(prefs))
+	for _, p := range prefs {
+		rows = append(rows, model.NotificationPreference{UserID: userID, Category: p.Category, Channel: p.Channel})
+	}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "category"}},
+		DoUpdates: clause.AssignmentColumns([]string{"channel", "updated_at"}),
+	}).Create(&rows).Error
+}
+
+// ClaimEmails выбирает уведомления, ждущие письма, вместе с адресом продавца и помечает их взятыми.
+// Письма, взятые больше lease назад и не отмеченные отправленными (сервер упал), берутся снова.
+func (r *NotificationRepository) ClaimEmails(ctx context.Context, limit int, lease time.Duration) ([]model.Notification, error) {
+	var ids []uuid.UUID
+	err := r.db.WithContext(ctx).Raw(`
+		UPDATE notifications SET email_status = ?, emailed_at = NOW()
+		WHERE id IN (
+			SELECT id FROM notifications
+			WHERE email_status = ? OR (email_status = ? AND emailed_at < NOW() - make_interval(secs => ?))
+			ORDER BY created_at
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED)
+		RETURNING id`,
+		model.NotificationEmailSending, model.NotificationEmailPending, model.NotificationEmailSending, lease.Seconds(), limit).
+		Scan(&ids).Error
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+
+	var notifications []model.Notification
+	err = r.db.WithContext(ctx).Model(&model.Notification{}).
+		Select("notifications.*, users.email AS user_email").
+		Joins("JOIN users ON users.id = notifications.user_id").
+		Where("notifications.id IN ?", ids).
+		Order("notifications.created_at").
+		Find(&notifications).Error
+	return notifications, err
+}
+
+// RecordEmail сохраняет результат отправки письма. Неудачное письмо возвращается в очередь,
+// пока не исчерпаны попытки.
+func (r *NotificationRepository) RecordEmail(ctx context.Context, id uuid.UUID, sendErr error) error {
+	if sendErr == nil {
+		return r.db.WithContext(ctx).Model(&model.Notification{}).Where("id = ?", id).
+			Updates(map[string]interface{}{"email_status": model.NotificationEmailSent, "emailed_at": time.Now()}).Error
+	}
+	return r.db.WithContext(ctx).Model(&model.Notification{}).Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"email_attempts": gorm.Expr("email_attempts + 1"),
+			"email_status": gorm.Expr("CASE WHEN email_attempts + 1 >= ? THEN ? ELSE ? END",
+				maxEmailAttempts, model.NotificationEmailFailed, model.NotificationEmailPending),
+		}).Error
+}
@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lamoda-seller-app/internal/model"
+	"gorm.io/gorm"
+)
+
+var ErrOrderNotFound = errors.New("order not found")
+
+// OrderDocumentRepository хранит номера документов, выданных по заказам.
+type OrderDocumentRepository struct {
+	db *gorm.DB
+}
+
+func NewOrderDocumentRepository(db *gorm.DB) *OrderDocumentRepository {
+	return &OrderDocumentRepository{db: db}
+}
+
+// OrderDocumentSnapshot собирает содержимое документа по заказу на момент выдачи номера.
+type OrderDocumentSnapshot func(orderID uuid.UUID) (model.OrderDocumentData, error)
+
+// Issue возвращает документы вида docType для заказов продавца в порядке запроса.
+// Заказам, по которым документ еще не выдавался, присваивается номер вида PS-20261018-001:
+// префикс вида, дата и порядковый номер за день; содержимое документа сохраняется через snapshot.
+func (r *OrderDocumentRepository) Issue(ctx context.Context, userID uuid.UUID, docType string, orderIDs []uuid.UUID, snapshot OrderDocumentSnapshot) ([]model.OrderDocument, error) {
+	byOrder := make(map[uuid.UUID]model.OrderDocument, len(orderIDs))
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var owned int64
+		if err := tx.Model(&model.Order{}).Where("user_id = ? AND id IN ?", userID, orderIDs).Count(&owned).Error; err != nil {
+			return err
+		}
+		if int(owned) != len(uniqueUUIDs(orderIDs)) {
+			return ErrOrderNotFound
+		}
+
+		// Нумерация последовательна в пределах продавца, поэтому выдачи номеров выполняются по очереди
+		if err := tx.Exec("SELECT id FROM users WHERE id = ? FOR UPDATE", userID).Error; err != nil {
+			return err
+		}
+
+		var existing []model.OrderDocument
+		if err := tx.Where("order_id IN ? AND type = ?", orderIDs, docType).Find(&existing).Error; err != nil {
+			return err
+		}
+		for _, doc := range existing {
+			byOrder[doc.OrderID] = doc
+		}
+
+		now := time.Now()
+		prefix := fmt.Sprintf("%s-%s-", model.OrderDocumentPrefix(docType), now.Format("20060102"))
+		var sameDay int64
+		if err := tx.Model(&model.OrderDocument{}).Where("user_id = ? AND number LIKE ?", userID, prefix+"%").Count(&sameDay).Error; err != nil {
+			return err
+		}
+
+		for _, orderID := range orderIDs {
+			if _, ok := byOrder[orderID]; ok {
+				continue
+			}
+			data, err := snapshot(orderID)
+			if err != nil {
+				return err
+			}
+			sameDay++
+			doc := model.OrderDocument{
+				ID:       uuid.New(),
+				UserID:   userID,
+				OrderID:  orderID,
+				Type:     docType,
+				Number:   fmt.Sprintf("%s%03d", prefix, sameDay),
+				Data:     data,
+				IssuedAt: now,
+			}
+			if err := tx.Create(&doc).Error; err != nil {
+				return err
+			}
+			byOrder[orderID] = doc
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	docs := make([]model.OrderDocument, len(orderIDs))
+	for i, orderID := range orderIDs {
+		docs[i] = byOrder[orderID]
+	}
+	return docs, nil
+}
+
+// ListByOrder возвращает документы, выданные по заказу.
+func (r *OrderDocumentRepository) ListByOrder(ctx context.Context, userID, orderID uuid.UUID) ([]model.OrderDocument, error) {
+	var docs []model.OrderDocument
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND order_id = ?", userID, orderID).
+		Order("issued_at").
+		Find(&docs).Error
+	return docs, err
+}
+
+func uniqueUUIDs(ids []uuid.UUID) []uuid.UUID {
+	seen := make(map[uuid.UUID]struct{}, len(ids))
+	result := make([]uuid.UUID, 0, len(ids))
+	for _, id := range ids {
+		if _, ok := seen[id]; !ok {
+			seen[id] = struct{}{}
+			result = append(result, id)
+		}
+	}
+	return result
+}
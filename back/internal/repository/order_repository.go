@@ -2,13 +2,22 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/lamoda-seller-app/internal/model"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrOrderNotCancellable  = errors.New("order can no longer be cancelled")
+	ErrCancelQuantityTooBig = errors.New("cancelled quantity exceeds the quantity in the order line")
+	ErrInvalidTransition    = errors.New("order status transition is not allowed")
 )
 
 // OrderRepository инкапсулирует логику работы с заказами в БД.
@@ -26,7 +35,7 @@ type ListOrdersParams struct {
 	Status     string
 	DateFrom   *time.Time
 	DateTo     *time.Time
-	CustomerID uuid.UUID
+	CustomerID uuid.UUID // ID покупателя из маркетплейса или из справочника покупателей
 	ProductID  uuid.UUID
 	MinAmount  float64
 	MaxAmount  float64
@@ -34,6 +43,8 @@ type ListOrdersParams struct {
 	SortOrder  string
 	Limit      int
 	Offset     int
+	Cursor     *Cursor // Если задан, используется keyset-пагинация, Offset игнорируется
+	Keyset     bool    // Первая страница в keyset-режиме (курсора еще нет)
 }
 
 // StatusBreakdown - часть сводной информации по статусам.
@@ -52,11 +63,62 @@ type ListOrdersSummary struct {
 }
 
 // List возвращает отфильтрованный и отсортированный список заказов с пагинацией и сводкой.
-func (r *OrderRepository) List(ctx context.Context, params ListOrdersParams) ([]model.Order, *ListOrdersSummary, int64, error) {
+func (r *OrderRepository) List(ctx context.Context, params ListOrdersParams) ([]model.Order, *ListOrdersSummary, int64, *PageInfo, error) {
 	var orders []model.Order
 	var total int64
 	var summary ListOrdersSummary
 
+	// --- 1-2. Создаем базовый запрос с фильтрами ---
+	query := r.filtered(ctx, params)
+
+	// --- 3. Вычисляем сводную информацию (summary) на основе отфильтрованного набора ---
+	if err := r.calculateSummary(query, &summary); err != nil {
+		return nil, nil, 0, nil, fmt.Errorf("failed to calculate summary: %w", err)
+	}
+
+	// --- 4. Считаем общее количество для пагинации ---
+	if err := query.Count(&total).Error; err != nil {
+		return nil, nil, 0, nil, err
+	}
+
+	// --- 5. Применяем сортировку ---
+	// Курсор хранит сортировку, с которой была получена предыдущая страница
+	sortBy, desc := params.SortBy, strings.ToLower(params.SortOrder) == "desc"
+	if params.Cursor != nil {
+		sortBy, desc = params.Cursor.SortBy, params.Cursor.Desc
+	}
+	sortKey, ok := orderSortKey(sortBy)
+	if !ok {
+		if params.Cursor != nil {
+			return nil, nil, 0, nil, ErrInvalidCursor
+		}
+		sortBy, desc = "date", true // Сортировка по умолчанию
+		sortKey, _ = orderSortKey(sortBy)
+	}
+
+	// --- 6. Применяем пагинацию и Eager Loading для связанных данных ---
+	query = query.Preload("Items").Preload("StatusHistory")
+	key := func(o *model.Order) (string, uuid.UUID) { return sortKey.value(o), o.ID }
+	var page *PageInfo
+	if params.Cursor != nil || params.Keyset {
+		if err := keysetPage(query, sortKey.column, "orders.id", desc, params.Cursor, params.Limit).Find(&orders).Error; err != nil {
+			return nil, nil, 0, nil, err
+		}
+		orders, page = finishKeysetPage(orders, sortBy, desc, params.Cursor, params.Limit, key)
+	} else {
+		err := keysetOrder(query, sortKey.column, "orders.id", desc).
+			Offset(params.Offset).Limit(params.Limit).Find(&orders).Error
+		if err != nil {
+			return nil, nil, 0, nil, err
+		}
+		page = offsetPageInfo(orders, sortBy, desc, total, params.Limit, params.Offset, key)
+	}
+
+	return orders, &summary, total, page, nil
+}
+
+// filtered возвращает запрос заказов продавца со всеми фильтрами из params.
+func (r *OrderRepository) filtered(ctx context.Context, params ListOrdersParams) *gorm.DB {
 	// --- 1. Создаем базовый запрос с обязательным фильтром по ID продавца ---
 	query := r.db.WithContext(ctx).Model(&model.Order{}).Where("user_id = ?", params.UserID)
 
@@ -71,7 +133,7 @@ func (r *OrderRepository) List(ctx context.Context, params ListOrdersParams) ([]
 		query = query.Where("date <= ?", params.DateTo)
 	}
 	if params.CustomerID != uuid.Nil {
-		query = query.Where("customer_id = ?", params.CustomerID)
+		query = query.Where("(customer_id = ? OR customer_ref = ?)", params.CustomerID, params.CustomerID)
 	}
 	// Для фильтра по ID товара нужен подзапрос
 	if params.ProductID != uuid.Nil {
@@ -83,44 +145,37 @@ func (r *OrderRepository) List(ctx context.Context, params ListOrdersParams) ([]
 	if params.MaxAmount > 0 {
 		query = query.Where("totals ->> 'total' <= ?", fmt.Sprintf("%f", params.MaxAmount))
 	}
+	return query
+}
 
-	// --- 3. Вычисляем сводную информацию (summary) на основе отфильтрованного набора ---
-	if err := r.calculateSummary(query, &summary); err != nil {
-		return nil, nil, 0, fmt.Errorf("failed to calculate summary: %w", err)
-	}
+// Count возвращает количество заказов, подходящих под фильтры.
+func (r *OrderRepository) Count(ctx context.Context, params ListOrdersParams) (int64, error) {
+	var total int64
+	err := r.filtered(ctx, params).Count(&total).Error
+	return total, err
+}
 
-	// --- 4. Считаем общее количество для пагинации ---
-	if err := query.Count(&total).Error; err != nil {
-		return nil, nil, 0, err
-	}
+// orderSortKeyDef описывает ключ сортировки списка заказов и способ получить его значение для курсора.
+type orderSortKeyDef struct {
+	column keysetColumn
+	value  func(o *model.Order) string
+}
 
-	// --- 5. Применяем сортировку ---
-	if params.SortBy != "" {
-		sortColumn := ""
-		switch params.SortBy {
-		case "date":
-			sortColumn = "date"
-		case "amount":
-			sortColumn = "totals -> 'total'" // Сортировка по JSON-полю
-		case "status":
-			sortColumn = "status"
-		}
-		if sortColumn != "" {
-			orderDirection := "ASC"
-			if strings.ToLower(params.SortOrder) == "desc" {
-				orderDirection = "DESC"
-			}
-			query = query.Order(fmt.Sprintf("%s %s", sortColumn, orderDirection))
-		}
-	} else {
-		query = query.Order("date DESC") // Сортировка по умолчанию
+// orderSortKey возвращает ключ сортировки по значению параметра sort_by.
+func orderSortKey(sortBy string) (orderSortKeyDef, bool) {
+	switch sortBy {
+	case "date":
+		return orderSortKeyDef{keysetColumn{expr: "orders.date", cast: "timestamptz"},
+			func(o *model.Order) string { return cursorTime(o.Date) }}, true
+	case "amount":
+		// Сумма хранится в JSON-поле totals
+		return orderSortKeyDef{keysetColumn{expr: "COALESCE((orders.totals ->> 'total')::numeric, 0)", cast: "numeric"},
+			func(o *model.Order) string { return cursorFloat(o.Totals.Total) }}, true
+	case "status":
+		return orderSortKeyDef{keysetColumn{expr: "orders.status", cast: "text"},
+			func(o *model.Order) string { return o.Status }}, true
 	}
-
-	// --- 6. Применяем пагинацию и Eager Loading для связанных данных ---
-	err := query.Preload("Items").Preload("StatusHistory").
-		Offset(params.Offset).Limit(params.Limit).Find(&orders).Error
-
-	return orders, &summary, total, err
+	return orderSortKeyDef{}, false
 }
 
 // calculateSummary вычисляет сводку по заказам на основе предоставленного запроса.
@@ -168,6 +223,9 @@ func (r *OrderRepository) GetByID(ctx context.Context, orderID, userID uuid.UUID
 	err := r.db.WithContext(ctx).
 		Preload("Items").
 		Preload("StatusHistory").
+		Preload("Cancellations", func(db *gorm.DB) *gorm.DB { return db.Order("created_at") }).
+		Preload("Shipments", func(db *gorm.DB) *gorm.DB { return db.Order("created_at") }).
+		Preload("Shipments.Items").
 		Where("user_id = ?", userID). // Проверка, что заказ принадлежит этому продавцу
 		First(&order, orderID).Error
 	return &order, err
@@ -178,17 +236,34 @@ func (r *OrderRepository) UpdateStatus(ctx context.Context, orderID, userID uuid
 	var order model.Order
 	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		// 1. Найти заказ, убедившись, что он принадлежит продавцу
-		if err := tx.Where("user_id = ?", userID).First(&order, orderID).Error; err != nil {
+		if err := tx.Preload("Items").Where("user_id = ?", userID).First(&order, orderID).Error; err != nil {
 			return err // Возвращает gorm.ErrRecordNotFound, если не найден
 		}
 
+		// Резервирование, отгрузка или освобождение остатков на складах и кодов маркировки.
+		// Повтор текущего статуса только добавляет запись в историю (комментарий, дата доставки)
+		if status != order.Status {
+			if !model.CanTransitionOrder(order.Status, status) {
+				return fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, order.Status, status)
+			}
+			if err := applyOrderStock(tx, &order, status); err != nil {
+				return err
+			}
+			if err := applyOrderMarking(tx, &order, status); err != nil {
+				return err
+			}
+			if err := applyOrderShipments(tx, &order, status); err != nil {
+				return err
+			}
+		}
+
 		// 2. Обновить статус и дату доставки в самом заказе
 		order.Status = status
 		if estimatedDate != nil {
 			order.Delivery.EstimatedDate = *estimatedDate
 			// GORM автоматически обработает обновление JSONB поля
 		}
-		if err := tx.Save(&order).Error; err != nil {
+		if err := tx.Omit(clause.Associations).Save(&order).Error; err != nil {
 			return err
 		}
 
@@ -210,4 +285,214 @@ func (r *OrderRepository) UpdateStatus(ctx context.Context, orderID, userID uuid
 		return nil, err
 	}
 	return &order, nil
-}
\ No newline at end of file
+}
+
+// Cancel отменяет заказ целиком или, если в req переданы позиции, только указанные единицы.
+// Отменить можно только заказ, который еще не отгружен. Резервы и коды маркировки отмененных единиц
+// освобождаются, из несобранных посылок они убираются, приходящаяся на них доля выплаты продавцу
+// (PaymentInfo.SellerAmount) удерживается с баланса (без ухода в минус, иначе ErrInsufficientFunds),
+// отмена фиксируется в истории статусов.
+// Если отменяются все оставшиеся единицы, заказ отменяется целиком.
+func (r *OrderRepository) Cancel(ctx context.Context, orderID, userID uuid.UUID, req model.CancelOrderRequest) (*model.Order, *model.OrderCancellation, error) {
+	var order model.Order
+	var cancellation model.OrderCancellation
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Preload("Items").Where("user_id = ?", userID).First(&order, orderID).Error
+		if err != nil {
+			return err
+		}
+		if order.Status != model.OrderStatusNew && order.Status != model.OrderStatusConfirmed {
+			return fmt.Errorf("%w: status is %s", ErrOrderNotCancellable, order.Status)
+		}
+
+		quantities, full, err := cancelQuantities(&order, req.Items)
+		if err != nil {
+			return err
+		}
+
+		cancellation = model.OrderCancellation{
+			ID:         uuid.New(),
+			UserID:     userID,
+			OrderID:    order.ID,
+			Reason:     req.Reason,
+			Initiator:  req.Initiator,
+			Comment:    req.Comment,
+			FullCancel: full,
+			Items:      model.CancelledItemsList{},
+		}
+		var comment string
+		var cancelled float64 // Сумма отмененных позиций при частичной отмене
+		if full {
+			if err := applyOrderStock(tx, &order, model.OrderStatusCancelled); err != nil {
+				return err
+			}
+			if err := applyOrderMarking(tx, &order, model.OrderStatusCancelled); err != nil {
+				return err
+			}
+			if err := applyOrderShipments(tx, &order, model.OrderStatusCancelled); err != nil {
+				return err
+			}
+			for i := range order.Items {
+				item := &order.Items[i]
+				if item.Quantity == 0 {
+					continue
+				}
+				cancellation.Items = append(cancellation.Items, model.CancelledItem{OrderItemID: item.ID, Quantity: item.Quantity, Amount: item.Total})
+				item.CancelledQuantity += item.Quantity
+				item.Quantity = 0
+				if err := tx.Model(item).Select("quantity", "cancelled_quantity").Updates(item).Error; err != nil {
+					return err
+				}
+			}
+			order.Status = model.OrderStatusCancelled
+			comment = "Заказ отменен"
+		} else {
+			var lines []string
+			for i := range order.Items {
+				item := &order.Items[i]
+				q := quantities[item.ID]
+				if q == 0 {
+					continue
+				}
+				amount := roundMoney(item.Total * float64(q) / float64(item.Quantity))
+				discount := roundMoney(item.Discount * float64(q) / float64(item.Quantity))
+
+				order.Totals.Subtotal = roundMoney(order.Totals.Subtotal - item.Price*float64(q))
+				order.Totals.Discount = roundMoney(order.Totals.Discount - discount)
+				order.Totals.Total = roundMoney(order.Totals.Total - amount)
+
+				item.Quantity -= q
+				item.CancelledQuantity += q
+				item.Total = roundMoney(item.Total - amount)
+				item.Discount = roundMoney(item.Discount - discount)
+				err := tx.Model(item).Select("quantity", "cancelled_quantity", "total", "discount").Updates(item).Error
+				if err != nil {
+					return err
+				}
+				if err := releaseOrderItem(tx, item.ID, q); err != nil {
+					return err
+				}
+				if err := releaseItemCodes(tx, item.ID, item.Quantity); err != nil {
+					return err
+				}
+				if err := shrinkPendingShipments(tx, item.ID, item.Quantity); err != nil {
+					return err
+				}
+
+				cancelled += amount
+				cancellation.Items = append(cancellation.Items, model.CancelledItem{OrderItemID: item.ID, Quantity: q, Amount: amount})
+				lines = append(lines, fmt.Sprintf("%s × %d", item.SKU, q))
+			}
+
+			comment = "Отменены позиции: " + strings.Join(lines, ", ")
+		}
+
+		var kopecks int64
+		cancellation.RefundAmount, kopecks = cancelPayment(&order.Payment, cancelled, full)
+		if kopecks > 0 {
+			cancellation.SellerAmount = float64(kopecks) / 100
+			err := changeBalance(tx, userID, -kopecks, "balance.order_reversal",
+				fmt.Sprintf("Списание %.2f ₽ по отмене заказа №%s", cancellation.SellerAmount, order.OrderNumber),
+				"Выплата за отмененные позиции удержана с баланса", "/orders/"+order.ID.String(),
+				model.NotificationData{"order_id": order.ID, "amount": cancellation.SellerAmount})
+			if err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Omit(clause.Associations).Save(&order).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&cancellation).Error; err != nil {
+			return err
+		}
+
+		comment = fmt.Sprintf("%s (причина: %s, инициатор: %s)", comment, req.Reason, req.Initiator)
+		if req.Comment != "" {
+			comment += ". " + req.Comment
+		}
+		// Об отмене, сделанной самим продавцом, уведомлять не нужно
+		if req.Initiator == model.CancelInitiatorCustomer {
+			err := notify(tx, userID, model.NotificationCategoryOrders, "order.cancelled",
+				"Покупатель отменил заказ №"+order.OrderNumber, comment, "/orders/"+order.ID.String(),
+				model.NotificationData{"order_id": order.ID, "full_cancel": full, "reason": req.Reason})
+			if err != nil {
+				return err
+			}
+		}
+		return tx.Create(&model.StatusHistory{
+			OrderID: order.ID,
+			Status:  order.Status,
+			Date:    time.Now(),
+			Comment: comment,
+		}).Error
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return &order, &cancellation, nil
+}
+
+// cancelPayment уменьшает оплату заказа при отмене и возвращает сумму возврата покупателю и долю выплаты
+// продавцу в копейках, которая удерживается с его баланса. При полной отмене возвращается вся оплата
+// и удерживается вся выплата, при частичной - пропорционально доле отмененной суммы cancelled в оплате.
+func cancelPayment(payment *model.PaymentInfo, cancelled float64, full bool) (refund float64, sellerKopecks int64) {
+	if payment.Amount <= 0 {
+		return 0, 0
+	}
+	if full {
+		payment.Status = model.PaymentStatusRefunded
+		return payment.Amount, int64(math.Round(payment.SellerAmount * 100))
+	}
+
+	share := math.Min(cancelled/payment.Amount, 1)
+	refund = roundMoney(payment.Amount * share)
+	sellerShare := roundMoney(payment.SellerAmount * share)
+	commission := roundMoney(payment.CommissionLamoda * share)
+	payment.Amount = roundMoney(payment.Amount - refund)
+	payment.SellerAmount = roundMoney(payment.SellerAmount - sellerShare)
+	payment.CommissionLamoda = roundMoney(payment.CommissionLamoda - commission)
+	payment.Status = model.PaymentStatusPartiallyRefunded
+	return refund, int64(math.Round(sellerShare * 100))
+}
+
+// cancelQuantities проверяет отменяемые позиции и возвращает количество к отмене по ID позиции.
+// full - отменяется весь заказ: позиции не указаны или указаны все оставшиеся единицы.
+func cancelQuantities(order *model.Order, items []model.CancelOrderItem) (map[uuid.UUID]int, bool, error) {
+	if len(items) == 0 {
+		return nil, true, nil
+	}
+	quantities := make(map[uuid.UUID]int, len(items))
+	for _, it := range items {
+		quantities[it.OrderItemID] += it.Quantity
+	}
+
+	found := 0
+	full := true
+	for _, item := range order.Items {
+		q, ok := quantities[item.ID]
+		if !ok {
+			if item.Quantity > 0 {
+				full = false
+			}
+			continue
+		}
+		found++
+		if q > item.Quantity {
+			return nil, false, fmt.Errorf("%w: %s (%d of %d)", ErrCancelQuantityTooBig, item.SKU, q, item.Quantity)
+		}
+		if q < item.Quantity {
+			full = false
+		}
+	}
+	if found != len(quantities) {
+		return nil, false, ErrOrderItemNotFound
+	}
+	return quantities, full, nil
+}
+
+// roundMoney округляет сумму до копеек.
+func roundMoney(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/lamoda-seller-app/internal/model"
+)
+
+func TestCancelPayment(t *testing.T) {
+	paid := model.PaymentInfo{Amount: 3000, CommissionLamoda: 450, SellerAmount: 2550}
+	tests := []struct {
+		name      string
+		payment   model.PaymentInfo
+		cancelled float64
+		full      bool
+		refund    float64
+		kopecks   int64
+		after     model.PaymentInfo // Оплата после отмены
+	}{
+		{
+			name: "full", payment: paid, full: true,
+			refund: 3000, kopecks: 255000,
+			after: model.PaymentInfo{Status: model.PaymentStatusRefunded, Amount: 3000, CommissionLamoda: 450, SellerAmount: 2550},
+		},
+		{
+			name: "partial third", payment: paid, cancelled: 1000,
+			refund: 1000, kopecks: 85000,
+			after: model.PaymentInfo{Status: model.PaymentStatusPartiallyRefunded, Amount: 2000, CommissionLamoda: 300, SellerAmount: 1700},
+		},
+		{
+			name: "partial rounds to kopecks", payment: model.PaymentInfo{Amount: 100, CommissionLamoda: 15, SellerAmount: 85}, cancelled: 33.33,
+			refund: 33.33, kopecks: 2833,
+			after: model.PaymentInfo{Status: model.PaymentStatusPartiallyRefunded, Amount: 66.67, CommissionLamoda: 10, SellerAmount: 56.67},
+		},
+		{
+			name: "partial above payment is capped", payment: paid, cancelled: 5000,
+			refund: 3000, kopecks: 255000,
+			after: model.PaymentInfo{Status: model.PaymentStatusPartiallyRefunded, Amount: 0, CommissionLamoda: 0, SellerAmount: 0},
+		},
+		{
+			name: "unpaid", payment: model.PaymentInfo{Status: "pending"}, full: true,
+			after: model.PaymentInfo{Status: "pending"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payment := tt.payment
+			refund, kopecks := cancelPayment(&payment, tt.cancelled, tt.full)
+			if refund != tt.refund || kopecks != tt.kopecks {
+				t.Errorf("cancelPayment = (%v, %d), want (%v, %d)", refund, kopecks, tt.refund, tt.kopecks)
+			}
+			if payment != tt.after {
+				t.Errorf("payment after cancel = %+v, want %+v", payment, tt.after)
+			}
+		})
+	}
+}
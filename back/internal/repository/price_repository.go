@@ -0,0 +1,527 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lamoda-seller-app/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrPriceScheduleOverlap  = errors.New("price schedule overlaps with another pending or active schedule")
+	ErrPriceScheduleFinished = errors.New("price schedule is already completed or cancelled")
+	ErrVariantNotInProduct   = errors.New("variant does not belong to the product")
+)
+
+// PriceRepository хранит историю цен и запланированные изменения цен.
+type PriceRepository struct {
+	db *gorm.DB
+}
+
+func NewPriceRepository(db *gorm.DB) *PriceRepository {
+	return &PriceRepository{db: db}
+}
+
+// RecordChanges сравнивает текущие цены товара и его вариантов с последними записями истории
+// и добавляет записи только для изменившихся значений.
+func (r *PriceRepository) RecordChanges(ctx context.Context, productID uuid.UUID, source string, userID *uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return recordPriceChanges(tx, productID, source, userID, nil)
+	})
+}
+
+func recordPriceChanges(tx *gorm.DB, productID uuid.UUID, source string, userID, scheduleID *uuid.UUID) error {
+	var product model.Product
+	if err := tx.Unscoped().Preload("Variants").First(&product, "id = ?", productID).Error; err != nil {
+		return err
+	}
+
+	// Последняя запись истории для товара и для каждого варианта
+	var latest []model.PriceHistory
+	err := tx.Raw(`
+		SELECT DISTINCT ON (variant_id) *
+		FROM price_history
+		WHERE product_id = ?
+		ORDER BY variant_id, created_at DESC
+	`, productID).Scan(&latest).Error
+	if err != nil {
+		return err
+	}
+
+	var productLast *model.PriceHistory
+	variantLast := make(map[uuid.UUID]*model.PriceHistory)
+	for i := range latest {
+		if latest[i].VariantID == nil {
+			productLast = &latest[i]
+		} else {
+			variantLast[*latest[i].VariantID] = &latest[i]
+		}
+	}
+
+	now := time.Now()
+	var entries []model.PriceHistory
+	if productLast == nil || productLast.Price != product.Price ||
+		productLast.CostPrice != product.CostPrice || productLast.DiscountPercent != product.DiscountPercent {
+		entries = append(entries, model.PriceHistory{
+			ID:              uuid.New(),
+			ProductID:       productID,
+			Price:           product.Price,
+			CostPrice:       product.CostPrice,
+			DiscountPercent: product.DiscountPercent,
+			Source:          source,
+			ScheduleID:      scheduleID,
+			UserID:          userID,
+			CreatedAt:       now,
+		})
+	}
+
+	for _, v := range product.Variants {
+		last := variantLast[v.ID]
+		// Нулевая цена варианта означает, что он продается по цене товара
+		if (last == nil && v.Price == 0) || (last != nil && last.Price == v.Price) {
+			continue
+		}
+		variantID := v.ID
+		entries = append(entries, model.PriceHistory{
+			ID:         uuid.New(),
+			ProductID:  productID,
+			VariantID:  &variantID,
+			Price:      v.Price,
+			Source:     source,
+			ScheduleID: scheduleID,
+			UserID:     userID,
+			CreatedAt:  now,
+		})
+	}
+
+	if len(entries) == 0 {
+		return nil
+	}
+	return tx.Create(&entries).Error
+}
+
+// --- Запланированные изменения ---
+
+// CreateSchedule сохраняет запланированное изменение цены.
+// Для одного товара (или варианта) не может быть пересекающихся по времени незавершенных изменений.
+func (r *PriceRepository) CreateSchedule(ctx context.Context, schedule *model.PriceSchedule) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Блокируем товар, чтобы параллельные запросы не создали пересекающиеся изменения
+		var product model.Product
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Table: clause.Table{Name: clause.CurrentTable}}).
+			Select("id").First(&product, "id = ?", schedule.ProductID).Error
+		if err != nil {
+			return err
+		}
+
+		if schedule.VariantID != nil {
+			var count int64
+			if err := tx.Model(&model.ProductVariant{}).
+				Where("id = ? AND product_id = ?", *schedule.VariantID, schedule.ProductID).
+				Count(&count).Error; err != nil {
+				return err
+			}
+			if count == 0 {
+				return ErrVariantNotInProduct
+			}
+		}
+
+		overlap := tx.Model(&model.PriceSchedule{}).
+			Where("product_id = ? AND variant_id IS NOT DISTINCT FROM ?", schedule.ProductID, schedule.VariantID).
+			Where("status IN ?", []string{model.PriceScheduleStatusPending, model.PriceScheduleStatusActive}).
+			Where("ends_at IS NULL OR ends_at > ?", schedule.StartsAt)
+		if schedule.EndsAt != nil {
+			overlap = overlap.Where("starts_at < ?", *schedule.EndsAt)
+		}
+		var count int64
+		if err := overlap.Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrPriceScheduleOverlap
+		}
+
+		return tx.Create(schedule).Error
+	})
+}
+
+// ListSchedules возвращает запланированные изменения цены товара, новые первыми.
+func (r *PriceRepository) ListSchedules(ctx context.Context, productID uuid.UUID, status string) ([]model.PriceSchedule, error) {
+	var schedules []model.PriceSchedule
+	query := r.db.WithContext(ctx).Where("product_id = ?", productID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err := query.Order("starts_at DESC").Find(&schedules).Error
+	return schedules, err
+}
+
+// CancelSchedule отменяет запланированное изменение.
+// Еще не начавшееся изменение отменяется сразу, а действующее досрочно завершается:
+// фоновая задача вернет прежнюю цену при следующем запуске, если это предусмотрено.
+func (r *PriceRepository) CancelSchedule(ctx context.Context, productID, scheduleID uuid.UUID) (*model.PriceSchedule, error) {
+	var schedule model.PriceSchedule
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&schedule, "id = ? AND product_id = ?", scheduleID, productID).Error
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		switch schedule.Status {
+		case model.PriceScheduleStatusPending:
+			schedule.Status = model.PriceScheduleStatusCancelled
+			schedule.CompletedAt = &now
+			return tx.Model(&schedule).Updates(map[string]interface{}{
+				"status":       schedule.Status,
+				"completed_at": now,
+			}).Error
+		case model.PriceScheduleStatusActive:
+			schedule.EndsAt = &now
+			return tx.Model(&schedule).Update("ends_at", now).Error
+		default:
+			return ErrPriceScheduleFinished
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+// ApplyDue применяет наступившие изменения цен и завершает закончившиеся.
+// Каждое изменение обрабатывается в отдельной транзакции, поэтому ошибка в одном не блокирует остальные.
+func (r *PriceRepository) ApplyDue(ctx context.Context, now time.Time) (applied, completed int, err error) {
+	var due []uuid.UUID
+	if err := r.db.WithContext(ctx).Model(&model.PriceSchedule{}).
+		Where("status = ? AND starts_at <= ?", model.PriceScheduleStatusPending, now).
+		Order("starts_at ASC").
+		Pluck("id", &due).Error; err != nil {
+		return 0, 0, err
+	}
+	var errs []error
+	for _, id := range due {
+		ok, err := r.processSchedule(ctx, id, model.PriceScheduleStatusPending, now, applySchedule)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to apply price schedule %s: %w", id, err))
+			continue
+		}
+		if ok {
+			applied++
+		}
+	}
+
+	// Изменения, которые начались и закончились между запусками, будут завершены в этом же проходе
+	var ended []uuid.UUID
+	if err := r.db.WithContext(ctx).Model(&model.PriceSchedule{}).
+		Where("status = ? AND ends_at <= ?", model.PriceScheduleStatusActive, now).
+		Order("ends_at ASC").
+		Pluck("id", &ended).Error; err != nil {
+		return applied, completed, errors.Join(append(errs, err)...)
+	}
+	for _, id := range ended {
+		ok, err := r.processSchedule(ctx, id, model.PriceScheduleStatusActive, now, completeSchedule)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to complete price schedule %s: %w", id, err))
+			continue
+		}
+		if ok {
+			completed++
+		}
+	}
+	return applied, completed, errors.Join(errs...)
+}
+
+// processSchedule блокирует изменение и выполняет step, если оно все еще в ожидаемом статусе.
+// SKIP LOCKED позволяет нескольким экземплярам сервера работать параллельно.
+func (r *PriceRepository) processSchedule(ctx context.Context, id uuid.UUID, status string, now time.Time,
+	step func(tx *gorm.DB, s *model.PriceSchedule, now time.Time) error) (bool, error) {
+	processed := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var schedule model.PriceSchedule
+		res := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("id = ? AND status = ?", id, status).
+			Limit(1).Find(&schedule)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return nil // Уже обработано другим экземпляром
+		}
+		processed = true
+		return step(tx, &schedule, now)
+	})
+	return processed, err
+}
+
+// applySchedule устанавливает запланированную цену и запоминает прежние значения для возврата.
+func applySchedule(tx *gorm.DB, s *model.PriceSchedule, now time.Time) error {
+	if s.VariantID != nil {
+		var variant model.ProductVariant
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&variant, "id = ?", *s.VariantID).Error; err != nil {
+			return err
+		}
+		s.PreviousPrice = &variant.Price
+		if s.Price != nil {
+			if err := tx.Model(&variant).Update("price", *s.Price).Error; err != nil {
+				return err
+			}
+		}
+	} else {
+		var product model.Product
+		if err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE", Table: clause.Table{Name: clause.CurrentTable}}).
+			Select("id", "price", "discount_percent").First(&product, "id = ?", s.ProductID).Error; err != nil {
+			return err
+		}
+		s.PreviousPrice = &product.Price
+		s.PreviousDiscountPercent = &product.DiscountPercent
+
+		updates := map[string]interface{}{}
+		if s.Price != nil {
+			updates["price"] = *s.Price
+		}
+		if s.DiscountPercent != nil {
+			updates["discount_percent"] = *s.DiscountPercent
+		}
+		if err := tx.Unscoped().Model(&model.Product{}).Where("id = ?", s.ProductID).Updates(updates).Error; err != nil {
+			return err
+		}
+	}
+
+	status := model.PriceScheduleStatusCompleted
+	updates := map[string]interface{}{
+		"previous_price":            s.PreviousPrice,
+		"previous_discount_percent": s.PreviousDiscountPercent,
+		"applied_at":                now,
+	}
+	// Изменение без даты окончания - это просто отложенная смена цены
+	if s.EndsAt != nil {
+		status = model.PriceScheduleStatusActive
+	} else {
+		updates["completed_at"] = now
+	}
+	updates["status"] = status
+	if err := tx.Model(s).Updates(updates).Error; err != nil {
+		return err
+	}
+
+	return recordPriceChanges(tx, s.ProductID, model.PriceSourceSchedule, s.UserID, &s.ID)
+}
+
+// completeSchedule завершает действующее изменение и, если нужно, возвращает прежнюю цену.
+// Возвращаются только те значения, которые меняло само изменение.
+func completeSchedule(tx *gorm.DB, s *model.PriceSchedule, now time.Time) error {
+	if s.RevertOnEnd {
+		if s.VariantID != nil {
+			if s.Price != nil && s.PreviousPrice != nil {
+				if err := tx.Model(&model.ProductVariant{}).Where("id = ?", *s.VariantID).
+					Update("price", *s.PreviousPrice).Error; err != nil {
+					return err
+				}
+			}
+		} else {
+			updates := map[string]interface{}{}
+			if s.Price != nil && s.PreviousPrice != nil {
+				updates["price"] = *s.PreviousPrice
+			}
+			if s.DiscountPercent != nil && s.PreviousDiscountPercent != nil {
+				updates["discount_percent"] = *s.PreviousDiscountPercent
+			}
+			if len(updates) > 0 {
+				if err := tx.Unscoped().Model(&model.Product{}).Where("id = ?", s.ProductID).Updates(updates).Error; err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if err := tx.Model(s).Updates(map[string]interface{}{
+		"status":       model.PriceScheduleStatusCompleted,
+		"completed_at": now,
+	}).Error; err != nil {
+		return err
+	}
+
+	if !s.RevertOnEnd {
+		return nil
+	}
+	return recordPriceChanges(tx, s.ProductID, model.PriceSourceRevert, s.UserID, &s.ID)
+}
+
+// --- Динамика цены ---
+
+// Timeline возвращает историю цены товара (или варианта) и продажи за период,
+// разбитые на интервалы, в течение которых действовала одна цена.
+func (r *PriceRepository) Timeline(ctx context.Context, productID uuid.UUID, variantID *uuid.UUID, period string) (*model.PriceTimelineResponse, error) {
+	db := r.db.WithContext(ctx)
+
+	var product model.Product
+	if err := db.Unscoped().Select("id", "price", "cost_price", "discount_percent", "created_at").
+		First(&product, "id = ?", productID).Error; err != nil {
+		return nil, err
+	}
+
+	var variantPrice float64
+	if variantID != nil {
+		var variant model.ProductVariant
+		if err := db.First(&variant, "id = ? AND product_id = ?", *variantID, productID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, ErrVariantNotInProduct
+			}
+			return nil, err
+		}
+		variantPrice = variant.Price
+	}
+
+	historyQuery := db.Where("product_id = ?", productID)
+	if variantID != nil {
+		historyQuery = historyQuery.Where("variant_id IS NULL OR variant_id = ?", *variantID)
+	} else {
+		historyQuery = historyQuery.Where("variant_id IS NULL")
+	}
+	var history []model.PriceHistory
+	if err := historyQuery.Order("created_at ASC").Find(&history).Error; err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	startDate := getStartDate(period)
+	if startDate.IsZero() {
+		startDate = product.CreatedAt
+		if len(history) > 0 && history[0].CreatedAt.Before(startDate) {
+			startDate = history[0].CreatedAt
+		}
+	}
+
+	// Продажи группируются по времени заказа, чтобы точно отнести их к интервалу цены
+	var orderSales []struct {
+		Date      time.Time
+		UnitsSold int64
+		Revenue   float64
+	}
+	salesQuery := db.Table("order_items oi").
+		Select("o.date AS date, SUM(oi.quantity) AS units_sold, SUM(oi.total) AS revenue").
+		Joins("JOIN orders o ON o.id = oi.order_id").
+		Where("oi.product_id = ? AND o.date >= ? AND o.status <> 'cancelled'", productID, startDate)
+	if variantID != nil {
+		salesQuery = salesQuery.Where("oi.variant_id = ?", *variantID)
+	}
+	if err := salesQuery.Group("o.date").Order("o.date ASC").Scan(&orderSales).Error; err != nil {
+		return nil, fmt.Errorf("failed to load sales: %w", err)
+	}
+
+	// Точки смены цены: состояние товара (и варианта) после каждой записи истории
+	type pricePoint struct {
+		at              time.Time
+		price           float64
+		discountPercent float64
+	}
+	var points []pricePoint
+	productPrice, discount, ownPrice := product.Price, product.DiscountPercent, variantPrice
+	if len(history) > 0 {
+		productPrice, discount, ownPrice = 0, 0, 0
+	}
+	effective := func() float64 {
+		if ownPrice > 0 {
+			return ownPrice
+		}
+		return productPrice
+	}
+	for _, h := range history {
+		if h.VariantID == nil {
+			productPrice, discount = h.Price, h.DiscountPercent
+		} else {
+			ownPrice = h.Price
+		}
+		points = append(points, pricePoint{at: h.CreatedAt, price: effective(), discountPercent: discount})
+	}
+	if len(points) == 0 {
+		points = append(points, pricePoint{at: startDate, price: effective(), discountPercent: discount})
+	}
+
+	// Интервалы, пересекающиеся с периодом отчета
+	var periods []model.PricePeriod
+	for i, p := range points {
+		from := p.at
+		to := now
+		if i+1 < len(points) {
+			to = points[i+1].at
+		}
+		if !to.After(startDate) || !to.After(from) {
+			continue
+		}
+		if from.Before(startDate) {
+			from = startDate
+		}
+		// Несколько записей подряд с одной ценой объединяются в один интервал
+		if n := len(periods); n > 0 && periods[n-1].Price == p.price && periods[n-1].DiscountPercent == p.discountPercent {
+			periods[n-1].To = to
+			continue
+		}
+		periods = append(periods, model.PricePeriod{
+			From:            from,
+			To:              to,
+			Price:           p.price,
+			DiscountPercent: p.discountPercent,
+			FinalPrice:      p.price * (1 - p.discountPercent/100),
+		})
+	}
+
+	dailyIndex := make(map[time.Time]int)
+	daily := []model.DailySales{}
+	for _, s := range orderSales {
+		idx := sort.Search(len(periods), func(i int) bool { return periods[i].To.After(s.Date) })
+		if idx < len(periods) && !s.Date.Before(periods[idx].From) {
+			periods[idx].UnitsSold += s.UnitsSold
+			periods[idx].Revenue += s.Revenue
+		}
+
+		day := time.Date(s.Date.Year(), s.Date.Month(), s.Date.Day(), 0, 0, 0, 0, s.Date.Location())
+		i, ok := dailyIndex[day]
+		if !ok {
+			i = len(daily)
+			dailyIndex[day] = i
+			daily = append(daily, model.DailySales{Date: day})
+		}
+		daily[i].UnitsSold += s.UnitsSold
+		daily[i].Revenue += s.Revenue
+	}
+
+	for i := range periods {
+		p := &periods[i]
+		p.Days = p.To.Sub(p.From).Hours() / 24
+		if p.Days > 0 {
+			p.UnitsPerDay = float64(p.UnitsSold) / p.Days
+		}
+	}
+
+	scheduleQuery := db.Where("product_id = ? AND status IN ?", productID,
+		[]string{model.PriceScheduleStatusPending, model.PriceScheduleStatusActive})
+	if variantID != nil {
+		scheduleQuery = scheduleQuery.Where("variant_id IS NULL OR variant_id = ?", *variantID)
+	}
+	var schedules []model.PriceSchedule
+	if err := scheduleQuery.Order("starts_at ASC").Find(&schedules).Error; err != nil {
+		return nil, err
+	}
+
+	if periods == nil {
+		periods = []model.PricePeriod{}
+	}
+	return &model.PriceTimelineResponse{
+		ProductID: productID,
+		VariantID: variantID,
+		Period:    period,
+		History:   history,
+		Periods:   periods,
+		Sales:     daily,
+		Schedules: schedules,
+	}, nil
+}
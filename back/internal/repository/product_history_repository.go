@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/lamoda-seller-app/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ProductHistoryRepository хранит версии карточек товаров.
+type ProductHistoryRepository struct {
+	db *gorm.DB
+}
+
+func NewProductHistoryRepository(db *gorm.DB) *ProductHistoryRepository {
+	return &ProductHistoryRepository{db: db}
+}
+
+// Record сохраняет текущее состояние товара (с вариантами и изображениями) как новую версию.
+func (r *ProductHistoryRepository) Record(ctx context.Context, productID uuid.UUID, userID *uuid.UUID, action, comment string) (*model.ProductVersion, error) {
+	var version model.ProductVersion
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Блокируем строку товара, чтобы параллельные изменения не получили одинаковый номер версии
+		var product model.Product
+		err := tx.Unscoped().
+			Clauses(clause.Locking{Strength: "UPDATE", Table: clause.Table{Name: clause.CurrentTable}}).
+			First(&product, "id = ?", productID).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Preload("Images").Preload("Variants").Preload("Supplier").First(&product, "id = ?", productID).Error; err != nil {
+			return err
+		}
+
+		var last int
+		if err := tx.Model(&model.ProductVersion{}).Where("product_id = ?", productID).
+			Select("COALESCE(MAX(version), 0)").Scan(&last).Error; err != nil {
+			return err
+		}
+
+		version = model.ProductVersion{
+			ID:        uuid.New(),
+			ProductID: productID,
+			Version:   last + 1,
+			UserID:    userID,
+			Action:    action,
+			Comment:   comment,
+			Snapshot:  model.ProductSnapshot(product),
+		}
+		return tx.Create(&version).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &version, nil
+}
+
+// List возвращает все версии товара от новых к старым с изменениями относительно предыдущей версии.
+func (r *ProductHistoryRepository) List(ctx context.Context, productID uuid.UUID) ([]model.ProductVersion, error) {
+	var versions []model.ProductVersion
+	err := r.db.WithContext(ctx).Model(&model.ProductVersion{}).
+		Select("product_versions.*, users.name AS user_name").
+		Joins("LEFT JOIN users ON users.id = product_versions.user_id").
+		Where("product_versions.product_id = ?", productID).
+		Order("product_versions.version ASC").
+		Find(&versions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range versions {
+		var previous *model.ProductSnapshot
+		if i > 0 {
+			previous = &versions[i-1].Snapshot
+		}
+		versions[i].Changes = model.DiffProductSnapshots(previous, &versions[i].Snapshot)
+	}
+
+	// Новые версии первыми
+	for i, j := 0, len(versions)-1; i < j; i, j = i+1, j-1 {
+		versions[i], versions[j] = versions[j], versions[i]
+	}
+	return versions, nil
+}
+
+// Get возвращает конкретную версию товара вместе со снимком.
+func (r *ProductHistoryRepository) Get(ctx context.Context, productID uuid.UUID, version int) (*model.ProductVersion, error) {
+	var v model.ProductVersion
+	err := r.db.WithContext(ctx).
+		Where("product_id = ? AND version = ?", productID, version).
+		First(&v).Error
+	return &v, err
+}
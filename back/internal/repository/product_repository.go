@@ -2,12 +2,22 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/lamoda-seller-app/internal/model"
+	"github.com/lib/pq"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrRestoreWindowExpired = errors.New("restore window has expired")
+	ErrSKUTaken             = errors.New("sku is already used by another product")
+	ErrVariantInUse         = errors.New("variants are referenced by orders, stock or marking codes")
 )
 
 // ProductRepository инкапсулирует логику работы с продуктами в БД.
@@ -20,23 +30,49 @@ func NewProductRepository(db *gorm.DB) *ProductRepository {
 }
 
 // ListProductsParams определяет все параметры для получения списка товаров.
+// Пустые значения и nil означают, что фильтр не применяется.
 type ListProductsParams struct {
-	Search      string
-	Category    string
-	Brand       string
-	MinPrice    float64
-	MaxPrice    float64
-	StockStatus string
-	SortBy      string
-	SortOrder   string
-	Limit       int
-	Offset      int
+	Search            string
+	Category          string
+	Subcategory       string
+	Brand             string
+	Statuses          []string
+	Tags              []string // Товар должен содержать хотя бы один из тегов
+	Sizes             []string // Есть вариант в наличии с одним из размеров
+	Colors            []string // Есть вариант в наличии с одним из цветов (вместе с Sizes - тот же вариант)
+	SupplierID        *uuid.UUID
+	IsBestseller      *bool
+	IsNew             *bool
+	MinPrice          float64
+	MaxPrice          float64
+	MinMargin         *float64 // Маржинальность в процентах
+	MaxMargin         *float64
+	MinDiscount       *float64 // Скидка в процентах
+	MaxDiscount       *float64
+	CreatedFrom       *time.Time
+	CreatedTo         *time.Time
+	UpdatedFrom       *time.Time
+	UpdatedTo         *time.Time
+	StockStatus       string
+	LowStockThreshold int  // Порог "мало на складе" продавца (0 - не выделять), см. LowStockThreshold
+	MinSales          *int // Продано штук за 30 дней, включительно
+	MaxSales          *int
+	SortBy            string
+	SortOrder         string
+	Limit             int
+	Offset            int
+	Cursor            *Cursor // Если задан, используется keyset-пагинация, Offset игнорируется
+	Keyset            bool    // Первая страница в keyset-режиме (курсора еще нет)
 }
 
 // FilterValues содержит данные для блока `filters` в ответе API.
 type FilterValues struct {
 	Categories []FilterCount `json:"categories"`
 	Brands     []FilterCount `json:"brands"`
+	Statuses   []FilterCount `json:"statuses"`
+	Sizes      []FilterCount `json:"sizes"`
+	Colors     []FilterCount `json:"colors"`
+	Tags       []FilterCount `json:"tags"`
 	PriceRange PriceRange    `json:"price_range"`
 }
 
@@ -52,138 +88,409 @@ type PriceRange struct {
 }
 
 // List возвращает список товаров с фильтрацией, сортировкой, пагинацией, а также данные для фильтров.
-func (r *ProductRepository) List(ctx context.Context, params ListProductsParams) ([]model.Product, int64, *FilterValues, error) {
+func (r *ProductRepository) List(ctx context.Context, params ListProductsParams) ([]model.Product, int64, *FilterValues, *PageInfo, error) {
 	var products []model.Product
 	var total int64
 	var filters FilterValues
 
 	// --- 1. Создаем базовый запрос с фильтрами ---
-	query := r.db.WithContext(ctx).Model(&model.Product{})
-
-	if params.Search != "" {
-		searchQuery := "%" + strings.ToLower(params.Search) + "%"
-		query = query.Where("LOWER(name) LIKE ? OR LOWER(sku) LIKE ? OR LOWER(brand) LIKE ?", searchQuery, searchQuery, searchQuery)
-	}
-	if params.Category != "" {
-		// Используем текстовое поле category вместо category_id
-		query = query.Where("category = ?", params.Category)
-	}
-	if params.Brand != "" {
-		query = query.Where("brand = ?", params.Brand)
-	}
-	if params.MinPrice > 0 {
-		query = query.Where("price >= ?", params.MinPrice)
-	}
-	if params.MaxPrice > 0 {
-		query = query.Where("price <= ?", params.MaxPrice)
-	}
-	switch params.StockStatus {
-	case "in_stock":
-		query = query.Where("total_stock > 10")
-	case "low_stock":
-		query = query.Where("total_stock > 0 AND total_stock <= 10")
-	case "out_of_stock":
-		query = query.Where("total_stock = 0")
-	}
+	search := strings.TrimSpace(params.Search)
+	params.Search = search
+	query := r.filtered(ctx, params, "")
 
 	// --- 2. Получаем данные для блока `filters` (до применения пагинации) ---
-	err := r.calculateFilters(r.db.WithContext(ctx).Model(&model.Product{}), &filters, params)
+	err := r.calculateFilters(ctx, &filters, params)
 	if err != nil {
-		return nil, 0, nil, fmt.Errorf("failed to calculate filters: %w", err)
+		return nil, 0, nil, nil, fmt.Errorf("failed to calculate filters: %w", err)
 	}
 
 	// --- 3. Считаем общее количество для пагинации ---
 	if err := query.Count(&total).Error; err != nil {
-		return nil, 0, nil, err
+		return nil, 0, nil, nil, err
 	}
 
 	// --- 4. Применяем сортировку ---
-	if params.SortBy != "" {
-		allowedSorts := map[string]string{
-			"name": "name", "price": "price", "stock": "total_stock",
-			"sales":        "sales_count_30d",
-			"created_date": "created_at",
-		}
-		dbColumn, ok := allowedSorts[params.SortBy]
-		if ok {
-			order := "ASC"
-			if strings.ToLower(params.SortOrder) == "desc" {
-				order = "DESC"
-			}
-			query = query.Order(fmt.Sprintf("%s %s", dbColumn, order))
+	// Курсор хранит сортировку, с которой была получена предыдущая страница
+	sortBy, desc := params.SortBy, strings.ToLower(params.SortOrder) == "desc"
+	if params.Cursor != nil {
+		sortBy, desc = params.Cursor.SortBy, params.Cursor.Desc
+	}
+	sortKey, ok := productSortKey(sortBy, search)
+	if !ok {
+		if params.Cursor != nil {
+			return nil, 0, nil, nil, ErrInvalidCursor
 		}
+		// При поиске по умолчанию сортируем по релевантности, иначе - по дате создания
+		sortBy, desc = "created_date", true
+		if search != "" {
+			sortBy = "relevance"
+		}
+		sortKey, _ = productSortKey(sortBy, search)
+	}
+
+	if search != "" {
+		selectSQL, selectArgs := searchColumns(search)
+		query = query.Select(productWithSalesColumns+", "+selectSQL, selectArgs...)
 	} else {
-		query = query.Order("created_at DESC")
+		query = query.Select(productWithSalesColumns)
 	}
+	query = query.Preload("Images")
 
 	// --- 5. Применяем пагинацию и получаем товары ---
-	err = query.Preload("Images").Offset(params.Offset).Limit(params.Limit).Find(&products).Error
-	if err != nil {
-		return nil, 0, nil, err
+	key := func(p *model.Product) (string, uuid.UUID) { return sortKey.value(p), p.ID }
+	var page *PageInfo
+	if params.Cursor != nil || params.Keyset {
+		err = keysetPage(query, sortKey.column, "products.id", desc, params.Cursor, params.Limit).Find(&products).Error
+		if err != nil {
+			return nil, 0, nil, nil, err
+		}
+		products, page = finishKeysetPage(products, sortBy, desc, params.Cursor, params.Limit, key)
+	} else {
+		err = keysetOrder(query, sortKey.column, "products.id", desc).
+			Offset(params.Offset).Limit(params.Limit).Find(&products).Error
+		if err != nil {
+			return nil, 0, nil, nil, err
+		}
+		page = offsetPageInfo(products, sortBy, desc, total, params.Limit, params.Offset, key)
 	}
 
-	return products, total, &filters, nil
+	return products, total, &filters, page, nil
 }
 
-// calculateFilters вычисляет доступные фильтры на основе текущего запроса.
-func (r *ProductRepository) calculateFilters(baseQuery *gorm.DB, filters *FilterValues, params ListProductsParams) error {
-	// Категории: считаем количество товаров в каждой категории
-	categoryQuery := baseQuery.Session(&gorm.Session{}) // Создаем новую сессию
+// Фасеты фильтров. Каждый фасет считается по всем фильтрам, кроме своего собственного,
+// чтобы выбор одного значения не скрывал остальные варианты этого же фильтра.
+const (
+	facetCategory = "category"
+	facetBrand    = "brand"
+	facetStatus   = "status"
+	facetSize     = "size"
+	facetColor    = "color"
+	facetTag      = "tag"
+	facetPrice    = "price"
+)
+
+// facetTagsLimit - сколько самых популярных тегов возвращать в фасете
+const facetTagsLimit = 50
+
+// marginExpr - маржинальность товара в процентах (как Product.MarginPercent)
+const marginExpr = "CASE WHEN products.price > 0 THEN (products.price - COALESCE(products.cost_price, 0)) / products.price * 100 ELSE 0 END"
+
+// filtered возвращает запрос товаров со всеми фильтрами из params, кроме фасета skip.
+func (r *ProductRepository) filtered(ctx context.Context, params ListProductsParams, skip string) *gorm.DB {
+	query := r.db.WithContext(ctx).Model(&model.Product{}).Scopes(withSales)
+
 	if params.Search != "" {
-		searchQuery := "%" + strings.ToLower(params.Search) + "%"
-		categoryQuery = categoryQuery.Where("LOWER(name) LIKE ? OR LOWER(sku) LIKE ?", searchQuery, searchQuery)
+		query = applySearch(query, params.Search)
+	}
+	if params.Category != "" && skip != facetCategory {
+		// Используем текстовое поле category вместо category_id
+		query = query.Where("products.category = ?", params.Category)
+	}
+	if params.Subcategory != "" {
+		query = query.Where("products.subcategory = ?", params.Subcategory)
+	}
+	if params.Brand != "" && skip != facetBrand {
+		query = query.Where("products.brand = ?", params.Brand)
+	}
+	if len(params.Statuses) > 0 && skip != facetStatus {
+		query = query.Where("products.status IN ?", params.Statuses)
+	}
+	if len(params.Tags) > 0 && skip != facetTag {
+		query = query.Where("products.tags && ?", pq.StringArray(params.Tags))
 	}
 
-	// Используем текстовое поле category напрямую
-	err := categoryQuery.Select("category as id, category as name, count(*) as count").
-		Group("category").
-		Scan(&filters.Categories).Error
-	if err != nil {
-		return err
+	// Размер и цвет проверяются по вариантам в наличии; если заданы оба, они должны совпасть у одного варианта
+	sizes, colors := params.Sizes, params.Colors
+	if skip == facetSize {
+		sizes = nil
+	}
+	if skip == facetColor {
+		colors = nil
+	}
+	if len(sizes) > 0 || len(colors) > 0 {
+		variantQuery := r.db.Table("product_variants pv").Select("1").
+			Where("pv.product_id = products.id AND pv.stock > pv.reserved")
+		if len(sizes) > 0 {
+			variantQuery = variantQuery.Where("pv.size IN ?", sizes)
+		}
+		if len(colors) > 0 {
+			variantQuery = variantQuery.Where("pv.color IN ?", colors)
+		}
+		query = query.Where("EXISTS (?)", variantQuery)
+	}
+
+	if params.SupplierID != nil {
+		query = query.Where("products.supplier_id = ?", *params.SupplierID)
+	}
+	if params.IsBestseller != nil {
+		query = query.Where("products.is_bestseller = ?", *params.IsBestseller)
+	}
+	if params.IsNew != nil {
+		query = query.Where("products.is_new = ?", *params.IsNew)
+	}
+	if skip != facetPrice {
+		if params.MinPrice > 0 {
+			query = query.Where("products.price >= ?", params.MinPrice)
+		}
+		if params.MaxPrice > 0 {
+			query = query.Where("products.price <= ?", params.MaxPrice)
+		}
+	}
+	if params.MinMargin != nil {
+		query = query.Where(marginExpr+" >= ?", *params.MinMargin)
+	}
+	if params.MaxMargin != nil {
+		query = query.Where(marginExpr+" <= ?", *params.MaxMargin)
+	}
+	if params.MinDiscount != nil {
+		query = query.Where("COALESCE(products.discount_percent, 0) >= ?", *params.MinDiscount)
+	}
+	if params.MaxDiscount != nil {
+		query = query.Where("COALESCE(products.discount_percent, 0) <= ?", *params.MaxDiscount)
+	}
+	if params.CreatedFrom != nil {
+		query = query.Where("products.created_at >= ?", *params.CreatedFrom)
+	}
+	if params.CreatedTo != nil {
+		query = query.Where("products.created_at <= ?", *params.CreatedTo)
+	}
+	if params.UpdatedFrom != nil {
+		query = query.Where("products.updated_at >= ?", *params.UpdatedFrom)
+	}
+	if params.UpdatedTo != nil {
+		query = query.Where("products.updated_at <= ?", *params.UpdatedTo)
 	}
 
-	// Бренды
-	brandQuery := baseQuery.Session(&gorm.Session{}) // Новая сессия для брендов
-	// Применяем все фильтры, КРОМЕ бренда
-	if params.Category != "" {
-		brandQuery = brandQuery.Where("category = ?", params.Category)
+	// Порог 0 допустим: товаров "мало на складе" у продавца тогда нет
+	threshold := params.LowStockThreshold
+	switch params.StockStatus {
+	case "in_stock":
+		query = query.Where("products.total_stock > ?", threshold)
+	case "low_stock":
+		query = query.Where("products.total_stock > 0 AND products.total_stock <= ?", threshold)
+	case "out_of_stock":
+		query = query.Where("products.total_stock = 0")
+	case "needs_restock":
+		// Есть вариант с открытым оповещением: остаток не выше его точки заказа (см. StockRepository.EvaluateAlerts)
+		query = query.Where("EXISTS (SELECT 1 FROM stock_alerts sa WHERE sa.product_id = products.id AND sa.resolved_at IS NULL)")
+	}
+	if params.MinSales != nil {
+		query = query.Where("COALESCE(ps.units_sold, 0) >= ?", *params.MinSales)
+	}
+	if params.MaxSales != nil {
+		query = query.Where("COALESCE(ps.units_sold, 0) <= ?", *params.MaxSales)
 	}
+	return query
+}
 
-	rows, err := brandQuery.Select("brand as id, brand as name, count(*) as count").Group("brand").Rows()
-	if err != nil {
-		return err
+// Count возвращает количество товаров, подходящих под фильтры.
+func (r *ProductRepository) Count(ctx context.Context, params ListProductsParams) (int64, error) {
+	var total int64
+	params.Search = strings.TrimSpace(params.Search)
+	err := r.filtered(ctx, params, "").Count(&total).Error
+	return total, err
+}
+
+// calculateFilters вычисляет значения фасетов для блока `filters`.
+func (r *ProductRepository) calculateFilters(ctx context.Context, filters *FilterValues, params ListProductsParams) error {
+	facets := []struct {
+		facet  string
+		target *[]FilterCount
+		build  func(q *gorm.DB) *gorm.DB
+	}{
+		{facetCategory, &filters.Categories, func(q *gorm.DB) *gorm.DB {
+			// Используем текстовое поле category напрямую
+			return q.Select("products.category AS id, products.category AS name, COUNT(*) AS count").Group("products.category")
+		}},
+		{facetBrand, &filters.Brands, func(q *gorm.DB) *gorm.DB {
+			return q.Select("products.brand AS id, products.brand AS name, COUNT(*) AS count").Group("products.brand")
+		}},
+		{facetStatus, &filters.Statuses, func(q *gorm.DB) *gorm.DB {
+			return q.Select("products.status AS id, products.status AS name, COUNT(*) AS count").Group("products.status")
+		}},
+		{facetSize, &filters.Sizes, func(q *gorm.DB) *gorm.DB {
+			return q.Joins("JOIN product_variants fv ON fv.product_id = products.id AND fv.stock > fv.reserved AND fv.size <> ''").
+				Select("fv.size AS id, fv.size AS name, COUNT(DISTINCT products.id) AS count").Group("fv.size").Order("fv.size")
+		}},
+		{facetColor, &filters.Colors, func(q *gorm.DB) *gorm.DB {
+			return q.Joins("JOIN product_variants fv ON fv.product_id = products.id AND fv.stock > fv.reserved AND fv.color <> ''").
+				Select("fv.color AS id, fv.color AS name, COUNT(DISTINCT products.id) AS count").Group("fv.color").Order("count DESC")
+		}},
+		{facetTag, &filters.Tags, func(q *gorm.DB) *gorm.DB {
+			return q.Joins("CROSS JOIN LATERAL unnest(products.tags) AS ft(tag)").
+				Select("ft.tag AS id, ft.tag AS name, COUNT(*) AS count").Group("ft.tag").
+				Order("count DESC").Limit(facetTagsLimit)
+		}},
 	}
-	defer rows.Close()
-	for rows.Next() {
-		var brand FilterCount
-		if err := r.db.ScanRows(rows, &brand); err == nil {
-			filters.Brands = append(filters.Brands, brand)
+
+	for _, f := range facets {
+		if err := f.build(r.filtered(ctx, params, f.facet)).Scan(f.target).Error; err != nil {
+			return fmt.Errorf("facet %s: %w", f.facet, err)
 		}
 	}
 
 	// Диапазон цен (считаем на основе всех фильтров, кроме цены)
-	priceQuery := baseQuery.Session(&gorm.Session{})
-	if params.Category != "" {
-		priceQuery = priceQuery.Where("category = ?", params.Category)
-	}
-	if params.Brand != "" {
-		priceQuery = priceQuery.Where("brand = ?", params.Brand)
+	return r.filtered(ctx, params, facetPrice).
+		Select("COALESCE(MIN(products.price), 0) as min, COALESCE(MAX(products.price), 0) as max").
+		Row().Scan(&filters.PriceRange.Min, &filters.PriceRange.Max)
+}
+
+// LowStockThreshold возвращает порог "мало на складе" продавца. Порог 0 сохраняется как есть,
+// значение по умолчанию берется только для неизвестного продавца.
+func (r *ProductRepository) LowStockThreshold(ctx context.Context, userID uuid.UUID) (int, error) {
+	var user model.User
+	err := r.db.WithContext(ctx).Select("low_stock_threshold").First(&user, "id = ?", userID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return model.DefaultLowStockThreshold, nil
 	}
+	return user.LowStockThreshold, err
+}
+
+// productWithSalesColumns - колонки товара вместе с продажами за 30 дней (требует scope withSales)
+const productWithSalesColumns = "products.*, COALESCE(ps.units_sold, 0) AS sales_count_30d, COALESCE(ps.revenue, 0) AS revenue_30d"
+
+// withSales присоединяет продажи товара за 30 дней из таблицы product_sales_30d.
+func withSales(db *gorm.DB) *gorm.DB {
+	return db.Joins("LEFT JOIN product_sales_30d ps ON ps.product_id = products.id")
+}
+
+// --- Поиск ---
+
+// searchTSQuery объединяет запрос, разобранный в русской, английской и простой (без морфологии) конфигурациях.
+// Русская морфология находит "куртки" по "куртка", английская - "jackets" по "jacket",
+// простая - артикулы и штрихкоды как есть. Аргумент запроса передается три раза.
+const searchTSQuery = "(websearch_to_tsquery('russian', ?) || websearch_to_tsquery('english', ?) || websearch_to_tsquery('simple', ?))"
+
+// searchHeadlineOptions - параметры подсветки совпадений в ts_headline
+const searchHeadlineOptions = "StartSel=<mark>, StopSel=</mark>"
 
-	return priceQuery.Select("COALESCE(MIN(price), 0) as min, COALESCE(MAX(price), 0) as max").Row().Scan(&filters.PriceRange.Min, &filters.PriceRange.Max)
+// escapeHTMLSQL оборачивает SQL-выражение с текстом в экранирование HTML. Текст экранируется
+// до ts_headline, поэтому в подсветке из тегов остаются только <mark>, а разметка из названия
+// или описания товара выводится как текст. Парсер полнотекстового поиска читает сущности
+// вроде &lt; как отдельные лексемы и не разрывает их на фрагменты.
+func escapeHTMLSQL(expr string) string {
+	return "replace(replace(replace(replace(" + expr + ", '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '\"', '&quot;')"
+}
+
+// applySearch фильтрует товары по поисковому запросу: полнотекстовое совпадение
+// или, для запросов с опечатками, триграммное сходство со словами названия, бренда, артикула, материала и тегов.
+// Штрихкод ищется точным совпадением.
+func applySearch(query *gorm.DB, search string) *gorm.DB {
+	lowered := strings.ToLower(search)
+	return query.Where(
+		"products.search_vector @@ "+searchTSQuery+" OR ? <% products.search_text OR products.barcode = ?",
+		search, search, search, lowered, search,
+	)
+}
+
+// searchRank возвращает выражение релевантности товара поисковому запросу.
+// Полнотекстовое совпадение весит больше триграммного, чтобы точные попадания были выше опечаток.
+func searchRank(search string) (string, []interface{}) {
+	return "(ts_rank_cd(products.search_vector, " + searchTSQuery + ") * 2 + word_similarity(?, products.search_text))::float8",
+		[]interface{}{search, search, search, strings.ToLower(search)}
+}
+
+// searchColumns возвращает выражения релевантности и подсветки для SELECT.
+func searchColumns(search string) (string, []interface{}) {
+	rankSQL, args := searchRank(search)
+	sql := rankSQL + " AS search_rank, " +
+		"jsonb_build_object(" +
+		"'name', ts_headline('russian', " + escapeHTMLSQL("products.name") + ", " + searchTSQuery + ", 'HighlightAll=true, " + searchHeadlineOptions + "'), " +
+		"'description', ts_headline('russian', " + escapeHTMLSQL("COALESCE(products.description, '')") + ", " + searchTSQuery + ", 'MaxFragments=2, MaxWords=20, MinWords=5, " + searchHeadlineOptions + "')" +
+		") AS search_highlight"
+	args = append(args,
+		search, search, search,
+		search, search, search,
+	)
+	return sql, args
+}
+
+// productSortKeyDef описывает ключ сортировки списка товаров и способ получить его значение для курсора.
+type productSortKeyDef struct {
+	column keysetColumn
+	value  func(p *model.Product) string
+}
+
+// productSortKey возвращает ключ сортировки по значению параметра sort_by.
+// Выражения повторяют колонки из productWithSalesColumns, потому что в WHERE нельзя ссылаться на алиасы.
+func productSortKey(sortBy, search string) (productSortKeyDef, bool) {
+	switch sortBy {
+	case "name":
+		return productSortKeyDef{keysetColumn{expr: "products.name", cast: "text"},
+			func(p *model.Product) string { return p.Name }}, true
+	case "price":
+		return productSortKeyDef{keysetColumn{expr: "products.price", cast: "numeric"},
+			func(p *model.Product) string { return cursorFloat(p.Price) }}, true
+	case "stock":
+		return productSortKeyDef{keysetColumn{expr: "products.total_stock", cast: "bigint"},
+			func(p *model.Product) string { return cursorInt(int64(p.TotalStock)) }}, true
+	case "sales":
+		return productSortKeyDef{keysetColumn{expr: "COALESCE(ps.units_sold, 0)", cast: "bigint"},
+			func(p *model.Product) string { return cursorInt(int64(p.SalesCount30d)) }}, true
+	case "revenue":
+		return productSortKeyDef{keysetColumn{expr: "COALESCE(ps.revenue, 0)", cast: "numeric"},
+			func(p *model.Product) string { return cursorFloat(p.Revenue30d) }}, true
+	case "created_date":
+		return productSortKeyDef{keysetColumn{expr: "products.created_at", cast: "timestamptz"},
+			func(p *model.Product) string { return cursorTime(p.CreatedAt) }}, true
+	case "relevance":
+		if search == "" {
+			return productSortKeyDef{}, false
+		}
+		rankSQL, args := searchRank(search)
+		return productSortKeyDef{keysetColumn{expr: rankSQL, args: args, cast: "float8"},
+			func(p *model.Product) string { return cursorFloat(p.SearchRank) }}, true
+	}
+	return productSortKeyDef{}, false
 }
 
 // GetByID возвращает товар по его ID со всеми связанными данными.
 func (r *ProductRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.Product, error) {
 	var product model.Product
 	err := r.db.WithContext(ctx).
+		Scopes(withSales).
+		Select(productWithSalesColumns).
 		Preload("Images").
 		Preload("Variants").
 		Preload("Supplier").
-		First(&product, "id = ?", id).Error
+		First(&product, "products.id = ?", id).Error
 	return &product, err
 }
 
+// salesRollupLockKey - ключ advisory-блокировки пересчета продаж, общий для всех экземпляров приложения
+const salesRollupLockKey int64 = 0x53414c4553 // "SALES"
+
+// RefreshSalesRollup пересчитывает продажи всех товаров за последние model.SalesWindowDays дней.
+// Таблица перестраивается целиком в одной транзакции, читатели видят старые данные до коммита.
+// Если пересчет уже идет на другом экземпляре, вызов ничего не делает и возвращает 0.
+func (r *ProductRepository) RefreshSalesRollup(ctx context.Context) (int64, error) {
+	var rows int64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var locked bool
+		if err := tx.Raw("SELECT pg_try_advisory_xact_lock(?)", salesRollupLockKey).Scan(&locked).Error; err != nil {
+			return err
+		}
+		if !locked {
+			return nil
+		}
+		if err := tx.Exec("DELETE FROM product_sales_30d").Error; err != nil {
+			return err
+		}
+		res := tx.Exec(`
+			INSERT INTO product_sales_30d (product_id, units_sold, revenue, refreshed_at)
+			SELECT oi.product_id, SUM(oi.quantity), SUM(oi.total), NOW()
+			FROM order_items oi
+			JOIN orders o ON o.id = oi.order_id
+			JOIN products p ON p.id = oi.product_id
+			WHERE o.date >= ? AND o.status <> 'cancelled'
+			GROUP BY oi.product_id
+		`, time.Now().AddDate(0, 0, -model.SalesWindowDays))
+		rows = res.RowsAffected
+		return res.Error
+	})
+	return rows, err
+}
+
 // Create создает новый товар.
 func (r *ProductRepository) Create(ctx context.Context, product *model.Product) error {
 	var totalStock int
@@ -192,7 +499,13 @@ func (r *ProductRepository) Create(ctx context.Context, product *model.Product)
 	}
 	product.TotalStock = totalStock
 
-	return r.db.WithContext(ctx).Create(product).Error
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(product).Error; err != nil {
+			return err
+		}
+		// Остатки новых вариантов попадают на склад продавца по умолчанию
+		return adoptCardStock(tx, product)
+	})
 }
 
 // Update обновляет товар.
@@ -203,44 +516,311 @@ func (r *ProductRepository) Update(ctx context.Context, product *model.Product)
 	}
 	product.TotalStock = totalStock
 
-	return r.db.WithContext(ctx).Session(&gorm.Session{FullSaveAssociations: true}).Save(product).Error
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Изменения остатка в карточке записываются корректировками на складе по умолчанию
+		if err := syncCardStock(tx, product); err != nil {
+			return err
+		}
+		if err := tx.Session(&gorm.Session{FullSaveAssociations: true}).Save(product).Error; err != nil {
+			return err
+		}
+		return adoptCardStock(tx, product)
+	})
+}
+
+// Replace полностью заменяет карточку товара: варианты и изображения, которых нет в product, удаляются.
+// Используется для отката к сохраненной версии, поэтому остатки и резервы не откатываются:
+// у существующих вариантов сохраняются текущие, удаленные с тех пор варианты создаются заново
+// с нулевым остатком. Вариант, на который ссылаются заказы, складские остатки, резервы или коды
+// маркировки, удалить нельзя - возвращается ErrVariantInUse со списком артикулов.
+func (r *ProductRepository) Replace(ctx context.Context, product *model.Product) error {
+	for i := range product.Images {
+		product.Images[i].ProductID = product.ID
+	}
+	product.Supplier = nil // Данные поставщика не откатываются вместе с карточкой
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var current []model.ProductVariant
+		// Строки блокируются, чтобы параллельное резервирование не затерлось сохранением карточки
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id", "sku", "stock", "reserved").
+			Where("product_id = ?", product.ID).
+			Find(&current).Error
+		if err != nil {
+			return err
+		}
+		byID := make(map[uuid.UUID]model.ProductVariant, len(current))
+		for _, v := range current {
+			byID[v.ID] = v
+		}
+
+		keepVariants := []uuid.UUID{uuid.Nil}
+		totalStock := 0
+		for i := range product.Variants {
+			v := &product.Variants[i]
+			v.ProductID = product.ID
+			v.Stock, v.Reserved = byID[v.ID].Stock, byID[v.ID].Reserved
+			totalStock += v.Stock
+			keepVariants = append(keepVariants, v.ID)
+		}
+		product.TotalStock = totalStock
+
+		var inUse []string
+		err = tx.Model(&model.ProductVariant{}).
+			Where("product_id = ? AND id NOT IN ?", product.ID, keepVariants).
+			Where(`stock <> 0 OR reserved <> 0
+				OR EXISTS (SELECT 1 FROM order_items oi WHERE oi.variant_id = product_variants.id)
+				OR EXISTS (SELECT 1 FROM warehouse_stocks ws WHERE ws.variant_id = product_variants.id AND (ws.stock <> 0 OR ws.reserved <> 0))
+				OR EXISTS (SELECT 1 FROM stock_reservations sr WHERE sr.variant_id = product_variants.id)
+				OR EXISTS (SELECT 1 FROM marking_codes mc WHERE mc.variant_id = product_variants.id)`).
+			Order("sku").
+			Pluck("sku", &inUse).Error
+		if err != nil {
+			return err
+		}
+		if len(inUse) > 0 {
+			return fmt.Errorf("%w: %s", ErrVariantInUse, strings.Join(inUse, ", "))
+		}
+		if err := tx.Where("product_id = ? AND id NOT IN ?", product.ID, keepVariants).Delete(&model.ProductVariant{}).Error; err != nil {
+			return err
+		}
+
+		keepImages := []uuid.UUID{uuid.Nil}
+		for _, img := range product.Images {
+			keepImages = append(keepImages, img.ID)
+		}
+		if err := tx.Where("product_id = ? AND id NOT IN ?", product.ID, keepImages).Delete(&model.ProductImage{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Session(&gorm.Session{FullSaveAssociations: true}).Save(product).Error; err != nil {
+			return err
+		}
+		return adoptCardStock(tx, product)
+	})
 }
 
-// Delete удаляет товар.
+// Delete мягко удаляет товар: проставляет deleted_at, варианты и изображения сохраняются.
 func (r *ProductRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	return r.db.WithContext(ctx).Select("Variants", "Images").Delete(&model.Product{ID: id}).Error
+	tx := r.db.WithContext(ctx).Delete(&model.Product{ID: id})
+	if tx.Error != nil {
+		return tx.Error
+	}
+	if tx.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
 }
 
-// CreateImage создает запись об изображении для продукта.
-func (r *ProductRepository) CreateImage(ctx context.Context, image *model.ProductImage) error {
-	return r.db.WithContext(ctx).Create(image).Error
+// GetByIDWithDeleted возвращает товар по ID, включая удаленные (для истории заказов).
+func (r *ProductRepository) GetByIDWithDeleted(ctx context.Context, id uuid.UUID) (*model.Product, error) {
+	var product model.Product
+	err := r.db.WithContext(ctx).Unscoped().
+		Preload("Images").
+		Preload("Variants").
+		Preload("Supplier").
+		First(&product, "id = ?", id).Error
+	return &product, err
 }
 
-// GetCategories получает все уникальные категории из продуктов.
-func (r *ProductRepository) GetCategories(ctx context.Context) ([]model.Category, error) {
-	var categories []model.Category
+// ListDeleted возвращает удаленные товары, которые еще можно восстановить (удалены после deletedAfter).
+func (r *ProductRepository) ListDeleted(ctx context.Context, deletedAfter time.Time, limit, offset int) ([]model.Product, int64, error) {
+	var products []model.Product
+	var total int64
 
-	// Получаем уникальные категории из таблицы products
-	var categoryNames []string
-	err := r.db.WithContext(ctx).
-		Model(&model.Product{}).
-		Distinct("category").
-		Where("category IS NOT NULL AND category != ''").
-		Pluck("category", &categoryNames).Error
+	query := r.db.WithContext(ctx).Unscoped().Model(&model.Product{}).
+		Where("deleted_at IS NOT NULL AND deleted_at > ?", deletedAfter)
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Preload("Images").Order("deleted_at DESC").Offset(offset).Limit(limit).Find(&products).Error
+	return products, total, err
+}
 
+// Restore восстанавливает удаленный товар, если он был удален после deletedAfter.
+func (r *ProductRepository) Restore(ctx context.Context, id uuid.UUID, deletedAfter time.Time) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var product model.Product
+		if err := tx.Unscoped().Where("deleted_at IS NOT NULL").First(&product, "id = ?", id).Error; err != nil {
+			return err
+		}
+		if !product.DeletedAt.Time.After(deletedAfter) {
+			return ErrRestoreWindowExpired
+		}
+
+		// Артикул мог быть занят новым товаром, пока этот лежал в корзине
+		var conflicts int64
+		if err := tx.Model(&model.Product{}).Where("sku = ? AND id <> ?", product.SKU, id).Count(&conflicts).Error; err != nil {
+			return err
+		}
+		if conflicts > 0 {
+			return ErrSKUTaken
+		}
+
+		return tx.Unscoped().Model(&model.Product{}).Where("id = ?", id).Update("deleted_at", nil).Error
+	})
+}
+
+// PurgeDeleted окончательно удаляет товары, удаленные раньше deletedBefore.
+// Товары, на которые ссылаются позиции заказов, остаются в БД навсегда, чтобы история заказов и аналитика не ломались.
+func (r *ProductRepository) PurgeDeleted(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	var purged int64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var ids []uuid.UUID
+		err := tx.Unscoped().Model(&model.Product{}).
+			Where("deleted_at IS NOT NULL AND deleted_at < ?", deletedBefore).
+			Where("NOT EXISTS (SELECT 1 FROM order_items oi WHERE oi.product_id = products.id)").
+			Pluck("id", &ids).Error
+		if err != nil || len(ids) == 0 {
+			return err
+		}
+
+		if err := tx.Where("product_id IN ?", ids).Delete(&model.ProductImage{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("product_id IN ?", ids).Delete(&model.ProductVariant{}).Error; err != nil {
+			return err
+		}
+		res := tx.Unscoped().Where("id IN ?", ids).Delete(&model.Product{})
+		purged = res.RowsAffected
+		return res.Error
+	})
+	return purged, err
+}
+
+// SetStatus переводит товар в новый статус жизненного цикла и фиксирует время публикации/архивации.
+func (r *ProductRepository) SetStatus(ctx context.Context, id uuid.UUID, status string) error {
+	updates := map[string]interface{}{"status": status}
+	switch status {
+	case model.ProductStatusActive:
+		updates["published_at"] = time.Now()
+		updates["archived_at"] = nil
+	case model.ProductStatusArchived:
+		updates["archived_at"] = time.Now()
+	case model.ProductStatusDraft:
+		updates["archived_at"] = nil
+	}
+	return r.db.WithContext(ctx).Model(&model.Product{}).Where("id = ?", id).Updates(updates).Error
+}
+
+// BulkTargetIDs возвращает ID товаров продавца, подходящих под фильтры массовой операции.
+// Выбирается не больше limit товаров, чтобы вызывающий код мог обнаружить превышение лимита.
+func (r *ProductRepository) BulkTargetIDs(ctx context.Context, userID uuid.UUID, params ListProductsParams, limit int) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	params.Search = strings.TrimSpace(params.Search)
+	err := r.filtered(ctx, params, "").
+		Where("products.user_id = ?", userID).
+		Order("products.id").
+		Limit(limit).
+		Pluck("products.id", &ids).Error
+	return ids, err
+}
+
+// Bulk применяет apply к каждому товару продавца из ids в одной транзакции.
+// Товар загружается с изображениями и вариантами; если apply возвращает ошибку, товар пропускается,
+// остальные обрабатываются дальше. В режиме preview изменения только вычисляются и не сохраняются.
+// Результаты возвращаются в порядке ids.
+func (r *ProductRepository) Bulk(ctx context.Context, userID uuid.UUID, ids []uuid.UUID, preview bool, apply func(*model.Product) error) ([]model.BulkItemResult, error) {
+	results := make([]model.BulkItemResult, 0, len(ids))
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Блокируем строки товаров до конца транзакции, затем загружаем их со связями
+		var locked []uuid.UUID
+		err := tx.Model(&model.Product{}).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id IN ? AND user_id = ?", ids, userID).
+			Pluck("id", &locked).Error
+		if err != nil {
+			return err
+		}
+
+		var products []model.Product
+		if len(locked) > 0 {
+			if err := tx.Preload("Images").Preload("Variants").Where("id IN ?", locked).Find(&products).Error; err != nil {
+				return err
+			}
+		}
+		byID := make(map[uuid.UUID]*model.Product, len(products))
+		for i := range products {
+			byID[products[i].ID] = &products[i]
+		}
+
+		for _, id := range ids {
+			product, ok := byID[id]
+			if !ok {
+				results = append(results, model.BulkItemResult{ProductID: id, Result: model.BulkResultNotFound})
+				continue
+			}
+			result := model.BulkItemResult{ProductID: id, SKU: product.SKU, Name: product.Name}
+
+			before := *product
+			before.Variants = append([]model.ProductVariant(nil), product.Variants...)
+			before.Tags = append(pq.StringArray(nil), product.Tags...)
+
+			if err := apply(product); err != nil {
+				result.Result, result.Error = model.BulkResultSkipped, err.Error()
+				results = append(results, result)
+				continue
+			}
+
+			beforeSnapshot, afterSnapshot := model.ProductSnapshot(before), model.ProductSnapshot(*product)
+			result.Changes = model.DiffProductSnapshots(&beforeSnapshot, &afterSnapshot)
+			// SupplierID не попадает в снимок (в JSON отдается только связанный Supplier), сравниваем отдельно
+			if !equalUUIDPtr(before.SupplierID, product.SupplierID) {
+				result.Changes = append(result.Changes, model.FieldChange{Field: "supplier_id", Old: before.SupplierID, New: product.SupplierID})
+			}
+			if len(result.Changes) == 0 {
+				result.Result = model.BulkResultUnchanged
+				results = append(results, result)
+				continue
+			}
+			result.Result = model.BulkResultUpdated
+			results = append(results, result)
+			if preview {
+				continue
+			}
+
+			// Сохраняем только колонки, которые могут меняться массовыми операциями
+			updates := map[string]interface{}{
+				"status":           product.Status,
+				"published_at":     product.PublishedAt,
+				"archived_at":      product.ArchivedAt,
+				"price":            product.Price,
+				"discount_percent": product.DiscountPercent,
+				"tags":             product.Tags,
+				"supplier_id":      product.SupplierID,
+			}
+			if err := tx.Model(&model.Product{}).Where("id = ?", id).Updates(updates).Error; err != nil {
+				return fmt.Errorf("failed to update product %s: %w", id, err)
+			}
+			for i, v := range product.Variants {
+				if v.Price == before.Variants[i].Price {
+					continue
+				}
+				if err := tx.Model(&model.ProductVariant{}).Where("id = ?", v.ID).Update("price", v.Price).Error; err != nil {
+					return fmt.Errorf("failed to update variant %s: %w", v.SKU, err)
+				}
+			}
+		}
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
+	return results, nil
+}
 
-	// Преобразуем в формат Category
-	for _, name := range categoryNames {
-		categories = append(categories, model.Category{
-			ID:   name,
-			Name: name,
-		})
+func equalUUIDPtr(a, b *uuid.UUID) bool {
+	if a == nil || b == nil {
+		return a == b
 	}
+	return *a == *b
+}
 
-	return categories, nil
+// CreateImage создает запись об изображении для продукта.
+func (r *ProductRepository) CreateImage(ctx context.Context, image *model.ProductImage) error {
+	return r.db.WithContext(ctx).Create(image).Error
 }
 
 // GetSizeChart получает размерную сетку для указанной категории из базы данных.
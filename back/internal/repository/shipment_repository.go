@@ -0,0 +1,463 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lamoda-seller-app/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrOrderNotShippable        = errors.New("order is closed for new shipments")
+	ErrShipmentQuantityExceeded = errors.New("shipment quantity exceeds the quantity left to ship in the order line")
+	ErrShipmentStatusTransition = errors.New("shipment status cannot be changed this way")
+)
+
+// shipmentTransitions - допустимые переходы статусов отправления
+var shipmentTransitions = map[string][]string{
+	model.ShipmentStatusPending: {model.ShipmentStatusShipped, model.ShipmentStatusCancelled},
+	model.ShipmentStatusShipped: {model.ShipmentStatusDelivered},
+}
+
+// ShipmentRepository инкапсулирует работу с отправлениями (посылками) заказов.
+type ShipmentRepository struct {
+	db *gorm.DB
+}
+
+func NewShipmentRepository(db *gorm.DB) *ShipmentRepository {
+	return &ShipmentRepository{db: db}
+}
+
+// ListByOrder возвращает отправления заказа продавца в порядке создания.
+func (r *ShipmentRepository) ListByOrder(ctx context.Context, userID, orderID uuid.UUID) ([]model.Shipment, error) {
+	db := r.db.WithContext(ctx)
+	if err := db.Where("user_id = ?", userID).Select("id").First(&model.Order{}, orderID).Error; err != nil {
+		return nil, err
+	}
+	var shipments []model.Shipment
+	err := db.Preload("Items").Where("order_id = ?", orderID).Order("created_at").Find(&shipments).Error
+	return shipments, err
+}
+
+// GetByID возвращает отправление заказа продавца с позициями.
+func (r *ShipmentRepository) GetByID(ctx context.Context, userID, orderID, shipmentID uuid.UUID) (*model.Shipment, error) {
+	var shipment model.Shipment
+	err := r.db.WithContext(ctx).Preload("Items").
+		Where("user_id = ? AND order_id = ?", userID, orderID).
+		First(&shipment, shipmentID).Error
+	return &shipment, err
+}
+
+// Create создает отправление с частью позиций заказа. Единицы, уже включенные в другие
+// неотмененные отправления, повторно включить нельзя.
+func (r *ShipmentRepository) Create(ctx context.Context, userID, orderID uuid.UUID, req model.CreateShipmentRequest) (*model.Shipment, error) {
+	var shipment model.Shipment
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		order, err := lockOrder(tx, userID, orderID)
+		if err != nil {
+			return err
+		}
+		switch order.Status {
+		case model.OrderStatusNew, model.OrderStatusConfirmed, model.OrderStatusPartiallyShipped:
+		default:
+			return fmt.Errorf("%w: status is %s", ErrOrderNotShippable, order.Status)
+		}
+		if req.WarehouseID != nil {
+			if err := checkWarehouseOwner(tx, userID, *req.WarehouseID); err != nil {
+				return err
+			}
+		}
+
+		allocated, err := allocatedQuantities(tx, orderID)
+		if err != nil {
+			return err
+		}
+		requested := make(map[uuid.UUID]int, len(req.Items))
+		for _, it := range req.Items {
+			requested[it.OrderItemID] += it.Quantity
+		}
+		items := make(map[uuid.UUID]model.OrderItem, len(order.Items))
+		for _, item := range order.Items {
+			items[item.ID] = item
+		}
+		for id, quantity := range requested {
+			item, ok := items[id]
+			if !ok {
+				return ErrOrderItemNotFound
+			}
+			if left := item.Quantity - allocated[id]; quantity > left {
+				return fmt.Errorf("%w: %s (%d, left %d)", ErrShipmentQuantityExceeded, item.SKU, quantity, left)
+			}
+		}
+
+		var count int64
+		if err := tx.Model(&model.Shipment{}).Where("order_id = ?", orderID).Count(&count).Error; err != nil {
+			return err
+		}
+		shipment = model.Shipment{
+			ID:             uuid.New(),
+			UserID:         userID,
+			OrderID:        orderID,
+			Number:         fmt.Sprintf("%s-%d", order.OrderNumber, count+1),
+			WarehouseID:    req.WarehouseID,
+			Carrier:        req.Carrier,
+			TrackingNumber: req.TrackingNumber,
+			Status:         model.ShipmentStatusPending,
+		}
+		// Позиции в порядке заказа, повторы в запросе уже сложены
+		for _, item := range order.Items {
+			if quantity := requested[item.ID]; quantity > 0 {
+				shipment.Items = append(shipment.Items, model.ShipmentItem{ID: uuid.New(), ShipmentID: shipment.ID, OrderItemID: item.ID, Quantity: quantity})
+			}
+		}
+		return tx.Create(&shipment).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &shipment, nil
+}
+
+// Update меняет перевозчика и трек-номер отправления.
+func (r *ShipmentRepository) Update(ctx context.Context, userID, orderID, shipmentID uuid.UUID, req model.UpdateShipmentRequest) (*model.Shipment, error) {
+	var shipment model.Shipment
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Preload("Items").Where("user_id = ? AND order_id = ?", userID, orderID).First(&shipment, shipmentID).Error; err != nil {
+			return err
+		}
+		if req.Carrier != nil {
+			shipment.Carrier = *req.Carrier
+		}
+		if req.TrackingNumber != nil {
+			shipment.TrackingNumber = *req.TrackingNumber
+		}
+		return tx.Omit(clause.Associations).Save(&shipment).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &shipment, nil
+}
+
+// UpdateStatus переводит отправление в новый статус и пересчитывает статус заказа по его отправлениям.
+// При отгрузке посылки со склада списываются только ее позиции, отгружаются их коды маркировки.
+func (r *ShipmentRepository) UpdateStatus(ctx context.Context, userID, orderID, shipmentID uuid.UUID, status, comment string) (*model.Shipment, *model.Order, error) {
+	var shipment model.Shipment
+	var order *model.Order
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		order, err = lockOrder(tx, userID, orderID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Preload("Items").Where("order_id = ?", orderID).First(&shipment, shipmentID).Error; err != nil {
+			return err
+		}
+		if !shipmentTransitionAllowed(shipment.Status, status) {
+			return fmt.Errorf("%w: %s -> %s", ErrShipmentStatusTransition, shipment.Status, status)
+		}
+
+		now := time.Now()
+		switch status {
+		case model.ShipmentStatusShipped:
+			if err := shipShipment(tx, order, &shipment); err != nil {
+				return err
+			}
+			shipment.ShippedAt = &now
+		case model.ShipmentStatusDelivered:
+			shipment.DeliveredAt = &now
+		}
+		shipment.Status = status
+		if err := tx.Omit(clause.Associations).Save(&shipment).Error; err != nil {
+			return err
+		}
+
+		historyComment := fmt.Sprintf("Посылка %s: %s", shipment.Number, status)
+		if comment != "" {
+			historyComment += ". " + comment
+		}
+		return syncOrderWithShipments(tx, order, historyComment)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return &shipment, order, nil
+}
+
+// TrackedShipments возвращает отправления перевозчиков carriers с трек-номером, которые еще не вручены
+// и не опрашивались после polledBefore. Первыми идут давно не опрашивавшиеся.
+func (r *ShipmentRepository) TrackedShipments(ctx context.Context, carriers []string, polledBefore time.Time, limit int) ([]model.Shipment, error) {
+	var shipments []model.Shipment
+	if len(carriers) == 0 {
+		return shipments, nil
+	}
+	err := r.db.WithContext(ctx).
+		Where("carrier IN ? AND tracking_number <> '' AND status IN ?", carriers,
+			[]string{model.ShipmentStatusPending, model.ShipmentStatusShipped}).
+		Where("tracking_polled_at IS NULL OR tracking_polled_at < ?", polledBefore).
+		Order("tracking_polled_at NULLS FIRST").
+		Limit(limit).
+		Find(&shipments).Error
+	return shipments, err
+}
+
+// SaveTrackingEvents сохраняет новые события отслеживания посылки (уже сохраненные пропускаются
+// по external_id) и отмечает время опроса. Возвращает количество новых событий.
+func (r *ShipmentRepository) SaveTrackingEvents(ctx context.Context, shipment *model.Shipment, events []model.TrackingEvent) (int, error) {
+	var added int64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for i := range events {
+			e := &events[i]
+			e.ID = uuid.New()
+			e.UserID = shipment.UserID
+			e.OrderID = shipment.OrderID
+			e.ShipmentID = shipment.ID
+			res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(e)
+			if res.Error != nil {
+				return res.Error
+			}
+			added += res.RowsAffected
+		}
+		return tx.Model(&model.Shipment{}).Where("id = ?", shipment.ID).
+			UpdateColumn("tracking_polled_at", time.Now()).Error
+	})
+	return int(added), err
+}
+
+// ListTrackingEvents возвращает события отслеживания всех посылок заказа продавца по времени.
+func (r *ShipmentRepository) ListTrackingEvents(ctx context.Context, userID, orderID uuid.UUID) ([]model.TrackingEvent, error) {
+	var events []model.TrackingEvent
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND order_id = ?", userID, orderID).
+		Order("occurred_at, created_at").
+		Find(&events).Error
+	return events, err
+}
+
+func shipmentTransitionAllowed(from, to string) bool {
+	for _, next := range shipmentTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// lockOrder блокирует заказ продавца до конца транзакции и загружает его позиции.
+func lockOrder(tx *gorm.DB, userID, orderID uuid.UUID) (*model.Order, error) {
+	var order model.Order
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Preload("Items").Where("user_id = ?", userID).First(&order, orderID).Error
+	return &order, err
+}
+
+// allocatedQuantities возвращает, сколько единиц каждой позиции заказа уже включено в неотмененные отправления.
+func allocatedQuantities(tx *gorm.DB, orderID uuid.UUID) (map[uuid.UUID]int, error) {
+	var rows []struct {
+		OrderItemID uuid.UUID
+		Quantity    int
+	}
+	err := tx.Table("shipment_items si").
+		Select("si.order_item_id, SUM(si.quantity) AS quantity").
+		Joins("JOIN shipments s ON s.id = si.shipment_id").
+		Where("s.order_id = ? AND s.status <> ?", orderID, model.ShipmentStatusCancelled).
+		Group("si.order_item_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	allocated := make(map[uuid.UUID]int, len(rows))
+	for _, row := range rows {
+		allocated[row.OrderItemID] = row.Quantity
+	}
+	return allocated, nil
+}
+
+// shrinkPendingShipments убирает из несобранных посылок единицы позиции заказа сверх quantity
+// (например, после частичной отмены), начиная с последних посылок. Посылка, в которой
+// не осталось позиций, отменяется.
+func shrinkPendingShipments(tx *gorm.DB, orderItemID uuid.UUID, quantity int) error {
+	var items []struct {
+		ID         uuid.UUID
+		ShipmentID uuid.UUID
+		Quantity   int
+	}
+	err := tx.Table("shipment_items si").
+		Select("si.id, si.shipment_id, si.quantity").
+		Joins("JOIN shipments s ON s.id = si.shipment_id").
+		Where("si.order_item_id = ? AND s.status = ?", orderItemID, model.ShipmentStatusPending).
+		Order("s.created_at DESC, si.id").
+		Scan(&items).Error
+	if err != nil {
+		return err
+	}
+
+	excess := -quantity
+	for _, si := range items {
+		excess += si.Quantity
+	}
+	for _, si := range items {
+		if excess <= 0 {
+			break
+		}
+		take := min(excess, si.Quantity)
+		excess -= take
+		if take < si.Quantity {
+			err := tx.Model(&model.ShipmentItem{}).Where("id = ?", si.ID).
+				Update("quantity", si.Quantity-take).Error
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if err := tx.Delete(&model.ShipmentItem{}, "id = ?", si.ID).Error; err != nil {
+			return err
+		}
+		err := tx.Model(&model.Shipment{}).
+			Where("id = ? AND NOT EXISTS (SELECT 1 FROM shipment_items WHERE shipment_id = ?)", si.ShipmentID, si.ShipmentID).
+			Update("status", model.ShipmentStatusCancelled).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// shipShipment списывает позиции посылки со склада и отгружает их коды маркировки.
+// Единицы, уже отгруженные с заказом целиком, повторно не списываются.
+func shipShipment(tx *gorm.DB, order *model.Order, shipment *model.Shipment) error {
+	quantities := make(map[uuid.UUID]int, len(shipment.Items))
+	for _, si := range shipment.Items {
+		quantities[si.OrderItemID] += si.Quantity
+	}
+	for _, item := range order.Items {
+		if q := quantities[item.ID]; q > item.Quantity-item.ShippedQuantity {
+			return fmt.Errorf("%w: %s (%d, left %d)", ErrShipmentQuantityExceeded, item.SKU, q, item.Quantity-item.ShippedQuantity)
+		}
+	}
+
+	if err := shipOrderItems(tx, order, quantities, shipment.WarehouseID); err != nil {
+		return err
+	}
+	if err := shipItemCodes(tx, order, quantities); err != nil {
+		return err
+	}
+	for i := range order.Items {
+		item := &order.Items[i]
+		q := quantities[item.ID]
+		if q == 0 {
+			continue
+		}
+		item.ShippedQuantity += q
+		if err := tx.Model(item).Update("shipped_quantity", item.ShippedQuantity).Error; err != nil {
+			return err
+		}
+	}
+
+	// Старое поле заказа сохраняет трек-номер первой посылки для клиентов, не знающих об отправлениях
+	if order.Delivery.TrackingNumber == "" && shipment.TrackingNumber != "" {
+		order.Delivery.TrackingNumber = shipment.TrackingNumber
+		return tx.Model(order).Update("delivery", order.Delivery).Error
+	}
+	return nil
+}
+
+// syncOrderWithShipments выводит статус заказа из его отправлений и, если он изменился,
+// сохраняет его и пишет запись в историю статусов с комментарием comment.
+func syncOrderWithShipments(tx *gorm.DB, order *model.Order, comment string) error {
+	switch order.Status {
+	case model.OrderStatusCancelled, model.OrderStatusReturned:
+		return nil
+	}
+
+	var shipments []model.Shipment
+	if err := tx.Preload("Items").Where("order_id = ?", order.ID).Find(&shipments).Error; err != nil {
+		return err
+	}
+	status := deriveOrderStatus(order, shipments)
+	if status == order.Status {
+		return nil
+	}
+
+	order.Status = status
+	if err := tx.Model(order).Update("status", status).Error; err != nil {
+		return err
+	}
+	if status == model.OrderStatusDelivered {
+		err := notify(tx, order.UserID, model.NotificationCategoryOrders, "order.delivered",
+			"Заказ №"+order.OrderNumber+" доставлен", comment, "/orders/"+order.ID.String(),
+			model.NotificationData{"order_id": order.ID})
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Create(&model.StatusHistory{
+		OrderID: order.ID,
+		Status:  status,
+		Date:    time.Now(),
+		Comment: comment,
+	}).Error
+}
+
+// deriveOrderStatus возвращает статус заказа по его отправлениям: пока ничего не отгружено,
+// статус не меняется; отгружена часть единиц - partially_shipped; все - in_transit;
+// все вручены - delivered. Отгруженными считаются и единицы, отгруженные с заказом целиком.
+func deriveOrderStatus(order *model.Order, shipments []model.Shipment) string {
+	deliveredInParcels := make(map[uuid.UUID]int)
+	for _, s := range shipments {
+		if s.Status != model.ShipmentStatusDelivered {
+			continue
+		}
+		for _, si := range s.Items {
+			deliveredInParcels[si.OrderItemID] += si.Quantity
+		}
+	}
+
+	var total, shipped, delivered int
+	for _, item := range order.Items {
+		total += item.Quantity
+		shipped += min(item.ShippedQuantity, item.Quantity)
+		delivered += min(deliveredInParcels[item.ID], item.Quantity)
+	}
+	switch {
+	case shipped == 0 || total == 0:
+		return order.Status
+	case shipped < total:
+		return model.OrderStatusPartiallyShipped
+	case delivered == total:
+		return model.OrderStatusDelivered
+	case order.Status == model.OrderStatusDelivered:
+		return order.Status
+	}
+	return model.OrderStatusInTransit
+}
+
+// applyOrderShipments согласует позиции и отправления заказа со сменой статуса всего заказа:
+// при отгрузке целиком все единицы считаются отгруженными, а несобранные посылки отменяются;
+// при доставке вручаются все отгруженные посылки.
+func applyOrderShipments(tx *gorm.DB, order *model.Order, status string) error {
+	switch status {
+	case model.OrderStatusInTransit:
+		if err := tx.Model(&model.OrderItem{}).Where("order_id = ?", order.ID).
+			Update("shipped_quantity", gorm.Expr("quantity")).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.Shipment{}).
+			Where("order_id = ? AND status = ?", order.ID, model.ShipmentStatusPending).
+			Update("status", model.ShipmentStatusCancelled).Error
+	case model.OrderStatusDelivered:
+		return tx.Model(&model.Shipment{}).
+			Where("order_id = ? AND status = ?", order.ID, model.ShipmentStatusShipped).
+			Updates(map[string]interface{}{"status": model.ShipmentStatusDelivered, "delivered_at": time.Now()}).Error
+	case model.OrderStatusCancelled:
+		return tx.Model(&model.Shipment{}).
+			Where("order_id = ? AND status = ?", order.ID, model.ShipmentStatusPending).
+			Update("status", model.ShipmentStatusCancelled).Error
+	}
+	return nil
+}
@@ -0,0 +1,265 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lamoda-seller-app/internal/model"
+	"gorm.io/gorm"
+)
+
+// StockRepository рассчитывает точки заказа вариантов и хранит оповещения об остатках.
+type StockRepository struct {
+	db *gorm.DB
+}
+
+func NewStockRepository(db *gorm.DB) *StockRepository {
+	return &StockRepository{db: db}
+}
+
+// stockLevelsSQL - остатки вариантов неархивных товаров со скоростью продаж, точкой заказа
+// и рекомендуемым количеством к заказу (см. константы в model/stock.go).
+// Именованные параметры заполняются stockLevelsArgs.
+const stockLevelsSQL = `
+	WITH sold AS (
+		SELECT oi.variant_id, SUM(oi.quantity) AS units_sold
+		FROM order_items oi
+		JOIN orders o ON o.id = oi.order_id
+		WHERE o.status <> 'cancelled' AND o.date >= @since
+		GROUP BY oi.variant_id
+	), levels AS (
+		SELECT
+			p.user_id, p.status AS product_status, p.id AS product_id, p.name AS product_name,
+			p.cost_price, p.supplier_id, s.name AS supplier_name,
+			v.id AS variant_id, v.sku, v.size, v.color, v.stock, v.reserved,
+			GREATEST(v.stock - v.reserved, 0) AS available,
+			COALESCE(sold.units_sold, 0) AS units_sold,
+			COALESCE(sold.units_sold, 0)::float8 / @window AS daily_velocity,
+			COALESCE(v.lead_time_days, s.lead_time_days, @default_lead_time) AS lead_time_days,
+			v.reorder_point IS NOT NULL AS reorder_point_manual,
+			v.reorder_point AS manual_reorder_point
+		FROM product_variants v
+		JOIN products p ON p.id = v.product_id AND p.deleted_at IS NULL AND p.status <> 'archived'
+		LEFT JOIN suppliers s ON s.id = p.supplier_id
+		LEFT JOIN sold ON sold.variant_id = v.id
+	), points AS (
+		SELECT l.*,
+			COALESCE(l.manual_reorder_point, CEIL(l.daily_velocity * (l.lead_time_days + @safety_days))::int) AS reorder_point
+		FROM levels l
+	)
+	SELECT pt.*,
+		CASE WHEN pt.available <= pt.reorder_point
+			THEN GREATEST(pt.reorder_point + CEIL(pt.daily_velocity * @cover_days)::int - pt.available, 0)
+			ELSE 0
+		END AS suggested_quantity,
+		CASE WHEN pt.daily_velocity > 0 THEN ROUND((pt.available / pt.daily_velocity)::numeric, 1)::float8 END AS days_of_stock
+	FROM points pt`
+
+func stockLevelsArgs(now time.Time) map[string]interface{} {
+	return map[string]interface{}{
+		"since":             now.AddDate(0, 0, -model.SalesWindowDays),
+		"window":            model.SalesWindowDays,
+		"default_lead_time": model.DefaultLeadTimeDays,
+		"safety_days":       model.SafetyStockDays,
+		"cover_days":        model.RestockCoverDays,
+	}
+}
+
+// levels возвращает запрос к остаткам вариантов, к которому можно добавлять условия и сортировку.
+func (r *StockRepository) levels(ctx context.Context) *gorm.DB {
+	sub := r.db.Raw(stockLevelsSQL, stockLevelsArgs(time.Now()))
+	return r.db.WithContext(ctx).Table("(?) AS sl", sub)
+}
+
+// VariantLevels возвращает остатки и точки заказа вариантов товара продавца.
+func (r *StockRepository) VariantLevels(ctx context.Context, userID, productID uuid.UUID) ([]model.VariantStockLevel, error) {
+	var levels []model.VariantStockLevel
+	err := r.levels(ctx).
+		Where("sl.user_id = ? AND sl.product_id = ?", userID, productID).
+		Order("sl.sku").
+		Scan(&levels).Error
+	return levels, err
+}
+
+// RestockReport возвращает варианты, которые пора дозаказать, сгруппированные по поставщикам.
+// Если supplierID задан, отчет строится только по одному поставщику.
+func (r *StockRepository) RestockReport(ctx context.Context, userID uuid.UUID, supplierID *uuid.UUID) ([]model.RestockGroup, error) {
+	query := r.levels(ctx).Where("sl.user_id = ? AND sl.suggested_quantity > 0", userID)
+	if supplierID != nil {
+		query = query.Where("sl.supplier_id = ?", *supplierID)
+	}
+
+	var levels []model.VariantStockLevel
+	// Товары без поставщика идут последней группой
+	err := query.Order("sl.supplier_name ASC NULLS LAST, sl.supplier_id, sl.product_name, sl.sku").Scan(&levels).Error
+	if err != nil {
+		return nil, err
+	}
+
+	groups := []model.RestockGroup{}
+	for _, l := range levels {
+		if n := len(groups); n == 0 || !equalUUIDPtr(groups[n-1].SupplierID, l.SupplierID) {
+			groups = append(groups, model.RestockGroup{SupplierID: l.SupplierID, SupplierName: l.SupplierName})
+		}
+		g := &groups[len(groups)-1]
+		g.Items = append(g.Items, l)
+		g.TotalUnits += l.SuggestedQuantity
+		g.TotalCost += float64(l.SuggestedQuantity) * l.CostPrice
+	}
+	return groups, nil
+}
+
+// SetReorderPoint сохраняет ручную точку заказа и срок поставки варианта.
+// nil в поле возвращает автоматический расчет.
+func (r *StockRepository) SetReorderPoint(ctx context.Context, userID, productID, variantID uuid.UUID, req model.UpdateReorderPointRequest) error {
+	res := r.db.WithContext(ctx).Model(&model.ProductVariant{}).
+		Where("id = ? AND product_id = ?", variantID, productID).
+		Where("EXISTS (SELECT 1 FROM products p WHERE p.id = product_variants.product_id AND p.user_id = ? AND p.deleted_at IS NULL)", userID).
+		Updates(map[string]interface{}{
+			"reorder_point":  req.ReorderPoint,
+			"lead_time_days": req.LeadTimeDays,
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// --- Оповещения ---
+
+// ListStockAlertsParams - параметры списка оповещений
+type ListStockAlertsParams struct {
+	UserID uuid.UUID
+	Status string // open (по умолчанию), resolved, all
+	Type   string
+	Limit  int
+	Offset int
+}
+
+// ListAlerts возвращает оповещения продавца от новых к старым.
+func (r *StockRepository) ListAlerts(ctx context.Context, params ListStockAlertsParams) ([]model.StockAlert, int64, error) {
+	query := r.db.WithContext(ctx).Model(&model.StockAlert{}).Where("stock_alerts.user_id = ?", params.UserID)
+	switch params.Status {
+	case "resolved":
+		query = query.Where("stock_alerts.resolved_at IS NOT NULL")
+	case "all":
+	default:
+		query = query.Where("stock_alerts.resolved_at IS NULL")
+	}
+	if params.Type != "" {
+		query = query.Where("stock_alerts.type = ?", params.Type)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var alerts []model.StockAlert
+	err := query.
+		Select("stock_alerts.*, p.name AS product_name, v.sku, v.size, v.color").
+		Joins("JOIN products p ON p.id = stock_alerts.product_id").
+		Joins("JOIN product_variants v ON v.id = stock_alerts.variant_id").
+		Order("stock_alerts.created_at DESC, stock_alerts.id").
+		Limit(params.Limit).Offset(params.Offset).
+		Find(&alerts).Error
+	return alerts, total, err
+}
+
+// AcknowledgeAlert отмечает оповещение продавца как просмотренное.
+func (r *StockRepository) AcknowledgeAlert(ctx context.Context, id, userID uuid.UUID) error {
+	res := r.db.WithContext(ctx).Model(&model.StockAlert{}).
+		Where("id = ? AND user_id = ?", id, userID).
+		Update("acknowledged_at", gorm.Expr("COALESCE(acknowledged_at, NOW())"))
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// stockBreachesSQL - варианты опубликованных товаров, остаток которых не выше точки заказа
+const stockBreachesSQL = `
+	SELECT sl.user_id, sl.product_id, sl.variant_id, sl.available, sl.reorder_point,
+		CASE WHEN sl.available = 0 THEN 'out_of_stock' ELSE 'low_stock' END AS type
+	FROM (` + stockLevelsSQL + `) sl
+	WHERE sl.product_status = 'active' AND sl.user_id IS NOT NULL
+		AND (sl.available = 0 OR sl.available <= sl.reorder_point)`
+
+// EvaluateAlerts сверяет остатки с точками заказа: открывает оповещения для новых нарушений,
+// обновляет тип и остаток у уже открытых и закрывает оповещения, остаток по которым восстановился.
+// Возвращает открытые в этот раз оповещения (для уведомлений) и количество закрытых.
+func (r *StockRepository) EvaluateAlerts(ctx context.Context) (opened []model.StockAlert, resolved int64, err error) {
+	args := stockLevelsArgs(time.Now())
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Exec(`
+			UPDATE stock_alerts a SET resolved_at = NOW()
+			WHERE a.resolved_at IS NULL
+				AND NOT EXISTS (SELECT 1 FROM (`+stockBreachesSQL+`) b WHERE b.variant_id = a.variant_id)`, args)
+		if res.Error != nil {
+			return res.Error
+		}
+		resolved = res.RowsAffected
+
+		// При смене типа (low_stock → out_of_stock) оповещение снова требует внимания.
+		// prev - та же строка до изменения, по ней видно, сменился ли тип
+		var escalated []uuid.UUID
+		err := tx.Raw(`
+			UPDATE stock_alerts a SET
+				acknowledged_at = CASE WHEN a.type <> b.type THEN NULL ELSE a.acknowledged_at END,
+				type = b.type, available = b.available, reorder_point = b.reorder_point
+			FROM (`+stockBreachesSQL+`) b, stock_alerts prev
+			WHERE a.variant_id = b.variant_id AND a.resolved_at IS NULL AND prev.id = a.id
+				AND (a.type <> b.type OR a.available <> b.available OR a.reorder_point <> b.reorder_point)
+			RETURNING CASE WHEN prev.type <> b.type THEN a.id END`, args).Scan(&escalated).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Raw(`
+			INSERT INTO stock_alerts (user_id, product_id, variant_id, type, available, reorder_point)
+			SELECT b.user_id, b.product_id, b.variant_id, b.type, b.available, b.reorder_point
+			FROM (`+stockBreachesSQL+`) b
+			WHERE NOT EXISTS (SELECT 1 FROM stock_alerts a WHERE a.variant_id = b.variant_id AND a.resolved_at IS NULL)
+			ON CONFLICT (variant_id) WHERE resolved_at IS NULL DO NOTHING
+			RETURNING *`, args).Scan(&opened).Error
+		if err != nil {
+			return err
+		}
+
+		ids := make([]uuid.UUID, 0, len(opened)+len(escalated))
+		for _, a := range opened {
+			ids = append(ids, a.ID)
+		}
+		for _, id := range escalated {
+			if id != uuid.Nil {
+				ids = append(ids, id)
+			}
+		}
+		return notifyStockAlerts(tx, ids)
+	})
+	return opened, resolved, err
+}
+
+// notifyStockAlerts создает уведомления об открытых оповещениях и о закончившихся товарах.
+func notifyStockAlerts(tx *gorm.DB, alertIDs []uuid.UUID) error {
+	if len(alertIDs) == 0 {
+		return nil
+	}
+	return tx.Exec(`
+		SELECT create_notification(a.user_id, ?, 'stock.' || a.type,
+			CASE WHEN a.type = 'out_of_stock' THEN 'Закончился товар ' ELSE 'Заканчивается товар ' END || v.sku,
+			p.name || ': доступно ' || a.available || ' шт., точка заказа ' || a.reorder_point || ' шт.',
+			'/products/' || a.product_id,
+			jsonb_build_object('alert_id', a.id, 'product_id', a.product_id, 'variant_id', a.variant_id))
+		FROM stock_alerts a
+		JOIN product_variants v ON v.id = a.variant_id
+		JOIN products p ON p.id = a.product_id
+		WHERE a.id IN ?`, model.NotificationCategoryStock, alertIDs).Error
+}
@@ -0,0 +1,176 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/lamoda-seller-app/internal/model"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrProductsNotOwned    = errors.New("some products not found or belong to another seller")
+	ErrProductsNotAssigned = errors.New("some products are not assigned to this supplier")
+)
+
+// SupplierRepository инкапсулирует логику работы с поставщиками в БД.
+// Все методы работают только с поставщиками конкретного продавца.
+type SupplierRepository struct {
+	db *gorm.DB
+}
+
+func NewSupplierRepository(db *gorm.DB) *SupplierRepository {
+	return &SupplierRepository{db: db}
+}
+
+// List возвращает поставщиков продавца с количеством привязанных товаров.
+func (r *SupplierRepository) List(ctx context.Context, userID uuid.UUID, search string) ([]model.Supplier, error) {
+	var suppliers []model.Supplier
+	query := r.db.WithContext(ctx).Model(&model.Supplier{}).
+		Select("suppliers.*, (SELECT COUNT(*) FROM products p WHERE p.supplier_id = suppliers.id AND p.deleted_at IS NULL) AS products_count").
+		Where("suppliers.user_id = ?", userID)
+	if search != "" {
+		query = query.Where("suppliers.name ILIKE ?", "%"+search+"%")
+	}
+	err := query.Order("suppliers.name ASC").Find(&suppliers).Error
+	return suppliers, err
+}
+
+// GetByID возвращает поставщика, если он принадлежит продавцу.
+func (r *SupplierRepository) GetByID(ctx context.Context, id, userID uuid.UUID) (*model.Supplier, error) {
+	var supplier model.Supplier
+	err := r.db.WithContext(ctx).Model(&model.Supplier{}).
+		Select("suppliers.*, (SELECT COUNT(*) FROM products p WHERE p.supplier_id = suppliers.id AND p.deleted_at IS NULL) AS products_count").
+		Where("suppliers.id = ? AND suppliers.user_id = ?", id, userID).
+		First(&supplier).Error
+	return &supplier, err
+}
+
+// Create создает нового поставщика.
+func (r *SupplierRepository) Create(ctx context.Context, supplier *model.Supplier) error {
+	return r.db.WithContext(ctx).Create(supplier).Error
+}
+
+// Update обновляет данные поставщика.
+func (r *SupplierRepository) Update(ctx context.Context, supplier *model.Supplier) error {
+	return r.db.WithContext(ctx).Model(supplier).
+		Select("name", "contact", "email", "phone", "notes").
+		Updates(supplier).Error
+}
+
+// Delete удаляет поставщика продавца. У товаров ссылка обнуляется внешним ключом (ON DELETE SET NULL).
+func (r *SupplierRepository) Delete(ctx context.Context, id, userID uuid.UUID) error {
+	tx := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).Delete(&model.Supplier{})
+	if tx.Error != nil {
+		return tx.Error
+	}
+	if tx.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// AssignProducts привязывает товары продавца к поставщику.
+func (r *SupplierRepository) AssignProducts(ctx context.Context, userID, supplierID uuid.UUID, productIDs []uuid.UUID) error {
+	return r.setSupplier(ctx, productIDs, &supplierID, ErrProductsNotOwned, "user_id = ?", userID)
+}
+
+// UnassignProducts снимает привязку товаров к поставщику. Товары других поставщиков не трогаются:
+// если среди productIDs есть такие, ничего не меняется и возвращается ErrProductsNotAssigned.
+func (r *SupplierRepository) UnassignProducts(ctx context.Context, userID, supplierID uuid.UUID, productIDs []uuid.UUID) error {
+	return r.setSupplier(ctx, productIDs, nil, ErrProductsNotAssigned, "user_id = ? AND supplier_id = ?", userID, supplierID)
+}
+
+// setSupplier меняет supplier_id у товаров, подходящих под условие. Если подошли не все товары,
+// изменения откатываются и возвращается mismatchErr.
+func (r *SupplierRepository) setSupplier(ctx context.Context, productIDs []uuid.UUID, supplierID *uuid.UUID, mismatchErr error, cond string, args ...interface{}) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&model.Product{}).
+			Where("id IN ?", productIDs).
+			Where(cond, args...).
+			Update("supplier_id", supplierID)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected != int64(len(productIDs)) {
+			return mismatchErr
+		}
+		return nil
+	})
+}
+
+// Report вычисляет показатели по поставщикам продавца за период.
+// Если supplierID != uuid.Nil, отчет строится только по одному поставщику.
+func (r *SupplierRepository) Report(ctx context.Context, userID, supplierID uuid.UUID, period string) ([]model.SupplierReport, error) {
+	startDate := getStartDate(period)
+
+	// Каждая метрика считается отдельным подзапросом, чтобы JOIN-ы не размножали строки
+	query := r.db.WithContext(ctx).Table("suppliers s").
+		Select(`
+			s.id AS supplier_id,
+			s.name,
+			COALESCE(cat.products_count, 0) AS products_count,
+			COALESCE(cat.sku_count, 0) AS sku_count,
+			COALESCE(cat.stock_units, 0) AS stock_units,
+			COALESCE(cat.stock_value_cost, 0) AS stock_value_cost,
+			COALESCE(sales.units_sold, 0) AS units_sold,
+			COALESCE(sales.revenue, 0) AS revenue,
+			COALESCE(sales.profit, 0) AS profit,
+			COALESCE(ret.units_returned, 0) AS units_returned
+		`).
+		Joins(`LEFT JOIN (
+			SELECT p.supplier_id,
+				COUNT(DISTINCT p.id) AS products_count,
+				COUNT(pv.id) AS sku_count,
+				COALESCE(SUM(pv.stock), 0) AS stock_units,
+				COALESCE(SUM(pv.stock * COALESCE(p.cost_price, 0)), 0) AS stock_value_cost
+			FROM products p
+			LEFT JOIN product_variants pv ON pv.product_id = p.id
+			WHERE p.user_id = ? AND p.deleted_at IS NULL
+			GROUP BY p.supplier_id
+		) cat ON cat.supplier_id = s.id`, userID).
+		Joins(`LEFT JOIN (
+			SELECT p.supplier_id,
+				SUM(oi.quantity) AS units_sold,
+				SUM(oi.total) AS revenue,
+				SUM(oi.total - COALESCE(oi.cost_price, 0) * oi.quantity) AS profit
+			FROM order_items oi
+			JOIN orders o ON o.id = oi.order_id
+			JOIN products p ON p.id = oi.product_id
+			WHERE o.user_id = ? AND o.date >= ? AND o.status <> 'cancelled'
+			GROUP BY p.supplier_id
+		) sales ON sales.supplier_id = s.id`, userID, startDate).
+		Joins(`LEFT JOIN (
+			SELECT p.supplier_id, SUM(r.quantity) AS units_returned
+			FROM order_returns r
+			JOIN order_items oi ON oi.id = r.order_item_id
+			JOIN orders o ON o.id = oi.order_id
+			JOIN products p ON p.id = oi.product_id
+			WHERE o.user_id = ? AND o.date >= ? AND r.status <> 'rejected'
+			GROUP BY p.supplier_id
+		) ret ON ret.supplier_id = s.id`, userID, startDate).
+		Where("s.user_id = ?", userID)
+
+	if supplierID != uuid.Nil {
+		query = query.Where("s.id = ?", supplierID)
+	}
+
+	var reports []model.SupplierReport
+	if err := query.Order("revenue DESC").Scan(&reports).Error; err != nil {
+		return nil, fmt.Errorf("failed to build supplier report: %w", err)
+	}
+
+	for i := range reports {
+		rep := &reports[i]
+		if rep.Revenue > 0 {
+			rep.MarginPercent = (rep.Profit / rep.Revenue) * 100
+		}
+		if rep.UnitsSold > 0 {
+			rep.ReturnRate = (float64(rep.UnitsReturned) / float64(rep.UnitsSold)) * 100
+		}
+	}
+
+	return reports, nil
+}
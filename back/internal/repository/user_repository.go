@@ -5,6 +5,7 @@ package repository
 import (
 	"context"
 	"errors"
+	"fmt"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -29,12 +30,27 @@ func NewUserRepository(db *gorm.DB) *UserRepository {
 
 // UpdateBalance атомарно изменяет баланс пользователя.
 // amount может быть положительным (пополнение) или отрицательным (снятие).
-// Метод проверяет, что баланс не станет отрицательным.
+// Метод проверяет, что баланс не станет отрицательным. О движении средств создается уведомление.
 func (r *UserRepository) UpdateBalance(ctx context.Context, userID uuid.UUID, amount int64) error {
+	return r.db.WithContext(ctx).Transaction(func(db *gorm.DB) error {
+		title := fmt.Sprintf("Баланс пополнен на %.2f ₽", float64(amount)/100)
+		notificationType := "balance.credited"
+		if amount < 0 {
+			title = fmt.Sprintf("С баланса списано %.2f ₽", float64(-amount)/100)
+			notificationType = "balance.debited"
+		}
+		return changeBalance(db, userID, amount, notificationType, title, "", "/profile",
+			model.NotificationData{"amount_kopecks": amount})
+	})
+}
+
+// changeBalance - единственный путь изменения баланса: изменяет его в транзакции db, не допуская
+// отрицательного остатка, и создает уведомление о движении средств.
+func changeBalance(db *gorm.DB, userID uuid.UUID, amount int64, notificationType, title, body, link string, data model.NotificationData) error {
 	// Для снятия средств (amount < 0) мы добавляем условие в WHERE,
 	// чтобы запрос не выполнился, если итоговый баланс будет меньше нуля.
 	// Для пополнения (amount >= 0) это условие всегда будет истинным.
-	tx := r.db.WithContext(ctx).Model(&model.User{}).
+	tx := db.Model(&model.User{}).
 		Where("id = ? AND balance_kopecks + ? >= 0", userID, amount).
 		Update("balance_kopecks", gorm.Expr("balance_kopecks + ?", amount))
 
@@ -48,13 +64,17 @@ func (r *UserRepository) UpdateBalance(ctx context.Context, userID uuid.UUID, am
 		// Мы должны проверить, существует ли пользователь вообще, чтобы не возвращать
 		// ложную ошибку о нехватке средств для несуществующего ID.
 		var count int64
-		r.db.WithContext(ctx).Model(&model.User{}).Where("id = ?", userID).Count(&count)
+		db.Model(&model.User{}).Where("id = ?", userID).Count(&count)
 		if count > 0 {
 			return ErrInsufficientFunds
 		}
+		return nil
 	}
 
-	return nil
+	if amount == 0 {
+		return nil
+	}
+	return notify(db, userID, model.NotificationCategoryBalance, notificationType, title, body, link, data)
 }
 
 // --- СУЩЕСТВУЮЩИЕ МЕТОДЫ ---
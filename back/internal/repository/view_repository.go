@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/lamoda-seller-app/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrViewNotOwner = errors.New("only the owner can modify a saved view")
+
+// ViewRepository хранит сохраненные представления списков.
+type ViewRepository struct {
+	db *gorm.DB
+}
+
+func NewViewRepository(db *gorm.DB) *ViewRepository {
+	return &ViewRepository{db: db}
+}
+
+// visible ограничивает выборку представлениями, доступными пользователю:
+// своими и общими представлениями связанных аккаунтов (связь учитывается в обе стороны).
+func (r *ViewRepository) visible(ctx context.Context, userID uuid.UUID) *gorm.DB {
+	return r.db.WithContext(ctx).Model(&model.SavedView{}).
+		Select("saved_views.*, users.name AS owner_name").
+		Joins("JOIN users ON users.id = saved_views.user_id").
+		Where(`saved_views.user_id = ? OR (saved_views.shared AND saved_views.user_id IN (
+			SELECT linked_user_id FROM account_links WHERE primary_user_id = ?
+			UNION
+			SELECT primary_user_id FROM account_links WHERE linked_user_id = ?))`, userID, userID, userID)
+}
+
+// List возвращает представления списка, доступные пользователю.
+// Свои представления идут первыми, представление по умолчанию - самым первым.
+func (r *ViewRepository) List(ctx context.Context, userID uuid.UUID, list string) ([]model.SavedView, error) {
+	var views []model.SavedView
+	query := r.visible(ctx, userID)
+	if list != "" {
+		query = query.Where("saved_views.list = ?", list)
+	}
+	err := query.
+		Order(clause.OrderBy{Expression: clause.Expr{
+			SQL:                "saved_views.user_id = ? DESC, saved_views.is_default DESC, saved_views.name ASC",
+			Vars:               []interface{}{userID},
+			WithoutParentheses: true,
+		}}).
+		Find(&views).Error
+	if err != nil {
+		return nil, err
+	}
+	for i := range views {
+		views[i].IsOwner = views[i].UserID == userID
+	}
+	return views, nil
+}
+
+// GetVisible возвращает представление, если оно доступно пользователю.
+func (r *ViewRepository) GetVisible(ctx context.Context, id, userID uuid.UUID) (*model.SavedView, error) {
+	var view model.SavedView
+	if err := r.visible(ctx, userID).Where("saved_views.id = ?", id).First(&view).Error; err != nil {
+		return nil, err
+	}
+	view.IsOwner = view.UserID == userID
+	return &view, nil
+}
+
+// Create сохраняет новое представление. Если оно помечено как представление по умолчанию,
+// флаг снимается с остальных представлений этого списка.
+func (r *ViewRepository) Create(ctx context.Context, view *model.SavedView) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if view.IsDefault {
+			if err := unsetDefaultViews(tx, view.UserID, view.List, uuid.Nil); err != nil {
+				return err
+			}
+		}
+		return tx.Create(view).Error
+	})
+}
+
+// Update изменяет представление владельца.
+func (r *ViewRepository) Update(ctx context.Context, view *model.SavedView) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if view.IsDefault {
+			if err := unsetDefaultViews(tx, view.UserID, view.List, view.ID); err != nil {
+				return err
+			}
+		}
+		result := tx.Model(&model.SavedView{}).
+			Where("id = ? AND user_id = ?", view.ID, view.UserID).
+			Select("list", "name", "filters", "sort_by", "sort_order", "columns", "is_default", "shared").
+			Updates(view)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+// Delete удаляет представление владельца.
+func (r *ViewRepository) Delete(ctx context.Context, id, userID uuid.UUID) error {
+	result := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).Delete(&model.SavedView{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// unsetDefaultViews снимает флаг "по умолчанию" с представлений списка, кроме exceptID.
+func unsetDefaultViews(tx *gorm.DB, userID uuid.UUID, list string, exceptID uuid.UUID) error {
+	return tx.Model(&model.SavedView{}).
+		Where("user_id = ? AND list = ? AND is_default AND id <> ?", userID, list, exceptID).
+		Update("is_default", false).Error
+}
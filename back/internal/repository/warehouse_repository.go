@@ -0,0 +1,803 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/lamoda-seller-app/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrInsufficientStock  = errors.New("insufficient stock")
+	ErrVariantNotFound    = errors.New("variant not found")
+	ErrSameWarehouse      = errors.New("source and destination warehouses must differ")
+	ErrWarehouseInUse     = errors.New("warehouse still holds stock or reservations")
+	ErrNoDefaultWarehouse = errors.New("no default warehouse to apply stock changes to")
+
+	ErrDefaultWarehouseRequired = errors.New("default warehouse cannot be deleted or unset: make another warehouse the default first")
+	ErrDefaultWarehouseType     = errors.New("default warehouse must be an active own or FBO warehouse")
+)
+
+// WarehouseRepository хранит склады продавца, остатки по складам и движения остатков.
+// Остаток и резерв варианта (ProductVariant.Stock/Reserved) и общий остаток товара
+// пересчитываются триггерами БД как суммы по складам (см. миграцию 15).
+type WarehouseRepository struct {
+	db *gorm.DB
+}
+
+func NewWarehouseRepository(db *gorm.DB) *WarehouseRepository {
+	return &WarehouseRepository{db: db}
+}
+
+// --- Склады ---
+
+// List возвращает склады продавца с суммарными остатками.
+func (r *WarehouseRepository) List(ctx context.Context, userID uuid.UUID) ([]model.Warehouse, error) {
+	var warehouses []model.Warehouse
+	err := r.withTotals(ctx).
+		Where("warehouses.user_id = ?", userID).
+		Order("warehouses.priority, warehouses.name").
+		Find(&warehouses).Error
+	return warehouses, err
+}
+
+// GetByID возвращает склад, если он принадлежит продавцу.
+func (r *WarehouseRepository) GetByID(ctx context.Context, id, userID uuid.UUID) (*model.Warehouse, error) {
+	var warehouse model.Warehouse
+	err := r.withTotals(ctx).
+		Where("warehouses.id = ? AND warehouses.user_id = ?", id, userID).
+		First(&warehouse).Error
+	return &warehouse, err
+}
+
+func (r *WarehouseRepository) withTotals(ctx context.Context) *gorm.DB {
+	return r.db.WithContext(ctx).Model(&model.Warehouse{}).
+		Select(`warehouses.*,
+			(SELECT COALESCE(SUM(ws.stock), 0) FROM warehouse_stocks ws WHERE ws.warehouse_id = warehouses.id) AS stock_units,
+			(SELECT COALESCE(SUM(ws.reserved), 0) FROM warehouse_stocks ws WHERE ws.warehouse_id = warehouses.id) AS reserved_units`)
+}
+
+// Create создает склад. Первый склад продавца становится складом по умолчанию,
+// и на него переносятся остатки вариантов, которые еще не разнесены по складам.
+// Склад по умолчанию должен быть активным собственным складом или складом FBO (ErrDefaultWarehouseType).
+func (r *WarehouseRepository) Create(ctx context.Context, warehouse *model.Warehouse) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&model.Warehouse{}).Where("user_id = ?", warehouse.UserID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			warehouse.IsDefault = true
+		}
+		if warehouse.IsDefault && !warehouse.Reservable() {
+			return ErrDefaultWarehouseType
+		}
+		if warehouse.IsDefault {
+			if err := unsetDefaultWarehouse(tx, warehouse.UserID, uuid.Nil); err != nil {
+				return err
+			}
+		}
+		if err := tx.Create(warehouse).Error; err != nil {
+			return err
+		}
+		if warehouse.IsDefault {
+			return adoptUnassignedStock(tx, warehouse.UserID, warehouse.ID, nil)
+		}
+		return nil
+	})
+}
+
+// Update изменяет склад продавца. Склад по умолчанию нельзя сделать обычным (ErrDefaultWarehouseRequired):
+// вместо этого складом по умолчанию назначается другой склад, и с прежнего отметка снимается в той же транзакции.
+// Склад по умолчанию должен оставаться активным собственным складом или складом FBO (ErrDefaultWarehouseType).
+func (r *WarehouseRepository) Update(ctx context.Context, warehouse *model.Warehouse) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var current model.Warehouse
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND user_id = ?", warehouse.ID, warehouse.UserID).
+			First(&current).Error
+		if err != nil {
+			return err
+		}
+		if current.IsDefault && !warehouse.IsDefault {
+			return ErrDefaultWarehouseRequired
+		}
+		if warehouse.IsDefault && !warehouse.Reservable() {
+			return ErrDefaultWarehouseType
+		}
+
+		if warehouse.IsDefault {
+			if err := unsetDefaultWarehouse(tx, warehouse.UserID, warehouse.ID); err != nil {
+				return err
+			}
+		}
+		res := tx.Model(&model.Warehouse{}).
+			Where("id = ? AND user_id = ?", warehouse.ID, warehouse.UserID).
+			Select("name", "code", "type", "address", "priority", "is_default", "is_active").
+			Updates(warehouse)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+// Delete удаляет пустой склад продавца. Склад с остатком или резервами удалить нельзя -
+// сначала нужно переместить товары. Склад по умолчанию можно удалить, только если он последний:
+// иначе сначала назначается другой склад по умолчанию (ErrDefaultWarehouseRequired).
+func (r *WarehouseRepository) Delete(ctx context.Context, id, userID uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var warehouse model.Warehouse
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND user_id = ?", id, userID).First(&warehouse).Error
+		if err != nil {
+			return err
+		}
+
+		if warehouse.IsDefault {
+			var others int64
+			if err := tx.Model(&model.Warehouse{}).Where("user_id = ? AND id <> ?", userID, id).Count(&others).Error; err != nil {
+				return err
+			}
+			if others > 0 {
+				return ErrDefaultWarehouseRequired
+			}
+		}
+
+		var busy int64
+		err = tx.Model(&model.WarehouseStock{}).
+			Where("warehouse_id = ? AND (stock > 0 OR reserved > 0)", id).
+			Count(&busy).Error
+		if err != nil {
+			return err
+		}
+		if busy > 0 {
+			return ErrWarehouseInUse
+		}
+
+		if err := tx.Where("warehouse_id = ?", id).Delete(&model.WarehouseStock{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&warehouse).Error
+	})
+}
+
+func unsetDefaultWarehouse(tx *gorm.DB, userID, exceptID uuid.UUID) error {
+	return tx.Model(&model.Warehouse{}).
+		Where("user_id = ? AND is_default AND id <> ?", userID, exceptID).
+		Update("is_default", false).Error
+}
+
+// --- Остатки по складам ---
+
+// ListStockParams - параметры списка остатков
+type ListStockParams struct {
+	UserID      uuid.UUID
+	WarehouseID *uuid.UUID
+	VariantID   *uuid.UUID
+	ProductID   *uuid.UUID
+	Limit       int
+	Offset      int
+}
+
+// ListStock возвращает остатки вариантов продавца по складам.
+func (r *WarehouseRepository) ListStock(ctx context.Context, params ListStockParams) ([]model.WarehouseStock, int64, error) {
+	query := r.db.WithContext(ctx).Model(&model.WarehouseStock{}).
+		Joins("JOIN warehouses w ON w.id = warehouse_stocks.warehouse_id").
+		Joins("JOIN product_variants v ON v.id = warehouse_stocks.variant_id").
+		Joins("JOIN products p ON p.id = v.product_id").
+		Where("w.user_id = ?", params.UserID)
+	if params.WarehouseID != nil {
+		query = query.Where("warehouse_stocks.warehouse_id = ?", *params.WarehouseID)
+	}
+	if params.VariantID != nil {
+		query = query.Where("warehouse_stocks.variant_id = ?", *params.VariantID)
+	}
+	if params.ProductID != nil {
+		query = query.Where("v.product_id = ?", *params.ProductID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var stocks []model.WarehouseStock
+	err := query.
+		Select("warehouse_stocks.*, w.name AS warehouse_name, p.id AS product_id, p.name AS product_name, v.sku, v.size, v.color").
+		Order("p.name, v.sku, w.priority, w.name").
+		Limit(params.Limit).Offset(params.Offset).
+		Find(&stocks).Error
+	return stocks, total, err
+}
+
+// Adjust корректирует остаток варианта на складе: задает новое значение или изменяет на delta.
+// Остаток не может стать меньше зарезервированного количества.
+func (r *WarehouseRepository) Adjust(ctx context.Context, userID uuid.UUID, actor *uuid.UUID, warehouseID uuid.UUID, req model.AdjustStockRequest) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkWarehouseOwner(tx, userID, warehouseID); err != nil {
+			return err
+		}
+		if err := checkVariantOwner(tx, userID, req.VariantID); err != nil {
+			return err
+		}
+
+		delta := 0
+		if req.Delta != nil {
+			delta = *req.Delta
+		}
+		if req.Stock != nil {
+			current, err := lockWarehouseStock(tx, warehouseID, req.VariantID)
+			if err != nil {
+				return err
+			}
+			delta = *req.Stock - current.Stock
+		}
+		if delta == 0 {
+			return nil
+		}
+		return changeWarehouseStock(tx, userID, actor, warehouseID, req.VariantID, delta, model.StockMovementAdjustment, nil, req.Comment)
+	})
+}
+
+// Transfer перемещает остатки вариантов между складами продавца и записывает движения.
+// Перемещать можно только свободный (незарезервированный) остаток.
+func (r *WarehouseRepository) Transfer(ctx context.Context, userID uuid.UUID, actor *uuid.UUID, req model.TransferStockRequest) ([]model.StockMovement, error) {
+	if req.FromWarehouseID == req.ToWarehouseID {
+		return nil, ErrSameWarehouse
+	}
+
+	var movements []model.StockMovement
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, id := range []uuid.UUID{req.FromWarehouseID, req.ToWarehouseID} {
+			if err := checkWarehouseOwner(tx, userID, id); err != nil {
+				return err
+			}
+		}
+
+		for _, item := range req.Items {
+			if err := checkVariantOwner(tx, userID, item.VariantID); err != nil {
+				return err
+			}
+			if err := moveStock(tx, req.FromWarehouseID, item.VariantID, -item.Quantity); err != nil {
+				return err
+			}
+			if err := moveStock(tx, req.ToWarehouseID, item.VariantID, item.Quantity); err != nil {
+				return err
+			}
+
+			from, to := req.FromWarehouseID, req.ToWarehouseID
+			movement := model.StockMovement{
+				ID:              uuid.New(),
+				UserID:          userID,
+				VariantID:       item.VariantID,
+				FromWarehouseID: &from,
+				ToWarehouseID:   &to,
+				Quantity:        item.Quantity,
+				Type:            model.StockMovementTransfer,
+				Comment:         req.Comment,
+				CreatedBy:       actor,
+			}
+			if err := tx.Create(&movement).Error; err != nil {
+				return err
+			}
+			movements = append(movements, movement)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return movements, nil
+}
+
+// ListMovementsParams - параметры журнала движений
+type ListMovementsParams struct {
+	UserID      uuid.UUID
+	WarehouseID *uuid.UUID
+	VariantID   *uuid.UUID
+	Type        string
+	Limit       int
+	Offset      int
+}
+
+// ListMovements возвращает движения остатков продавца от новых к старым.
+func (r *WarehouseRepository) ListMovements(ctx context.Context, params ListMovementsParams) ([]model.StockMovement, int64, error) {
+	query := r.db.WithContext(ctx).Model(&model.StockMovement{}).Where("stock_movements.user_id = ?", params.UserID)
+	if params.WarehouseID != nil {
+		query = query.Where("(stock_movements.from_warehouse_id = ? OR stock_movements.to_warehouse_id = ?)", *params.WarehouseID, *params.WarehouseID)
+	}
+	if params.VariantID != nil {
+		query = query.Where("stock_movements.variant_id = ?", *params.VariantID)
+	}
+	if params.Type != "" {
+		query = query.Where("stock_movements.type = ?", params.Type)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var movements []model.StockMovement
+	err := query.
+		Select("stock_movements.*, v.sku, wf.name AS from_warehouse_name, wt.name AS to_warehouse_name").
+		Joins("JOIN product_variants v ON v.id = stock_movements.variant_id").
+		Joins("LEFT JOIN warehouses wf ON wf.id = stock_movements.from_warehouse_id").
+		Joins("LEFT JOIN warehouses wt ON wt.id = stock_movements.to_warehouse_id").
+		Order("stock_movements.created_at DESC, stock_movements.id").
+		Limit(params.Limit).Offset(params.Offset).
+		Find(&movements).Error
+	return movements, total, err
+}
+
+// --- Вспомогательные функции, работающие внутри транзакции ---
+
+func checkWarehouseOwner(tx *gorm.DB, userID, warehouseID uuid.UUID) error {
+	var count int64
+	if err := tx.Model(&model.Warehouse{}).Where("id = ? AND user_id = ?", warehouseID, userID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func checkVariantOwner(tx *gorm.DB, userID, variantID uuid.UUID) error {
+	var count int64
+	err := tx.Model(&model.ProductVariant{}).
+		Joins("JOIN products p ON p.id = product_variants.product_id").
+		Where("product_variants.id = ? AND p.user_id = ?", variantID, userID).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrVariantNotFound
+	}
+	return nil
+}
+
+// lockWarehouseStock блокирует строку остатка варианта на складе, создавая ее при необходимости.
+func lockWarehouseStock(tx *gorm.DB, warehouseID, variantID uuid.UUID) (*model.WarehouseStock, error) {
+	row := model.WarehouseStock{WarehouseID: warehouseID, VariantID: variantID}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&row).Error; err != nil {
+		return nil, err
+	}
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("warehouse_id = ? AND variant_id = ?", warehouseID, variantID).
+		First(&row).Error
+	return &row, err
+}
+
+// moveStock изменяет остаток варианта на складе на delta без записи движения.
+// Уменьшать можно только свободный остаток.
+func moveStock(tx *gorm.DB, warehouseID, variantID uuid.UUID, delta int) error {
+	row, err := lockWarehouseStock(tx, warehouseID, variantID)
+	if err != nil {
+		return err
+	}
+	if row.Stock+delta < row.Reserved {
+		return ErrInsufficientStock
+	}
+	return tx.Model(&model.WarehouseStock{}).
+		Where("warehouse_id = ? AND variant_id = ?", warehouseID, variantID).
+		Update("stock", row.Stock+delta).Error
+}
+
+// changeWarehouseStock изменяет остаток на складе и записывает движение типа movementType.
+// Положительный delta - поступление на склад, отрицательный - списание.
+func changeWarehouseStock(tx *gorm.DB, userID uuid.UUID, actor *uuid.UUID, warehouseID, variantID uuid.UUID, delta int, movementType string, orderID *uuid.UUID, comment string) error {
+	if err := moveStock(tx, warehouseID, variantID, delta); err != nil {
+		return err
+	}
+
+	movement := model.StockMovement{
+		ID:        uuid.New(),
+		UserID:    userID,
+		VariantID: variantID,
+		Quantity:  delta,
+		Type:      movementType,
+		OrderID:   orderID,
+		Comment:   comment,
+		CreatedBy: actor,
+	}
+	if delta > 0 {
+		movement.ToWarehouseID = &warehouseID
+	} else {
+		movement.FromWarehouseID = &warehouseID
+		movement.Quantity = -delta
+	}
+	return tx.Create(&movement).Error
+}
+
+// defaultWarehouseID возвращает склад продавца по умолчанию или nil, если складов нет.
+func defaultWarehouseID(tx *gorm.DB, userID uuid.UUID) (*uuid.UUID, error) {
+	var ids []uuid.UUID
+	if err := tx.Model(&model.Warehouse{}).Where("user_id = ? AND is_default", userID).Limit(1).Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	return &ids[0], nil
+}
+
+// adoptUnassignedStock переносит на склад остатки вариантов продавца, которые еще не разнесены по складам.
+// Если productID задан, обрабатываются только варианты этого товара.
+func adoptUnassignedStock(tx *gorm.DB, userID, warehouseID uuid.UUID, productID *uuid.UUID) error {
+	query := `
+		WITH adopted AS (
+			INSERT INTO warehouse_stocks (warehouse_id, variant_id, stock, reserved)
+			SELECT ?, v.id, GREATEST(v.stock, 0), GREATEST(v.reserved, 0)
+			FROM product_variants v
+			JOIN products p ON p.id = v.product_id
+			WHERE p.user_id = ? AND (CAST(? AS uuid) IS NULL OR p.id = ?)
+				AND NOT EXISTS (SELECT 1 FROM warehouse_stocks ws WHERE ws.variant_id = v.id)
+			RETURNING variant_id, stock
+		)
+		INSERT INTO stock_movements (user_id, variant_id, to_warehouse_id, quantity, type, comment)
+		SELECT ?, variant_id, ?, stock, 'initial', 'Начальный остаток'
+		FROM adopted WHERE stock > 0`
+	// Резервы этих вариантов переносятся на тот же склад
+	err := tx.Exec(query, warehouseID, userID, productID, productID, userID, warehouseID).Error
+	if err != nil {
+		return err
+	}
+	return tx.Exec(`
+		UPDATE stock_reservations r SET warehouse_id = ?
+		WHERE r.warehouse_id IS NULL AND EXISTS (
+			SELECT 1 FROM warehouse_stocks ws WHERE ws.variant_id = r.variant_id AND ws.warehouse_id = ?)`,
+		warehouseID, warehouseID).Error
+}
+
+// syncCardStock переносит остатки, измененные в карточке товара, на склад по умолчанию.
+// Вызывается перед сохранением карточки: для вариантов, разнесенных по складам, разница между
+// новым и текущим остатком записывается корректировкой. Новые варианты переносятся на склад
+// после сохранения (см. adoptUnassignedStock).
+func syncCardStock(tx *gorm.DB, product *model.Product) error {
+	if product.UserID == nil {
+		return nil
+	}
+
+	type current struct {
+		ID      uuid.UUID
+		Stock   int
+		Managed bool
+	}
+	var rows []current
+	err := tx.Model(&model.ProductVariant{}).
+		Select("id, stock, EXISTS (SELECT 1 FROM warehouse_stocks ws WHERE ws.variant_id = product_variants.id) AS managed").
+		Where("product_id = ?", product.ID).
+		Scan(&rows).Error
+	if err != nil {
+		return err
+	}
+	byID := make(map[uuid.UUID]current, len(rows))
+	for _, row := range rows {
+		byID[row.ID] = row
+	}
+
+	var warehouseID *uuid.UUID
+	for _, v := range product.Variants {
+		cur, ok := byID[v.ID]
+		if !ok || !cur.Managed || cur.Stock == v.Stock {
+			continue
+		}
+		if warehouseID == nil {
+			if warehouseID, err = defaultWarehouseID(tx, *product.UserID); err != nil {
+				return err
+			}
+			if warehouseID == nil {
+				return ErrNoDefaultWarehouse
+			}
+		}
+		err := changeWarehouseStock(tx, *product.UserID, product.UserID, *warehouseID, v.ID, v.Stock-cur.Stock,
+			model.StockMovementAdjustment, nil, "Изменение остатка в карточке товара")
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// adoptCardStock переносит на склад по умолчанию остатки новых вариантов товара.
+func adoptCardStock(tx *gorm.DB, product *model.Product) error {
+	if product.UserID == nil {
+		return nil
+	}
+	warehouseID, err := defaultWarehouseID(tx, *product.UserID)
+	if err != nil || warehouseID == nil {
+		return err
+	}
+	return adoptUnassignedStock(tx, *product.UserID, *warehouseID, &product.ID)
+}
+
+// --- Резервирование заказов ---
+
+// reservationCandidate - остаток варианта на складе, доступный для резервирования
+type reservationCandidate struct {
+	WarehouseID uuid.UUID
+	Priority    int
+	Name        string
+	Stock       int
+	Reserved    int
+}
+
+func (c reservationCandidate) available() int { return c.Stock - c.Reserved }
+
+// allocateReservation распределяет quantity по складам согласно правилу.
+// Возвращает количество по складам или false, если свободного остатка не хватает.
+func allocateReservation(candidates []reservationCandidate, quantity int, rule string) (map[uuid.UUID]int, bool) {
+	ordered := append([]reservationCandidate(nil), candidates...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		if ordered[i].Priority != ordered[j].Priority {
+			return ordered[i].Priority < ordered[j].Priority
+		}
+		return ordered[i].Name < ordered[j].Name
+	})
+
+	switch rule {
+	case model.ReservationRuleMostAvailable:
+		sort.SliceStable(ordered, func(i, j int) bool { return ordered[i].available() > ordered[j].available() })
+	case model.ReservationRuleSingleWarehouse:
+		for _, c := range ordered {
+			if c.available() >= quantity {
+				return map[uuid.UUID]int{c.WarehouseID: quantity}, true
+			}
+		}
+	}
+
+	allocation := make(map[uuid.UUID]int)
+	left := quantity
+	for _, c := range ordered {
+		if left == 0 {
+			break
+		}
+		take := min(c.available(), left)
+		if take <= 0 {
+			continue
+		}
+		allocation[c.WarehouseID] = take
+		left -= take
+	}
+	return allocation, left == 0
+}
+
+// reserveOrder резервирует позиции заказа, для которых еще нет резерва.
+// Склады выбираются по правилу продавца (users.reservation_rule); транзитные и неактивные склады не используются.
+func reserveOrder(tx *gorm.DB, order *model.Order) error {
+	var rule string
+	if err := tx.Model(&model.User{}).Where("id = ?", order.UserID).Pluck("reservation_rule", &rule).Error; err != nil {
+		return err
+	}
+
+	var reservedItems []uuid.UUID
+	if err := tx.Model(&model.StockReservation{}).Where("order_id = ?", order.ID).Distinct().Pluck("order_item_id", &reservedItems).Error; err != nil {
+		return err
+	}
+	reserved := make(map[uuid.UUID]bool, len(reservedItems))
+	for _, id := range reservedItems {
+		reserved[id] = true
+	}
+
+	for _, item := range order.Items {
+		// Единицы, уже отгруженные отдельными посылками, не резервируются повторно
+		item.Quantity -= item.ShippedQuantity
+		if reserved[item.ID] || item.Quantity <= 0 {
+			continue
+		}
+		if err := reserveItem(tx, order, item, rule); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func reserveItem(tx *gorm.DB, order *model.Order, item model.OrderItem, rule string) error {
+	var managed int64
+	if err := tx.Model(&model.WarehouseStock{}).Where("variant_id = ?", item.VariantID).Count(&managed).Error; err != nil {
+		return err
+	}
+
+	newReservation := func(warehouseID *uuid.UUID, quantity int) error {
+		return tx.Create(&model.StockReservation{
+			ID:          uuid.New(),
+			OrderID:     order.ID,
+			OrderItemID: item.ID,
+			VariantID:   item.VariantID,
+			WarehouseID: warehouseID,
+			Quantity:    quantity,
+		}).Error
+	}
+
+	if managed == 0 {
+		// Остаток варианта еще не разнесен по складам - резервируем на самом варианте
+		res := tx.Model(&model.ProductVariant{}).
+			Where("id = ? AND stock - reserved >= ?", item.VariantID, item.Quantity).
+			Update("reserved", gorm.Expr("reserved + ?", item.Quantity))
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrInsufficientStock
+		}
+		return newReservation(nil, item.Quantity)
+	}
+
+	var candidates []reservationCandidate
+	err := tx.Raw(`
+		SELECT ws.warehouse_id, w.priority, w.name, ws.stock, ws.reserved
+		FROM warehouse_stocks ws
+		JOIN warehouses w ON w.id = ws.warehouse_id
+		WHERE ws.variant_id = ? AND w.user_id = ? AND w.is_active AND w.type <> ?
+		FOR UPDATE OF ws`, item.VariantID, order.UserID, model.WarehouseTypeTransit).
+		Scan(&candidates).Error
+	if err != nil {
+		return err
+	}
+
+	allocation, ok := allocateReservation(candidates, item.Quantity, rule)
+	if !ok {
+		return ErrInsufficientStock
+	}
+	for warehouseID, quantity := range allocation {
+		err := tx.Model(&model.WarehouseStock{}).
+			Where("warehouse_id = ? AND variant_id = ?", warehouseID, item.VariantID).
+			Update("reserved", gorm.Expr("reserved + ?", quantity)).Error
+		if err != nil {
+			return err
+		}
+		id := warehouseID
+		if err := newReservation(&id, quantity); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// releaseOrder снимает все резервы заказа.
+func releaseOrder(tx *gorm.DB, orderID uuid.UUID) error {
+	return consumeReservations(tx, orderID, func(r model.StockReservation) error {
+		return unreserve(tx, r, r.Quantity)
+	})
+}
+
+// releaseOrderItem снимает резерв с quantity единиц позиции заказа при ее частичной отмене.
+func releaseOrderItem(tx *gorm.DB, itemID uuid.UUID, quantity int) error {
+	return takeItemReservations(tx, itemID, quantity, nil, func(r model.StockReservation, n int) error {
+		return unreserve(tx, r, n)
+	})
+}
+
+// takeItemReservations вызывает apply для резервов позиции заказа, пока не наберется quantity единиц,
+// и уменьшает или удаляет использованные резервы. Сначала берутся резервы на складе warehouseID
+// (если он задан), затем последние созданные.
+func takeItemReservations(tx *gorm.DB, itemID uuid.UUID, quantity int, warehouseID *uuid.UUID, apply func(r model.StockReservation, n int) error) error {
+	query := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("order_item_id = ?", itemID)
+	if warehouseID != nil {
+		query = query.Order(clause.OrderBy{Expression: clause.Expr{SQL: "warehouse_id = ? DESC NULLS LAST", Vars: []interface{}{*warehouseID}}})
+	}
+	var reservations []model.StockReservation
+	if err := query.Order("created_at DESC").Find(&reservations).Error; err != nil {
+		return err
+	}
+	for _, r := range reservations {
+		if quantity <= 0 {
+			break
+		}
+		n := min(quantity, r.Quantity)
+		if err := apply(r, n); err != nil {
+			return err
+		}
+		var err error
+		if n == r.Quantity {
+			err = tx.Delete(&model.StockReservation{}, "id = ?", r.ID).Error
+		} else {
+			err = tx.Model(&model.StockReservation{}).Where("id = ?", r.ID).Update("quantity", r.Quantity-n).Error
+		}
+		if err != nil {
+			return err
+		}
+		quantity -= n
+	}
+	return nil
+}
+
+// unreserve уменьшает резерв варианта или складского остатка, на котором лежит резерв r.
+func unreserve(tx *gorm.DB, r model.StockReservation, quantity int) error {
+	if r.WarehouseID == nil {
+		return tx.Model(&model.ProductVariant{}).Where("id = ?", r.VariantID).
+			Update("reserved", gorm.Expr("GREATEST(reserved - ?, 0)", quantity)).Error
+	}
+	return tx.Model(&model.WarehouseStock{}).
+		Where("warehouse_id = ? AND variant_id = ?", *r.WarehouseID, r.VariantID).
+		Update("reserved", gorm.Expr("GREATEST(reserved - ?, 0)", quantity)).Error
+}
+
+// shipOrder списывает зарезервированный товар со складов при отгрузке заказа.
+// Позиции без резерва сначала резервируются.
+func shipOrder(tx *gorm.DB, order *model.Order) error {
+	if err := reserveOrder(tx, order); err != nil {
+		return err
+	}
+	return consumeReservations(tx, order.ID, func(r model.StockReservation) error {
+		return shipReservation(tx, order, r, r.Quantity)
+	})
+}
+
+// shipOrderItems списывает со складов часть заказа, уходящую одной посылкой: quantities - единиц по ID позиции.
+// Резервы на складе отгрузки warehouseID используются в первую очередь.
+func shipOrderItems(tx *gorm.DB, order *model.Order, quantities map[uuid.UUID]int, warehouseID *uuid.UUID) error {
+	if err := reserveOrder(tx, order); err != nil {
+		return err
+	}
+	for _, item := range order.Items {
+		if quantities[item.ID] <= 0 {
+			continue
+		}
+		err := takeItemReservations(tx, item.ID, quantities[item.ID], warehouseID, func(r model.StockReservation, n int) error {
+			return shipReservation(tx, order, r, n)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// shipReservation списывает quantity единиц из резерва r: снимает резерв и уменьшает остаток.
+func shipReservation(tx *gorm.DB, order *model.Order, r model.StockReservation, quantity int) error {
+	if r.WarehouseID == nil {
+		return tx.Model(&model.ProductVariant{}).Where("id = ?", r.VariantID).Updates(map[string]interface{}{
+			"stock":    gorm.Expr("GREATEST(stock - ?, 0)", quantity),
+			"reserved": gorm.Expr("GREATEST(reserved - ?, 0)", quantity),
+		}).Error
+	}
+	if err := unreserve(tx, r, quantity); err != nil {
+		return err
+	}
+	orderID := order.ID
+	return changeWarehouseStock(tx, order.UserID, nil, *r.WarehouseID, r.VariantID, -quantity,
+		model.StockMovementShipment, &orderID, "Отгрузка заказа "+order.OrderNumber)
+}
+
+// consumeReservations вызывает apply для каждого резерва заказа и удаляет резервы.
+func consumeReservations(tx *gorm.DB, orderID uuid.UUID, apply func(model.StockReservation) error) error {
+	var reservations []model.StockReservation
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("order_id = ?", orderID).Find(&reservations).Error
+	if err != nil {
+		return err
+	}
+	for _, r := range reservations {
+		if err := apply(r); err != nil {
+			return err
+		}
+	}
+	if len(reservations) == 0 {
+		return nil
+	}
+	return tx.Where("order_id = ?", orderID).Delete(&model.StockReservation{}).Error
+}
+
+// applyOrderStock резервирует, списывает или освобождает остатки при смене статуса заказа:
+// confirmed - резерв, in_transit - отгрузка со складов, cancelled - снятие резерва.
+func applyOrderStock(tx *gorm.DB, order *model.Order, status string) error {
+	switch status {
+	case model.OrderStatusConfirmed:
+		return reserveOrder(tx, order)
+	case model.OrderStatusInTransit:
+		return shipOrder(tx, order)
+	case model.OrderStatusCancelled:
+		return releaseOrder(tx, order.ID)
+	}
+	return nil
+}
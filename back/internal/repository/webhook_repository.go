@@ -0,0 +1,217 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lamoda-seller-app/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrDeliveryInProgress = errors.New("webhook delivery is still pending")
+
+// WebhookRepository хранит адреса вебхуков продавцов, исходящую очередь событий и журнал доставок.
+type WebhookRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhookRepository(db *gorm.DB) *WebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+// ListEndpoints возвращает адреса продавца.
+func (r *WebhookRepository) ListEndpoints(ctx context.Context, userID uuid.UUID) ([]model.WebhookEndpoint, error) {
+	var endpoints []model.WebhookEndpoint
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at").Find(&endpoints).Error
+	return endpoints, err
+}
+
+// GetEndpoint возвращает адрес продавца.
+func (r *WebhookRepository) GetEndpoint(ctx context.Context, userID, id uuid.UUID) (*model.WebhookEndpoint, error) {
+	var endpoint model.WebhookEndpoint
+	if err := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).First(&endpoint).Error; err != nil {
+		return nil, err
+	}
+	return &endpoint, nil
+}
+
+func (r *WebhookRepository) CreateEndpoint(ctx context.Context, endpoint *model.WebhookEndpoint) error {
+	return r.db.WithContext(ctx).Create(endpoint).Error
+}
+
+// UpdateEndpoint сохраняет изменения адреса продавца.
+func (r *WebhookRepository) UpdateEndpoint(ctx context.Context, endpoint *model.WebhookEndpoint) error {
+	result := r.db.WithContext(ctx).Model(&model.WebhookEndpoint{}).
+		Where("id = ? AND user_id = ?", endpoint.ID, endpoint.UserID).
+		Select("url", "secret", "event_types", "description", "is_active").
+		Updates(endpoint)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// DeleteEndpoint удаляет адрес продавца вместе с журналом его доставок.
+func (r *WebhookRepository) DeleteEndpoint(ctx context.Context, userID, id uuid.UUID) error {
+	result := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).Delete(&model.WebhookEndpoint{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// FanOut разбирает порцию неразосланных событий очереди: для каждого создает доставки
+// на активные адреса продавца, подписанные на его тип, и отмечает событие разосланным.
+// События без подписчиков тоже отмечаются, чтобы не копиться в очереди.
+// Возвращает количество созданных доставок.
+func (r *WebhookRepository) FanOut(ctx context.Context, limit int) (int64, error) {
+	var created int64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var ids []uuid.UUID
+		err := tx.Model(&model.WebhookEvent{}).
+			Where("dispatched_at IS NULL").
+			Order("created_at").
+			Limit(limit).
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Pluck("id", &ids).Error
+		if err != nil || len(ids) == 0 {
+			return err
+		}
+
+		result := tx.Exec(`
+			INSERT INTO webhook_deliveries (user_id, endpoint_id, event_id, next_attempt_at)
+			SELECT e.user_id, w.id, e.id, NOW()
+			FROM webhook_events e
+			JOIN webhook_endpoints w ON w.user_id = e.user_id AND w.is_active
+				AND (cardinality(w.event_types) = 0 OR e.type = ANY(w.event_types))
+			WHERE e.id IN ?
+			ON CONFLICT (endpoint_id, event_id) DO NOTHING`, ids)
+		if result.Error != nil {
+			return result.Error
+		}
+		created = result.RowsAffected
+
+		return tx.Model(&model.WebhookEvent{}).Where("id IN ?", ids).Update("dispatched_at", time.Now()).Error
+	})
+	return created, err
+}
+
+// PurgeEvents удаляет события, разосланные раньше before, вместе с журналом их доставок.
+// События с доставками, которые еще ждут попытки, остаются до ее завершения.
+func (r *WebhookRepository) PurgeEvents(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Exec(`
+		DELETE FROM webhook_events e
+		WHERE e.dispatched_at < ?
+			AND NOT EXISTS (SELECT 1 FROM webhook_deliveries d WHERE d.event_id = e.id AND d.status = ?)`,
+		before, model.WebhookDeliveryPending)
+	return result.RowsAffected, result.Error
+}
+
+// ClaimDue выбирает доставки, время попытки которых наступило, и откладывает их на lease,
+// чтобы параллельный диспетчер их не взял. Если попытка не будет записана (сервер упал),
+// доставка повторится по истечении lease. Доставки на отключенные адреса ждут их включения.
+func (r *WebhookRepository) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]model.WebhookDelivery, error) {
+	var ids []uuid.UUID
+	err := r.db.WithContext(ctx).Raw(`
+		UPDATE webhook_deliveries SET next_attempt_at = NOW() + make_interval(secs => ?)
+		WHERE id IN (
+			SELECT d.id FROM webhook_deliveries d
+			JOIN webhook_endpoints w ON w.id = d.endpoint_id AND w.is_active
+			WHERE d.status = ? AND d.next_attempt_at <= NOW()
+			ORDER BY d.next_attempt_at
+			LIMIT ?
+			FOR UPDATE OF d SKIP LOCKED)
+		RETURNING id`, lease.Seconds(), model.WebhookDeliveryPending, limit).
+		Scan(&ids).Error
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+
+	var deliveries []model.WebhookDelivery
+	err = r.db.WithContext(ctx).
+		Preload("Event").
+		Preload("Endpoint").
+		Where("id IN ?", ids).
+		Order("next_attempt_at").
+		Find(&deliveries).Error
+	return deliveries, err
+}
+
+// RecordAttempt сохраняет результат попытки доставки: статус, ответ адреса и время следующей попытки.
+// Об исчерпании попыток продавец получает уведомление.
+func (r *WebhookRepository) RecordAttempt(ctx context.Context, delivery *model.WebhookDelivery) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&model.WebhookDelivery{}).
+			Where("id = ?", delivery.ID).
+			Select("status", "attempts", "next_attempt_at", "last_attempt_at", "response_status", "response_body", "error").
+			Updates(delivery).Error
+		if err != nil || delivery.Status != model.WebhookDeliveryFailed {
+			return err
+		}
+		return notify(tx, delivery.UserID, model.NotificationCategoryWebhooks, "webhook.failed",
+			fmt.Sprintf("Вебхук %s не доставлен", delivery.Event.Type),
+			fmt.Sprintf("%s: %d попыток, последняя ошибка: %s", delivery.Endpoint.URL, delivery.Attempts, delivery.Error),
+			"/profile",
+			model.NotificationData{"delivery_id": delivery.ID, "endpoint_id": delivery.EndpointID, "event_id": delivery.EventID})
+	})
+}
+
+// ListDeliveries возвращает журнал доставок продавца, новые первыми, вместе с событиями.
+func (r *WebhookRepository) ListDeliveries(ctx context.Context, userID uuid.UUID, filter model.WebhookDeliveryFilter, limit, offset int) ([]model.WebhookDelivery, int64, error) {
+	query := r.db.WithContext(ctx).Model(&model.WebhookDelivery{}).Where("webhook_deliveries.user_id = ?", userID)
+	if filter.EndpointID != nil {
+		query = query.Where("webhook_deliveries.endpoint_id = ?", *filter.EndpointID)
+	}
+	if filter.Status != "" {
+		query = query.Where("webhook_deliveries.status = ?", filter.Status)
+	}
+	if filter.EventType != "" {
+		query = query.Where("EXISTS (SELECT 1 FROM webhook_events e WHERE e.id = webhook_deliveries.event_id AND e.type = ?)", filter.EventType)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var deliveries []model.WebhookDelivery
+	err := query.Preload("Event").
+		Order("webhook_deliveries.created_at DESC, webhook_deliveries.id").
+		Limit(limit).Offset(offset).
+		Find(&deliveries).Error
+	return deliveries, total, err
+}
+
+// Redeliver ставит доставку в очередь на немедленную отправку с новым счетчиком попыток.
+// Доставку, которая еще ждет попытки, повторить нельзя - ErrDeliveryInProgress.
+func (r *WebhookRepository) Redeliver(ctx context.Context, userID, id uuid.UUID) (*model.WebhookDelivery, error) {
+	var delivery model.WebhookDelivery
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND user_id = ?", id, userID).
+			First(&delivery).Error
+		if err != nil {
+			return err
+		}
+		if delivery.Status == model.WebhookDeliveryPending {
+			return ErrDeliveryInProgress
+		}
+		delivery.Status = model.WebhookDeliveryPending
+		delivery.Attempts = 0
+		delivery.NextAttemptAt = time.Now()
+		return tx.Model(&delivery).Select("status", "attempts", "next_attempt_at").Updates(&delivery).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
 	"strings"
@@ -17,16 +18,26 @@ import (
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 
+	"github.com/lamoda-seller-app/internal/carrier"
 	"github.com/lamoda-seller-app/internal/config"
+	"github.com/lamoda-seller-app/internal/events"
 	"github.com/lamoda-seller-app/internal/handler"
+	"github.com/lamoda-seller-app/internal/jobs"
+	"github.com/lamoda-seller-app/internal/mailer"
+	"github.com/lamoda-seller-app/internal/marking"
 	"github.com/lamoda-seller-app/internal/middleware"
 	"github.com/lamoda-seller-app/internal/repository"
+	"github.com/lamoda-seller-app/internal/webhook"
 )
 
 type Server struct {
 	Engine *gin.Engine
 	DB     *gorm.DB
 	Config *config.Config
+	Jobs   *jobs.Runner
+	// Шина живых обновлений и слушатель уведомлений Postgres, который ее наполняет
+	Events        *events.Bus
+	EventListener *events.Listener
 }
 
 // Middleware для подробного логирования запросов
@@ -38,7 +49,7 @@ func requestLoggingMiddleware() gin.HandlerFunc {
 			param.Method,
 			param.StatusCode,
 			param.Latency,
-			param.Path,
+			redactQuery(param.Path),
 			param.Request.Proto,
 			param.Request.UserAgent(),
 			param.ErrorMessage,
@@ -46,6 +57,32 @@ func requestLoggingMiddleware() gin.HandlerFunc {
 	})
 }
 
+// redactedQueryParams - параметры запроса с учетными данными, которые не пишутся в журнал
+var redactedQueryParams = []string{"ticket", "access_token", "token"}
+
+// redactQuery скрывает в адресе запроса значения параметров с учетными данными.
+func redactQuery(path string) string {
+	base, rawQuery, ok := strings.Cut(path, "?")
+	if !ok {
+		return path
+	}
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return base + "?[unparsed]"
+	}
+	redacted := false
+	for _, name := range redactedQueryParams {
+		if query.Has(name) {
+			query.Set(name, "***")
+			redacted = true
+		}
+	}
+	if !redacted {
+		return path
+	}
+	return base + "?" + query.Encode()
+}
+
 func Init(cfg *config.Config) (*Server, error) {
 	log.Printf("🚀 Инициализация сервера...")
 	log.Printf("📊 Конфигурация: DB=%s:%s, Server=:%s", cfg.DBHost, cfg.DBPort, cfg.ServerPort)
@@ -97,12 +134,150 @@ func Init(cfg *config.Config) (*Server, error) {
 	orderRepo := repository.NewOrderRepository(db)
 	dashboardRepo := repository.NewDashboardRepository(db)
 	analyticsRepo := repository.NewAnalyticsRepository(db)
+	categoryRepo := repository.NewCategoryRepository(db)
+	supplierRepo := repository.NewSupplierRepository(db)
+	productHistoryRepo := repository.NewProductHistoryRepository(db)
+	priceRepo := repository.NewPriceRepository(db)
+	viewRepo := repository.NewViewRepository(db)
+	stockRepo := repository.NewStockRepository(db)
+	warehouseRepo := repository.NewWarehouseRepository(db)
+	markingRepo := repository.NewMarkingRepository(db)
+	labelRepo := repository.NewLabelRepository(db)
+	orderDocumentRepo := repository.NewOrderDocumentRepository(db)
+	shipmentRepo := repository.NewShipmentRepository(db)
+	webhookRepo := repository.NewWebhookRepository(db)
+	eventRepo := repository.NewEventRepository(db)
+	notificationRepo := repository.NewNotificationRepository(db)
+	customerRepo := repository.NewCustomerRepository(db)
 
 	userHandler := handler.NewUserHandler(userRepo)
-	productHandler := handler.NewProductHandler(productRepo)
+	trashRetention := time.Duration(cfg.ProductTrashRetentionDays) * 24 * time.Hour
+	productHandler := handler.NewProductHandler(productRepo, categoryRepo, productHistoryRepo, priceRepo, supplierRepo, trashRetention)
 	orderHandler := handler.NewOrderHandler(orderRepo)
 	dashboardHandler := handler.NewDashboardHandler(dashboardRepo)
 	analyticsHandler := handler.NewAnalyticsHandler(analyticsRepo)
+	categoryHandler := handler.NewCategoryHandler(categoryRepo)
+	supplierHandler := handler.NewSupplierHandler(supplierRepo)
+	priceHandler := handler.NewPriceHandler(priceRepo)
+	viewHandler := handler.NewViewHandler(viewRepo, productRepo, orderRepo)
+	stockHandler := handler.NewStockHandler(stockRepo)
+	warehouseHandler := handler.NewWarehouseHandler(warehouseRepo)
+	markingHandler := handler.NewMarkingHandler(markingRepo, marking.NewFilePortal(cfg.MarkingPortalDir))
+	labelHandler := handler.NewLabelHandler(labelRepo, orderRepo, markingRepo, userRepo)
+	orderDocumentHandler := handler.NewOrderDocumentHandler(orderDocumentRepo, orderRepo, userRepo)
+	carriers := carrier.NewRegistry()
+	if cfg.CarrierAPIURL != "" {
+		carriers.Register(cfg.CarrierName, carrier.NewHTTPAdapter(cfg.CarrierAPIURL, cfg.CarrierAPIKey, 15*time.Second, carrier.ParseStatusMap(cfg.CarrierStatusMap)))
+		log.Printf("🚚 Подключен перевозчик %s: %s", cfg.CarrierName, cfg.CarrierAPIURL)
+	}
+	shipmentHandler := handler.NewShipmentHandler(shipmentRepo, orderRepo, carriers)
+	webhookHandler := handler.NewWebhookHandler(webhookRepo)
+	eventBus := events.NewBus()
+	eventListener := events.NewListener(dsn, eventBus, eventRepo)
+	eventStreamHandler := handler.NewEventStreamHandler(eventBus, eventRepo, dashboardRepo)
+	notificationHandler := handler.NewNotificationHandler(notificationRepo)
+	customerHandler := handler.NewCustomerHandler(customerRepo, orderRepo)
+
+	log.Printf("⏱️ Регистрация фоновых задач...")
+	jobRunner := jobs.NewRunner()
+	jobRunner.Add(jobs.Job{
+		Name:     "purge-deleted-products",
+		Interval: time.Hour,
+		Run: func(ctx context.Context) error {
+			purged, err := productRepo.PurgeDeleted(ctx, time.Now().Add(-trashRetention))
+			if purged > 0 {
+				log.Printf("🗑️ Окончательно удалено товаров из корзины: %d", purged)
+			}
+			return err
+		},
+	})
+	jobRunner.Add(jobs.Job{
+		Name:     "refresh-product-sales",
+		Interval: time.Duration(cfg.SalesRollupIntervalMinutes) * time.Minute,
+		Run: func(ctx context.Context) error {
+			_, err := productRepo.RefreshSalesRollup(ctx)
+			return err
+		},
+	})
+	jobRunner.Add(jobs.Job{
+		Name:     "apply-price-schedules",
+		Interval: time.Duration(cfg.PriceSchedulerIntervalSeconds) * time.Second,
+		Run: func(ctx context.Context) error {
+			applied, completed, err := priceRepo.ApplyDue(ctx, time.Now())
+			if applied > 0 || completed > 0 {
+				log.Printf("💸 Изменения цен: применено %d, завершено %d", applied, completed)
+			}
+			return err
+		},
+	})
+	jobRunner.Add(jobs.Job{
+		Name:     "evaluate-stock-alerts",
+		Interval: time.Duration(cfg.StockAlertsIntervalMinutes) * time.Minute,
+		Run: func(ctx context.Context) error {
+			opened, resolved, err := stockRepo.EvaluateAlerts(ctx)
+			if len(opened) > 0 || resolved > 0 {
+				log.Printf("📉 Оповещения об остатках: открыто %d, закрыто %d", len(opened), resolved)
+			}
+			return err
+		},
+	})
+
+	carrierPollInterval := time.Duration(cfg.CarrierPollIntervalMinutes) * time.Minute
+	carrierPoller := carrier.NewPoller(carriers, shipmentRepo, carrierPollInterval)
+	jobRunner.Add(jobs.Job{
+		Name:     "poll-carrier-tracking",
+		Interval: carrierPollInterval,
+		Run: func(ctx context.Context) error {
+			changed, err := carrierPoller.Poll(ctx)
+			if changed > 0 {
+				log.Printf("🚚 Отслеживание посылок: сменили статус %d", changed)
+			}
+			return err
+		},
+	})
+
+	webhookDispatcher := webhook.NewDispatcher(webhookRepo, time.Duration(cfg.WebhookTimeoutSeconds)*time.Second)
+	jobRunner.Add(jobs.Job{
+		Name:     "dispatch-webhooks",
+		Interval: time.Duration(cfg.WebhookDispatchIntervalSeconds) * time.Second,
+		Run: func(ctx context.Context) error {
+			succeeded, failed, err := webhookDispatcher.Run(ctx)
+			if succeeded > 0 || failed > 0 {
+				log.Printf("📨 Вебхуки: доставлено %d, неудачных попыток %d", succeeded, failed)
+			}
+			return err
+		},
+	})
+	webhookRetention := time.Duration(cfg.WebhookRetentionDays) * 24 * time.Hour
+	jobRunner.Add(jobs.Job{
+		Name:     "purge-webhook-events",
+		Interval: time.Hour,
+		Run: func(ctx context.Context) error {
+			purged, err := webhookRepo.PurgeEvents(ctx, time.Now().Add(-webhookRetention))
+			if purged > 0 {
+				log.Printf("🗑️ Удалено старых событий вебхуков: %d", purged)
+			}
+			return err
+		},
+	})
+
+	var mail mailer.Mailer = mailer.LogMailer{}
+	if cfg.SMTPHost != "" {
+		mail = mailer.NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUser, cfg.SMTPPassword, cfg.MailFrom)
+		log.Printf("✉️ Письма отправляются через %s:%d", cfg.SMTPHost, cfg.SMTPPort)
+	}
+	notificationSender := mailer.NewNotificationSender(notificationRepo, mail, cfg.AppBaseURL)
+	jobRunner.Add(jobs.Job{
+		Name:     "send-notification-emails",
+		Interval: time.Duration(cfg.MailIntervalSeconds) * time.Second,
+		Run: func(ctx context.Context) error {
+			sent, err := notificationSender.Send(ctx)
+			if sent > 0 {
+				log.Printf("✉️ Письма по уведомлениям: отправлено %d", sent)
+			}
+			return err
+		},
+	})
 
 	log.Printf("🛣️ Настройка маршрутов...")
 	// Создаем одну родительскую группу /api
@@ -127,6 +302,10 @@ func Init(cfg *config.Config) (*Server, error) {
 			auth.POST("/validate-tokens", userHandler.ValidateMultipleTokens)
 		}
 
+		// Поток живых обновлений: вместо заголовка Authorization можно передать
+		// билет параметром ticket (для EventSource), см. POST /api/events/stream-ticket
+		api.GET("/events/stream", middleware.StreamTicketAuth(eventRepo), eventStreamHandler.Stream)
+
 		// --- Protected Routes ---
 		protected := api.Group("/")
 		protected.Use(middleware.JWTAuthMiddleware())
@@ -138,6 +317,9 @@ func Init(cfg *config.Config) (*Server, error) {
 			// Password routes
 			protected.POST("/password/change", userHandler.ChangePassword)
 
+			// Билет на подключение к потоку живых обновлений
+			protected.POST("/events/stream-ticket", eventStreamHandler.IssueTicket)
+
 			// Account management routes
 			account := protected.Group("/account")
 			{
@@ -160,18 +342,160 @@ func Init(cfg *config.Config) (*Server, error) {
 				products.GET("", productHandler.ListProducts)
 				products.GET("/categories", productHandler.GetCategories)
 				products.GET("/sizes", productHandler.GetSizeChart)
+				products.GET("/trash", productHandler.ListTrash)
 				products.POST("", productHandler.CreateProduct)
+				products.POST("/bulk", productHandler.BulkUpdateProducts)
 				products.GET("/:id", productHandler.GetProductByID)
 				products.PUT("/:id", productHandler.UpdateProduct)
 				products.DELETE("/:id", productHandler.DeleteProduct)
 				products.POST("/:id/images", productHandler.UploadImages)
+				products.GET("/:id/readiness", productHandler.GetProductReadiness)
+				products.POST("/:id/publish", productHandler.PublishProduct)
+				products.POST("/:id/archive", productHandler.ArchiveProduct)
+				products.POST("/:id/unarchive", productHandler.UnarchiveProduct)
+				products.POST("/:id/restore", productHandler.RestoreProduct)
+				products.GET("/:id/history", productHandler.GetProductHistory)
+				products.GET("/:id/history/:version", productHandler.GetProductVersion)
+				products.POST("/:id/history/:version/restore", productHandler.RestoreProductVersion)
+				products.GET("/:id/prices", priceHandler.GetPriceTimeline)
+				products.GET("/:id/price-schedules", priceHandler.ListPriceSchedules)
+				products.POST("/:id/price-schedules", priceHandler.CreatePriceSchedule)
+				products.DELETE("/:id/price-schedules/:schedule_id", priceHandler.CancelPriceSchedule)
+				products.GET("/:id/stock-levels", stockHandler.GetProductStockLevels)
+				products.PUT("/:id/variants/:variant_id/reorder-point", stockHandler.UpdateReorderPoint)
+				products.GET("/:id/labels", labelHandler.GetProductLabels)
+			}
+			// --- Маршруты для категорий ---
+			categories := protected.Group("/categories")
+			{
+				categories.GET("", categoryHandler.ListCategories)
+				categories.GET("/:id", categoryHandler.GetCategory)
+				categories.GET("/:id/schema", categoryHandler.GetCategorySchema)
+
+				// Дерево категорий общее для всех продавцов - менять его могут только администраторы
+				adminCategories := categories.Group("", middleware.RequireAdmin(userRepo))
+				adminCategories.POST("", categoryHandler.CreateCategory)
+				adminCategories.PUT("/:id", categoryHandler.UpdateCategory)
+				adminCategories.DELETE("/:id", categoryHandler.DeleteCategory)
+				adminCategories.PUT("/:id/schema", categoryHandler.UpdateCategorySchema)
+			}
+			// --- Маршруты для поставщиков ---
+			suppliers := protected.Group("/suppliers")
+			{
+				suppliers.GET("", supplierHandler.ListSuppliers)
+				suppliers.POST("", supplierHandler.CreateSupplier)
+				suppliers.GET("/report", supplierHandler.GetSuppliersReport)
+				suppliers.GET("/:id", supplierHandler.GetSupplier)
+				suppliers.PUT("/:id", supplierHandler.UpdateSupplier)
+				suppliers.DELETE("/:id", supplierHandler.DeleteSupplier)
+				suppliers.POST("/:id/products", supplierHandler.AssignProducts)
+				suppliers.DELETE("/:id/products", supplierHandler.UnassignProducts)
+				suppliers.GET("/:id/report", supplierHandler.GetSupplierReport)
 			}
 			// --- Маршруты для заказов ---
 			orders := protected.Group("/orders")
 			{
 				orders.GET("", orderHandler.ListOrders)
+				orders.POST("/documents", orderDocumentHandler.BatchOrderDocuments)
 				orders.GET("/:order_id", orderHandler.GetOrderByID)
 				orders.PUT("/:order_id/status", orderHandler.UpdateOrderStatus)
+				orders.POST("/:order_id/cancel", orderHandler.CancelOrder)
+				orders.GET("/:order_id/shipments", shipmentHandler.ListShipments)
+				orders.POST("/:order_id/shipments", shipmentHandler.CreateShipment)
+				orders.PUT("/:order_id/shipments/:shipment_id", shipmentHandler.UpdateShipment)
+				orders.PUT("/:order_id/shipments/:shipment_id/status", shipmentHandler.UpdateShipmentStatus)
+				orders.POST("/:order_id/shipments/:shipment_id/register", shipmentHandler.RegisterShipment)
+				orders.GET("/:order_id/tracking", shipmentHandler.ListTrackingEvents)
+				orders.GET("/:order_id/marking-codes", markingHandler.GetOrderMarkingCodes)
+				orders.POST("/:order_id/marking-codes", markingHandler.AssignOrderMarkingCodes)
+				orders.DELETE("/:order_id/marking-codes/:code_id", markingHandler.UnassignOrderMarkingCode)
+				orders.GET("/:order_id/packing-label", labelHandler.GetPackingLabel)
+				orders.GET("/:order_id/documents", orderDocumentHandler.ListOrderDocuments)
+				orders.GET("/:order_id/documents/:type", orderDocumentHandler.GetOrderDocument)
+			}
+			// --- Остатки: оповещения и дозаказ ---
+			stock := protected.Group("/stock")
+			{
+				stock.GET("/alerts", stockHandler.ListStockAlerts)
+				stock.POST("/alerts/:id/acknowledge", stockHandler.AcknowledgeStockAlert)
+				stock.GET("/restock", stockHandler.GetRestockReport)
+			}
+			// --- Склады, остатки по складам и перемещения ---
+			warehouses := protected.Group("/warehouses")
+			{
+				warehouses.GET("", warehouseHandler.ListWarehouses)
+				warehouses.POST("", warehouseHandler.CreateWarehouse)
+				warehouses.GET("/stock", warehouseHandler.ListWarehouseStock)
+				warehouses.GET("/movements", warehouseHandler.ListStockMovements)
+				warehouses.POST("/transfers", warehouseHandler.TransferStock)
+				warehouses.GET("/:id", warehouseHandler.GetWarehouse)
+				warehouses.PUT("/:id", warehouseHandler.UpdateWarehouse)
+				warehouses.DELETE("/:id", warehouseHandler.DeleteWarehouse)
+				warehouses.GET("/:id/stock", warehouseHandler.ListWarehouseStock)
+				warehouses.POST("/:id/adjust", warehouseHandler.AdjustWarehouseStock)
+			}
+			// --- Маркировка "Честный ЗНАК" ---
+			markingGroup := protected.Group("/marking")
+			{
+				markingGroup.GET("/summary", markingHandler.GetMarkingSummary)
+				markingGroup.GET("/batches", markingHandler.ListMarkingBatches)
+				markingGroup.POST("/batches", markingHandler.ImportMarkingCodes)
+				markingGroup.GET("/codes", markingHandler.ListMarkingCodes)
+				markingGroup.POST("/codes/withdraw", markingHandler.WithdrawMarkingCodes)
+				markingGroup.POST("/codes/reintroduce", markingHandler.ReintroduceMarkingCodes)
+				markingGroup.GET("/reports", markingHandler.ListMarkingReports)
+				markingGroup.POST("/reports", markingHandler.CreateMarkingReport)
+				markingGroup.GET("/reports/:id", markingHandler.GetMarkingReport)
+				markingGroup.GET("/reports/:id/export", markingHandler.ExportMarkingReport)
+				markingGroup.POST("/reports/:id/submit", markingHandler.SubmitMarkingReport)
+			}
+			// --- Штрихкоды и этикетки ---
+			barcodes := protected.Group("/barcodes")
+			{
+				barcodes.GET("/validate", labelHandler.ValidateBarcode)
+				barcodes.POST("/generate", labelHandler.GenerateBarcodes)
+			}
+			labels := protected.Group("/labels")
+			{
+				labels.POST("/variants", labelHandler.PrintVariantLabels)
+				labels.POST("/marking", labelHandler.PrintMarkingLabels)
+			}
+			// --- Покупатели ---
+			customers := protected.Group("/customers")
+			{
+				customers.GET("", customerHandler.ListCustomers)
+				customers.GET("/:id", customerHandler.GetCustomer)
+				customers.GET("/:id/orders", customerHandler.ListCustomerOrders)
+			}
+			// --- Центр уведомлений ---
+			notifications := protected.Group("/notifications")
+			{
+				notifications.GET("", notificationHandler.ListNotifications)
+				notifications.GET("/unread-count", notificationHandler.GetUnreadCounts)
+				notifications.POST("/read", notificationHandler.MarkNotificationsRead)
+				notifications.POST("/read-all", notificationHandler.MarkAllNotificationsRead)
+				notifications.GET("/preferences", notificationHandler.GetNotificationPreferences)
+				notifications.PUT("/preferences", notificationHandler.UpdateNotificationPreferences)
+			}
+			// --- Вебхуки ---
+			webhooks := protected.Group("/webhooks")
+			{
+				webhooks.GET("", webhookHandler.ListWebhookEndpoints)
+				webhooks.POST("", webhookHandler.CreateWebhookEndpoint)
+				webhooks.GET("/event-types", webhookHandler.ListWebhookEventTypes)
+				webhooks.GET("/deliveries", webhookHandler.ListWebhookDeliveries)
+				webhooks.POST("/deliveries/:id/redeliver", webhookHandler.RedeliverWebhook)
+				webhooks.PUT("/:id", webhookHandler.UpdateWebhookEndpoint)
+				webhooks.DELETE("/:id", webhookHandler.DeleteWebhookEndpoint)
+			}
+			// --- Сохраненные представления списков ---
+			views := protected.Group("/views")
+			{
+				views.GET("", viewHandler.ListViews)
+				views.POST("", viewHandler.CreateView)
+				views.GET("/:id", viewHandler.GetView)
+				views.PUT("/:id", viewHandler.UpdateView)
+				views.DELETE("/:id", viewHandler.DeleteView)
 			}
 
 			// --- Маршруты для дашборда ---
@@ -187,15 +511,22 @@ func Init(cfg *config.Config) (*Server, error) {
 				analytics.GET("/size-distribution", analyticsHandler.GetSizeDistribution)
 				analytics.GET("/seasonal-trends", analyticsHandler.GetSeasonalTrends)
 				analytics.GET("/returns", analyticsHandler.GetReturnsAnalytics)
+				analytics.GET("/rfm", analyticsHandler.GetRFMAnalytics)
+				analytics.GET("/rfm/export", analyticsHandler.ExportRFMAnalytics)
+				analytics.GET("/cohorts", analyticsHandler.GetCohortAnalytics)
+				analytics.GET("/cohorts/export", analyticsHandler.ExportCohortAnalytics)
 			}
 		}
 	}
 
 	log.Printf("✅ Сервер инициализирован успешно")
 	return &Server{
-		Engine: r,
-		DB:     db,
-		Config: cfg,
+		Engine:        r,
+		DB:            db,
+		Config:        cfg,
+		Jobs:          jobRunner,
+		Events:        eventBus,
+		EventListener: eventListener,
 	}, nil
 }
 
@@ -221,7 +552,7 @@ func corsMiddleware(cfg *config.Config) gin.HandlerFunc {
 		}
 
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, Last-Event-ID")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
 
 		if c.Request.Method == "OPTIONS" {
@@ -246,6 +577,13 @@ func (s *Server) Run() {
 	log.Printf("🌍 Сервер доступен по адресу: http://localhost:%s", s.Config.ServerPort)
 	log.Printf("💊 Health check: http://localhost:%s/api/health", s.Config.ServerPort)
 
+	s.Jobs.Start(context.Background())
+
+	// Открытые потоки SSE не дают Shutdown дождаться соединений - закрываем их подписки
+	listenerCtx, stopListener := context.WithCancel(context.Background())
+	go s.EventListener.Run(listenerCtx)
+	srv.RegisterOnShutdown(s.Events.Close)
+
 	go func() {
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			log.Fatalf("❌ Ошибка запуска сервера: %s", err)
@@ -262,6 +600,8 @@ func (s *Server) Run() {
 	if err := srv.Shutdown(ctx); err != nil {
 		log.Fatalf("❌ Принудительное завершение сервера: %s", err)
 	}
+	s.Jobs.Stop()
+	stopListener()
 
 	log.Println("✅ Сервер корректно завершил работу")
 }
@@ -0,0 +1,149 @@
+package validation
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lamoda-seller-app/internal/barcode"
+	"github.com/lamoda-seller-app/internal/model"
+)
+
+// ValidateProductAttributes validates product attribute values against the category schema.
+// Attributes that are not described by the schema are rejected.
+func ValidateProductAttributes(attributes model.ProductAttributes, schema []model.CategoryAttribute) ValidationErrors {
+	var errors ValidationErrors
+
+	known := make(map[string]struct{}, len(schema))
+	for _, attr := range schema {
+		known[attr.Code] = struct{}{}
+		field := "attributes." + attr.Code
+
+		value, ok := attributes[attr.Code]
+		if !ok || value == nil || value == "" {
+			if attr.Required {
+				errors.Add(field, fmt.Sprintf("Attribute '%s' is required", attr.Name))
+			}
+			continue
+		}
+
+		switch attr.Type {
+		case model.AttributeTypeString:
+			if _, ok := value.(string); !ok {
+				errors.Add(field, "Value must be a string")
+			}
+		case model.AttributeTypeNumber:
+			if _, ok := value.(float64); !ok {
+				errors.Add(field, "Value must be a number")
+			}
+		case model.AttributeTypeBoolean:
+			if _, ok := value.(bool); !ok {
+				errors.Add(field, "Value must be a boolean")
+			}
+		case model.AttributeTypeEnum:
+			s, ok := value.(string)
+			if !ok || !containsString(attr.AllowedValues, s) {
+				errors.Add(field, fmt.Sprintf("Value must be one of: %s", strings.Join(attr.AllowedValues, ", ")))
+			}
+		case model.AttributeTypeMultiEnum:
+			values, ok := value.([]interface{})
+			if !ok {
+				errors.Add(field, "Value must be an array")
+				continue
+			}
+			for _, v := range values {
+				s, ok := v.(string)
+				if !ok || !containsString(attr.AllowedValues, s) {
+					errors.Add(field, fmt.Sprintf("Values must be from: %s", strings.Join(attr.AllowedValues, ", ")))
+					break
+				}
+			}
+		}
+	}
+
+	for code := range attributes {
+		if _, ok := known[code]; !ok {
+			errors.Add("attributes."+code, "Attribute is not defined for this category")
+		}
+	}
+
+	return errors
+}
+
+// ValidateCategorySchema checks that attribute definitions are consistent.
+func ValidateCategorySchema(attributes []model.CategoryAttribute) ValidationErrors {
+	var errors ValidationErrors
+
+	seen := make(map[string]struct{}, len(attributes))
+	for i, attr := range attributes {
+		field := fmt.Sprintf("attributes[%d]", i)
+		if _, ok := seen[attr.Code]; ok {
+			errors.Add(field, fmt.Sprintf("Duplicate attribute code '%s'", attr.Code))
+		}
+		seen[attr.Code] = struct{}{}
+
+		isEnum := attr.Type == model.AttributeTypeEnum || attr.Type == model.AttributeTypeMultiEnum
+		if isEnum && len(attr.AllowedValues) == 0 {
+			errors.Add(field, "Enum attributes must define allowed values")
+		}
+		if !isEnum && len(attr.AllowedValues) > 0 {
+			errors.Add(field, "Allowed values are only supported for enum attributes")
+		}
+	}
+
+	return errors
+}
+
+// StoredBarcodes collects the product barcode and variant GTINs as they are stored.
+// Values from this set are not re-validated, so legacy cards with barcodes saved
+// before the check existed can still be edited.
+func StoredBarcodes(product *model.Product) map[string]bool {
+	stored := make(map[string]bool, len(product.Variants)+1)
+	if product.Barcode != "" {
+		stored[product.Barcode] = true
+	}
+	for _, v := range product.Variants {
+		if v.GTIN != "" {
+			stored[v.GTIN] = true
+		}
+	}
+	return stored
+}
+
+// ValidateProductBarcodes checks the product barcode and variant GTINs.
+// Variant GTINs are normalized to 14 digits, the form used by marking codes.
+// Values present in stored (see StoredBarcodes) are checked only if they are
+// valid; invalid legacy values are kept as they are.
+func ValidateProductBarcodes(product *model.Product, stored map[string]bool) ValidationErrors {
+	var errors ValidationErrors
+
+	if product.Barcode != "" && !stored[product.Barcode] {
+		if err := barcode.ValidateGTIN(product.Barcode); err != nil {
+			errors.Add("barcode", err.Error())
+		}
+	}
+	for i := range product.Variants {
+		variant := &product.Variants[i]
+		if variant.GTIN == "" {
+			continue
+		}
+		gtin, err := barcode.NormalizeGTIN(variant.GTIN)
+		if err != nil {
+			if !stored[variant.GTIN] {
+				errors.Add(fmt.Sprintf("variants[%d].gtin", i), err.Error())
+			}
+			continue
+		}
+		variant.GTIN = gtin
+	}
+
+	return errors
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
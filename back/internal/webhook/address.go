@@ -0,0 +1,112 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+var (
+	ErrURLScheme        = errors.New("webhook URL must use http or https")
+	ErrURLHost          = errors.New("webhook URL must contain a host")
+	ErrForbiddenAddress = errors.New("webhook address is not publicly routable")
+)
+
+// blockedPrefixes - сети, куда вебхуки не отправляются, помимо loopback, частных, link-local
+// и multicast адресов: адрес продавца не должен вести во внутреннюю сеть сервера
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),       // "Эта" сеть
+	netip.MustParsePrefix("100.64.0.0/10"),   // Carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),    // Служебные назначения IETF
+	netip.MustParsePrefix("192.0.2.0/24"),    // Документация
+	netip.MustParsePrefix("192.88.99.0/24"),  // Устаревший 6to4 relay
+	netip.MustParsePrefix("198.18.0.0/15"),   // Тестирование производительности
+	netip.MustParsePrefix("198.51.100.0/24"), // Документация
+	netip.MustParsePrefix("203.0.113.0/24"),  // Документация
+	netip.MustParsePrefix("240.0.0.0/4"),     // Зарезервировано, включая широковещательный
+	netip.MustParsePrefix("64:ff9b::/96"),    // NAT64 - ведет на произвольный IPv4
+	netip.MustParsePrefix("64:ff9b:1::/48"),  // Локальный NAT64
+	netip.MustParsePrefix("100::/64"),        // Discard-only
+	netip.MustParsePrefix("2001::/23"),       // Служебные назначения IETF, включая Teredo
+	netip.MustParsePrefix("2001:db8::/32"),   // Документация
+	netip.MustParsePrefix("2002::/16"),       // 6to4 - ведет на произвольный IPv4
+	netip.MustParsePrefix("fec0::/10"),       // Устаревшие site-local
+}
+
+// PublicAddress сообщает, можно ли отправлять вебхуки на IP-адрес.
+func PublicAddress(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || addr.IsUnspecified() || addr.IsLoopback() || addr.IsPrivate() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() ||
+		addr.IsMulticast() {
+		return false
+	}
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidateURL проверяет адрес вебхука при его подключении: схема http или https, есть хост,
+// и хост, заданный IP-адресом или localhost, не ведет во внутреннюю сеть. Имена хостов
+// проверяются при каждом соединении (см. NewClient), так как DNS может измениться.
+func ValidateURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return ErrURLScheme
+	}
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if host == "" {
+		return ErrURLHost
+	}
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, host)
+	}
+	if addr, err := netip.ParseAddr(host); err == nil && !PublicAddress(addr) {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, host)
+	}
+	return nil
+}
+
+// NewClient возвращает HTTP-клиент для отправки вебхуков. Адрес проверяется при соединении,
+// уже после разрешения имени, поэтому имя, указывающее во внутреннюю сеть, не поможет обойти проверку.
+// Прокси из окружения не используется, переадресации не выполняются: ответ 3xx считается неудачей.
+func NewClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			addr, err := netip.ParseAddr(host)
+			if err != nil || !PublicAddress(addr) {
+				return fmt.Errorf("%w: %s", ErrForbiddenAddress, host)
+			}
+			return nil
+		},
+	}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+			MaxIdleConnsPerHost: 2,
+			IdleConnTimeout:     time.Minute,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
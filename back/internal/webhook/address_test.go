@@ -0,0 +1,110 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestPublicAddress(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"2a00:1450:4010:c05::65", true},
+		{"127.0.0.1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.10", false},
+		{"169.254.169.254", false}, // Метаданные облака
+		{"100.64.0.1", false},
+		{"0.0.0.0", false},
+		{"255.255.255.255", false},
+		{"224.0.0.1", false},
+		{"::1", false},
+		{"::", false},
+		{"fe80::1", false},
+		{"fd00::1", false},
+		{"::ffff:127.0.0.1", false},
+		{"::ffff:10.0.0.1", false},
+		{"64:ff9b::7f00:1", false},
+	}
+	for _, tt := range tests {
+		if got := PublicAddress(netip.MustParseAddr(tt.addr)); got != tt.want {
+			t.Errorf("PublicAddress(%s) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestValidateURL(t *testing.T) {
+	tests := []struct {
+		url     string
+		wantErr error
+	}{
+		{url: "https://hooks.example.com/lamoda"},
+		{url: "http://93.184.216.34:8080/hook"},
+		{url: "ftp://hooks.example.com/", wantErr: ErrURLScheme},
+		{url: "file:///etc/passwd", wantErr: ErrURLScheme},
+		{url: "gopher://127.0.0.1:6379/_INFO", wantErr: ErrURLScheme},
+		{url: "https:///path", wantErr: ErrURLHost},
+		{url: "http://localhost:8080/", wantErr: ErrForbiddenAddress},
+		{url: "http://api.localhost./", wantErr: ErrForbiddenAddress},
+		{url: "http://127.0.0.1/", wantErr: ErrForbiddenAddress},
+		{url: "http://[::1]:8080/", wantErr: ErrForbiddenAddress},
+		{url: "http://169.254.169.254/latest/meta-data/", wantErr: ErrForbiddenAddress},
+	}
+	for _, tt := range tests {
+		err := ValidateURL(tt.url)
+		if tt.wantErr == nil && err != nil {
+			t.Errorf("ValidateURL(%q) = %v, want nil", tt.url, err)
+		}
+		if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+			t.Errorf("ValidateURL(%q) = %v, want %v", tt.url, err, tt.wantErr)
+		}
+	}
+}
+
+func TestClientRefusesInternalAddresses(t *testing.T) {
+	called := false
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer ts.Close()
+
+	req, _ := http.NewRequestWithContext(context.Background(), http.MethodPost, ts.URL, nil)
+	_, err := NewClient(time.Second).Do(req)
+	if !errors.Is(err, ErrForbiddenAddress) {
+		t.Fatalf("request to %s: %v, want %v", ts.URL, err, ErrForbiddenAddress)
+	}
+	if called {
+		t.Fatal("request reached the loopback server")
+	}
+}
+
+func TestClientDoesNotFollowRedirects(t *testing.T) {
+	client := NewClient(time.Second)
+	// Проверка адреса отключена, чтобы дойти до тестового сервера на loopback
+	client.Transport = http.DefaultTransport
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/hook" {
+			http.Redirect(w, r, "http://169.254.169.254/latest/meta-data/", http.StatusTemporaryRedirect)
+			return
+		}
+		t.Errorf("redirect followed to %s", r.URL)
+	}))
+	defer ts.Close()
+
+	resp, err := client.Post(ts.URL+"/hook", "application/json", nil)
+	if err != nil {
+		t.Fatalf("Post: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTemporaryRedirect {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusTemporaryRedirect)
+	}
+}
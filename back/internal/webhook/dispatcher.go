@@ -0,0 +1,152 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lamoda-seller-app/internal/model"
+	"github.com/lamoda-seller-app/internal/repository"
+)
+
+const (
+	fanOutBatch   = 500              // Событий очереди, разбираемых за один запуск
+	deliveryBatch = 100              // Доставок, отправляемых за один запуск
+	requestLease  = 5 * time.Minute  // На сколько откладывается взятая доставка до записи результата
+	maxAttempts   = 8                // После стольких неудачных попыток доставка помечается failed
+	baseBackoff   = 30 * time.Second // Задержка перед второй попыткой, дальше удваивается
+	maxBackoff    = 6 * time.Hour
+	maxLogBody    = 2048 // Сколько байт ответа адреса сохраняется в журнале
+)
+
+// Dispatcher разбирает исходящую очередь событий и отправляет доставки на адреса продавцов.
+// Несколько экземпляров сервера могут работать одновременно: события и доставки
+// берутся с блокировкой SKIP LOCKED.
+type Dispatcher struct {
+	repo   *repository.WebhookRepository
+	client *http.Client
+}
+
+func NewDispatcher(repo *repository.WebhookRepository, timeout time.Duration) *Dispatcher {
+	return &Dispatcher{repo: repo, client: NewClient(timeout)}
+}
+
+// envelope - тело запроса вебхука
+type envelope struct {
+	ID        string          `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// Run создает доставки по новым событиям и отправляет доставки, время которых наступило.
+// Возвращает количество успешных и неудачных попыток.
+func (d *Dispatcher) Run(ctx context.Context) (succeeded, failed int, err error) {
+	if _, err := d.repo.FanOut(ctx, fanOutBatch); err != nil {
+		return 0, 0, err
+	}
+	deliveries, err := d.repo.ClaimDue(ctx, deliveryBatch, requestLease)
+	if err != nil {
+		return 0, 0, err
+	}
+	for i := range deliveries {
+		if ctx.Err() != nil {
+			return succeeded, failed, ctx.Err()
+		}
+		delivery := &deliveries[i]
+		d.attempt(ctx, delivery)
+		if err := d.repo.RecordAttempt(ctx, delivery); err != nil {
+			log.Printf("❌ Webhooks: не удалось сохранить попытку доставки %s: %v", delivery.ID, err)
+			continue
+		}
+		if delivery.Status == model.WebhookDeliverySucceeded {
+			succeeded++
+		} else {
+			failed++
+		}
+	}
+	return succeeded, failed, nil
+}
+
+// attempt отправляет доставку и заполняет ее результат: статус, ответ адреса и время следующей попытки.
+func (d *Dispatcher) attempt(ctx context.Context, delivery *model.WebhookDelivery) {
+	now := time.Now()
+	delivery.Attempts++
+	delivery.LastAttemptAt = &now
+	delivery.ResponseStatus = nil
+	delivery.ResponseBody = ""
+	delivery.Error = ""
+
+	status, body, err := d.send(ctx, delivery, now)
+	if status != 0 {
+		delivery.ResponseStatus = &status
+	}
+	delivery.ResponseBody = body
+	if err == nil && status >= 200 && status < 300 {
+		delivery.Status = model.WebhookDeliverySucceeded
+		return
+	}
+	if err != nil {
+		delivery.Error = err.Error()
+	} else {
+		delivery.Error = fmt.Sprintf("unexpected response status %d", status)
+	}
+
+	if delivery.Attempts >= maxAttempts {
+		delivery.Status = model.WebhookDeliveryFailed
+		log.Printf("❌ Webhooks: доставка %s на %s не удалась после %d попыток: %s",
+			delivery.ID, delivery.Endpoint.URL, delivery.Attempts, delivery.Error)
+		return
+	}
+	delivery.NextAttemptAt = now.Add(Backoff(delivery.Attempts))
+}
+
+func (d *Dispatcher) send(ctx context.Context, delivery *model.WebhookDelivery, now time.Time) (int, string, error) {
+	event := delivery.Event
+	payload, err := json.Marshal(envelope{
+		ID:        event.ID.String(),
+		Type:      event.Type,
+		CreatedAt: event.CreatedAt,
+		Data:      event.Payload,
+	})
+	if err != nil {
+		return 0, "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.Endpoint.URL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "lamoda-seller-app-webhooks/1.0")
+	req.Header.Set(HeaderEventID, event.ID.String())
+	req.Header.Set(HeaderEventType, event.Type)
+	req.Header.Set(HeaderTimestamp, fmt.Sprint(now.Unix()))
+	req.Header.Set(HeaderSignature, Sign(delivery.Endpoint.Secret, now, payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxLogBody))
+	return resp.StatusCode, strings.ToValidUTF8(string(body), ""), nil
+}
+
+// Backoff возвращает задержку перед следующей попыткой после attempts неудачных:
+// 30с, 1м, 2м, 4м... но не больше 6 часов, со случайным разбросом до 10%,
+// чтобы повторы на один адрес не шли одной пачкой.
+func Backoff(attempts int) time.Duration {
+	delay := maxBackoff
+	if attempts < 20 {
+		delay = min(baseBackoff<<(attempts-1), maxBackoff)
+	}
+	return delay + time.Duration(rand.Int63n(int64(delay)/10+1))
+}
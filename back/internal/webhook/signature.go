@@ -0,0 +1,49 @@
+// Package webhook отправляет события продавца на его адреса: подписывает тело запроса
+// HMAC-SHA256 и повторяет неудачные доставки с экспоненциальной задержкой.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"time"
+)
+
+// Заголовки запроса вебхука
+const (
+	HeaderEventID   = "X-Webhook-Id"        // ID события, одинаковый во всех попытках - для дедупликации
+	HeaderEventType = "X-Webhook-Event"     // Тип события, например order.created
+	HeaderTimestamp = "X-Webhook-Timestamp" // Время отправки попытки, Unix-секунды
+	HeaderSignature = "X-Webhook-Signature" // sha256=<hex HMAC-SHA256(secret, timestamp + "." + body)>
+)
+
+// Sign возвращает значение заголовка X-Webhook-Signature. Время входит в подпись,
+// чтобы получатель мог отклонять повторно отправленные перехваченные запросы.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify проверяет подпись запроса вебхука. Используется получателями на Go и в отладке.
+func Verify(secret, timestamp, signature string, body []byte) bool {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	expected := Sign(secret, time.Unix(ts, 0), body)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
+
+// NewSecret выпускает ключ подписи для нового адреса.
+func NewSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,22 @@
+-- +migrate Up
+
+-- Продажи товаров за скользящие 30 дней. Таблица пересчитывается фоновой задачей,
+-- чтобы сортировка и фильтрация списка товаров по продажам не агрегировали заказы на каждый запрос.
+CREATE TABLE product_sales_30d (
+    product_id UUID PRIMARY KEY,
+    units_sold INTEGER NOT NULL DEFAULT 0,
+    revenue NUMERIC(14, 2) NOT NULL DEFAULT 0,
+    refreshed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_product FOREIGN KEY(product_id) REFERENCES products(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_product_sales_30d_units_sold ON product_sales_30d(units_sold);
+CREATE INDEX idx_product_sales_30d_revenue ON product_sales_30d(revenue);
+
+INSERT INTO product_sales_30d (product_id, units_sold, revenue)
+SELECT oi.product_id, SUM(oi.quantity), SUM(oi.total)
+FROM order_items oi
+JOIN orders o ON o.id = oi.order_id
+JOIN products p ON p.id = oi.product_id
+WHERE o.date >= NOW() - INTERVAL '30 days' AND o.status <> 'cancelled'
+GROUP BY oi.product_id;
@@ -0,0 +1,41 @@
+-- +migrate Up
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Полнотекстовый поиск по товарам.
+-- search_vector - лексемы русской и английской морфологии с весами (A - название и артикул, B - бренд и теги,
+-- C - материал, D - описание). search_text - нормализованный текст для триграммного поиска с опечатками.
+ALTER TABLE products ADD COLUMN search_vector TSVECTOR;
+ALTER TABLE products ADD COLUMN search_text TEXT;
+
+CREATE OR REPLACE FUNCTION products_search_update() RETURNS TRIGGER AS $$
+DECLARE
+    tags_text TEXT := COALESCE(array_to_string(NEW.tags, ' '), '');
+BEGIN
+    NEW.search_vector :=
+        setweight(to_tsvector('russian', COALESCE(NEW.name, '')), 'A') ||
+        setweight(to_tsvector('english', COALESCE(NEW.name, '')), 'A') ||
+        setweight(to_tsvector('simple', COALESCE(NEW.sku, '') || ' ' || COALESCE(NEW.barcode, '')), 'A') ||
+        setweight(to_tsvector('simple', COALESCE(NEW.brand, '')), 'B') ||
+        setweight(to_tsvector('russian', tags_text), 'B') ||
+        setweight(to_tsvector('english', tags_text), 'B') ||
+        setweight(to_tsvector('russian', COALESCE(NEW.material, '')), 'C') ||
+        setweight(to_tsvector('english', COALESCE(NEW.material, '')), 'C') ||
+        setweight(to_tsvector('russian', COALESCE(NEW.description, '')), 'D') ||
+        setweight(to_tsvector('english', COALESCE(NEW.description, '')), 'D');
+    NEW.search_text := lower(concat_ws(' ', NEW.name, NEW.brand, NEW.sku, NEW.material, tags_text));
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER products_search_vector_update
+BEFORE INSERT OR UPDATE OF name, description, brand, sku, barcode, tags, material ON products
+FOR EACH ROW EXECUTE PROCEDURE products_search_update();
+
+-- Заполняем поиск для существующих товаров, не трогая updated_at
+ALTER TABLE products DISABLE TRIGGER update_products_updated_at;
+UPDATE products SET name = name;
+ALTER TABLE products ENABLE TRIGGER update_products_updated_at;
+
+CREATE INDEX idx_products_search_vector ON products USING GIN (search_vector);
+CREATE INDEX idx_products_search_text_trgm ON products USING GIN (search_text gin_trgm_ops);
@@ -0,0 +1,5 @@
+-- +migrate Down
+
+DROP INDEX IF EXISTS idx_products_updated_at;
+DROP INDEX IF EXISTS idx_products_tags;
+ALTER TABLE users DROP COLUMN IF EXISTS low_stock_threshold;
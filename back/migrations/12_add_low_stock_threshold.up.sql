@@ -0,0 +1,8 @@
+-- +migrate Up
+
+-- Порог "мало на складе" настраивается каждым продавцом
+ALTER TABLE users ADD COLUMN low_stock_threshold INTEGER NOT NULL DEFAULT 10 CHECK (low_stock_threshold >= 0);
+
+-- Индексы для новых фильтров списка товаров
+CREATE INDEX idx_products_tags ON products USING GIN (tags);
+CREATE INDEX idx_products_updated_at ON products(updated_at);
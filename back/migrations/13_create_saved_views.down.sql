@@ -0,0 +1,3 @@
+-- +migrate Down
+
+DROP TABLE IF EXISTS saved_views;
@@ -0,0 +1,24 @@
+-- +migrate Up
+
+-- Сохраненные представления списков (фильтры, сортировка, колонки)
+CREATE TABLE saved_views (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL,
+    list VARCHAR(20) NOT NULL CHECK (list IN ('products', 'orders')),
+    name VARCHAR(255) NOT NULL,
+    filters JSONB NOT NULL DEFAULT '{}',
+    sort_by VARCHAR(50),
+    sort_order VARCHAR(4),
+    columns TEXT[],
+    is_default BOOLEAN NOT NULL DEFAULT FALSE,
+    shared BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_user FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_saved_views_user_list ON saved_views(user_id, list);
+-- У пользователя может быть только одно представление по умолчанию для каждого списка
+CREATE UNIQUE INDEX uq_saved_views_default ON saved_views(user_id, list) WHERE is_default;
+
+CREATE TRIGGER update_saved_views_updated_at BEFORE UPDATE ON saved_views FOR EACH ROW EXECUTE PROCEDURE update_updated_at_column();
@@ -0,0 +1,7 @@
+-- +migrate Down
+
+DROP TABLE IF EXISTS stock_alerts;
+DROP INDEX IF EXISTS idx_order_items_variant_id;
+ALTER TABLE product_variants DROP COLUMN IF EXISTS lead_time_days;
+ALTER TABLE product_variants DROP COLUMN IF EXISTS reorder_point;
+ALTER TABLE suppliers DROP COLUMN IF EXISTS lead_time_days;
@@ -0,0 +1,35 @@
+-- +migrate Up
+
+-- Срок поставки: по умолчанию берется у поставщика, для варианта может быть переопределен
+ALTER TABLE suppliers ADD COLUMN lead_time_days INTEGER NOT NULL DEFAULT 14 CHECK (lead_time_days >= 0);
+
+-- Точка заказа варианта, заданная вручную. NULL - рассчитывается по скорости продаж и сроку поставки
+ALTER TABLE product_variants ADD COLUMN reorder_point INTEGER CHECK (reorder_point >= 0);
+ALTER TABLE product_variants ADD COLUMN lead_time_days INTEGER CHECK (lead_time_days >= 0);
+
+-- Скорость продаж считается по вариантам
+CREATE INDEX idx_order_items_variant_id ON order_items(variant_id);
+
+-- Оповещения о низком остатке и отсутствии варианта на складе
+CREATE TABLE stock_alerts (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL,
+    product_id UUID NOT NULL,
+    variant_id UUID NOT NULL,
+    type VARCHAR(20) NOT NULL CHECK (type IN ('low_stock', 'out_of_stock')),
+    available INTEGER NOT NULL,
+    reorder_point INTEGER NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    acknowledged_at TIMESTAMPTZ,
+    resolved_at TIMESTAMPTZ,
+    CONSTRAINT fk_user FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_product FOREIGN KEY(product_id) REFERENCES products(id) ON DELETE CASCADE,
+    CONSTRAINT fk_variant FOREIGN KEY(variant_id) REFERENCES product_variants(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_stock_alerts_user_id ON stock_alerts(user_id, created_at DESC);
+-- У варианта может быть только одно открытое оповещение
+CREATE UNIQUE INDEX uq_stock_alerts_open ON stock_alerts(variant_id) WHERE resolved_at IS NULL;
+
+CREATE TRIGGER update_stock_alerts_updated_at BEFORE UPDATE ON stock_alerts FOR EACH ROW EXECUTE PROCEDURE update_updated_at_column();
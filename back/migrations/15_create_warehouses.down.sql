@@ -0,0 +1,12 @@
+-- +migrate Down
+
+DROP TRIGGER IF EXISTS product_variants_sync_total_stock ON product_variants;
+DROP FUNCTION IF EXISTS product_variants_sync_total_stock();
+DROP TRIGGER IF EXISTS product_variants_keep_warehouse_totals ON product_variants;
+DROP FUNCTION IF EXISTS product_variants_keep_warehouse_totals();
+DROP TABLE IF EXISTS stock_reservations;
+DROP TABLE IF EXISTS stock_movements;
+DROP TABLE IF EXISTS warehouse_stocks;
+DROP FUNCTION IF EXISTS warehouse_stocks_sync_variant();
+DROP TABLE IF EXISTS warehouses;
+ALTER TABLE users DROP COLUMN IF EXISTS reservation_rule;
@@ -0,0 +1,161 @@
+-- +migrate Up
+
+-- Склады продавца: собственный склад, склады Lamoda FBO и товары в пути
+CREATE TABLE warehouses (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    code VARCHAR(50),
+    type VARCHAR(20) NOT NULL CHECK (type IN ('own', 'fbo', 'transit')),
+    address TEXT,
+    priority INTEGER NOT NULL DEFAULT 0, -- Чем меньше, тем раньше склад выбирается при резервировании
+    is_default BOOLEAN NOT NULL DEFAULT FALSE,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_user FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT uq_warehouses_user_code UNIQUE(user_id, code)
+);
+
+CREATE INDEX idx_warehouses_user_id ON warehouses(user_id);
+-- Склад по умолчанию (для правки остатка из карточки товара) у продавца один
+CREATE UNIQUE INDEX uq_warehouses_default ON warehouses(user_id) WHERE is_default;
+CREATE TRIGGER update_warehouses_updated_at BEFORE UPDATE ON warehouses FOR EACH ROW EXECUTE PROCEDURE update_updated_at_column();
+
+-- Остатки вариантов по складам
+CREATE TABLE warehouse_stocks (
+    warehouse_id UUID NOT NULL,
+    variant_id UUID NOT NULL,
+    stock INTEGER NOT NULL DEFAULT 0 CHECK (stock >= 0),
+    reserved INTEGER NOT NULL DEFAULT 0 CHECK (reserved >= 0),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (warehouse_id, variant_id),
+    CONSTRAINT fk_warehouse FOREIGN KEY(warehouse_id) REFERENCES warehouses(id) ON DELETE RESTRICT,
+    CONSTRAINT fk_variant FOREIGN KEY(variant_id) REFERENCES product_variants(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_warehouse_stocks_variant_id ON warehouse_stocks(variant_id);
+CREATE TRIGGER update_warehouse_stocks_updated_at BEFORE UPDATE ON warehouse_stocks FOR EACH ROW EXECUTE PROCEDURE update_updated_at_column();
+
+-- Движения остатков: перемещения, корректировки, отгрузки
+CREATE TABLE stock_movements (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL,
+    variant_id UUID NOT NULL,
+    from_warehouse_id UUID,
+    to_warehouse_id UUID,
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    type VARCHAR(20) NOT NULL CHECK (type IN ('transfer', 'adjustment', 'shipment', 'initial')),
+    order_id UUID,
+    comment TEXT,
+    created_by UUID,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_user FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_variant FOREIGN KEY(variant_id) REFERENCES product_variants(id) ON DELETE CASCADE,
+    CONSTRAINT fk_from_warehouse FOREIGN KEY(from_warehouse_id) REFERENCES warehouses(id) ON DELETE SET NULL,
+    CONSTRAINT fk_to_warehouse FOREIGN KEY(to_warehouse_id) REFERENCES warehouses(id) ON DELETE SET NULL,
+    CONSTRAINT fk_order FOREIGN KEY(order_id) REFERENCES orders(id) ON DELETE SET NULL,
+    CONSTRAINT fk_created_by FOREIGN KEY(created_by) REFERENCES users(id) ON DELETE SET NULL,
+    CONSTRAINT chk_movement_direction CHECK (from_warehouse_id IS NOT NULL OR to_warehouse_id IS NOT NULL)
+);
+
+CREATE INDEX idx_stock_movements_user_id ON stock_movements(user_id, created_at DESC);
+CREATE INDEX idx_stock_movements_variant_id ON stock_movements(variant_id);
+
+-- Резервы позиций заказов по складам. warehouse_id = NULL - резерв варианта, остаток которого еще не разнесен по складам
+CREATE TABLE stock_reservations (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    order_id UUID NOT NULL,
+    order_item_id UUID NOT NULL,
+    variant_id UUID NOT NULL,
+    warehouse_id UUID,
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_order FOREIGN KEY(order_id) REFERENCES orders(id) ON DELETE CASCADE,
+    CONSTRAINT fk_order_item FOREIGN KEY(order_item_id) REFERENCES order_items(id) ON DELETE CASCADE,
+    CONSTRAINT fk_variant FOREIGN KEY(variant_id) REFERENCES product_variants(id) ON DELETE CASCADE,
+    CONSTRAINT fk_warehouse FOREIGN KEY(warehouse_id) REFERENCES warehouses(id) ON DELETE RESTRICT
+);
+
+CREATE INDEX idx_stock_reservations_order_id ON stock_reservations(order_id);
+
+-- Правило выбора склада при резервировании
+ALTER TABLE users ADD COLUMN reservation_rule VARCHAR(20) NOT NULL DEFAULT 'priority'
+    CHECK (reservation_rule IN ('priority', 'single_warehouse', 'most_available'));
+
+-- Остаток и резерв варианта - суммы по складам, если остаток варианта разнесен по складам
+CREATE OR REPLACE FUNCTION warehouse_stocks_sync_variant() RETURNS TRIGGER AS $$
+DECLARE
+    vid UUID;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        vid := OLD.variant_id;
+    ELSE
+        vid := NEW.variant_id;
+    END IF;
+
+    UPDATE product_variants v
+    SET stock = s.stock, reserved = s.reserved
+    FROM (
+        SELECT COALESCE(SUM(stock), 0) AS stock, COALESCE(SUM(reserved), 0) AS reserved
+        FROM warehouse_stocks WHERE variant_id = vid
+    ) s
+    WHERE v.id = vid AND (v.stock, v.reserved) IS DISTINCT FROM (s.stock, s.reserved);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER warehouse_stocks_sync_variant AFTER INSERT OR UPDATE OR DELETE ON warehouse_stocks
+FOR EACH ROW EXECUTE PROCEDURE warehouse_stocks_sync_variant();
+
+-- Остаток и резерв разнесенного по складам варианта нельзя изменить напрямую (например, сохранением карточки товара)
+CREATE OR REPLACE FUNCTION product_variants_keep_warehouse_totals() RETURNS TRIGGER AS $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM warehouse_stocks WHERE variant_id = NEW.id) THEN
+        SELECT COALESCE(SUM(stock), 0), COALESCE(SUM(reserved), 0) INTO NEW.stock, NEW.reserved
+        FROM warehouse_stocks WHERE variant_id = NEW.id;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER product_variants_keep_warehouse_totals BEFORE UPDATE OF stock, reserved ON product_variants
+FOR EACH ROW EXECUTE PROCEDURE product_variants_keep_warehouse_totals();
+
+-- Общий остаток товара - сумма остатков вариантов
+CREATE OR REPLACE FUNCTION product_variants_sync_total_stock() RETURNS TRIGGER AS $$
+DECLARE
+    pid UUID;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        pid := OLD.product_id;
+    ELSE
+        pid := NEW.product_id;
+    END IF;
+
+    UPDATE products p
+    SET total_stock = s.total
+    FROM (SELECT COALESCE(SUM(stock), 0) AS total FROM product_variants WHERE product_id = pid) s
+    WHERE p.id = pid AND p.total_stock IS DISTINCT FROM s.total;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER product_variants_sync_total_stock AFTER INSERT OR UPDATE OF stock OR DELETE ON product_variants
+FOR EACH ROW EXECUTE PROCEDURE product_variants_sync_total_stock();
+
+-- Каждый продавец получает основной склад, текущие остатки переносятся на него
+INSERT INTO warehouses (user_id, name, code, type, is_default)
+SELECT id, 'Основной склад', 'MAIN', 'own', TRUE FROM users;
+
+INSERT INTO warehouse_stocks (warehouse_id, variant_id, stock, reserved)
+SELECT w.id, v.id, GREATEST(v.stock, 0), GREATEST(v.reserved, 0)
+FROM product_variants v
+JOIN products p ON p.id = v.product_id
+JOIN warehouses w ON w.user_id = p.user_id AND w.is_default;
+
+INSERT INTO stock_movements (user_id, variant_id, to_warehouse_id, quantity, type, comment)
+SELECT w.user_id, ws.variant_id, ws.warehouse_id, ws.stock, 'initial', 'Перенос остатка на основной склад'
+FROM warehouse_stocks ws
+JOIN warehouses w ON w.id = ws.warehouse_id
+WHERE ws.stock > 0;
@@ -0,0 +1,8 @@
+-- +migrate Down
+
+DROP TABLE IF EXISTS marking_codes;
+DROP TABLE IF EXISTS marking_reports;
+DROP TABLE IF EXISTS marking_code_batches;
+ALTER TABLE users DROP COLUMN IF EXISTS inn;
+DROP INDEX IF EXISTS idx_product_variants_gtin;
+ALTER TABLE product_variants DROP COLUMN IF EXISTS gtin;
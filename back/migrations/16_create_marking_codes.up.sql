@@ -0,0 +1,84 @@
+-- +migrate Up
+
+-- GTIN варианта: коды маркировки "Честного ЗНАКа" выпускаются на конкретный GTIN
+ALTER TABLE product_variants ADD COLUMN gtin VARCHAR(14);
+CREATE INDEX idx_product_variants_gtin ON product_variants(gtin);
+
+-- ИНН продавца нужен для отчетов о выводе кодов из оборота
+ALTER TABLE users ADD COLUMN inn VARCHAR(12);
+
+-- Загруженные партии кодов маркировки
+CREATE TABLE marking_code_batches (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL,
+    variant_id UUID NOT NULL,
+    gtin VARCHAR(14) NOT NULL,
+    file_name VARCHAR(255),
+    imported INTEGER NOT NULL DEFAULT 0,
+    duplicates INTEGER NOT NULL DEFAULT 0,
+    created_by UUID,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_user FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_variant FOREIGN KEY(variant_id) REFERENCES product_variants(id) ON DELETE CASCADE,
+    CONSTRAINT fk_created_by FOREIGN KEY(created_by) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX idx_marking_code_batches_user_id ON marking_code_batches(user_id, created_at DESC);
+
+-- Отчеты о выводе отгруженных кодов из оборота (дистанционная продажа)
+CREATE TABLE marking_reports (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL,
+    number VARCHAR(50) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'draft' CHECK (status IN ('draft', 'submitted')),
+    codes_count INTEGER NOT NULL DEFAULT 0,
+    total_kopecks BIGINT NOT NULL DEFAULT 0,
+    portal_document_id VARCHAR(100),
+    submitted_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_user FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT uq_marking_reports_user_number UNIQUE(user_id, number)
+);
+
+CREATE INDEX idx_marking_reports_user_id ON marking_reports(user_id, created_at DESC);
+
+-- Коды маркировки. cis - код идентификации (01 GTIN 21 серийный номер), уникален во всей системе;
+-- code - полный код с криптохвостом, как он печатается в Data Matrix
+CREATE TABLE marking_codes (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL,
+    batch_id UUID NOT NULL,
+    variant_id UUID NOT NULL,
+    gtin VARCHAR(14) NOT NULL,
+    cis VARCHAR(64) NOT NULL UNIQUE,
+    code TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'available'
+        CHECK (status IN ('available', 'assigned', 'shipped', 'withdrawn', 'returned')),
+    order_id UUID,
+    order_item_id UUID,
+    report_id UUID,
+    withdraw_reason VARCHAR(50),
+    assigned_at TIMESTAMPTZ,
+    shipped_at TIMESTAMPTZ,
+    withdrawn_at TIMESTAMPTZ,
+    returned_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_user FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_batch FOREIGN KEY(batch_id) REFERENCES marking_code_batches(id) ON DELETE CASCADE,
+    CONSTRAINT fk_variant FOREIGN KEY(variant_id) REFERENCES product_variants(id) ON DELETE CASCADE,
+    CONSTRAINT fk_order FOREIGN KEY(order_id) REFERENCES orders(id) ON DELETE SET NULL,
+    CONSTRAINT fk_order_item FOREIGN KEY(order_item_id) REFERENCES order_items(id) ON DELETE SET NULL,
+    CONSTRAINT fk_report FOREIGN KEY(report_id) REFERENCES marking_reports(id) ON DELETE SET NULL,
+    -- Назначенный или отгруженный код всегда привязан к позиции заказа
+    CONSTRAINT chk_marking_code_order CHECK (status NOT IN ('assigned', 'shipped') OR order_item_id IS NOT NULL)
+);
+
+CREATE INDEX idx_marking_codes_user_status ON marking_codes(user_id, status);
+-- Выдача свободных кодов по варианту в порядке загрузки
+CREATE INDEX idx_marking_codes_variant_available ON marking_codes(variant_id, created_at) WHERE status = 'available';
+CREATE INDEX idx_marking_codes_order_id ON marking_codes(order_id);
+CREATE INDEX idx_marking_codes_batch_id ON marking_codes(batch_id);
+-- Отгруженные коды, еще не попавшие в отчет
+CREATE INDEX idx_marking_codes_unreported ON marking_codes(user_id, shipped_at) WHERE status = 'shipped' AND report_id IS NULL;
+CREATE TRIGGER update_marking_codes_updated_at BEFORE UPDATE ON marking_codes FOR EACH ROW EXECUTE PROCEDURE update_updated_at_column();
@@ -0,0 +1,4 @@
+-- +migrate Down
+
+ALTER TABLE users DROP COLUMN IF EXISTS gs1_next_ref;
+ALTER TABLE users DROP COLUMN IF EXISTS gs1_prefix;
@@ -0,0 +1,6 @@
+-- +migrate Up
+
+-- Префикс компании GS1, из диапазона которого продавцу выпускаются EAN-13,
+-- и следующий свободный номер товара в этом диапазоне
+ALTER TABLE users ADD COLUMN gs1_prefix VARCHAR(11);
+ALTER TABLE users ADD COLUMN gs1_next_ref BIGINT NOT NULL DEFAULT 0;
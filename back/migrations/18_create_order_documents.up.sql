@@ -0,0 +1,20 @@
+-- +migrate Up
+
+-- Номера документов по заказу (упаковочный лист, счет). Номер присваивается при первой печати
+-- и дальше не меняется, чтобы повторно распечатанный документ совпадал с выданным ранее.
+-- data - содержимое документа на момент выдачи номера: повторная печать не зависит от правок заказа
+CREATE TABLE order_documents (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL,
+    order_id UUID NOT NULL,
+    type VARCHAR(20) NOT NULL CHECK (type IN ('packing_slip', 'invoice')),
+    number VARCHAR(50) NOT NULL,
+    issued_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    data JSONB NOT NULL,
+    CONSTRAINT fk_user FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_order FOREIGN KEY(order_id) REFERENCES orders(id) ON DELETE CASCADE,
+    CONSTRAINT uq_order_documents_order_type UNIQUE(order_id, type),
+    CONSTRAINT uq_order_documents_user_number UNIQUE(user_id, number)
+);
+
+CREATE INDEX idx_order_documents_user_id ON order_documents(user_id, issued_at DESC);
@@ -0,0 +1,4 @@
+-- +migrate Down
+
+ALTER TABLE order_items DROP COLUMN IF EXISTS cancelled_quantity;
+DROP TABLE IF EXISTS order_cancellations;
@@ -0,0 +1,24 @@
+-- +migrate Up
+
+-- Отмены заказов: полные и частичные (по отдельным позициям) с причиной и инициатором
+CREATE TABLE order_cancellations (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL,
+    order_id UUID NOT NULL,
+    reason VARCHAR(50) NOT NULL,
+    initiator VARCHAR(20) NOT NULL CHECK (initiator IN ('seller', 'customer')),
+    comment TEXT,
+    full_cancel BOOLEAN NOT NULL DEFAULT FALSE,
+    items JSONB NOT NULL DEFAULT '[]',
+    refund_amount NUMERIC(12, 2) NOT NULL DEFAULT 0,
+    seller_amount NUMERIC(12, 2) NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_user FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_order FOREIGN KEY(order_id) REFERENCES orders(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_order_cancellations_order_id ON order_cancellations(order_id);
+CREATE INDEX idx_order_cancellations_user_id ON order_cancellations(user_id, created_at DESC);
+
+-- Сколько единиц позиции отменено. Quantity позиции уменьшается на эту величину
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS cancelled_quantity INT NOT NULL DEFAULT 0;
@@ -0,0 +1,5 @@
+-- +migrate Down
+
+ALTER TABLE order_items DROP COLUMN IF EXISTS shipped_quantity;
+DROP TABLE IF EXISTS shipment_items;
+DROP TABLE IF EXISTS shipments;
@@ -0,0 +1,42 @@
+-- +migrate Up
+
+-- Отправления: заказ может уходить несколькими посылками со своими трек-номерами и перевозчиками
+CREATE TABLE shipments (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL,
+    order_id UUID NOT NULL,
+    number VARCHAR(120) NOT NULL,
+    warehouse_id UUID,
+    carrier VARCHAR(100),
+    tracking_number VARCHAR(100),
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'shipped', 'delivered', 'cancelled')),
+    shipped_at TIMESTAMPTZ,
+    delivered_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_user FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_order FOREIGN KEY(order_id) REFERENCES orders(id) ON DELETE CASCADE,
+    CONSTRAINT fk_warehouse FOREIGN KEY(warehouse_id) REFERENCES warehouses(id) ON DELETE SET NULL,
+    CONSTRAINT uq_shipments_order_number UNIQUE(order_id, number)
+);
+
+CREATE INDEX idx_shipments_order_id ON shipments(order_id);
+CREATE INDEX idx_shipments_tracking_number ON shipments(tracking_number) WHERE tracking_number IS NOT NULL;
+
+CREATE TABLE shipment_items (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    shipment_id UUID NOT NULL,
+    order_item_id UUID NOT NULL,
+    quantity INT NOT NULL CHECK (quantity > 0),
+    CONSTRAINT fk_shipment FOREIGN KEY(shipment_id) REFERENCES shipments(id) ON DELETE CASCADE,
+    CONSTRAINT fk_order_item FOREIGN KEY(order_item_id) REFERENCES order_items(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_shipment_items_shipment_id ON shipment_items(shipment_id);
+CREATE INDEX idx_shipment_items_order_item_id ON shipment_items(order_item_id);
+
+-- Сколько единиц позиции уже отгружено (посылками или всем заказом при смене статуса на in_transit)
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS shipped_quantity INT NOT NULL DEFAULT 0;
+UPDATE order_items oi SET shipped_quantity = oi.quantity
+FROM orders o
+WHERE o.id = oi.order_id AND o.status IN ('in_transit', 'delivered', 'returned');
@@ -0,0 +1,4 @@
+-- +migrate Down
+
+ALTER TABLE shipments DROP COLUMN IF EXISTS tracking_polled_at;
+DROP TABLE IF EXISTS tracking_events;
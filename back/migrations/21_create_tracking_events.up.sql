@@ -0,0 +1,24 @@
+-- +migrate Up
+
+-- События отслеживания посылок, полученные от перевозчиков
+CREATE TABLE tracking_events (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL,
+    order_id UUID NOT NULL,
+    shipment_id UUID NOT NULL,
+    external_id VARCHAR(100) NOT NULL,
+    status VARCHAR(30) NOT NULL,
+    description TEXT,
+    location VARCHAR(255),
+    occurred_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_user FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_order FOREIGN KEY(order_id) REFERENCES orders(id) ON DELETE CASCADE,
+    CONSTRAINT fk_shipment FOREIGN KEY(shipment_id) REFERENCES shipments(id) ON DELETE CASCADE,
+    CONSTRAINT uq_tracking_events_shipment_external UNIQUE(shipment_id, external_id)
+);
+
+CREATE INDEX idx_tracking_events_order_id ON tracking_events(order_id, occurred_at);
+
+-- Когда отправление последний раз опрашивалось у перевозчика
+ALTER TABLE shipments ADD COLUMN IF NOT EXISTS tracking_polled_at TIMESTAMPTZ;
@@ -0,0 +1,13 @@
+-- +migrate Down
+
+DROP TRIGGER IF EXISTS webhook_returns_outbox ON order_returns;
+DROP TRIGGER IF EXISTS webhook_stock_alerts_outbox ON stock_alerts;
+DROP TRIGGER IF EXISTS webhook_products_outbox ON products;
+DROP TRIGGER IF EXISTS webhook_orders_outbox ON orders;
+DROP FUNCTION IF EXISTS webhook_returns_outbox();
+DROP FUNCTION IF EXISTS webhook_stock_alerts_outbox();
+DROP FUNCTION IF EXISTS webhook_products_outbox();
+DROP FUNCTION IF EXISTS webhook_orders_outbox();
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhook_events;
+DROP TABLE IF EXISTS webhook_endpoints;
@@ -0,0 +1,184 @@
+-- +migrate Up
+
+-- Адреса, на которые продавец получает события (вебхуки). Пустой event_types - все события
+CREATE TABLE webhook_endpoints (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL,
+    url VARCHAR(2048) NOT NULL,
+    secret VARCHAR(100) NOT NULL,
+    event_types TEXT[] NOT NULL DEFAULT '{}',
+    description VARCHAR(255),
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_user FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_webhook_endpoints_user_id ON webhook_endpoints(user_id);
+CREATE TRIGGER update_webhook_endpoints_updated_at BEFORE UPDATE ON webhook_endpoints FOR EACH ROW EXECUTE PROCEDURE update_updated_at_column();
+
+-- Исходящие события (transactional outbox). Пишутся триггерами в той же транзакции, что и изменение,
+-- поэтому событие не теряется при падении сервера. Диспетчер раскладывает их по адресам продавца
+CREATE TABLE webhook_events (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL,
+    type VARCHAR(50) NOT NULL,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    dispatched_at TIMESTAMPTZ,
+    CONSTRAINT fk_user FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_webhook_events_pending ON webhook_events(created_at) WHERE dispatched_at IS NULL;
+CREATE INDEX idx_webhook_events_user_id ON webhook_events(user_id, created_at DESC);
+-- Очистка журнала: разосланные события старше срока хранения удаляются вместе с их доставками
+CREATE INDEX idx_webhook_events_dispatched ON webhook_events(dispatched_at) WHERE dispatched_at IS NOT NULL;
+
+-- Доставка события на адрес: попытки, ответ последней попытки и время следующей
+CREATE TABLE webhook_deliveries (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL,
+    endpoint_id UUID NOT NULL,
+    event_id UUID NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'succeeded', 'failed')),
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_attempt_at TIMESTAMPTZ,
+    response_status INT,
+    response_body TEXT,
+    error TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_user FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_endpoint FOREIGN KEY(endpoint_id) REFERENCES webhook_endpoints(id) ON DELETE CASCADE,
+    CONSTRAINT fk_event FOREIGN KEY(event_id) REFERENCES webhook_events(id) ON DELETE CASCADE,
+    CONSTRAINT uq_webhook_deliveries_endpoint_event UNIQUE(endpoint_id, event_id)
+);
+
+CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX idx_webhook_deliveries_user_id ON webhook_deliveries(user_id, created_at DESC);
+CREATE TRIGGER update_webhook_deliveries_updated_at BEFORE UPDATE ON webhook_deliveries FOR EACH ROW EXECUTE PROCEDURE update_updated_at_column();
+
+-- order.created и order.status_changed
+CREATE OR REPLACE FUNCTION webhook_orders_outbox() RETURNS TRIGGER AS $$
+BEGIN
+    IF NEW.user_id IS NULL THEN
+        RETURN NULL;
+    END IF;
+    IF TG_OP = 'INSERT' THEN
+        INSERT INTO webhook_events (user_id, type, payload)
+        VALUES (NEW.user_id, 'order.created', jsonb_build_object(
+            'order_id', NEW.id,
+            'order_number', NEW.order_number,
+            'date', NEW.date,
+            'status', NEW.status,
+            'customer', NEW.customer,
+            'delivery', NEW.delivery,
+            'totals', NEW.totals
+        ));
+    ELSIF OLD.status IS DISTINCT FROM NEW.status THEN
+        INSERT INTO webhook_events (user_id, type, payload)
+        VALUES (NEW.user_id, 'order.status_changed', jsonb_build_object(
+            'order_id', NEW.id,
+            'order_number', NEW.order_number,
+            'old_status', OLD.status,
+            'status', NEW.status,
+            'changed_at', NOW()
+        ));
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER webhook_orders_outbox AFTER INSERT OR UPDATE OF status ON orders
+FOR EACH ROW EXECUTE PROCEDURE webhook_orders_outbox();
+
+-- product.updated: только при изменении карточки, служебные и вычисляемые поля не в счет.
+-- Событие пишется на каждое сохранение карточки, поэтому создается, только если у продавца
+-- есть активный вебхук, подписанный на него
+CREATE OR REPLACE FUNCTION webhook_products_outbox() RETURNS TRIGGER AS $$
+DECLARE
+    ignored TEXT[] := ARRAY['updated_at', 'total_stock', 'search_vector', 'search_text', 'rating', 'reviews_count', 'return_rate'];
+    changed TEXT[];
+BEGIN
+    IF NEW.user_id IS NULL THEN
+        RETURN NULL;
+    END IF;
+    IF NOT EXISTS (
+        SELECT 1 FROM webhook_endpoints w
+        WHERE w.user_id = NEW.user_id AND w.is_active
+            AND (cardinality(w.event_types) = 0 OR 'product.updated' = ANY(w.event_types))
+    ) THEN
+        RETURN NULL;
+    END IF;
+    SELECT array_agg(n.key ORDER BY n.key) INTO changed
+    FROM jsonb_each(to_jsonb(NEW)) n
+    JOIN jsonb_each(to_jsonb(OLD)) o ON o.key = n.key
+    WHERE n.value IS DISTINCT FROM o.value AND NOT n.key = ANY(ignored);
+    IF changed IS NULL THEN
+        RETURN NULL;
+    END IF;
+
+    INSERT INTO webhook_events (user_id, type, payload)
+    VALUES (NEW.user_id, 'product.updated', jsonb_build_object(
+        'product_id', NEW.id,
+        'sku', NEW.sku,
+        'name', NEW.name,
+        'status', NEW.status,
+        'price', NEW.price,
+        'deleted', NEW.deleted_at IS NOT NULL,
+        'changed_fields', to_jsonb(changed)
+    ));
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER webhook_products_outbox AFTER UPDATE ON products
+FOR EACH ROW EXECUTE PROCEDURE webhook_products_outbox();
+
+-- stock.low: открыто оповещение о низком остатке или отсутствии товара
+CREATE OR REPLACE FUNCTION webhook_stock_alerts_outbox() RETURNS TRIGGER AS $$
+BEGIN
+    INSERT INTO webhook_events (user_id, type, payload)
+    SELECT NEW.user_id, 'stock.low', jsonb_build_object(
+        'alert_id', NEW.id,
+        'type', NEW.type,
+        'product_id', NEW.product_id,
+        'variant_id', NEW.variant_id,
+        'sku', v.sku,
+        'available', NEW.available,
+        'reorder_point', NEW.reorder_point
+    )
+    FROM product_variants v WHERE v.id = NEW.variant_id;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER webhook_stock_alerts_outbox AFTER INSERT ON stock_alerts
+FOR EACH ROW EXECUTE PROCEDURE webhook_stock_alerts_outbox();
+
+-- return.created
+CREATE OR REPLACE FUNCTION webhook_returns_outbox() RETURNS TRIGGER AS $$
+BEGIN
+    INSERT INTO webhook_events (user_id, type, payload)
+    SELECT o.user_id, 'return.created', jsonb_build_object(
+        'return_id', NEW.id,
+        'order_id', o.id,
+        'order_number', o.order_number,
+        'order_item_id', NEW.order_item_id,
+        'sku', oi.sku,
+        'quantity', NEW.quantity,
+        'reason_code', NEW.reason_code,
+        'comment', NEW.comment,
+        'status', NEW.status,
+        'returned_at', NEW.returned_at
+    )
+    FROM order_items oi
+    JOIN orders o ON o.id = oi.order_id
+    WHERE oi.id = NEW.order_item_id AND o.user_id IS NOT NULL;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER webhook_returns_outbox AFTER INSERT ON order_returns
+FOR EACH ROW EXECUTE PROCEDURE webhook_returns_outbox();
@@ -0,0 +1,49 @@
+-- +migrate Down
+
+DROP TABLE IF EXISTS stream_tickets;
+
+CREATE OR REPLACE FUNCTION webhook_products_outbox() RETURNS TRIGGER AS $$
+DECLARE
+    ignored TEXT[] := ARRAY['updated_at', 'total_stock', 'search_vector', 'search_text', 'rating', 'reviews_count', 'return_rate'];
+    changed TEXT[];
+BEGIN
+    IF NEW.user_id IS NULL THEN
+        RETURN NULL;
+    END IF;
+    IF NOT EXISTS (
+        SELECT 1 FROM webhook_endpoints w
+        WHERE w.user_id = NEW.user_id AND w.is_active
+            AND (cardinality(w.event_types) = 0 OR 'product.updated' = ANY(w.event_types))
+    ) THEN
+        RETURN NULL;
+    END IF;
+    SELECT array_agg(n.key ORDER BY n.key) INTO changed
+    FROM jsonb_each(to_jsonb(NEW)) n
+    JOIN jsonb_each(to_jsonb(OLD)) o ON o.key = n.key
+    WHERE n.value IS DISTINCT FROM o.value AND NOT n.key = ANY(ignored);
+    IF changed IS NULL THEN
+        RETURN NULL;
+    END IF;
+
+    INSERT INTO webhook_events (user_id, type, payload)
+    VALUES (NEW.user_id, 'product.updated', jsonb_build_object(
+        'product_id', NEW.id,
+        'sku', NEW.sku,
+        'name', NEW.name,
+        'status', NEW.status,
+        'price', NEW.price,
+        'deleted', NEW.deleted_at IS NOT NULL,
+        'changed_fields', to_jsonb(changed)
+    ));
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+ALTER TABLE users DROP COLUMN IF EXISTS events_stream_at;
+
+DROP TRIGGER IF EXISTS webhook_events_notify ON webhook_events;
+DROP FUNCTION IF EXISTS webhook_events_notify();
+DROP INDEX IF EXISTS idx_webhook_events_user_txid;
+ALTER TABLE webhook_events DROP COLUMN IF EXISTS txid;
+DROP INDEX IF EXISTS idx_webhook_events_user_seq;
+DROP INDEX IF EXISTS idx_webhook_events_seq;
+ALTER TABLE webhook_events DROP COLUMN IF EXISTS seq;
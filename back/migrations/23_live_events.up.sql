@@ -0,0 +1,81 @@
+-- +migrate Up
+
+-- Очередь webhook_events служит и журналом событий для живых обновлений (SSE).
+-- Порядковый номер - ID события в потоке, по нему клиент возобновляет поток (Last-Event-ID)
+ALTER TABLE webhook_events ADD COLUMN seq BIGINT GENERATED ALWAYS AS IDENTITY;
+CREATE UNIQUE INDEX idx_webhook_events_seq ON webhook_events(seq);
+CREATE INDEX idx_webhook_events_user_seq ON webhook_events(user_id, seq);
+
+-- Номер seq выдается при вставке, а не при фиксации: событие с меньшим номером может стать
+-- видимым позже события с большим, и чтение "seq больше последнего" его пропустит.
+-- Поэтому поток продолжается по номеру транзакции: все события транзакций с номером меньше
+-- pg_snapshot_xmin(pg_current_snapshot()) уже зафиксированы или откачены, новых среди них не появится
+ALTER TABLE webhook_events ADD COLUMN txid BIGINT NOT NULL DEFAULT (pg_current_xact_id()::text::bigint);
+CREATE INDEX idx_webhook_events_user_txid ON webhook_events(user_id, txid);
+
+-- Уведомление всем экземплярам сервера. Доставляется после фиксации транзакции,
+-- поэтому откаченные изменения в поток не попадают. Формат: "<user_id>:<seq>"
+CREATE OR REPLACE FUNCTION webhook_events_notify() RETURNS TRIGGER AS $$
+BEGIN
+    PERFORM pg_notify('seller_events', NEW.user_id::text || ':' || NEW.seq::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER webhook_events_notify AFTER INSERT ON webhook_events
+FOR EACH ROW EXECUTE PROCEDURE webhook_events_notify();
+
+-- Последнее подключение продавца к потоку. product.updated нужен и потоку: событие создается,
+-- если его кто-то получит - активный вебхук, подписанный на него, или поток, открытый за последние сутки
+ALTER TABLE users ADD COLUMN events_stream_at TIMESTAMPTZ;
+
+CREATE OR REPLACE FUNCTION webhook_products_outbox() RETURNS TRIGGER AS $$
+DECLARE
+    ignored TEXT[] := ARRAY['updated_at', 'total_stock', 'search_vector', 'search_text', 'rating', 'reviews_count', 'return_rate'];
+    changed TEXT[];
+BEGIN
+    IF NEW.user_id IS NULL THEN
+        RETURN NULL;
+    END IF;
+    IF NOT EXISTS (
+        SELECT 1 FROM webhook_endpoints w
+        WHERE w.user_id = NEW.user_id AND w.is_active
+            AND (cardinality(w.event_types) = 0 OR 'product.updated' = ANY(w.event_types))
+    ) AND NOT EXISTS (
+        SELECT 1 FROM users u
+        WHERE u.id = NEW.user_id AND u.events_stream_at > NOW() - INTERVAL '1 day'
+    ) THEN
+        RETURN NULL;
+    END IF;
+    SELECT array_agg(n.key ORDER BY n.key) INTO changed
+    FROM jsonb_each(to_jsonb(NEW)) n
+    JOIN jsonb_each(to_jsonb(OLD)) o ON o.key = n.key
+    WHERE n.value IS DISTINCT FROM o.value AND NOT n.key = ANY(ignored);
+    IF changed IS NULL THEN
+        RETURN NULL;
+    END IF;
+
+    INSERT INTO webhook_events (user_id, type, payload)
+    VALUES (NEW.user_id, 'product.updated', jsonb_build_object(
+        'product_id', NEW.id,
+        'sku', NEW.sku,
+        'name', NEW.name,
+        'status', NEW.status,
+        'price', NEW.price,
+        'deleted', NEW.deleted_at IS NOT NULL,
+        'changed_fields', to_jsonb(changed)
+    ));
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Билеты на подключение к потоку живых обновлений. EventSource не умеет передавать заголовки,
+-- а JWT в адресе попадает в журналы прокси и сервера, поэтому в адресе передается билет,
+-- который годится только для потока и истекает вскоре после закрытия потока. Хранится только SHA-256 билета
+CREATE TABLE stream_tickets (
+    token_hash TEXT PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX idx_stream_tickets_expires_at ON stream_tickets(expires_at);
@@ -0,0 +1,9 @@
+-- +migrate Down
+
+DROP TRIGGER IF EXISTS notify_return_created ON order_returns;
+DROP TRIGGER IF EXISTS notify_order_created ON orders;
+DROP FUNCTION IF EXISTS notify_return_created();
+DROP FUNCTION IF EXISTS notify_order_created();
+DROP FUNCTION IF EXISTS create_notification(UUID, TEXT, TEXT, TEXT, TEXT, TEXT, JSONB);
+DROP TABLE IF EXISTS notifications;
+DROP TABLE IF EXISTS notification_preferences;
@@ -0,0 +1,92 @@
+-- +migrate Up
+
+-- Как продавец получает уведомления категории. Нет строки - только в приложении
+CREATE TABLE notification_preferences (
+    user_id UUID NOT NULL,
+    category VARCHAR(20) NOT NULL CHECK (category IN ('orders', 'stock', 'returns', 'balance', 'webhooks')),
+    channel VARCHAR(20) NOT NULL DEFAULT 'in_app' CHECK (channel IN ('in_app', 'in_app_email')),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, category),
+    CONSTRAINT fk_user FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TRIGGER update_notification_preferences_updated_at BEFORE UPDATE ON notification_preferences FOR EACH ROW EXECUTE PROCEDURE update_updated_at_column();
+
+-- Уведомления центра уведомлений. link - путь страницы приложения, на которую ведет уведомление.
+-- email_status - очередь писем: pending ждет отправки, sending взято отправителем
+CREATE TABLE notifications (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL,
+    category VARCHAR(20) NOT NULL CHECK (category IN ('orders', 'stock', 'returns', 'balance', 'webhooks')),
+    type VARCHAR(50) NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    body TEXT,
+    link VARCHAR(500),
+    data JSONB NOT NULL DEFAULT '{}'::jsonb,
+    read_at TIMESTAMPTZ,
+    email_status VARCHAR(20) NOT NULL DEFAULT 'none' CHECK (email_status IN ('none', 'pending', 'sending', 'sent', 'failed')),
+    email_attempts INT NOT NULL DEFAULT 0,
+    emailed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_user FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_notifications_user_id ON notifications(user_id, created_at DESC);
+CREATE INDEX idx_notifications_unread ON notifications(user_id, category) WHERE read_at IS NULL;
+CREATE INDEX idx_notifications_email ON notifications(created_at) WHERE email_status IN ('pending', 'sending');
+
+-- Создает уведомление с учетом настройки категории. Вызывается из кода приложения
+-- и из триггеров для событий, которые приходят в БД минуя приложение
+CREATE OR REPLACE FUNCTION create_notification(
+    p_user_id UUID, p_category TEXT, p_type TEXT, p_title TEXT, p_body TEXT, p_link TEXT, p_data JSONB
+) RETURNS UUID AS $$
+DECLARE
+    v_channel TEXT;
+    v_id UUID;
+BEGIN
+    SELECT np.channel INTO v_channel FROM notification_preferences np
+    WHERE np.user_id = p_user_id AND np.category = p_category;
+
+    INSERT INTO notifications (user_id, category, type, title, body, link, data, email_status)
+    VALUES (p_user_id, p_category, p_type, p_title, p_body, p_link, COALESCE(p_data, '{}'::jsonb),
+        CASE WHEN v_channel = 'in_app_email' THEN 'pending' ELSE 'none' END)
+    RETURNING id INTO v_id;
+    RETURN v_id;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Новые заказы приходят из маркетплейса напрямую в БД
+CREATE OR REPLACE FUNCTION notify_order_created() RETURNS TRIGGER AS $$
+BEGIN
+    IF NEW.user_id IS NOT NULL THEN
+        PERFORM create_notification(NEW.user_id, 'orders', 'order.created',
+            'Новый заказ №' || NEW.order_number,
+            'Сумма заказа: ' || COALESCE(NEW.totals->>'total', '0') || ' ₽',
+            '/orders/' || NEW.id,
+            jsonb_build_object('order_id', NEW.id, 'order_number', NEW.order_number));
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER notify_order_created AFTER INSERT ON orders
+FOR EACH ROW EXECUTE PROCEDURE notify_order_created();
+
+-- Возвраты оформляются на стороне маркетплейса
+CREATE OR REPLACE FUNCTION notify_return_created() RETURNS TRIGGER AS $$
+BEGIN
+    PERFORM create_notification(o.user_id, 'returns', 'return.created',
+        'Возврат по заказу №' || o.order_number,
+        COALESCE(oi.sku, oi.name, 'Товар') || ' × ' || NEW.quantity || ', причина: ' || COALESCE(rr.name, NEW.reason_code),
+        '/orders/' || o.id,
+        jsonb_build_object('return_id', NEW.id, 'order_id', o.id, 'order_item_id', NEW.order_item_id))
+    FROM order_items oi
+    JOIN orders o ON o.id = oi.order_id
+    LEFT JOIN return_reasons rr ON rr.code = NEW.reason_code
+    WHERE oi.id = NEW.order_item_id AND o.user_id IS NOT NULL;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER notify_return_created AFTER INSERT ON order_returns
+FOR EACH ROW EXECUTE PROCEDURE notify_return_created();
@@ -0,0 +1,13 @@
+-- +migrate Down
+
+DROP TRIGGER IF EXISTS returns_refresh_customer ON order_returns;
+DROP TRIGGER IF EXISTS orders_refresh_customer_on_delete ON orders;
+DROP TRIGGER IF EXISTS orders_refresh_customer ON orders;
+DROP TRIGGER IF EXISTS orders_resolve_customer ON orders;
+DROP FUNCTION IF EXISTS returns_refresh_customer();
+DROP FUNCTION IF EXISTS orders_refresh_customer();
+DROP FUNCTION IF EXISTS orders_resolve_customer();
+DROP FUNCTION IF EXISTS refresh_customer_stats(UUID);
+DROP FUNCTION IF EXISTS resolve_customer(UUID, UUID, JSONB, TIMESTAMPTZ);
+ALTER TABLE orders DROP COLUMN IF EXISTS customer_ref;
+DROP TABLE IF EXISTS customers;
@@ -0,0 +1,192 @@
+-- +migrate Up
+
+-- Справочник покупателей продавца. Строится из снимков customer в заказах: покупатель определяется
+-- по customer_id, а для заказов без него - по email. Статистика пересчитывается триггерами
+CREATE TABLE customers (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL,
+    customer_id UUID,
+    email VARCHAR(255) NOT NULL DEFAULT '',
+    name VARCHAR(255) NOT NULL DEFAULT '',
+    phone VARCHAR(50) NOT NULL DEFAULT '',
+    orders_count INT NOT NULL DEFAULT 0,        -- Заказы без отмененных
+    cancelled_orders_count INT NOT NULL DEFAULT 0,
+    revenue NUMERIC(14, 2) NOT NULL DEFAULT 0,  -- Сумма неотмененных заказов
+    avg_check NUMERIC(14, 2) NOT NULL DEFAULT 0,
+    returns_count INT NOT NULL DEFAULT 0,       -- Единиц товара в возвратах
+    returned_amount NUMERIC(14, 2) NOT NULL DEFAULT 0,
+    is_regular BOOLEAN GENERATED ALWAYS AS (orders_count > 1) STORED,
+    first_order_at TIMESTAMPTZ,
+    last_order_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_user FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX uq_customers_user_customer_id ON customers(user_id, customer_id) WHERE customer_id IS NOT NULL;
+CREATE INDEX idx_customers_user_email ON customers(user_id, lower(email)) WHERE email <> '';
+-- Покупатель без customer_id определяется по email: одна запись на email у продавца
+CREATE UNIQUE INDEX uq_customers_user_email ON customers(user_id, lower(email)) WHERE customer_id IS NULL AND email <> '';
+CREATE INDEX idx_customers_user_last_order ON customers(user_id, last_order_at DESC);
+CREATE TRIGGER update_customers_updated_at BEFORE UPDATE ON customers FOR EACH ROW EXECUTE PROCEDURE update_updated_at_column();
+
+ALTER TABLE orders ADD COLUMN customer_ref UUID REFERENCES customers(id) ON DELETE SET NULL;
+CREATE INDEX idx_orders_customer_ref ON orders(customer_ref);
+
+-- resolve_customer находит покупателя продавца по снимку заказа или создает его (INSERT ... ON CONFLICT,
+-- поэтому одновременные первые заказы получают одну запись). Покупатель, известный только по email,
+-- получает customer_id при первом заказе с ним. Контакты обновляются только заказом не старше
+-- последнего заказа покупателя, чтобы правка старого заказа не вернула устаревший телефон или имя
+CREATE OR REPLACE FUNCTION resolve_customer(p_user_id UUID, p_customer_id UUID, p_customer JSONB, p_date TIMESTAMPTZ) RETURNS UUID AS $$
+DECLARE
+    v_email TEXT := lower(trim(COALESCE(p_customer->>'email', '')));
+    v_id UUID;
+BEGIN
+    IF p_user_id IS NULL THEN
+        RETURN NULL;
+    END IF;
+    IF p_customer_id = '00000000-0000-0000-0000-000000000000'::uuid THEN
+        p_customer_id := NULL;
+    END IF;
+
+    IF p_customer_id IS NOT NULL THEN
+        SELECT id INTO v_id FROM customers WHERE user_id = p_user_id AND customer_id = p_customer_id;
+        IF v_id IS NULL AND v_email <> '' THEN
+            BEGIN
+                UPDATE customers SET customer_id = p_customer_id
+                WHERE user_id = p_user_id AND customer_id IS NULL AND email <> '' AND lower(email) = v_email
+                RETURNING id INTO v_id;
+            EXCEPTION WHEN unique_violation THEN
+                v_id := NULL; -- Покупателя с этим customer_id только что создал параллельный заказ
+            END;
+        END IF;
+        IF v_id IS NULL THEN
+            INSERT INTO customers (user_id, customer_id, email)
+            VALUES (p_user_id, p_customer_id, v_email)
+            ON CONFLICT (user_id, customer_id) WHERE customer_id IS NOT NULL
+            DO UPDATE SET customer_id = EXCLUDED.customer_id
+            RETURNING id INTO v_id;
+        END IF;
+    ELSIF v_email <> '' THEN
+        SELECT id INTO v_id FROM customers
+        WHERE user_id = p_user_id AND lower(email) = v_email
+        ORDER BY customer_id NULLS FIRST, created_at LIMIT 1;
+        IF v_id IS NULL THEN
+            INSERT INTO customers (user_id, email)
+            VALUES (p_user_id, v_email)
+            ON CONFLICT (user_id, lower(email)) WHERE customer_id IS NULL AND email <> ''
+            DO UPDATE SET email = EXCLUDED.email
+            RETURNING id INTO v_id;
+        END IF;
+    ELSE
+        RETURN NULL; -- Анонимный заказ
+    END IF;
+
+    UPDATE customers SET
+        email = CASE WHEN v_email <> '' THEN v_email ELSE email END,
+        name = COALESCE(NULLIF(p_customer->>'name', ''), name),
+        phone = COALESCE(NULLIF(p_customer->>'phone', ''), phone)
+    WHERE id = v_id AND (p_date IS NULL OR last_order_at IS NULL OR p_date >= last_order_at);
+    RETURN v_id;
+END;
+$$ LANGUAGE plpgsql;
+
+-- refresh_customer_stats пересчитывает статистику покупателя по его заказам и возвратам
+CREATE OR REPLACE FUNCTION refresh_customer_stats(p_id UUID) RETURNS VOID AS $$
+BEGIN
+    IF p_id IS NULL THEN
+        RETURN;
+    END IF;
+    UPDATE customers c SET
+        orders_count = s.orders_count,
+        cancelled_orders_count = s.cancelled_orders_count,
+        revenue = s.revenue,
+        avg_check = CASE WHEN s.orders_count > 0 THEN round(s.revenue / s.orders_count, 2) ELSE 0 END,
+        returns_count = r.returns_count,
+        returned_amount = r.returned_amount,
+        first_order_at = s.first_order_at,
+        last_order_at = s.last_order_at
+    FROM (
+        SELECT
+            COUNT(*) FILTER (WHERE o.status <> 'cancelled') AS orders_count,
+            COUNT(*) FILTER (WHERE o.status = 'cancelled') AS cancelled_orders_count,
+            COALESCE(SUM((o.totals->>'total')::numeric) FILTER (WHERE o.status <> 'cancelled'), 0) AS revenue,
+            MIN(o.date) AS first_order_at,
+            MAX(o.date) AS last_order_at
+        FROM orders o WHERE o.customer_ref = p_id
+    ) s, (
+        SELECT
+            COALESCE(SUM(ret.quantity), 0) AS returns_count,
+            COALESCE(SUM(ret.quantity * oi.price), 0) AS returned_amount
+        FROM order_returns ret
+        JOIN order_items oi ON oi.id = ret.order_item_id
+        JOIN orders o ON o.id = oi.order_id
+        WHERE o.customer_ref = p_id AND ret.status <> 'rejected'
+    ) r
+    WHERE c.id = p_id;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Покупатель определяется заново только при смене продавца или покупателя заказа,
+-- а не при каждом сохранении заказа целиком (смена статуса, отмена)
+CREATE OR REPLACE FUNCTION orders_resolve_customer() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'UPDATE'
+        AND OLD.user_id IS NOT DISTINCT FROM NEW.user_id
+        AND OLD.customer_id IS NOT DISTINCT FROM NEW.customer_id
+        AND OLD.customer IS NOT DISTINCT FROM NEW.customer THEN
+        RETURN NEW;
+    END IF;
+    NEW.customer_ref := resolve_customer(NEW.user_id, NEW.customer_id, NEW.customer, NEW.date);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER orders_resolve_customer BEFORE INSERT OR UPDATE OF user_id, customer_id, customer ON orders
+FOR EACH ROW EXECUTE PROCEDURE orders_resolve_customer();
+
+CREATE OR REPLACE FUNCTION orders_refresh_customer() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP <> 'INSERT' AND OLD.customer_ref IS DISTINCT FROM NEW.customer_ref THEN
+        PERFORM refresh_customer_stats(OLD.customer_ref);
+    END IF;
+    IF TG_OP <> 'DELETE' THEN
+        PERFORM refresh_customer_stats(NEW.customer_ref);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER orders_refresh_customer AFTER INSERT OR UPDATE OF customer_ref, status, date, totals ON orders
+FOR EACH ROW EXECUTE PROCEDURE orders_refresh_customer();
+
+CREATE TRIGGER orders_refresh_customer_on_delete AFTER DELETE ON orders
+FOR EACH ROW EXECUTE PROCEDURE orders_refresh_customer();
+
+CREATE OR REPLACE FUNCTION returns_refresh_customer() RETURNS TRIGGER AS $$
+DECLARE
+    v_item_id UUID := CASE WHEN TG_OP = 'DELETE' THEN OLD.order_item_id ELSE NEW.order_item_id END;
+BEGIN
+    PERFORM refresh_customer_stats(o.customer_ref)
+    FROM order_items oi JOIN orders o ON o.id = oi.order_id
+    WHERE oi.id = v_item_id;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER returns_refresh_customer AFTER INSERT OR UPDATE OF status, quantity OR DELETE ON order_returns
+FOR EACH ROW EXECUTE PROCEDURE returns_refresh_customer();
+
+-- Заполнение справочника по существующим заказам: старые заказы первыми, чтобы контакты
+-- покупателя взялись из последнего заказа. updated_at заказов при этом не меняется
+ALTER TABLE orders DISABLE TRIGGER update_orders_updated_at;
+DO $$
+DECLARE
+    rec RECORD;
+BEGIN
+    FOR rec IN SELECT id, user_id, customer_id, customer, date FROM orders ORDER BY date, created_at LOOP
+        UPDATE orders SET customer_ref = resolve_customer(rec.user_id, rec.customer_id, rec.customer, rec.date) WHERE id = rec.id;
+    END LOOP;
+END;
+$$;
+ALTER TABLE orders ENABLE TRIGGER update_orders_updated_at;
@@ -0,0 +1,7 @@
+-- +migrate Down
+
+ALTER TABLE users DROP COLUMN IF EXISTS is_admin;
+ALTER TABLE products DROP COLUMN IF EXISTS attributes;
+
+DROP TABLE IF EXISTS category_attributes;
+DROP TABLE IF EXISTS categories;
@@ -0,0 +1,88 @@
+-- +migrate Up
+
+-- =================================================================
+-- Иерархический справочник категорий и схемы атрибутов
+-- =================================================================
+
+-- Таблица категорий (categories)
+-- products.category / products.subcategory ссылаются на categories.slug
+CREATE TABLE categories (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    parent_id UUID,
+    slug VARCHAR(100) NOT NULL UNIQUE,
+    name VARCHAR(255) NOT NULL,
+    names JSONB NOT NULL DEFAULT '{}'::jsonb, -- Локализованные названия: {"ru": "...", "en": "..."}
+    sort_order INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_category_parent FOREIGN KEY(parent_id) REFERENCES categories(id) ON DELETE RESTRICT
+);
+
+CREATE INDEX idx_categories_parent_id ON categories(parent_id);
+CREATE TRIGGER update_categories_updated_at BEFORE UPDATE ON categories FOR EACH ROW EXECUTE PROCEDURE update_updated_at_column();
+
+
+-- Таблица атрибутов категорий (category_attributes)
+-- Атрибуты родительской категории наследуются дочерними.
+CREATE TABLE category_attributes (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    category_id UUID NOT NULL,
+    code VARCHAR(100) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    names JSONB NOT NULL DEFAULT '{}'::jsonb,
+    type VARCHAR(20) NOT NULL, -- string, number, boolean, enum, multi_enum
+    required BOOLEAN NOT NULL DEFAULT FALSE,
+    allowed_values TEXT[],
+    unit VARCHAR(20),
+    sort_order INTEGER NOT NULL DEFAULT 0,
+    CONSTRAINT fk_category FOREIGN KEY(category_id) REFERENCES categories(id) ON DELETE CASCADE,
+    CONSTRAINT uq_category_attribute_code UNIQUE(category_id, code),
+    CONSTRAINT chk_category_attribute_type CHECK (type IN ('string', 'number', 'boolean', 'enum', 'multi_enum'))
+);
+
+CREATE INDEX idx_category_attributes_category_id ON category_attributes(category_id);
+
+
+-- Значения атрибутов товара
+ALTER TABLE products ADD COLUMN attributes JSONB NOT NULL DEFAULT '{}'::jsonb;
+
+-- Администраторы ведут общий справочник категорий и схем атрибутов
+ALTER TABLE users ADD COLUMN is_admin BOOLEAN NOT NULL DEFAULT FALSE;
+
+
+-- Наполняем базовое дерево категорий, совпадающее со слагами из seed.sql
+INSERT INTO categories (id, parent_id, slug, name, names, sort_order) VALUES
+('0c000000-0000-0000-0000-000000000001', NULL, 'clothing', 'Одежда', '{"ru": "Одежда", "en": "Clothing"}', 1),
+('0c000000-0000-0000-0000-000000000002', NULL, 'shoes', 'Обувь', '{"ru": "Обувь", "en": "Shoes"}', 2),
+('0c000000-0000-0000-0000-000000000003', NULL, 'accessories', 'Аксессуары', '{"ru": "Аксессуары", "en": "Accessories"}', 3),
+('0c000000-0000-0000-0000-000000000011', '0c000000-0000-0000-0000-000000000001', 'coats', 'Пальто', '{"ru": "Пальто", "en": "Coats"}', 1),
+('0c000000-0000-0000-0000-000000000012', '0c000000-0000-0000-0000-000000000001', 'sweaters', 'Свитеры', '{"ru": "Свитеры", "en": "Sweaters"}', 2),
+('0c000000-0000-0000-0000-000000000013', '0c000000-0000-0000-0000-000000000001', 'jeans', 'Джинсы', '{"ru": "Джинсы", "en": "Jeans"}', 3),
+('0c000000-0000-0000-0000-000000000014', '0c000000-0000-0000-0000-000000000001', 't-shirts', 'Футболки', '{"ru": "Футболки", "en": "T-shirts"}', 4),
+('0c000000-0000-0000-0000-000000000015', '0c000000-0000-0000-0000-000000000001', 'leggings', 'Леггинсы', '{"ru": "Леггинсы", "en": "Leggings"}', 5),
+('0c000000-0000-0000-0000-000000000016', '0c000000-0000-0000-0000-000000000001', 'dresses', 'Платья', '{"ru": "Платья", "en": "Dresses"}', 6),
+('0c000000-0000-0000-0000-000000000021', '0c000000-0000-0000-0000-000000000002', 'sneakers', 'Кроссовки', '{"ru": "Кроссовки", "en": "Sneakers"}', 1),
+('0c000000-0000-0000-0000-000000000022', '0c000000-0000-0000-0000-000000000002', 'boots', 'Сапоги', '{"ru": "Сапоги", "en": "Boots"}', 2);
+
+-- Категории, которые уже используются в товарах, но отсутствуют в базовом дереве.
+-- Без них существующие товары не прошли бы проверку категории при сохранении
+INSERT INTO categories (parent_id, slug, name)
+SELECT DISTINCT NULL::uuid, p.category, p.category
+FROM products p
+WHERE COALESCE(p.category, '') <> ''
+ON CONFLICT (slug) DO NOTHING;
+
+INSERT INTO categories (parent_id, slug, name)
+SELECT DISTINCT ON (p.subcategory) c.id, p.subcategory, p.subcategory
+FROM products p
+JOIN categories c ON c.slug = p.category
+WHERE COALESCE(p.subcategory, '') <> ''
+ORDER BY p.subcategory, c.id
+ON CONFLICT (slug) DO NOTHING;
+
+INSERT INTO category_attributes (category_id, code, name, names, type, required, allowed_values, unit, sort_order) VALUES
+('0c000000-0000-0000-0000-000000000001', 'season', 'Сезон', '{"ru": "Сезон", "en": "Season"}', 'enum', FALSE, ARRAY['winter', 'spring', 'summer', 'autumn', 'all_season'], NULL, 1),
+('0c000000-0000-0000-0000-000000000001', 'sleeve_length', 'Длина рукава', '{"ru": "Длина рукава", "en": "Sleeve length"}', 'enum', FALSE, ARRAY['sleeveless', 'short', 'three_quarter', 'long'], NULL, 2),
+('0c000000-0000-0000-0000-000000000013', 'rise', 'Посадка', '{"ru": "Посадка", "en": "Rise"}', 'enum', FALSE, ARRAY['low', 'mid', 'high'], NULL, 1),
+('0c000000-0000-0000-0000-000000000002', 'heel_height', 'Высота каблука', '{"ru": "Высота каблука", "en": "Heel height"}', 'number', FALSE, NULL, 'cm', 1),
+('0c000000-0000-0000-0000-000000000002', 'upper_material', 'Материал верха', '{"ru": "Материал верха", "en": "Upper material"}', 'string', FALSE, NULL, NULL, 2);
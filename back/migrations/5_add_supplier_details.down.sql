@@ -0,0 +1,13 @@
+-- +migrate Down
+
+DROP TRIGGER IF EXISTS update_suppliers_updated_at ON suppliers;
+DROP INDEX IF EXISTS idx_products_supplier_id;
+DROP INDEX IF EXISTS idx_suppliers_user_id;
+ALTER TABLE suppliers DROP CONSTRAINT IF EXISTS fk_suppliers_user;
+
+ALTER TABLE suppliers DROP COLUMN IF EXISTS updated_at;
+ALTER TABLE suppliers DROP COLUMN IF EXISTS created_at;
+ALTER TABLE suppliers DROP COLUMN IF EXISTS notes;
+ALTER TABLE suppliers DROP COLUMN IF EXISTS phone;
+ALTER TABLE suppliers DROP COLUMN IF EXISTS email;
+ALTER TABLE suppliers DROP COLUMN IF EXISTS user_id;
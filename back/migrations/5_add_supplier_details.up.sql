@@ -0,0 +1,54 @@
+-- +migrate Up
+
+-- Поставщики принадлежат продавцу и получают контактные данные
+ALTER TABLE suppliers ADD COLUMN user_id UUID;
+ALTER TABLE suppliers ADD COLUMN email VARCHAR(255);
+ALTER TABLE suppliers ADD COLUMN phone VARCHAR(50);
+ALTER TABLE suppliers ADD COLUMN notes TEXT;
+ALTER TABLE suppliers ADD COLUMN created_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+ALTER TABLE suppliers ADD COLUMN updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+ALTER TABLE suppliers ADD CONSTRAINT fk_suppliers_user
+    FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE;
+
+CREATE INDEX idx_suppliers_user_id ON suppliers(user_id);
+CREATE INDEX idx_products_supplier_id ON products(supplier_id);
+CREATE TRIGGER update_suppliers_updated_at BEFORE UPDATE ON suppliers FOR EACH ROW EXECUTE PROCEDURE update_updated_at_column();
+
+-- Существующие поставщики переходят к продавцу, чьи товары они поставляют.
+-- Если поставщик общий у нескольких продавцов, остальные получают собственную копию
+DO $$
+DECLARE
+    r RECORD;
+    copy_id UUID;
+BEGIN
+    FOR r IN
+        SELECT DISTINCT supplier_id, user_id FROM products
+        WHERE supplier_id IS NOT NULL AND user_id IS NOT NULL
+        ORDER BY supplier_id, user_id
+    LOOP
+        UPDATE suppliers SET user_id = r.user_id WHERE id = r.supplier_id AND user_id IS NULL;
+        IF NOT FOUND AND NOT EXISTS (SELECT 1 FROM suppliers WHERE id = r.supplier_id AND user_id = r.user_id) THEN
+            INSERT INTO suppliers (name, contact, user_id)
+            SELECT name, contact, r.user_id FROM suppliers WHERE id = r.supplier_id
+            RETURNING id INTO copy_id;
+            UPDATE products SET supplier_id = copy_id
+            WHERE supplier_id = r.supplier_id AND user_id = r.user_id;
+        END IF;
+    END LOOP;
+END $$;
+
+-- Поставщики без товаров не принадлежат ни одному продавцу: они удаляются с отчетом в лог миграции
+DO $$
+DECLARE
+    orphans TEXT;
+BEGIN
+    SELECT string_agg(name || ' (' || id || ')', ', ') INTO orphans FROM suppliers WHERE user_id IS NULL;
+    IF orphans IS NOT NULL THEN
+        RAISE NOTICE 'Удалены поставщики без продавца: %', orphans;
+        DELETE FROM suppliers WHERE user_id IS NULL;
+    END IF;
+END $$;
+
+ALTER TABLE suppliers ALTER COLUMN user_id SET NOT NULL;
+
@@ -0,0 +1,14 @@
+-- +migrate Down
+
+DELETE FROM category_attributes WHERE code = 'size' AND category_id IN (
+    '0c000000-0000-0000-0000-000000000001', '0c000000-0000-0000-0000-000000000013',
+    '0c000000-0000-0000-0000-000000000002', '0c000000-0000-0000-0000-000000000003'
+);
+
+ALTER TABLE products DROP COLUMN IF EXISTS archived_at;
+ALTER TABLE products DROP COLUMN IF EXISTS published_at;
+
+ALTER TABLE products DROP CONSTRAINT IF EXISTS chk_products_status;
+
+UPDATE products SET status = 'inactive' WHERE status = 'archived';
+UPDATE products SET status = 'draft' WHERE status = 'ready';
@@ -0,0 +1,22 @@
+-- +migrate Up
+
+-- Жизненный цикл товара: draft → ready → active → archived
+-- Старый статус inactive соответствует снятому с продажи товару
+UPDATE products SET status = 'archived' WHERE status = 'inactive';
+UPDATE products SET status = 'draft' WHERE status NOT IN ('draft', 'ready', 'active', 'archived');
+
+ALTER TABLE products ADD CONSTRAINT chk_products_status
+    CHECK (status IN ('draft', 'ready', 'active', 'archived'));
+
+-- Время публикации и архивации
+ALTER TABLE products ADD COLUMN published_at TIMESTAMPTZ;
+ALTER TABLE products ADD COLUMN archived_at TIMESTAMPTZ;
+
+-- Размерные сетки базовых категорий для проверки готовности: атрибут size, его допустимые
+-- значения - размеры вариантов. Джинсы переопределяют сетку одежды размерами по талии
+INSERT INTO category_attributes (category_id, code, name, names, type, required, allowed_values, sort_order) VALUES
+('0c000000-0000-0000-0000-000000000001', 'size', 'Размер', '{"ru": "Размер", "en": "Size"}', 'enum', FALSE, ARRAY['XXS', 'XS', 'S', 'M', 'L', 'XL', 'XXL', '3XL'], 0),
+('0c000000-0000-0000-0000-000000000013', 'size', 'Размер', '{"ru": "Размер", "en": "Size"}', 'enum', FALSE, ARRAY['24', '25', '26', '27', '28', '29', '30', '31', '32', '33', '34', '36', '38', '40'], 0),
+('0c000000-0000-0000-0000-000000000002', 'size', 'Размер', '{"ru": "Размер", "en": "Size"}', 'enum', FALSE, ARRAY['34', '35', '36', '37', '38', '39', '40', '41', '42', '43', '44', '45', '46', '47'], 0),
+('0c000000-0000-0000-0000-000000000003', 'size', 'Размер', '{"ru": "Размер", "en": "Size"}', 'enum', FALSE, ARRAY['ONE SIZE'], 0)
+ON CONFLICT (category_id, code) DO NOTHING;
@@ -0,0 +1,10 @@
+-- +migrate Down
+
+DROP INDEX IF EXISTS uq_products_sku_active;
+
+-- Удаленные товары удаляем окончательно, иначе уникальность артикула не восстановить
+DELETE FROM products WHERE deleted_at IS NOT NULL;
+ALTER TABLE products ADD CONSTRAINT products_sku_key UNIQUE (sku);
+
+DROP INDEX IF EXISTS idx_products_deleted_at;
+ALTER TABLE products DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,9 @@
+-- +migrate Up
+
+-- Мягкое удаление товаров: строка остается для истории заказов и аналитики
+ALTER TABLE products ADD COLUMN deleted_at TIMESTAMPTZ;
+CREATE INDEX idx_products_deleted_at ON products(deleted_at);
+
+-- Артикул должен быть уникален только среди неудаленных товаров
+ALTER TABLE products DROP CONSTRAINT IF EXISTS products_sku_key;
+CREATE UNIQUE INDEX uq_products_sku_active ON products(sku) WHERE deleted_at IS NULL;
@@ -0,0 +1,18 @@
+-- +migrate Up
+
+-- История изменений карточек товаров: полный снимок после каждого изменения
+CREATE TABLE product_versions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    product_id UUID NOT NULL,
+    version INTEGER NOT NULL,
+    user_id UUID,
+    action VARCHAR(50) NOT NULL,
+    comment TEXT,
+    snapshot JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_product FOREIGN KEY(product_id) REFERENCES products(id) ON DELETE CASCADE,
+    CONSTRAINT fk_user FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE SET NULL,
+    CONSTRAINT uq_product_version UNIQUE(product_id, version)
+);
+
+CREATE INDEX idx_product_versions_product_id ON product_versions(product_id);
@@ -0,0 +1,4 @@
+-- +migrate Down
+
+DROP TABLE IF EXISTS price_history;
+DROP TABLE IF EXISTS price_schedules;
@@ -0,0 +1,65 @@
+-- +migrate Up
+
+-- История цен: запись появляется при каждом изменении цены, себестоимости или скидки.
+-- variant_id IS NULL - цена товара целиком, иначе - собственная цена варианта.
+CREATE TABLE price_history (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    product_id UUID NOT NULL,
+    variant_id UUID,
+    price NUMERIC(12, 2) NOT NULL,
+    cost_price NUMERIC(12, 2),
+    discount_percent NUMERIC(5, 2) NOT NULL DEFAULT 0,
+    source VARCHAR(50) NOT NULL,
+    schedule_id UUID,
+    user_id UUID,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_product FOREIGN KEY(product_id) REFERENCES products(id) ON DELETE CASCADE,
+    CONSTRAINT fk_variant FOREIGN KEY(variant_id) REFERENCES product_variants(id) ON DELETE CASCADE,
+    CONSTRAINT fk_user FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX idx_price_history_product_id ON price_history(product_id, created_at);
+CREATE INDEX idx_price_history_variant_id ON price_history(variant_id, created_at);
+
+-- Запланированные изменения цены, которые применяет фоновая задача
+CREATE TABLE price_schedules (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    product_id UUID NOT NULL,
+    variant_id UUID,
+    user_id UUID,
+    price NUMERIC(12, 2),
+    discount_percent NUMERIC(5, 2),
+    starts_at TIMESTAMPTZ NOT NULL,
+    ends_at TIMESTAMPTZ,
+    revert_on_end BOOLEAN NOT NULL DEFAULT FALSE,
+    status VARCHAR(50) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'active', 'completed', 'cancelled')),
+    previous_price NUMERIC(12, 2),
+    previous_discount_percent NUMERIC(5, 2),
+    applied_at TIMESTAMPTZ,
+    completed_at TIMESTAMPTZ,
+    comment TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_product FOREIGN KEY(product_id) REFERENCES products(id) ON DELETE CASCADE,
+    CONSTRAINT fk_variant FOREIGN KEY(variant_id) REFERENCES product_variants(id) ON DELETE CASCADE,
+    CONSTRAINT fk_user FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE SET NULL,
+    CONSTRAINT chk_price_schedule_target CHECK (price IS NOT NULL OR discount_percent IS NOT NULL),
+    CONSTRAINT chk_price_schedule_period CHECK (ends_at IS NULL OR ends_at > starts_at)
+);
+
+CREATE INDEX idx_price_schedules_product_id ON price_schedules(product_id);
+CREATE INDEX idx_price_schedules_due ON price_schedules(status, starts_at);
+
+CREATE TRIGGER update_price_schedules_updated_at BEFORE UPDATE ON price_schedules FOR EACH ROW EXECUTE PROCEDURE update_updated_at_column();
+
+ALTER TABLE price_history ADD CONSTRAINT fk_schedule FOREIGN KEY(schedule_id) REFERENCES price_schedules(id) ON DELETE SET NULL;
+
+-- Начальная точка истории для существующих товаров и вариантов с собственной ценой
+INSERT INTO price_history (product_id, price, cost_price, discount_percent, source, created_at)
+SELECT id, price, cost_price, COALESCE(discount_percent, 0), 'initial', created_at FROM products;
+
+INSERT INTO price_history (product_id, variant_id, price, source, created_at)
+SELECT pv.product_id, pv.id, pv.price, 'initial', p.created_at
+FROM product_variants pv
+JOIN products p ON p.id = pv.product_id
+WHERE pv.price IS NOT NULL AND pv.price > 0;